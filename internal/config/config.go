@@ -3,3 +3,11 @@ package config
 var Protocol = "tcp"
 var Port = ":3000"
 var MaxConnection = 20000
+
+// a sorted set is converted from listpack to skiplist when one of these thresholds is crossed
+var ZSetMaxListPackEntries = 128
+var ZSetMaxListPackValue = 64
+
+// a new block of a stream is started when the last one holds this many entries or bytes
+var StreamNodeMaxEntries = 100
+var StreamNodeMaxBytes = 4096
@@ -3,6 +3,7 @@ package constant
 import "time"
 
 var RespNil = []byte("$-1\r\n")
+var RespNilArray = []byte("*-1\r\n")
 var RespOk = []byte("+OK\r\n")
 var TtlKeyNotExist = []byte(":-2\r\n")
 var TtlKeyExistNotExpired = []byte(":-1\r\n")
@@ -12,3 +13,35 @@ const ThresholdToStopActiveDelete = 0.1
 const ActiveDeleteFrequency = 100 * time.Millisecond
 
 const SkipListMaxLevel = 32
+
+const QuickListNodeMaxSize = 8 * 1024 // max size in bytes of the packed entries of a quicklist node
+
+// a hash is converted from listpack to hash table when one of these thresholds is crossed
+const HashMaxListPackEntries = 128
+const HashMaxListPackValue = 64
+
+// a set of integers is converted from intset to hash table when it holds more members than this
+const SetMaxIntSetEntries = 512
+
+const HashFieldExpireTimeMax = 1<<48 - 1 // max expiration time of a hash field (unix time in milliseconds)
+
+const StringMaxSize = 512 * 1024 * 1024 // 512MB, same as Redis's default proto-max-bulk-len
+
+// the parameters of a bloom filter created implicitly by BF.ADD, BF.MADD or BF.INSERT
+const BloomDefaultErrorRate = 0.01
+const BloomDefaultCapacity = 100
+const BloomDefaultExpansion = 2
+
+// the parameters of a cuckoo filter created implicitly by CF.ADD, CF.ADDNX, CF.INSERT or CF.INSERTNX
+const CuckooDefaultCapacity = 1024
+const CuckooDefaultBucketSize = 2
+const CuckooDefaultMaxIterations = 20
+const CuckooDefaultExpansion = 1
+
+// the parameters of a Top-K created by TOPK.RESERVE without width, depth and decay
+const TopKDefaultWidth = 8
+const TopKDefaultDepth = 7
+const TopKDefaultDecay = 0.9
+
+// the compression of a t-digest created without the COMPRESSION option
+const TDigestDefaultCompression = 100
@@ -0,0 +1,280 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"math"
+	"mtredis/internal/constant"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+/*
+A client blocked by a command such as BLPOP until one of its keys can serve it, or until its timeout is reached.
+serve tries to execute the command against the given key, it returns nil when the key can not serve the client yet.
+*/
+type blockedClient struct {
+	fd       int
+	keys     []string
+	deadline time.Time // zero value means the client blocks forever
+	keyType  int       // the type of value the client waits for: blockedOnList, blockedOnZSet or blockedOnStream
+	serve    func(key string) []byte
+}
+
+const (
+	blockedOnList = iota
+	blockedOnZSet
+	blockedOnStream
+)
+
+// the key holds a value of the type the client waits for
+func (c *blockedClient) keyHoldsType(key string) bool {
+	var exist bool
+	switch c.keyType {
+	case blockedOnList:
+		_, exist = listStore[key]
+	case blockedOnZSet:
+		_, exist = zSetStore[key]
+	default:
+		_, exist = streamStore[key]
+	}
+
+	return exist
+}
+
+// serving the client takes data from the key (BLPOP), unlike XREAD that only reads it
+func (c *blockedClient) consumes() bool {
+	return c.keyType != blockedOnStream
+}
+
+var blockedClients = make(map[int]*blockedClient)    // fd => blocked client
+var blockingKeys = make(map[string][]*blockedClient) // key => clients blocked on the key, in FIFO order
+var readyKeys = make([]string, 0)                    // keys that received data since the last time blocked clients were served
+var readyKeySet = make(map[string]struct{})
+
+/*
+Like Redis that stops reading from a blocked client, the commands a client sends while it is blocked are queued,
+they run once the client is unblocked by a reply.
+*/
+var pendingCommands = make(map[int][]*Command) // fd => commands received while the client is blocked
+var unblockedClients = make([]int, 0)          // fds of the clients unblocked with pending commands
+var processingUnblockedClients = false
+
+/*
+Parse a blocking timeout given in seconds (fractional values are allowed).
+It returns the deadline, or the zero time when the timeout is 0 (block forever).
+*/
+func parseBlockingTimeout(s string) (time.Time, error) {
+	timeout, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(timeout) || math.IsInf(timeout, 0) {
+		return time.Time{}, errors.New("(error) timeout is not a float or out of range")
+	}
+	if timeout < 0 {
+		return time.Time{}, errors.New("(error) timeout is negative")
+	}
+	if timeout > float64(math.MaxInt64/int64(time.Second)) {
+		return time.Time{}, errors.New("(error) timeout is out of range")
+	}
+	if timeout == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Now().Add(time.Duration(timeout * float64(time.Second))), nil
+}
+
+/*
+Try to serve the client right away with the first key that can serve it.
+If none of the keys can, the client is blocked and nil is returned: the reply is sent later, when a key gets ready or when the timeout is reached.
+keyType is the type of value the client waits for, see handleClientsBlockedOnKeys.
+*/
+func blockForKeys(fd int, keys []string, deadline time.Time, keyType int, serve func(key string) []byte) []byte {
+	for _, key := range keys {
+		if res := serve(key); res != nil {
+			return res
+		}
+	}
+
+	client := &blockedClient{
+		fd:       fd,
+		keys:     make([]string, 0, len(keys)),
+		deadline: deadline,
+		keyType:  keyType,
+		serve:    serve,
+	}
+
+	seen := make(map[string]struct{})
+	for _, key := range keys {
+		if _, exist := seen[key]; exist {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		client.keys = append(client.keys, key)
+		blockingKeys[key] = append(blockingKeys[key], client)
+	}
+	blockedClients[fd] = client
+
+	return nil
+}
+
+// remove the client from the blocking queues of all its keys
+func unblockClient(client *blockedClient) {
+	delete(blockedClients, client.fd)
+
+	for _, key := range client.keys {
+		queue := blockingKeys[key]
+		for i, c := range queue {
+			if c == client {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+
+		if len(queue) == 0 {
+			delete(blockingKeys, key)
+		} else {
+			blockingKeys[key] = queue
+		}
+	}
+}
+
+// mark the key as ready when some clients are blocked on it, it is called by the commands that add data to a key
+func signalKeyAsReady(key string) {
+	if _, blocked := blockingKeys[key]; !blocked {
+		return
+	}
+	if _, exist := readyKeySet[key]; exist {
+		return
+	}
+
+	readyKeySet[key] = struct{}{}
+	readyKeys = append(readyKeys, key)
+}
+
+/*
+Serve the clients blocked on the keys that got ready.
+For each key, clients are served in the order they were blocked, until the key can not serve a consuming client
+waiting for its type anymore. The queue goes on after a client waiting for another type (BZPOPMIN on a list),
+and after a client that only reads the key (XREAD), since the next ones may still be served.
+Serving a client may make other keys ready (e.g. BLMOVE pushes to its destination), so this runs until there is no ready key left.
+*/
+func handleClientsBlockedOnKeys() {
+	for len(readyKeys) > 0 {
+		keys := readyKeys
+		readyKeys = make([]string, 0)
+		readyKeySet = make(map[string]struct{})
+
+		for _, key := range keys {
+			queue := append([]*blockedClient(nil), blockingKeys[key]...)
+			for _, client := range queue {
+				res := client.serve(key)
+				if res == nil {
+					if client.consumes() && client.keyHoldsType(key) {
+						break
+					}
+					continue
+				}
+
+				unblockClient(client)
+				if _, err := syscall.Write(client.fd, res); err != nil {
+					log.Printf("write error: %v", err)
+				}
+				queueUnblockedClient(client.fd)
+			}
+		}
+	}
+}
+
+// queue the command if the client is blocked, it runs once the client is unblocked
+func queueIfBlocked(cmd *Command, fd int) bool {
+	if _, blocked := blockedClients[fd]; !blocked {
+		return false
+	}
+
+	pendingCommands[fd] = append(pendingCommands[fd], cmd)
+
+	return true
+}
+
+func queueUnblockedClient(fd int) {
+	if _, exist := pendingCommands[fd]; exist {
+		unblockedClients = append(unblockedClients, fd)
+	}
+}
+
+/*
+Run the pending commands of the unblocked clients, until a command blocks its client again.
+The commands are run once the blocked clients are served, not while they are, and a nested call does nothing:
+the clients unblocked by these commands are appended to the list that the outer call is processing.
+*/
+func processUnblockedClients() {
+	if processingUnblockedClients {
+		return
+	}
+	processingUnblockedClients = true
+	defer func() { processingUnblockedClients = false }()
+
+	for len(unblockedClients) > 0 {
+		fd := unblockedClients[0]
+		unblockedClients = unblockedClients[1:]
+
+		for len(pendingCommands[fd]) > 0 {
+			if _, blocked := blockedClients[fd]; blocked {
+				break
+			}
+			cmd := pendingCommands[fd][0]
+			pendingCommands[fd] = pendingCommands[fd][1:]
+			if err := ExecuteAndResponse(cmd, fd); err != nil {
+				log.Printf("write error: %v", err)
+			}
+		}
+		if len(pendingCommands[fd]) == 0 {
+			delete(pendingCommands, fd)
+		}
+	}
+}
+
+// reply to the clients whose timeout is reached, it is called periodically by the server's event loop
+func HandleBlockedClientsTimeout() {
+	now := time.Now()
+	for _, client := range blockedClients {
+		if client.deadline.IsZero() || client.deadline.After(now) {
+			continue
+		}
+
+		unblockClient(client)
+		if _, err := syscall.Write(client.fd, constant.RespNilArray); err != nil {
+			log.Printf("write error: %v", err)
+		}
+		queueUnblockedClient(client.fd)
+	}
+
+	processUnblockedClients()
+}
+
+// get the earliest deadline of the blocked clients, false when no client is waiting with a timeout
+func NextBlockedClientDeadline() (time.Time, bool) {
+	var res time.Time
+	found := false
+	for _, client := range blockedClients {
+		if client.deadline.IsZero() {
+			continue
+		}
+
+		if !found || client.deadline.Before(res) {
+			res = client.deadline
+			found = true
+		}
+	}
+
+	return res, found
+}
+
+// forget a disconnected client, it must not be served anymore
+func RemoveBlockedClient(fd int) {
+	if client, exist := blockedClients[fd]; exist {
+		unblockClient(client)
+	}
+	delete(pendingCommands, fd)
+}
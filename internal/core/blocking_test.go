@@ -0,0 +1,229 @@
+package core
+
+import (
+	"mtredis/internal/constant"
+	"testing"
+	"time"
+)
+
+func TestBLPOPServedRightAway(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("RPUSH b x y"), reply(2)},
+		{args("BLPOP a b 0"), reply([]string{"b", "x"})},
+		{args("BRPOP a b 0"), reply([]string{"b", "y"})},
+		{args("OBJECT ENCODING b"), nilReply},
+		{args("BLPOP a -1"), errReply("timeout is negative")},
+		{args("BLPOP a abc"), errReply("timeout is not a float or out of range")},
+		{args("BLPOP a 1e300"), errReply("timeout is out of range")},
+		{args("BLPOP 0"), errReply("wrong number of arguments for 'BLPOP' command")},
+	})
+}
+
+func TestBLPOPServedByPush(t *testing.T) {
+	resetStores(t)
+
+	first, second, pusher := newTestClient(t), newTestClient(t), newTestClient(t)
+	if got := first.do("BLPOP", "a", "l", "0"); got != "" {
+		t.Fatalf("BLPOP on missing keys = %q, want the client blocked", got)
+	}
+	if got := second.do("BRPOP", "l", "0"); got != "" {
+		t.Fatalf("BRPOP on a missing key = %q, want the client blocked", got)
+	}
+
+	// the push replies with the length before the blocked clients are served
+	if got := pusher.do("RPUSH", "l", "x", "y", "z"); got != reply(3) {
+		t.Fatalf("RPUSH = %q, want 3", got)
+	}
+
+	// the clients are served in the order they blocked
+	if got := first.read(); got != reply([]string{"l", "x"}) {
+		t.Errorf("first client got %q, want [l x]", got)
+	}
+	if got := second.read(); got != reply([]string{"l", "z"}) {
+		t.Errorf("second client got %q, want [l z]", got)
+	}
+	if got := pusher.do("LRANGE", "l", "0", "-1"); got != reply([]string{"y"}) {
+		t.Errorf("LRANGE = %q, want [y]", got)
+	}
+	if _, blocked := blockedClients[first.wr]; blocked {
+		t.Errorf("the served client is still blocked")
+	}
+	if len(blockingKeys) != 0 {
+		t.Errorf("blockingKeys = %v, want no key left", blockingKeys)
+	}
+}
+
+func TestBlockingTimeout(t *testing.T) {
+	resetStores(t)
+
+	c, forever := newTestClient(t), newTestClient(t)
+	if _, found := NextBlockedClientDeadline(); found {
+		t.Fatalf("NextBlockedClientDeadline found a deadline without any blocked client")
+	}
+
+	forever.do("BLPOP", "l", "0")
+	c.do("BLPOP", "l", "0.01")
+	deadline, found := NextBlockedClientDeadline()
+	if !found || time.Until(deadline) > 10*time.Millisecond {
+		t.Fatalf("NextBlockedClientDeadline = %v, %v, want the deadline in 10ms", deadline, found)
+	}
+
+	HandleBlockedClientsTimeout()
+	if got := c.read(); got != "" {
+		t.Fatalf("the client got %q before its timeout", got)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	HandleBlockedClientsTimeout()
+	if got := c.read(); got != string(constant.RespNilArray) {
+		t.Fatalf("the client got %q after its timeout, want a nil array", got)
+	}
+	if got := forever.read(); got != "" {
+		t.Fatalf("the client without a timeout got %q", got)
+	}
+	if _, found := NextBlockedClientDeadline(); found {
+		t.Fatalf("NextBlockedClientDeadline found a deadline after the timeout")
+	}
+}
+
+func TestRemoveBlockedClient(t *testing.T) {
+	resetStores(t)
+
+	gone, c := newTestClient(t), newTestClient(t)
+	gone.do("BLPOP", "l", "0")
+	RemoveBlockedClient(gone.wr)
+
+	// the element is not popped for the disconnected client
+	c.do("RPUSH", "l", "x")
+	if got := gone.read(); got != "" {
+		t.Fatalf("the removed client got %q", got)
+	}
+	if got := c.do("LLEN", "l"); got != reply(1) {
+		t.Fatalf("LLEN = %q, want 1", got)
+	}
+}
+
+func TestCommandsQueuedWhileBlocked(t *testing.T) {
+	resetStores(t)
+
+	c, pusher := newTestClient(t), newTestClient(t)
+	c.do("BLPOP", "l", "0")
+	// the second BLPOP and PING run once the client is unblocked, the BLPOP blocks it again
+	for _, line := range []string{"BLPOP m 0", "PING"} {
+		if got := c.do(args(line)...); got != "" {
+			t.Fatalf("%s from a blocked client = %q, want it queued", line, got)
+		}
+	}
+
+	pusher.do("RPUSH", "l", "x")
+	if got := c.read(); got != reply([]string{"l", "x"}) {
+		t.Fatalf("after RPUSH l: got %q, want only the BLPOP l reply", got)
+	}
+
+	pusher.do("RPUSH", "m", "y")
+	if got, want := c.read(), reply([]string{"m", "y"})+reply("PONG"); got != want {
+		t.Fatalf("after RPUSH m: got %q, want %q", got, want)
+	}
+	if len(pendingCommands) != 0 || len(blockedClients) != 0 {
+		t.Fatalf("pendingCommands = %v, blockedClients = %v, want both empty", pendingCommands, blockedClients)
+	}
+}
+
+func TestCommandsQueuedUntilTimeout(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("BLPOP", "l", "0.01")
+	c.do("PING")
+
+	time.Sleep(20 * time.Millisecond)
+	HandleBlockedClientsTimeout()
+	if got, want := c.read(), string(constant.RespNilArray)+reply("PONG"); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestBlockedClientOfAnotherTypeIsSkipped(t *testing.T) {
+	resetStores(t)
+
+	zPop, lPop, pusher := newTestClient(t), newTestClient(t), newTestClient(t)
+	zPop.do("BZPOPMIN", "k", "0")
+	lPop.do("BLPOP", "k", "0")
+
+	// the list can not serve BZPOPMIN, the next client in the queue is served
+	pusher.do("RPUSH", "k", "x")
+	if got := lPop.read(); got != reply([]string{"k", "x"}) {
+		t.Fatalf("BLPOP client got %q, want [k x]", got)
+	}
+	if got := zPop.read(); got != "" {
+		t.Fatalf("BZPOPMIN client got %q from a list", got)
+	}
+
+	pusher.do("ZADD", "k", "1", "a")
+	if got := zPop.read(); got != reply([]string{"k", "a", "1"}) {
+		t.Fatalf("BZPOPMIN client got %q, want [k a 1]", got)
+	}
+}
+
+func TestBLMOVEServesTheNextKey(t *testing.T) {
+	resetStores(t)
+
+	popper, mover, pusher := newTestClient(t), newTestClient(t), newTestClient(t)
+	popper.do("BLPOP", "dst", "0")
+	mover.do("BLMOVE", "src", "dst", "LEFT", "RIGHT", "0")
+
+	pusher.do("RPUSH", "src", "x")
+	if got := mover.read(); got != reply("x") {
+		t.Fatalf("BLMOVE client got %q, want x", got)
+	}
+	// the element moved to dst serves the client blocked on it
+	if got := popper.read(); got != reply([]string{"dst", "x"}) {
+		t.Fatalf("BLPOP client got %q, want [dst x]", got)
+	}
+	if got := pusher.do("LLEN", "dst"); got != reply(0) {
+		t.Fatalf("LLEN dst = %q, want 0", got)
+	}
+}
+
+func TestBLMPOP(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("RPUSH b 1 2 3"), reply(3)},
+		{args("BLMPOP 0 2 a b LEFT COUNT 2"), reply([]interface{}{"b", []string{"1", "2"}})},
+		{args("BLMPOP 0 2 a b RIGHT"), reply([]interface{}{"b", []string{"3"}})},
+		{args("BLMPOP 0 0 a LEFT"), errReply("numkeys should be greater than 0")},
+		{args("BLMPOP 0 3 a b LEFT"), errReply("syntax error")},
+		{args("BLMPOP 0 1 a LEFT COUNT 0"), errReply("count should be greater than 0")},
+		{args("BLMPOP 0 1 a UP"), errReply("syntax error")},
+	})
+
+	c, pusher := newTestClient(t), newTestClient(t)
+	if got := c.do(args("BLMPOP 0 1 a RIGHT COUNT 5")...); got != "" {
+		t.Fatalf("BLMPOP on a missing key = %q, want the client blocked", got)
+	}
+	pusher.do("RPUSH", "a", "x", "y")
+	if got := c.read(); got != reply([]interface{}{"a", []string{"y", "x"}}) {
+		t.Fatalf("BLMPOP client got %q, want [a [y x]]", got)
+	}
+}
+
+func TestBZPOP(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD z 1 a 2 b 3 c"), reply(3)},
+		{args("BZPOPMIN z 0"), reply([]string{"z", "a", "1"})},
+		{args("BZPOPMAX other z 0"), reply([]string{"z", "c", "3"})},
+		{args("BZPOPMIN z -1"), errReply("timeout is negative")},
+	})
+
+	c, adder := newTestClient(t), newTestClient(t)
+	c.do("BZPOPMAX", "y", "0")
+	adder.do("ZADD", "y", "1.5", "m", "2.5", "n")
+	if got := c.read(); got != reply([]string{"y", "n", "2.5"}) {
+		t.Fatalf("BZPOPMAX client got %q, want [y n 2.5]", got)
+	}
+}
@@ -3,9 +3,11 @@ package core
 import (
 	"errors"
 	"fmt"
+	"math"
 	"mtredis/internal/constant"
 	"mtredis/internal/data_structure"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -27,29 +29,141 @@ func cmdPING(args []string) []byte {
 	return res
 }
 
-// cmd: SET key value [...]
+// cmd: SET key value [NX | XX] [GET] [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
 func cmdSET(args []string) []byte {
-	if len(args) < 2 || len(args) == 3 || len(args) > 4 {
+	if len(args) < 2 {
 		return Encode(errors.New("(error) wrong number of arguments for 'SET' command"))
 	}
 
-	var ttlMs int64 = -1
 	key, value := args[0], args[1]
+	opts, err := parseSetOptions(args[2:])
+	if err != nil {
+		return Encode(err)
+	}
+
+	oldObj := dictStore.GetObj(key)
 
-	if len(args) > 2 {
-		ttlSec, err := strconv.ParseInt(args[3], 10, 64)
-		if err != nil {
-			return Encode(errors.New("(error) value is not an integer or out of range"))
+	// the condition of NX or XX is not met, the key is left untouched
+	if (opts.nx && oldObj != nil) || (opts.xx && oldObj == nil) {
+		if opts.get && oldObj != nil {
+			return Encode(stringValue(oldObj))
 		}
 
-		ttlMs = ttlSec * 1000
+		return constant.RespNil
 	}
 
-	dictStore.SetObj(key, dictStore.NewObj(key, value, ttlMs))
+	dictStore.SetObj(key, dictStore.NewObj(key, newStringValue(value), -1))
+
+	// overwriting a key discards its old TTL unless KEEPTTL is given
+	if opts.expireAtMs > 0 {
+		dictStore.SetExpiryAt(key, uint64(opts.expireAtMs))
+	} else if !opts.keepTTL {
+		dictStore.DeleteExpiry(key)
+	}
+
+	if opts.get {
+		if oldObj == nil {
+			return constant.RespNil
+		}
+
+		return Encode(stringValue(oldObj))
+	}
 
 	return constant.RespOk
 }
 
+type setOptions struct {
+	nx         bool
+	xx         bool
+	get        bool
+	keepTTL    bool
+	expireAtMs int64 // absolute unix time in milliseconds, -1 when no expiration option is given
+}
+
+/*
+Parse the options of the SET command (everything after key and value).
+NX/XX and the expiration options (EX, PX, EXAT, PXAT, KEEPTTL) are mutually exclusive within their own group.
+*/
+func parseSetOptions(args []string) (*setOptions, error) {
+	opts := &setOptions{expireAtMs: -1}
+	hasExpire := false
+
+	for i := 0; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch opt {
+		case "NX":
+			if opts.xx {
+				return nil, errors.New("(error) syntax error")
+			}
+			opts.nx = true
+		case "XX":
+			if opts.nx {
+				return nil, errors.New("(error) syntax error")
+			}
+			opts.xx = true
+		case "GET":
+			opts.get = true
+		case "KEEPTTL":
+			if hasExpire {
+				return nil, errors.New("(error) syntax error")
+			}
+			opts.keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if hasExpire || opts.keepTTL || i+1 >= len(args) {
+				return nil, errors.New("(error) syntax error")
+			}
+			i++
+
+			expireAtMs, err := parseExpireTime(opt, args[i], "set")
+			if err != nil {
+				return nil, err
+			}
+
+			opts.expireAtMs = expireAtMs
+			hasExpire = true
+		default:
+			return nil, errors.New("(error) syntax error")
+		}
+	}
+
+	return opts, nil
+}
+
+/*
+Convert an expiration given in one of the units EX, PX, EXAT or PXAT to an absolute unix time in milliseconds.
+The value must be a positive integer and the result must not overflow int64.
+*/
+func parseExpireTime(unit string, value string, cmdName string) (int64, error) {
+	invalidErr := fmt.Errorf("(error) invalid expire time in '%s' command", cmdName)
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("(error) value is not an integer or out of range")
+	}
+	if n <= 0 {
+		return 0, invalidErr
+	}
+
+	// convert seconds to milliseconds
+	if unit == "EX" || unit == "EXAT" {
+		if n > math.MaxInt64/1000 {
+			return 0, invalidErr
+		}
+		n *= 1000
+	}
+
+	// relative time, add the current time
+	if unit == "EX" || unit == "PX" {
+		now := time.Now().UnixMilli()
+		if n > math.MaxInt64-now {
+			return 0, invalidErr
+		}
+		n += now
+	}
+
+	return n, nil
+}
+
 // cmd: GET key
 func cmdGET(args []string) []byte {
 	if len(args) != 1 {
@@ -66,7 +180,7 @@ func cmdGET(args []string) []byte {
 		return constant.RespNil
 	}
 
-	return Encode(obj.Value)
+	return Encode(stringValue(obj))
 }
 
 // cmd: TTL key
@@ -94,6 +208,61 @@ func cmdTTL(args []string) []byte {
 	return Encode(remainMs / 1000)
 }
 
+// get the encoding of the value stored at the key, false if the key does not exist
+func objectEncoding(key string) (string, bool) {
+	if obj := dictStore.GetObj(key); obj != nil && !dictStore.HasExpired(key) {
+		switch v := obj.Value.(type) {
+		case int64:
+			return "int", true
+		case string:
+			// like Redis, short strings are embedded in the object itself
+			if len(v) <= 44 {
+				return "embstr", true
+			}
+		}
+		return "raw", true
+	}
+
+	if _, exist := listStore[key]; exist {
+		return "quicklist", true
+	}
+	if hash, exist := getHash(key); exist {
+		return hash.Encoding(), true
+	}
+	if set, exist := setStore[key]; exist {
+		return set.Encoding(), true
+	}
+	if zSet, exist := zSetStore[key]; exist {
+		return zSet.Encoding(), true
+	}
+	if _, exist := streamStore[key]; exist {
+		return "stream", true
+	}
+
+	return "", false
+}
+
+// cmd: OBJECT ENCODING key
+func cmdOBJECT(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'OBJECT' command"))
+	}
+
+	if strings.ToUpper(args[0]) != "ENCODING" {
+		return Encode(errors.New("(error) unknown subcommand '" + args[0] + "'. Try OBJECT HELP."))
+	}
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'OBJECT|ENCODING' command"))
+	}
+
+	encoding, exist := objectEncoding(args[1])
+	if !exist {
+		return constant.RespNil
+	}
+
+	return Encode(encoding)
+}
+
 // cmd: SADD key member [member ...]
 func cmdSADD(args []string) []byte {
 	if len(args) < 2 {
@@ -114,18 +283,18 @@ func cmdSADD(args []string) []byte {
 
 // cmd: SREM key member [member ...]
 func cmdSREM(args []string) []byte {
-	if len(args) != 1 {
+	if len(args) < 2 {
 		return Encode(errors.New("(error) wrong number of arguments for 'SREM' command"))
 	}
 
 	key := args[0]
 	set, exist := setStore[key]
 	if !exist {
-		set = data_structure.CreateSimpleSet(key)
-		setStore[key] = set
+		return Encode(0)
 	}
 
 	count := set.Remove(args[1:]...)
+	deleteSetIfEmpty(key, set)
 
 	return Encode(count)
 }
@@ -160,44 +329,35 @@ func cmdSMEMBERS(args []string) []byte {
 	return Encode(set.Members())
 }
 
-// cmd: ZADD key score1 member1 [score2 member2 ...]
+// cmd: ZADD key [NX | XX] [GT | LT] [CH] [INCR] score member [score member ...]
 func cmdZADD(args []string) []byte {
 	if len(args) < 3 {
 		return Encode(errors.New("(error) wrong number of arguments for 'ZADD' command"))
 	}
 
 	key := args[0]
-	// ensure remaining arguments must be even
+	flags := zAddFlags{}
 	scoreIdx := 1
-	numScoreElementArgs := len(args) - scoreIdx
-	if numScoreElementArgs%2 == 1 || numScoreElementArgs == 0 {
-		return Encode(errors.New(fmt.Sprintf("(error) wrong number of (score, member) args: %d", numScoreElementArgs)))
-	}
-
-	zSet, exist := zSetStore[key]
-	if !exist {
-		zSet = data_structure.CreatZSet()
-		zSetStore[key] = zSet
-	}
-
-	// insert (score, member) pairs
-	count := 0
-	for i := scoreIdx; i < len(args); i += 2 {
-		member := args[i+1]
-		score, err := strconv.ParseFloat(args[i], 64)
-		if err != nil {
-			return Encode(errors.New("(error) score must be floating point number"))
-		}
-
-		res := zSet.Add(score, member)
-		if res != 1 {
-			return Encode(errors.New("(error) adding element failed"))
+	for ; scoreIdx < len(args); scoreIdx++ {
+		switch strings.ToUpper(args[scoreIdx]) {
+		case "NX":
+			flags.nx = true
+		case "XX":
+			flags.xx = true
+		case "GT":
+			flags.gt = true
+		case "LT":
+			flags.lt = true
+		case "CH":
+			flags.ch = true
+		case "INCR":
+			flags.incr = true
+		default:
+			return zAdd(key, args[scoreIdx:], &flags)
 		}
-
-		count++
 	}
 
-	return Encode(count)
+	return Encode(errors.New("(error) syntax error"))
 }
 
 // cmd: ZSCORE key member
@@ -213,32 +373,24 @@ func cmdZSCORE(args []string) []byte {
 	}
 
 	res, score := zSet.GetScore(member)
-	if res == 0 {
+	if res == -1 {
 		return constant.RespNil
 	}
 
-	return Encode(fmt.Sprintf("%f", score))
+	return Encode(formatScore(score))
 }
 
-// cmd: ZRANK key member [...]
+// cmd: ZRANK key member [WITHSCORE]
 func cmdZRANK(args []string) []byte {
-	if len(args) != 2 {
-		return Encode(errors.New("(error) wrong number of arguments for 'ZRANK' command"))
-	}
-
-	key, member := args[0], args[1]
-	zSet, exist := zSetStore[key]
-	if !exist {
-		return constant.RespNil
-	}
-
-	rank, _ := zSet.GetRank(member, false)
-
-	return Encode(rank)
+	return zRank(args, "ZRANK", false)
 }
 
 // given a Command, execute and respnse
 func ExecuteAndResponse(cmd *Command, connFd int) error {
+	if queueIfBlocked(cmd, connFd) {
+		return nil
+	}
+
 	var res []byte
 
 	// execute command
@@ -249,8 +401,148 @@ func ExecuteAndResponse(cmd *Command, connFd int) error {
 		res = cmdSET(cmd.Args)
 	case "GET":
 		res = cmdGET(cmd.Args)
+	case "INCR":
+		res = cmdINCR(cmd.Args)
+	case "DECR":
+		res = cmdDECR(cmd.Args)
+	case "INCRBY":
+		res = cmdINCRBY(cmd.Args)
+	case "DECRBY":
+		res = cmdDECRBY(cmd.Args)
+	case "INCRBYFLOAT":
+		res = cmdINCRBYFLOAT(cmd.Args)
+	case "APPEND":
+		res = cmdAPPEND(cmd.Args)
+	case "STRLEN":
+		res = cmdSTRLEN(cmd.Args)
+	case "GETRANGE":
+		res = cmdGETRANGE(cmd.Args)
+	case "SETRANGE":
+		res = cmdSETRANGE(cmd.Args)
+	case "GETDEL":
+		res = cmdGETDEL(cmd.Args)
+	case "GETEX":
+		res = cmdGETEX(cmd.Args)
+	case "MGET":
+		res = cmdMGET(cmd.Args)
+	case "MSET":
+		res = cmdMSET(cmd.Args)
+	case "MSETNX":
+		res = cmdMSETNX(cmd.Args)
+	case "SETNX":
+		res = cmdSETNX(cmd.Args)
+	case "SETEX":
+		res = cmdSETEX(cmd.Args)
+	case "PSETEX":
+		res = cmdPSETEX(cmd.Args)
+	case "LCS":
+		res = cmdLCS(cmd.Args)
+	case "SETBIT":
+		res = cmdSETBIT(cmd.Args)
+	case "GETBIT":
+		res = cmdGETBIT(cmd.Args)
+	case "BITCOUNT":
+		res = cmdBITCOUNT(cmd.Args)
+	case "BITPOS":
+		res = cmdBITPOS(cmd.Args)
+	case "BITOP":
+		res = cmdBITOP(cmd.Args)
+	case "BITFIELD":
+		res = cmdBITFIELD(cmd.Args)
+	case "BITFIELD_RO":
+		res = cmdBITFIELD_RO(cmd.Args)
 	case "TTL":
 		res = cmdTTL(cmd.Args)
+	case "OBJECT":
+		res = cmdOBJECT(cmd.Args)
+	case "LPUSH":
+		res = cmdLPUSH(cmd.Args)
+	case "RPUSH":
+		res = cmdRPUSH(cmd.Args)
+	case "LPUSHX":
+		res = cmdLPUSHX(cmd.Args)
+	case "RPUSHX":
+		res = cmdRPUSHX(cmd.Args)
+	case "LPOP":
+		res = cmdLPOP(cmd.Args)
+	case "RPOP":
+		res = cmdRPOP(cmd.Args)
+	case "LLEN":
+		res = cmdLLEN(cmd.Args)
+	case "LRANGE":
+		res = cmdLRANGE(cmd.Args)
+	case "LINDEX":
+		res = cmdLINDEX(cmd.Args)
+	case "LSET":
+		res = cmdLSET(cmd.Args)
+	case "LINSERT":
+		res = cmdLINSERT(cmd.Args)
+	case "LREM":
+		res = cmdLREM(cmd.Args)
+	case "LTRIM":
+		res = cmdLTRIM(cmd.Args)
+	case "LPOS":
+		res = cmdLPOS(cmd.Args)
+	case "LMOVE":
+		res = cmdLMOVE(cmd.Args)
+	case "RPOPLPUSH":
+		res = cmdRPOPLPUSH(cmd.Args)
+	case "BLPOP":
+		res = cmdBLPOP(cmd.Args, connFd)
+	case "BRPOP":
+		res = cmdBRPOP(cmd.Args, connFd)
+	case "BLMOVE":
+		res = cmdBLMOVE(cmd.Args, connFd)
+	case "BLMPOP":
+		res = cmdBLMPOP(cmd.Args, connFd)
+	case "HSET":
+		res = cmdHSET(cmd.Args)
+	case "HSETNX":
+		res = cmdHSETNX(cmd.Args)
+	case "HGET":
+		res = cmdHGET(cmd.Args)
+	case "HMGET":
+		res = cmdHMGET(cmd.Args)
+	case "HDEL":
+		res = cmdHDEL(cmd.Args)
+	case "HEXISTS":
+		res = cmdHEXISTS(cmd.Args)
+	case "HLEN":
+		res = cmdHLEN(cmd.Args)
+	case "HSTRLEN":
+		res = cmdHSTRLEN(cmd.Args)
+	case "HKEYS":
+		res = cmdHKEYS(cmd.Args)
+	case "HVALS":
+		res = cmdHVALS(cmd.Args)
+	case "HGETALL":
+		res = cmdHGETALL(cmd.Args)
+	case "HINCRBY":
+		res = cmdHINCRBY(cmd.Args)
+	case "HINCRBYFLOAT":
+		res = cmdHINCRBYFLOAT(cmd.Args)
+	case "HRANDFIELD":
+		res = cmdHRANDFIELD(cmd.Args)
+	case "HSCAN":
+		res = cmdHSCAN(cmd.Args)
+	case "HEXPIRE":
+		res = cmdHEXPIRE(cmd.Args)
+	case "HPEXPIRE":
+		res = cmdHPEXPIRE(cmd.Args)
+	case "HEXPIREAT":
+		res = cmdHEXPIREAT(cmd.Args)
+	case "HPEXPIREAT":
+		res = cmdHPEXPIREAT(cmd.Args)
+	case "HTTL":
+		res = cmdHTTL(cmd.Args)
+	case "HPTTL":
+		res = cmdHPTTL(cmd.Args)
+	case "HPERSIST":
+		res = cmdHPERSIST(cmd.Args)
+	case "HGETEX":
+		res = cmdHGETEX(cmd.Args)
+	case "HSETEX":
+		res = cmdHSETEX(cmd.Args)
 	case "SADD":
 		res = cmdSADD(cmd.Args)
 	case "SREM":
@@ -259,18 +551,233 @@ func ExecuteAndResponse(cmd *Command, connFd int) error {
 		res = cmdSISMEMBER(cmd.Args)
 	case "SMEMBERS":
 		res = cmdSMEMBERS(cmd.Args)
+	case "SINTER":
+		res = cmdSINTER(cmd.Args)
+	case "SUNION":
+		res = cmdSUNION(cmd.Args)
+	case "SDIFF":
+		res = cmdSDIFF(cmd.Args)
+	case "SINTERSTORE":
+		res = cmdSINTERSTORE(cmd.Args)
+	case "SUNIONSTORE":
+		res = cmdSUNIONSTORE(cmd.Args)
+	case "SDIFFSTORE":
+		res = cmdSDIFFSTORE(cmd.Args)
+	case "SINTERCARD":
+		res = cmdSINTERCARD(cmd.Args)
+	case "SMISMEMBER":
+		res = cmdSMISMEMBER(cmd.Args)
+	case "SMOVE":
+		res = cmdSMOVE(cmd.Args)
+	case "SCARD":
+		res = cmdSCARD(cmd.Args)
+	case "SPOP":
+		res = cmdSPOP(cmd.Args)
+	case "SRANDMEMBER":
+		res = cmdSRANDMEMBER(cmd.Args)
+	case "SSCAN":
+		res = cmdSSCAN(cmd.Args)
 	case "ZADD":
 		res = cmdZADD(cmd.Args)
+	case "ZINCRBY":
+		res = cmdZINCRBY(cmd.Args)
 	case "ZSCORE":
 		res = cmdZSCORE(cmd.Args)
 	case "ZRANK":
 		res = cmdZRANK(cmd.Args)
+	case "ZREVRANK":
+		res = cmdZREVRANK(cmd.Args)
+	case "ZMSCORE":
+		res = cmdZMSCORE(cmd.Args)
+	case "ZRANDMEMBER":
+		res = cmdZRANDMEMBER(cmd.Args)
+	case "ZRANGE":
+		res = cmdZRANGE(cmd.Args)
+	case "ZRANGEBYSCORE":
+		res = cmdZRANGEBYSCORE(cmd.Args)
+	case "ZREVRANGE":
+		res = cmdZREVRANGE(cmd.Args)
+	case "ZRANGEBYLEX":
+		res = cmdZRANGEBYLEX(cmd.Args)
+	case "ZRANGESTORE":
+		res = cmdZRANGESTORE(cmd.Args)
+	case "ZREM":
+		res = cmdZREM(cmd.Args)
+	case "ZREMRANGEBYRANK":
+		res = cmdZREMRANGEBYRANK(cmd.Args)
+	case "ZREMRANGEBYSCORE":
+		res = cmdZREMRANGEBYSCORE(cmd.Args)
+	case "ZREMRANGEBYLEX":
+		res = cmdZREMRANGEBYLEX(cmd.Args)
+	case "ZCARD":
+		res = cmdZCARD(cmd.Args)
+	case "ZCOUNT":
+		res = cmdZCOUNT(cmd.Args)
+	case "ZLEXCOUNT":
+		res = cmdZLEXCOUNT(cmd.Args)
+	case "ZPOPMIN":
+		res = cmdZPOPMIN(cmd.Args)
+	case "ZPOPMAX":
+		res = cmdZPOPMAX(cmd.Args)
+	case "ZMPOP":
+		res = cmdZMPOP(cmd.Args)
+	case "ZUNION":
+		res = cmdZUNION(cmd.Args)
+	case "ZINTER":
+		res = cmdZINTER(cmd.Args)
+	case "ZDIFF":
+		res = cmdZDIFF(cmd.Args)
+	case "ZUNIONSTORE":
+		res = cmdZUNIONSTORE(cmd.Args)
+	case "ZINTERSTORE":
+		res = cmdZINTERSTORE(cmd.Args)
+	case "ZDIFFSTORE":
+		res = cmdZDIFFSTORE(cmd.Args)
+	case "ZINTERCARD":
+		res = cmdZINTERCARD(cmd.Args)
+	case "BZPOPMIN":
+		res = cmdBZPOPMIN(cmd.Args, connFd)
+	case "BZPOPMAX":
+		res = cmdBZPOPMAX(cmd.Args, connFd)
+	case "GEOADD":
+		res = cmdGEOADD(cmd.Args)
+	case "GEOPOS":
+		res = cmdGEOPOS(cmd.Args)
+	case "GEODIST":
+		res = cmdGEODIST(cmd.Args)
+	case "GEOHASH":
+		res = cmdGEOHASH(cmd.Args)
+	case "GEOSEARCH":
+		res = cmdGEOSEARCH(cmd.Args)
+	case "GEOSEARCHSTORE":
+		res = cmdGEOSEARCHSTORE(cmd.Args)
+	case "CMS.INITBYDIM":
+		res = cmdCMSINITBYDIM(cmd.Args)
+	case "CMS.INITBYPROB":
+		res = cmdCMSINITBYPROB(cmd.Args)
+	case "CMS.INCRBY":
+		res = cmdCMSINCRBY(cmd.Args)
+	case "CMS.QUERY":
+		res = cmdCMSQUERY(cmd.Args)
+	case "CMS.MERGE":
+		res = cmdCMSMERGE(cmd.Args)
+	case "CMS.INFO":
+		res = cmdCMSINFO(cmd.Args)
+	case "BF.RESERVE":
+		res = cmdBFRESERVE(cmd.Args)
+	case "BF.ADD":
+		res = cmdBFADD(cmd.Args)
+	case "BF.MADD":
+		res = cmdBFMADD(cmd.Args)
+	case "BF.INSERT":
+		res = cmdBFINSERT(cmd.Args)
+	case "BF.EXISTS":
+		res = cmdBFEXISTS(cmd.Args)
+	case "BF.MEXISTS":
+		res = cmdBFMEXISTS(cmd.Args)
+	case "BF.CARD":
+		res = cmdBFCARD(cmd.Args)
+	case "BF.INFO":
+		res = cmdBFINFO(cmd.Args)
+	case "CF.RESERVE":
+		res = cmdCFRESERVE(cmd.Args)
+	case "CF.ADD":
+		res = cmdCFADD(cmd.Args)
+	case "CF.ADDNX":
+		res = cmdCFADDNX(cmd.Args)
+	case "CF.INSERT":
+		res = cmdCFINSERT(cmd.Args)
+	case "CF.INSERTNX":
+		res = cmdCFINSERTNX(cmd.Args)
+	case "CF.EXISTS":
+		res = cmdCFEXISTS(cmd.Args)
+	case "CF.MEXISTS":
+		res = cmdCFMEXISTS(cmd.Args)
+	case "CF.DEL":
+		res = cmdCFDEL(cmd.Args)
+	case "CF.COUNT":
+		res = cmdCFCOUNT(cmd.Args)
+	case "CF.INFO":
+		res = cmdCFINFO(cmd.Args)
+	case "PFADD":
+		res = cmdPFADD(cmd.Args)
+	case "PFCOUNT":
+		res = cmdPFCOUNT(cmd.Args)
+	case "PFMERGE":
+		res = cmdPFMERGE(cmd.Args)
+	case "TOPK.RESERVE":
+		res = cmdTOPKRESERVE(cmd.Args)
+	case "TOPK.ADD":
+		res = cmdTOPKADD(cmd.Args)
+	case "TOPK.INCRBY":
+		res = cmdTOPKINCRBY(cmd.Args)
+	case "TOPK.QUERY":
+		res = cmdTOPKQUERY(cmd.Args)
+	case "TOPK.COUNT":
+		res = cmdTOPKCOUNT(cmd.Args)
+	case "TOPK.LIST":
+		res = cmdTOPKLIST(cmd.Args)
+	case "TOPK.INFO":
+		res = cmdTOPKINFO(cmd.Args)
+	case "TDIGEST.CREATE":
+		res = cmdTDIGESTCREATE(cmd.Args)
+	case "TDIGEST.ADD":
+		res = cmdTDIGESTADD(cmd.Args)
+	case "TDIGEST.QUANTILE":
+		res = cmdTDIGESTQUANTILE(cmd.Args)
+	case "TDIGEST.CDF":
+		res = cmdTDIGESTCDF(cmd.Args)
+	case "TDIGEST.RANK":
+		res = cmdTDIGESTRANK(cmd.Args)
+	case "TDIGEST.REVRANK":
+		res = cmdTDIGESTREVRANK(cmd.Args)
+	case "TDIGEST.BYRANK":
+		res = cmdTDIGESTBYRANK(cmd.Args)
+	case "TDIGEST.BYREVRANK":
+		res = cmdTDIGESTBYREVRANK(cmd.Args)
+	case "TDIGEST.MIN":
+		res = cmdTDIGESTMIN(cmd.Args)
+	case "TDIGEST.MAX":
+		res = cmdTDIGESTMAX(cmd.Args)
+	case "TDIGEST.TRIMMED_MEAN":
+		res = cmdTDIGESTTRIMMEDMEAN(cmd.Args)
+	case "TDIGEST.MERGE":
+		res = cmdTDIGESTMERGE(cmd.Args)
+	case "TDIGEST.RESET":
+		res = cmdTDIGESTRESET(cmd.Args)
+	case "TDIGEST.INFO":
+		res = cmdTDIGESTINFO(cmd.Args)
+	case "XADD":
+		res = cmdXADD(cmd.Args)
+	case "XRANGE":
+		res = cmdXRANGE(cmd.Args)
+	case "XREVRANGE":
+		res = cmdXREVRANGE(cmd.Args)
+	case "XLEN":
+		res = cmdXLEN(cmd.Args)
+	case "XDEL":
+		res = cmdXDEL(cmd.Args)
+	case "XTRIM":
+		res = cmdXTRIM(cmd.Args)
+	case "XINFO":
+		res = cmdXINFO(cmd.Args)
+	case "XREAD":
+		res = cmdXREAD(cmd.Args, connFd)
 	default:
 		res = []byte("-command not found\r\n")
 	}
 
+	// the client is blocked, it gets its response once a key is ready or its timeout is reached
+	if res == nil {
+		return nil
+	}
+
 	// response the result
 	_, err := syscall.Write(connFd, res)
 
+	// the command may have pushed data to keys that other clients are blocked on
+	handleClientsBlockedOnKeys()
+	processUnblockedClients()
+
 	return err
 }
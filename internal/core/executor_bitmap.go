@@ -0,0 +1,655 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+/*
+Get the bytes of a string object for bit operations.
+The object is switched to the raw []byte representation, so that consecutive bit operations on the same key don't copy the whole string.
+*/
+func bitmapValue(obj *data_structure.Obj) []byte {
+	if b, ok := obj.Value.([]byte); ok {
+		return b
+	}
+
+	b := []byte(stringValue(obj))
+	obj.Value = b
+
+	return b
+}
+
+/*
+Get the bitmap of the key for writing, the key is created when it does not exist.
+The string is padded with zero bytes so that it has at least minLen bytes.
+*/
+func growBitmap(key string, minLen int) *data_structure.Obj {
+	obj := dictStore.GetObj(key)
+	if obj == nil {
+		obj = dictStore.NewObj(key, make([]byte, 0, minLen), -1)
+		dictStore.SetObj(key, obj)
+	}
+
+	b := bitmapValue(obj)
+	if len(b) < minLen {
+		b = append(b, make([]byte, minLen-len(b))...)
+		obj.Value = b
+	}
+
+	return obj
+}
+
+// parse a bit offset, it must be positive and must not exceed the maximum string size
+func parseBitOffset(s string) (int64, error) {
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || offset < 0 || offset>>3 >= constant.StringMaxSize {
+		return 0, errors.New("(error) bit offset is not an integer or out of range")
+	}
+
+	return offset, nil
+}
+
+// cmd: SETBIT key offset value
+func cmdSETBIT(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SETBIT' command"))
+	}
+
+	offset, err := parseBitOffset(args[1])
+	if err != nil {
+		return Encode(err)
+	}
+	if args[2] != "0" && args[2] != "1" {
+		return Encode(errors.New("(error) bit is not an integer or out of range"))
+	}
+
+	obj := growBitmap(args[0], int(offset>>3)+1)
+	b := bitmapValue(obj)
+
+	// bit 0 is the most significant bit of the first byte
+	byteIdx, mask := offset>>3, byte(1<<(7-offset&7))
+	old := 0
+	if b[byteIdx]&mask != 0 {
+		old = 1
+	}
+
+	if args[2] == "1" {
+		b[byteIdx] |= mask
+	} else {
+		b[byteIdx] &^= mask
+	}
+
+	return Encode(old)
+}
+
+// cmd: GETBIT key offset
+func cmdGETBIT(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GETBIT' command"))
+	}
+
+	offset, err := parseBitOffset(args[1])
+	if err != nil {
+		return Encode(err)
+	}
+
+	obj := dictStore.GetObj(args[0])
+	if obj == nil {
+		return Encode(0)
+	}
+
+	b := bitmapValue(obj)
+	if offset>>3 >= int64(len(b)) {
+		return Encode(0)
+	}
+
+	if b[offset>>3]&byte(1<<(7-offset&7)) != 0 {
+		return Encode(1)
+	}
+
+	return Encode(0)
+}
+
+/*
+Normalize a [start, end] range given in bytes (or in bits if isBit is true) against a string of strLen bytes.
+Negative indexes count from the end. The returned byte range comes with the masks of the bits
+that are out of the range in the first and the last byte (always 0 in BYTE mode).
+*/
+func bitRange(start, end int64, isBit bool, strLen int64) (int64, int64, byte, byte) {
+	totalLen := strLen
+	if isBit {
+		totalLen <<= 3
+	}
+
+	if start < 0 {
+		start += totalLen
+	}
+	if end < 0 {
+		end += totalLen
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= totalLen {
+		end = totalLen - 1
+	}
+
+	var firstMask, lastMask byte = 0, 0
+	if isBit && start <= end {
+		firstMask = ^byte((1 << (8 - start&7)) - 1)
+		lastMask = byte((1 << (7 - end&7)) - 1)
+		start >>= 3
+		end >>= 3
+	}
+
+	return start, end, firstMask, lastMask
+}
+
+// parse the optional BYTE|BIT argument of BITCOUNT and BITPOS
+func parseBitUnit(s string) (bool, error) {
+	switch strings.ToUpper(s) {
+	case "BYTE":
+		return false, nil
+	case "BIT":
+		return true, nil
+	default:
+		return false, errors.New("(error) syntax error")
+	}
+}
+
+// cmd: BITCOUNT key [start end [BYTE | BIT]]
+func cmdBITCOUNT(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BITCOUNT' command"))
+	}
+	if len(args) != 1 && len(args) != 3 && len(args) != 4 {
+		return Encode(errors.New("(error) syntax error"))
+	}
+
+	var start, end int64 = 0, -1
+	isBit := false
+	if len(args) > 1 {
+		var err1, err2 error
+		start, err1 = strconv.ParseInt(args[1], 10, 64)
+		end, err2 = strconv.ParseInt(args[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+
+		if len(args) == 4 {
+			var err error
+			if isBit, err = parseBitUnit(args[3]); err != nil {
+				return Encode(err)
+			}
+		}
+	}
+
+	obj := dictStore.GetObj(args[0])
+	if obj == nil {
+		return Encode(0)
+	}
+
+	b := bitmapValue(obj)
+	start, end, firstMask, lastMask := bitRange(start, end, isBit, int64(len(b)))
+	if start > end {
+		return Encode(0)
+	}
+
+	count := 0
+	for _, c := range b[start : end+1] {
+		count += bits.OnesCount8(c)
+	}
+	count -= bits.OnesCount8(b[start]&firstMask) + bits.OnesCount8(b[end]&lastMask)
+
+	return Encode(count)
+}
+
+// cmd: BITPOS key bit [start [end [BYTE | BIT]]]
+func cmdBITPOS(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BITPOS' command"))
+	}
+	if len(args) > 5 {
+		return Encode(errors.New("(error) syntax error"))
+	}
+
+	if args[1] != "0" && args[1] != "1" {
+		return Encode(errors.New("(error) The bit argument must be 1 or 0."))
+	}
+	bit := args[1] == "1"
+
+	var start, end int64 = 0, -1
+	endGiven, isBit := false, false
+	var err error
+	if len(args) > 2 {
+		if start, err = strconv.ParseInt(args[2], 10, 64); err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+	}
+	if len(args) > 3 {
+		if end, err = strconv.ParseInt(args[3], 10, 64); err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+		endGiven = true
+	}
+	if len(args) > 4 {
+		if isBit, err = parseBitUnit(args[4]); err != nil {
+			return Encode(err)
+		}
+	}
+
+	// a missing key is an empty string: only zeros
+	obj := dictStore.GetObj(args[0])
+	if obj == nil {
+		if bit {
+			return Encode(-1)
+		}
+		return Encode(0)
+	}
+
+	b := bitmapValue(obj)
+	start, end, firstMask, lastMask := bitRange(start, end, isBit, int64(len(b)))
+	if start > end {
+		return Encode(-1)
+	}
+
+	// hide the bits out of the range by setting them to the opposite of the searched bit
+	buf := b[start : end+1]
+	if firstMask != 0 || lastMask != 0 {
+		buf = append([]byte(nil), buf...)
+		if bit {
+			buf[0] &^= firstMask
+			buf[len(buf)-1] &^= lastMask
+		} else {
+			buf[0] |= firstMask
+			buf[len(buf)-1] |= lastMask
+		}
+	}
+
+	pos := int64(-1)
+	for i, c := range buf {
+		if !bit {
+			c = ^c
+		}
+		if c != 0 {
+			pos = int64(i)*8 + int64(bits.LeadingZeros8(c))
+			break
+		}
+	}
+
+	if pos == -1 {
+		// looking for a clear bit without an explicit end: the string is considered padded with zeros on the right
+		if !bit && !endGiven {
+			return Encode((end + 1) * 8)
+		}
+		return Encode(-1)
+	}
+
+	return Encode(start*8 + pos)
+}
+
+// cmd: BITOP <AND | OR | XOR | NOT | DIFF> destkey key [key ...]
+func cmdBITOP(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BITOP' command"))
+	}
+
+	op, destKey, srcKeys := strings.ToUpper(args[0]), args[1], args[2:]
+	switch op {
+	case "AND", "OR", "XOR":
+	case "NOT":
+		if len(srcKeys) != 1 {
+			return Encode(errors.New("(error) BITOP NOT must be called with a single source key."))
+		}
+	case "DIFF":
+		if len(srcKeys) < 2 {
+			return Encode(errors.New("(error) BITOP DIFF must be called with at least two source keys."))
+		}
+	default:
+		return Encode(errors.New("(error) syntax error"))
+	}
+
+	// missing keys are considered as empty strings
+	srcs := make([][]byte, len(srcKeys))
+	maxLen := 0
+	for i, key := range srcKeys {
+		if obj := dictStore.GetObj(key); obj != nil {
+			srcs[i] = bitmapValue(obj)
+		}
+		maxLen = max(maxLen, len(srcs[i]))
+	}
+
+	if maxLen == 0 {
+		dictStore.DeleteObj(destKey)
+		return Encode(0)
+	}
+
+	// shorter strings are padded with zero bytes
+	byteAt := func(src []byte, i int) byte {
+		if i < len(src) {
+			return src[i]
+		}
+		return 0
+	}
+
+	res := make([]byte, maxLen)
+	for i := range res {
+		switch op {
+		case "AND":
+			res[i] = 0xFF
+			for _, src := range srcs {
+				res[i] &= byteAt(src, i)
+			}
+		case "OR":
+			for _, src := range srcs {
+				res[i] |= byteAt(src, i)
+			}
+		case "XOR":
+			for _, src := range srcs {
+				res[i] ^= byteAt(src, i)
+			}
+		case "NOT":
+			res[i] = ^byteAt(srcs[0], i)
+		case "DIFF": // bits set in the first key but not in any of the other keys
+			var others byte = 0
+			for _, src := range srcs[1:] {
+				others |= byteAt(src, i)
+			}
+			res[i] = byteAt(srcs[0], i) &^ others
+		}
+	}
+
+	dictStore.SetObj(destKey, dictStore.NewObj(destKey, res, -1))
+	dictStore.DeleteExpiry(destKey)
+
+	return Encode(maxLen)
+}
+
+const (
+	bitfieldOverflowWrap = iota
+	bitfieldOverflowSat
+	bitfieldOverflowFail
+)
+
+type bitfieldOp struct {
+	op       string // GET, SET or INCRBY
+	signed   bool
+	bits     uint
+	offset   int64
+	value    int64 // the value of SET or the increment of INCRBY
+	overflow int
+}
+
+// parse a bitfield encoding: i1..i64 or u1..u63
+func parseBitfieldType(s string) (bool, uint, error) {
+	typeErr := errors.New("(error) Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+	if len(s) < 2 || (s[0] != 'i' && s[0] != 'I' && s[0] != 'u' && s[0] != 'U') {
+		return false, 0, typeErr
+	}
+
+	signed := s[0] == 'i' || s[0] == 'I'
+	n, err := strconv.Atoi(s[1:])
+	if err != nil || n < 1 || (signed && n > 64) || (!signed && n > 63) {
+		return false, 0, typeErr
+	}
+
+	return signed, uint(n), nil
+}
+
+// parse a bitfield offset, "#N" means N times the width of the type
+func parseBitfieldOffset(s string, width uint) (int64, error) {
+	offsetErr := errors.New("(error) bit offset is not an integer or out of range")
+	multiply := strings.HasPrefix(s, "#")
+	if multiply {
+		s = s[1:]
+	}
+
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, offsetErr
+	}
+	if multiply {
+		if offset > math.MaxInt64/int64(width) {
+			return 0, offsetErr
+		}
+		offset *= int64(width)
+	}
+	if (offset+int64(width)-1)>>3 >= constant.StringMaxSize {
+		return 0, offsetErr
+	}
+
+	return offset, nil
+}
+
+// read `width` bits starting from the bit `offset` as an unsigned integer, bits past the end of the string are 0
+func getBitfield(b []byte, offset int64, width uint) uint64 {
+	var value uint64 = 0
+	for i := int64(0); i < int64(width); i++ {
+		byteIdx := (offset + i) >> 3
+		bit := uint64(0)
+		if byteIdx < int64(len(b)) && b[byteIdx]&byte(1<<(7-(offset+i)&7)) != 0 {
+			bit = 1
+		}
+		value = value<<1 | bit
+	}
+
+	return value
+}
+
+// write the lowest `width` bits of value starting from the bit `offset`, the string must be large enough
+func setBitfield(b []byte, offset int64, width uint, value uint64) {
+	for i := int64(0); i < int64(width); i++ {
+		bit := value >> (uint64(width) - 1 - uint64(i)) & 1
+		byteIdx, mask := (offset+i)>>3, byte(1<<(7-(offset+i)&7))
+		if bit == 1 {
+			b[byteIdx] |= mask
+		} else {
+			b[byteIdx] &^= mask
+		}
+	}
+}
+
+// interpret the lowest `width` bits as a two's complement signed integer
+func signExtend(value uint64, width uint) int64 {
+	if width < 64 && value&(1<<(width-1)) != 0 {
+		value |= ^uint64(0) << width
+	}
+
+	return int64(value)
+}
+
+/*
+Compute value + incr for a signed field of the given width.
+It returns the result (wrapped or saturated depending on the overflow mode) and whether an overflow happened.
+*/
+func signedBitfieldAdd(value int64, incr int64, width uint, overflow int) (int64, bool) {
+	var maxVal int64 = math.MaxInt64
+	if width < 64 {
+		maxVal = 1<<(width-1) - 1
+	}
+	minVal := -maxVal - 1
+	maxIncr, minIncr := maxVal-value, minVal-value
+
+	overflowUp := value > maxVal || (width != 64 && incr > maxIncr) || (value >= 0 && incr > 0 && incr > maxIncr)
+	overflowDown := value < minVal || (width != 64 && incr < minIncr) || (value < 0 && incr < 0 && incr < minIncr)
+	if !overflowUp && !overflowDown {
+		return value + incr, false
+	}
+
+	if overflow == bitfieldOverflowSat {
+		if overflowUp {
+			return maxVal, true
+		}
+		return minVal, true
+	}
+
+	// wrap around (two's complement)
+	return signExtend(uint64(value)+uint64(incr), width), true
+}
+
+/*
+Compute value + incr for an unsigned field of the given width.
+It returns the result (wrapped or saturated depending on the overflow mode) and whether an overflow happened.
+*/
+func unsignedBitfieldAdd(value uint64, incr int64, width uint, overflow int) (uint64, bool) {
+	maxVal := uint64(1)<<width - 1
+	maxIncr, minIncr := int64(maxVal-value), -int64(value)
+
+	overflowUp := value > maxVal || (incr > 0 && incr > maxIncr)
+	overflowDown := incr < 0 && incr < minIncr
+	if !overflowUp && !overflowDown {
+		return uint64(int64(value) + incr), false
+	}
+
+	if overflow == bitfieldOverflowSat {
+		if overflowUp {
+			return maxVal, true
+		}
+		return 0, true
+	}
+
+	return (value + uint64(incr)) & maxVal, true
+}
+
+// cmd: BITFIELD key [GET encoding offset | [OVERFLOW <WRAP | SAT | FAIL>] <SET encoding offset value | INCRBY encoding offset increment> [GET encoding offset | [OVERFLOW <WRAP | SAT | FAIL>] <SET encoding offset value | INCRBY encoding offset increment> ...]]
+func cmdBITFIELD(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BITFIELD' command"))
+	}
+
+	return bitfield(args, false)
+}
+
+// cmd: BITFIELD_RO key [GET encoding offset [GET encoding offset ...]]
+func cmdBITFIELD_RO(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BITFIELD_RO' command"))
+	}
+
+	return bitfield(args, true)
+}
+
+/*
+Parse all the sub-commands of BITFIELD first, then execute them in order.
+Sub-commands that fail because of an overflow in FAIL mode return nil and do not write anything.
+*/
+func bitfield(args []string, readOnly bool) []byte {
+	key := args[0]
+	ops := make([]bitfieldOp, 0)
+	overflow := bitfieldOverflowWrap
+	var highestWriteOffset int64 = -1
+
+	for i := 1; i < len(args); i++ {
+		subCmd := strings.ToUpper(args[i])
+		remaining := len(args) - i - 1
+
+		if subCmd == "OVERFLOW" && remaining >= 1 {
+			switch strings.ToUpper(args[i+1]) {
+			case "WRAP":
+				overflow = bitfieldOverflowWrap
+			case "SAT":
+				overflow = bitfieldOverflowSat
+			case "FAIL":
+				overflow = bitfieldOverflowFail
+			default:
+				return Encode(errors.New("(error) Invalid OVERFLOW type specified"))
+			}
+			i++
+			continue
+		}
+
+		if !((subCmd == "GET" && remaining >= 2) || ((subCmd == "SET" || subCmd == "INCRBY") && remaining >= 3)) {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		if readOnly && subCmd != "GET" {
+			return Encode(errors.New("(error) BITFIELD_RO only supports the GET subcommand"))
+		}
+
+		signed, width, err := parseBitfieldType(args[i+1])
+		if err != nil {
+			return Encode(err)
+		}
+		offset, err := parseBitfieldOffset(args[i+2], width)
+		if err != nil {
+			return Encode(err)
+		}
+
+		op := bitfieldOp{op: subCmd, signed: signed, bits: width, offset: offset, overflow: overflow}
+		if subCmd != "GET" {
+			if op.value, err = strconv.ParseInt(args[i+3], 10, 64); err != nil {
+				return Encode(errors.New("(error) value is not an integer or out of range"))
+			}
+			highestWriteOffset = max(highestWriteOffset, offset+int64(width)-1)
+			i++
+		}
+
+		ops = append(ops, op)
+		i += 2
+	}
+
+	// only grow (or create) the string if there is at least one write
+	var b []byte
+	if highestWriteOffset >= 0 {
+		obj := growBitmap(key, int(highestWriteOffset>>3)+1)
+		b = bitmapValue(obj)
+	} else if obj := dictStore.GetObj(key); obj != nil {
+		b = bitmapValue(obj)
+	}
+
+	res := make([]interface{}, 0, len(ops))
+	for _, op := range ops {
+		current := getBitfield(b, op.offset, op.bits)
+
+		if op.op == "GET" {
+			if op.signed {
+				res = append(res, signExtend(current, op.bits))
+			} else {
+				res = append(res, int64(current))
+			}
+			continue
+		}
+
+		var newValue uint64
+		var overflowed bool
+		var reply int64
+		if op.signed {
+			oldValue := signExtend(current, op.bits)
+			var v int64
+			if op.op == "SET" {
+				v, overflowed = signedBitfieldAdd(op.value, 0, op.bits, op.overflow)
+				reply = oldValue
+			} else {
+				v, overflowed = signedBitfieldAdd(oldValue, op.value, op.bits, op.overflow)
+				reply = v
+			}
+			newValue = uint64(v)
+		} else {
+			if op.op == "SET" {
+				newValue, overflowed = unsignedBitfieldAdd(uint64(op.value), 0, op.bits, op.overflow)
+				reply = int64(current)
+			} else {
+				newValue, overflowed = unsignedBitfieldAdd(current, op.value, op.bits, op.overflow)
+				reply = int64(newValue)
+			}
+		}
+
+		if overflowed && op.overflow == bitfieldOverflowFail {
+			res = append(res, nil)
+			continue
+		}
+
+		setBitfield(b, op.offset, op.bits, newValue)
+		res = append(res, reply)
+	}
+
+	return Encode(res)
+}
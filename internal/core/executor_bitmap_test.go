@@ -0,0 +1,123 @@
+package core
+
+import "testing"
+
+func TestSETBITAndGETBIT(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("SETBIT k 7 1"), reply(0)},
+		{args("GET k"), reply("\x01")},
+		{args("GETBIT k 7"), reply(1)},
+		{args("GETBIT k 0"), reply(0)},
+		{args("GETBIT k 100"), reply(0)},
+		{args("GETBIT missing 0"), reply(0)},
+		{args("SETBIT k 7 0"), reply(1)},
+		{args("GET k"), reply("\x00")},
+		// the string is extended with zero bytes
+		{args("SETBIT k 23 1"), reply(0)},
+		{args("GET k"), reply("\x00\x00\x01")},
+		{args("SETBIT k 7 2"), errReply("bit is not an integer or out of range")},
+		{args("SETBIT k 4294967296 1"), errReply("bit offset is not an integer or out of range")},
+		{args("SETBIT k -1 1"), errReply("bit offset is not an integer or out of range")},
+		// bit operations keep the integer value readable
+		{args("SET n 1"), okReply},
+		{args("SETBIT n 6 1"), reply(0)},
+		{args("GET n"), reply("3")},
+		{args("INCR n"), reply(4)},
+	})
+}
+
+func TestBITCOUNT(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("BITCOUNT missing"), reply(0)},
+		{args("SET k foobar"), okReply},
+		{args("BITCOUNT k"), reply(26)},
+		{args("BITCOUNT k 0 0"), reply(4)},
+		{args("BITCOUNT k 1 1"), reply(6)},
+		{args("BITCOUNT k 1 1 BYTE"), reply(6)},
+		{args("BITCOUNT k 5 30 BIT"), reply(17)},
+		{args("BITCOUNT k -2 -1"), reply(7)},
+		{args("BITCOUNT k 2 1"), reply(0)},
+		{args("BITCOUNT k 0"), errReply("syntax error")},
+		{args("BITCOUNT k 0 1 WORD"), errReply("syntax error")},
+		{args("BITCOUNT k a 1"), errReply("value is not an integer or out of range")},
+	})
+}
+
+func TestBITPOS(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("SET k \xff\xf0\x00"), okReply},
+		{args("BITPOS k 0"), reply(12)},
+		{args("SET k \x00\xff\xf0"), okReply},
+		{args("BITPOS k 1 0"), reply(8)},
+		{args("BITPOS k 1 2"), reply(16)},
+		{args("BITPOS k 1 2 -1 BYTE"), reply(16)},
+		{args("BITPOS k 1 7 15 BIT"), reply(8)},
+		{args("SET k \x00\x00\x00"), okReply},
+		{args("BITPOS k 1"), reply(-1)},
+		// looking for a clear bit past the end of the string
+		{args("SET k \xff\xff\xff"), okReply},
+		{args("BITPOS k 0"), reply(24)},
+		{args("BITPOS k 0 0 -1"), reply(-1)},
+		{args("BITPOS missing 0"), reply(0)},
+		{args("BITPOS missing 1"), reply(-1)},
+		{args("BITPOS k 2"), errReply("The bit argument must be 1 or 0.")},
+	})
+}
+
+func TestBITOP(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("MSET a foobar b abcdef"), okReply},
+		{args("BITOP AND d a b"), reply(6)},
+		{args("GET d"), reply("`bc`ab")},
+		{args("BITOP OR d a b"), reply(6)},
+		{args("GET d"), reply("goofev")},
+		{args("BITOP XOR d a b"), reply(6)},
+		{args("GET d"), reply("\x07\x0d\x0c\x06\x04\x14")},
+		{args("SET x \xff\x0f"), okReply},
+		{args("BITOP NOT d x"), reply(2)},
+		{args("GET d"), reply("\x00\xf0")},
+		// the shorter strings are padded with zero bytes
+		{args("SET y \x0f"), okReply},
+		{args("BITOP DIFF d x y"), reply(2)},
+		{args("GET d"), reply("\xf0\x0f")},
+		{args("BITOP NOT d x y"), errReply("BITOP NOT must be called with a single source key.")},
+		{args("BITOP DIFF d x"), errReply("BITOP DIFF must be called with at least two source keys.")},
+		{args("BITOP NAND d x y"), errReply("syntax error")},
+		// an empty result deletes the destination
+		{args("BITOP AND d missing1 missing2"), reply(0)},
+		{args("GET d"), nilReply},
+	})
+}
+
+func TestBITFIELD(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("BITFIELD k INCRBY i5 100 1 GET u4 0"), reply([]interface{}{1, 0})},
+		{args("BITFIELD o INCRBY u2 100 1 OVERFLOW SAT INCRBY u2 102 1"), reply([]interface{}{1, 1})},
+		{args("BITFIELD o INCRBY u2 100 1 OVERFLOW SAT INCRBY u2 102 1"), reply([]interface{}{2, 2})},
+		{args("BITFIELD o INCRBY u2 100 1 OVERFLOW SAT INCRBY u2 102 1"), reply([]interface{}{3, 3})},
+		{args("BITFIELD o INCRBY u2 100 1 OVERFLOW SAT INCRBY u2 102 1"), reply([]interface{}{0, 3})},
+		{args("BITFIELD o OVERFLOW FAIL INCRBY u2 102 1"), reply([]interface{}{nil})},
+		{args("BITFIELD s SET i8 0 -100 GET i8 0 SET u8 #1 255 GET u8 8"), reply([]interface{}{0, -100, 0, 255})},
+		{args("BITFIELD s SET i8 0 200"), reply([]interface{}{-100})},
+		{args("BITFIELD s GET i8 0"), reply([]interface{}{-56})},
+		{args("BITFIELD s OVERFLOW SAT SET i8 0 200"), reply([]interface{}{-56})},
+		{args("BITFIELD s GET i8 0"), reply([]interface{}{127})},
+		{args("BITFIELD_RO s GET u8 8"), reply([]interface{}{255})},
+		{args("BITFIELD_RO s SET u8 8 1"), errReply("BITFIELD_RO only supports the GET subcommand")},
+		{args("BITFIELD s GET u64 0"), errReply("Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")},
+		{args("BITFIELD s OVERFLOW MAYBE"), errReply("Invalid OVERFLOW type specified")},
+		{args("BITFIELD s GET u8 -1"), errReply("bit offset is not an integer or out of range")},
+		{args("BITFIELD s BOGUS"), errReply("syntax error")},
+		{args("BITFIELD missing GET u8 0"), reply([]interface{}{0})},
+	})
+}
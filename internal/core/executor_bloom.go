@@ -0,0 +1,324 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+type bloomOptions struct {
+	errorRate  float64
+	capacity   uint64
+	expansion  uint32
+	nonScaling bool
+}
+
+func parseBloomErrorRate(s string) (float64, error) {
+	errorRate, err := parseFloat(s)
+	if err != nil {
+		return 0, errors.New("(error) bad error rate")
+	}
+	if errorRate <= 0 || errorRate >= 1 {
+		return 0, errors.New("(error) (0 < error rate range < 1)")
+	}
+
+	return errorRate, nil
+}
+
+func parseBloomCapacity(s string) (uint64, error) {
+	capacity, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errors.New("(error) bad capacity")
+	}
+	if capacity <= 0 {
+		return 0, errors.New("(error) (capacity should be larger than 0)")
+	}
+
+	return uint64(capacity), nil
+}
+
+func parseBloomExpansion(s string) (uint32, error) {
+	expansion, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errors.New("(error) bad expansion")
+	}
+	if expansion < 1 || expansion > math.MaxUint32 {
+		return 0, errors.New("(error) expansion should be greater or equal to 1")
+	}
+
+	return uint32(expansion), nil
+}
+
+func createBloomFilter(key string, opts *bloomOptions) (*data_structure.BloomFilter, error) {
+	if data_structure.BloomLinkBytes(opts.capacity, opts.errorRate) > constant.StringMaxSize {
+		return nil, errors.New("(error) filter is too large")
+	}
+
+	bf := data_structure.CreateBloomFilter(opts.capacity, opts.errorRate, opts.expansion, opts.nonScaling)
+	bloomStore[key] = bf
+
+	return bf, nil
+}
+
+// get the filter of the key, it is created with the options if it does not exist
+func getOrCreateBloomFilter(key string, opts *bloomOptions) (*data_structure.BloomFilter, error) {
+	if bf, exist := bloomStore[key]; exist {
+		return bf, nil
+	}
+
+	return createBloomFilter(key, opts)
+}
+
+func defaultBloomOptions() *bloomOptions {
+	return &bloomOptions{
+		errorRate: constant.BloomDefaultErrorRate,
+		capacity:  constant.BloomDefaultCapacity,
+		expansion: constant.BloomDefaultExpansion,
+	}
+}
+
+// add the items to the filter, the reply of an item is 1 if it is added, 0 if it may exist or an error if the filter is full
+func addBloomItems(bf *data_structure.BloomFilter, items []string) []interface{} {
+	res := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		added, err := bf.Add(item)
+		switch {
+		case err != nil:
+			res = append(res, errors.New("(error) "+err.Error()))
+		case added:
+			res = append(res, 1)
+		default:
+			res = append(res, 0)
+		}
+	}
+
+	return res
+}
+
+// cmd: BF.RESERVE key error_rate capacity [EXPANSION expansion] [NONSCALING]
+func cmdBFRESERVE(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.RESERVE' command"))
+	}
+
+	key := args[0]
+	opts := defaultBloomOptions()
+	var err error
+	if opts.errorRate, err = parseBloomErrorRate(args[1]); err != nil {
+		return Encode(err)
+	}
+	if opts.capacity, err = parseBloomCapacity(args[2]); err != nil {
+		return Encode(err)
+	}
+
+	hasExpansion := false
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "EXPANSION":
+			if i+1 >= len(args) {
+				return Encode(errors.New("(error) no expansion"))
+			}
+			i++
+			if opts.expansion, err = parseBloomExpansion(args[i]); err != nil {
+				return Encode(err)
+			}
+			hasExpansion = true
+		case "NONSCALING":
+			opts.nonScaling = true
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+	if hasExpansion && opts.nonScaling {
+		return Encode(errors.New("(error) Nonscaling filters cannot expand"))
+	}
+
+	if _, exist := bloomStore[key]; exist {
+		return Encode(errors.New("(error) item exists"))
+	}
+	if _, err := createBloomFilter(key, opts); err != nil {
+		return Encode(err)
+	}
+
+	return constant.RespOk
+}
+
+// cmd: BF.ADD key item
+func cmdBFADD(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.ADD' command"))
+	}
+
+	bf, err := getOrCreateBloomFilter(args[0], defaultBloomOptions())
+	if err != nil {
+		return Encode(err)
+	}
+
+	return Encode(addBloomItems(bf, args[1:])[0])
+}
+
+// cmd: BF.MADD key item [item ...]
+func cmdBFMADD(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.MADD' command"))
+	}
+
+	bf, err := getOrCreateBloomFilter(args[0], defaultBloomOptions())
+	if err != nil {
+		return Encode(err)
+	}
+
+	return Encode(addBloomItems(bf, args[1:]))
+}
+
+// cmd: BF.INSERT key [CAPACITY capacity] [ERROR error] [EXPANSION expansion] [NOCREATE] [NONSCALING] ITEMS item [item ...]
+func cmdBFINSERT(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.INSERT' command"))
+	}
+
+	key := args[0]
+	opts := defaultBloomOptions()
+	noCreate, hasCapacityOrError, hasExpansion := false, false, false
+	itemsIdx := -1
+	var err error
+	for i := 1; i < len(args) && itemsIdx < 0; i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "CAPACITY" && i+1 < len(args):
+			i++
+			if opts.capacity, err = parseBloomCapacity(args[i]); err != nil {
+				return Encode(err)
+			}
+			hasCapacityOrError = true
+		case option == "ERROR" && i+1 < len(args):
+			i++
+			if opts.errorRate, err = parseBloomErrorRate(args[i]); err != nil {
+				return Encode(err)
+			}
+			hasCapacityOrError = true
+		case option == "EXPANSION" && i+1 < len(args):
+			i++
+			if opts.expansion, err = parseBloomExpansion(args[i]); err != nil {
+				return Encode(err)
+			}
+			hasExpansion = true
+		case option == "NOCREATE":
+			noCreate = true
+		case option == "NONSCALING":
+			opts.nonScaling = true
+		case option == "ITEMS":
+			itemsIdx = i + 1
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+
+	if itemsIdx < 0 || itemsIdx >= len(args) {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.INSERT' command"))
+	}
+	if noCreate && hasCapacityOrError {
+		return Encode(errors.New("(error) NOCREATE cannot be used together with CAPACITY or ERROR"))
+	}
+	if hasExpansion && opts.nonScaling {
+		return Encode(errors.New("(error) Nonscaling filters cannot expand"))
+	}
+
+	bf, exist := bloomStore[key]
+	if !exist {
+		if noCreate {
+			return Encode(errors.New("(error) not found"))
+		}
+		if bf, err = createBloomFilter(key, opts); err != nil {
+			return Encode(err)
+		}
+	}
+
+	return Encode(addBloomItems(bf, args[itemsIdx:]))
+}
+
+// cmd: BF.EXISTS key item
+func cmdBFEXISTS(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.EXISTS' command"))
+	}
+
+	bf, exist := bloomStore[args[0]]
+	if !exist || !bf.Exists(args[1]) {
+		return Encode(0)
+	}
+
+	return Encode(1)
+}
+
+// cmd: BF.MEXISTS key item [item ...]
+func cmdBFMEXISTS(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.MEXISTS' command"))
+	}
+
+	bf, exist := bloomStore[args[0]]
+	res := make([]interface{}, 0, len(args)-1)
+	for _, item := range args[1:] {
+		if exist && bf.Exists(item) {
+			res = append(res, 1)
+		} else {
+			res = append(res, 0)
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: BF.CARD key
+func cmdBFCARD(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.CARD' command"))
+	}
+
+	bf, exist := bloomStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(int64(bf.Size))
+}
+
+// cmd: BF.INFO key [CAPACITY | SIZE | FILTERS | ITEMS | EXPANSION]
+func cmdBFINFO(args []string) []byte {
+	if len(args) != 1 && len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BF.INFO' command"))
+	}
+
+	bf, exist := bloomStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) not found"))
+	}
+
+	var expansion interface{} = nil
+	if !bf.NonScaling {
+		expansion = int64(bf.Expansion)
+	}
+
+	info := []interface{}{
+		"Capacity", int64(bf.Capacity()),
+		"Size", int64(bf.Bytes()),
+		"Number of filters", len(bf.Filters),
+		"Number of items inserted", int64(bf.Size),
+		"Expansion rate", expansion,
+	}
+	if len(args) == 1 {
+		return Encode(info)
+	}
+
+	fields := []string{"CAPACITY", "SIZE", "FILTERS", "ITEMS", "EXPANSION"}
+	for i, field := range fields {
+		if strings.ToUpper(args[1]) == field {
+			return Encode([]interface{}{info[2*i+1]})
+		}
+	}
+
+	return Encode(errors.New("(error) Invalid information value"))
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestBloomCommands(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("BF.EXISTS bf a"), reply(0)},
+		{args("BF.CARD bf"), reply(0)},
+		{args("BF.ADD bf a"), reply(1)},
+		{args("BF.ADD bf a"), reply(0)},
+		{args("BF.MADD bf a b c"), reply([]interface{}{0, 1, 1})},
+		{args("BF.EXISTS bf b"), reply(1)},
+		{args("BF.MEXISTS bf a x c"), reply([]interface{}{1, 0, 1})},
+		{args("BF.MEXISTS missing a"), reply([]interface{}{0})},
+		{args("BF.CARD bf"), reply(3)},
+		// the filter is created with the default options by BF.ADD
+		{args("BF.INFO bf"), reply([]interface{}{
+			"Capacity", int64(100),
+			"Size", int64(120),
+			"Number of filters", 1,
+			"Number of items inserted", int64(3),
+			"Expansion rate", int64(2),
+		})},
+		{args("BF.INFO bf ITEMS"), reply([]interface{}{int64(3)})},
+		{args("BF.INFO bf filters"), reply([]interface{}{1})},
+		{args("BF.INFO bf other"), errReply("Invalid information value")},
+		{args("BF.INFO missing"), errReply("not found")},
+		{args("BF.ADD bf"), errReply("wrong number of arguments for 'BF.ADD' command")},
+	})
+}
+
+func TestBFRESERVE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("BF.RESERVE bf 0.001 1000 EXPANSION 4"), okReply},
+		{args("BF.RESERVE bf 0.001 1000"), errReply("item exists")},
+		{args("BF.INFO bf CAPACITY"), reply([]interface{}{int64(1000)})},
+		{args("BF.INFO bf EXPANSION"), reply([]interface{}{int64(4)})},
+		{args("BF.RESERVE ns 0.01 10 NONSCALING"), okReply},
+		{args("BF.INFO ns EXPANSION"), reply([]interface{}{nil})},
+		{args("BF.RESERVE k x 10"), errReply("bad error rate")},
+		{args("BF.RESERVE k 1 10"), errReply("(0 < error rate range < 1)")},
+		{args("BF.RESERVE k 0.01 x"), errReply("bad capacity")},
+		{args("BF.RESERVE k 0.01 0"), errReply("(capacity should be larger than 0)")},
+		{args("BF.RESERVE k 0.01 10 EXPANSION"), errReply("no expansion")},
+		{args("BF.RESERVE k 0.01 10 EXPANSION x"), errReply("bad expansion")},
+		{args("BF.RESERVE k 0.01 10 EXPANSION 0"), errReply("expansion should be greater or equal to 1")},
+		{args("BF.RESERVE k 0.01 10 EXPANSION 4294967296"), errReply("expansion should be greater or equal to 1")},
+		{args("BF.RESERVE k 0.01 10 EXPANSION 2 NONSCALING"), errReply("Nonscaling filters cannot expand")},
+		{args("BF.RESERVE k 0.01 10 OTHER"), errReply("syntax error")},
+		{args("BF.RESERVE k 0.0000001 1000000000"), errReply("filter is too large")},
+		{args("BF.INFO k"), errReply("not found")},
+		{args("BF.RESERVE k 0.01"), errReply("wrong number of arguments for 'BF.RESERVE' command")},
+	})
+}
+
+func TestBFINSERT(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("BF.INSERT bf NOCREATE ITEMS a"), errReply("not found")},
+		{args("BF.INSERT bf CAPACITY 1000 ERROR 0.001 EXPANSION 3 ITEMS a b a"), reply([]interface{}{1, 1, 0})},
+		{args("BF.INFO bf CAPACITY"), reply([]interface{}{int64(1000)})},
+		{args("BF.INFO bf EXPANSION"), reply([]interface{}{int64(3)})},
+		// the options are ignored when the filter exists
+		{args("BF.INSERT bf CAPACITY 10 ITEMS c"), reply([]interface{}{1})},
+		{args("BF.INSERT bf NOCREATE ITEMS d"), reply([]interface{}{1})},
+		{args("BF.INFO bf CAPACITY"), reply([]interface{}{int64(1000)})},
+		{args("BF.INSERT k NOCREATE CAPACITY 10 ITEMS a"), errReply("NOCREATE cannot be used together with CAPACITY or ERROR")},
+		{args("BF.INSERT k EXPANSION 2 NONSCALING ITEMS a"), errReply("Nonscaling filters cannot expand")},
+		{args("BF.INSERT k CAPACITY 10 ITEMS"), errReply("wrong number of arguments for 'BF.INSERT' command")},
+		{args("BF.INSERT k CAPACITY 10 a"), errReply("syntax error")},
+		{args("BF.INSERT k ERROR 2 ITEMS a"), errReply("(0 < error rate range < 1)")},
+	})
+}
+
+func TestBFADDFull(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do(args("BF.RESERVE ns 0.01 10 NONSCALING")...)
+	for i := range 10 {
+		if got := c.do("BF.ADD", "ns", "item"+strconv.Itoa(i)); got != reply(1) {
+			t.Fatalf("BF.ADD ns item%d = %q, want 1", i, got)
+		}
+	}
+
+	runReplyTests(t, []replyTest{
+		{args("BF.ADD ns one-more"), errReply("non scaling filter is full")},
+		{args("BF.MADD ns item0 one-more"), reply([]interface{}{0, errors.New("(error) non scaling filter is full")})},
+		{args("BF.CARD ns"), reply(10)},
+		// the next sub-filter would need 4294967295 times the capacity of the first one
+		{args("BF.RESERVE big 0.01 1 EXPANSION 4294967295"), okReply},
+		{args("BF.ADD big a"), reply(1)},
+		{args("BF.ADD big b"), errReply("filter is too large to scale")},
+		{args("BF.INFO big FILTERS"), reply([]interface{}{1})},
+	})
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+func createCMS(key string, width uint32, depth uint32) []byte {
+	if _, exist := cmsStore[key]; exist {
+		return Encode(errors.New("(error) CMS: key already exists"))
+	}
+	// each counter takes 4 bytes, divide the bound so that width * depth can not overflow
+	if uint64(width) > constant.StringMaxSize/4/uint64(depth) {
+		return Encode(errors.New("(error) CMS: width * depth is too large"))
+	}
+
+	cmsStore[key] = data_structure.CreateCMS(width, depth)
+
+	return constant.RespOk
+}
+
+// cmd: CMS.INITBYDIM key width depth
+func cmdCMSINITBYDIM(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CMS.INITBYDIM' command"))
+	}
+
+	width, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil || width == 0 {
+		return Encode(errors.New("(error) CMS: invalid width"))
+	}
+	depth, err := strconv.ParseUint(args[2], 10, 32)
+	if err != nil || depth == 0 {
+		return Encode(errors.New("(error) CMS: invalid depth"))
+	}
+
+	return createCMS(args[0], uint32(width), uint32(depth))
+}
+
+// cmd: CMS.INITBYPROB key error probability
+func cmdCMSINITBYPROB(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CMS.INITBYPROB' command"))
+	}
+
+	errorRate, err := parseFloat(args[1])
+	if err != nil || errorRate <= 0 || errorRate >= 1 {
+		return Encode(errors.New("(error) CMS: invalid overestimation value"))
+	}
+	probability, err := parseFloat(args[2])
+	if err != nil || probability <= 0 || probability >= 1 {
+		return Encode(errors.New("(error) CMS: invalid prob value"))
+	}
+
+	width, depth := data_structure.CMSDimByProb(errorRate, probability)
+
+	return createCMS(args[0], width, depth)
+}
+
+// cmd: CMS.INCRBY key item increment [item increment ...]
+func cmdCMSINCRBY(args []string) []byte {
+	if len(args) < 3 || len(args)%2 == 0 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CMS.INCRBY' command"))
+	}
+
+	cms, exist := cmsStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) CMS: key does not exist"))
+	}
+
+	// parse all the increments before changing anything
+	increments := make([]uint32, 0, len(args)/2)
+	for i := 2; i < len(args); i += 2 {
+		value, err := strconv.ParseUint(args[i], 10, 32)
+		if err != nil {
+			return Encode(errors.New("(error) CMS: Cannot parse number"))
+		}
+		increments = append(increments, uint32(value))
+	}
+
+	res := make([]interface{}, 0, len(increments))
+	for i, value := range increments {
+		res = append(res, int64(cms.IncrBy(args[1+2*i], value)))
+	}
+
+	return Encode(res)
+}
+
+// cmd: CMS.QUERY key item [item ...]
+func cmdCMSQUERY(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CMS.QUERY' command"))
+	}
+
+	cms, exist := cmsStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) CMS: key does not exist"))
+	}
+
+	res := make([]interface{}, 0, len(args)-1)
+	for _, item := range args[1:] {
+		res = append(res, int64(cms.Query(item)))
+	}
+
+	return Encode(res)
+}
+
+// cmd: CMS.MERGE destination numKeys source [source ...] [WEIGHTS weight [weight ...]]
+func cmdCMSMERGE(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CMS.MERGE' command"))
+	}
+
+	dst, exist := cmsStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) CMS: key does not exist"))
+	}
+
+	numKeys, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || numKeys <= 0 || numKeys > int64(len(args)-2) {
+		return Encode(errors.New("(error) CMS: invalid numkeys"))
+	}
+
+	keys := args[2 : 2+numKeys]
+	rest := args[2+numKeys:]
+	weights := make([]int64, numKeys)
+	for i := range weights {
+		weights[i] = 1
+	}
+	if len(rest) > 0 {
+		if len(rest) != len(keys)+1 || strings.ToUpper(rest[0]) != "WEIGHTS" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		for i, w := range rest[1:] {
+			if weights[i], err = strconv.ParseInt(w, 10, 64); err != nil || weights[i] < math.MinInt32 || weights[i] > math.MaxInt32 {
+				return Encode(errors.New("(error) CMS: invalid weight value"))
+			}
+		}
+	}
+
+	sources := make([]*data_structure.CMS, 0, len(keys))
+	for _, key := range keys {
+		src, exist := cmsStore[key]
+		if !exist {
+			return Encode(errors.New("(error) CMS: key does not exist"))
+		}
+		if src.Width != dst.Width || src.Depth != dst.Depth {
+			return Encode(errors.New("(error) CMS: width/depth is not equal"))
+		}
+		sources = append(sources, src)
+	}
+
+	dst.Merge(sources, weights)
+
+	return constant.RespOk
+}
+
+// cmd: CMS.INFO key
+func cmdCMSINFO(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CMS.INFO' command"))
+	}
+
+	cms, exist := cmsStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) CMS: key does not exist"))
+	}
+
+	return Encode([]interface{}{
+		"width", int64(cms.Width),
+		"depth", int64(cms.Depth),
+		"count", int64(cms.Counter),
+	})
+}
@@ -0,0 +1,70 @@
+package core
+
+import "testing"
+
+func TestCMSCommands(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("CMS.INITBYDIM a 2000 5"), okReply},
+		{args("CMS.INITBYDIM a 2000 5"), errReply("CMS: key already exists")},
+		{args("CMS.INITBYPROB b 0.001 0.01"), okReply},
+		{args("CMS.INFO b"), reply([]interface{}{"width", int64(2000), "depth", int64(7), "count", int64(0)})},
+		{args("CMS.INCRBY a foo 10 bar 42 foo 1"), reply([]interface{}{int64(10), int64(42), int64(11)})},
+		{args("CMS.QUERY a foo bar missing"), reply([]interface{}{int64(11), int64(42), int64(0)})},
+		{args("CMS.INFO a"), reply([]interface{}{"width", int64(2000), "depth", int64(5), "count", int64(53)})},
+		// nothing is incremented when an increment is invalid
+		{args("CMS.INCRBY a foo 1 bar -1"), errReply("CMS: Cannot parse number")},
+		{args("CMS.INCRBY a foo 4294967296"), errReply("CMS: Cannot parse number")},
+		{args("CMS.QUERY a foo"), reply([]interface{}{int64(11)})},
+		{args("CMS.INCRBY a foo"), errReply("wrong number of arguments for 'CMS.INCRBY' command")},
+		{args("CMS.INCRBY missing foo 1"), errReply("CMS: key does not exist")},
+		{args("CMS.QUERY missing foo"), errReply("CMS: key does not exist")},
+		{args("CMS.INFO missing"), errReply("CMS: key does not exist")},
+	})
+}
+
+func TestCMSInitErrors(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("CMS.INITBYDIM k 0 5"), errReply("CMS: invalid width")},
+		{args("CMS.INITBYDIM k 10 x"), errReply("CMS: invalid depth")},
+		{args("CMS.INITBYDIM k 4294967296 1"), errReply("CMS: invalid width")},
+		{args("CMS.INITBYPROB k 0 0.1"), errReply("CMS: invalid overestimation value")},
+		{args("CMS.INITBYPROB k 0.1 1"), errReply("CMS: invalid prob value")},
+		// 4 * width * depth is the size of the counters, 2^31 * 2^31 * 4 overflows to 0 with 64 bits
+		{args("CMS.INITBYDIM k 2147483648 2147483648"), errReply("CMS: width * depth is too large")},
+		{args("CMS.INITBYDIM k 4294967295 4294967295"), errReply("CMS: width * depth is too large")},
+		{args("CMS.INITBYDIM k 134217729 1"), errReply("CMS: width * depth is too large")},
+		{args("CMS.INITBYPROB k 0.000000000001 0.5"), errReply("CMS: width * depth is too large")},
+		{args("CMS.INFO k"), errReply("CMS: key does not exist")},
+		{args("CMS.INITBYDIM k 10"), errReply("wrong number of arguments for 'CMS.INITBYDIM' command")},
+	})
+}
+
+func TestCMSMERGE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("CMS.INITBYDIM a 100 4"), okReply},
+		{args("CMS.INITBYDIM b 100 4"), okReply},
+		{args("CMS.INITBYDIM dst 100 4"), okReply},
+		{args("CMS.INITBYDIM small 10 4"), okReply},
+		{args("CMS.INCRBY a x 5"), reply([]interface{}{int64(5)})},
+		{args("CMS.INCRBY b x 2"), reply([]interface{}{int64(2)})},
+		{args("CMS.MERGE dst 2 a b"), okReply},
+		{args("CMS.QUERY dst x"), reply([]interface{}{int64(7)})},
+		{args("CMS.MERGE dst 2 a b WEIGHTS 1 3"), okReply},
+		{args("CMS.QUERY dst x"), reply([]interface{}{int64(11)})},
+		{args("CMS.INFO dst"), reply([]interface{}{"width", int64(100), "depth", int64(4), "count", int64(11)})},
+		{args("CMS.MERGE dst 2 a small"), errReply("CMS: width/depth is not equal")},
+		{args("CMS.MERGE dst 2 a missing"), errReply("CMS: key does not exist")},
+		{args("CMS.MERGE missing 1 a"), errReply("CMS: key does not exist")},
+		{args("CMS.MERGE dst 3 a b"), errReply("CMS: invalid numkeys")},
+		{args("CMS.MERGE dst 0 a"), errReply("CMS: invalid numkeys")},
+		{args("CMS.MERGE dst 2 a b WEIGHTS 1"), errReply("syntax error")},
+		{args("CMS.MERGE dst 1 a WEIGHTS 2147483648"), errReply("CMS: invalid weight value")},
+		{args("CMS.QUERY dst x"), reply([]interface{}{int64(11)})},
+	})
+}
@@ -0,0 +1,307 @@
+package core
+
+import (
+	"errors"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+type cuckooOptions struct {
+	capacity      uint64
+	bucketSize    uint8
+	maxIterations uint16
+	expansion     uint16
+}
+
+func defaultCuckooOptions() *cuckooOptions {
+	return &cuckooOptions{
+		capacity:      constant.CuckooDefaultCapacity,
+		bucketSize:    constant.CuckooDefaultBucketSize,
+		maxIterations: constant.CuckooDefaultMaxIterations,
+		expansion:     constant.CuckooDefaultExpansion,
+	}
+}
+
+// parse an integer in the range [lo, hi], msg is the error if it is not
+func parseCuckooParam(s string, lo int64, hi int64, msg string) (int64, error) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < lo || v > hi {
+		return 0, errors.New(msg)
+	}
+
+	return v, nil
+}
+
+func parseCuckooCapacity(s string) (uint64, error) {
+	capacity, err := parseCuckooParam(s, 1, constant.StringMaxSize, "(error) Bad capacity")
+	return uint64(capacity), err
+}
+
+func createCuckooFilter(key string, opts *cuckooOptions) (*data_structure.CuckooFilter, error) {
+	if opts.capacity < uint64(opts.bucketSize)*2 {
+		return nil, errors.New("(error) Capacity must be at least (BucketSize * 2)")
+	}
+	// the number of buckets is rounded up to a power of 2, so the filter takes up to twice the capacity in bytes
+	if opts.capacity*2 > constant.StringMaxSize {
+		return nil, errors.New("(error) filter is too large")
+	}
+
+	cf := data_structure.CreateCuckooFilter(opts.capacity, opts.bucketSize, opts.maxIterations, opts.expansion)
+	cuckooStore[key] = cf
+
+	return cf, nil
+}
+
+/*
+Add the items to the filter, with nx an item is not added if it may already exist.
+The reply of an item is 1 if it is added, 0 if it may exist (only with nx), -1 if the filter is full
+or an error if it can not expand anymore.
+*/
+func addCuckooItems(cf *data_structure.CuckooFilter, items []string, nx bool) []interface{} {
+	res := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if nx && cf.Exists(item) {
+			res = append(res, 0)
+		} else if err := cf.Add(item); errors.Is(err, data_structure.ErrCuckooFull) {
+			res = append(res, -1)
+		} else if err != nil {
+			res = append(res, errors.New("(error) "+err.Error()))
+		} else {
+			res = append(res, 1)
+		}
+	}
+
+	return res
+}
+
+// CF.ADD and CF.ADDNX create the filter if it does not exist, and reply with an error if it is full
+func cuckooAdd(args []string, cmdName string, nx bool) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	cf, exist := cuckooStore[args[0]]
+	if !exist {
+		var err error
+		if cf, err = createCuckooFilter(args[0], defaultCuckooOptions()); err != nil {
+			return Encode(err)
+		}
+	}
+
+	res := addCuckooItems(cf, args[1:], nx)[0]
+	if res == -1 {
+		return Encode(errors.New("(error) Filter is full"))
+	}
+
+	return Encode(res)
+}
+
+// CF.INSERT and CF.INSERTNX: key [CAPACITY capacity] [NOCREATE] ITEMS item [item ...]
+func cuckooInsert(args []string, cmdName string, nx bool) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	key := args[0]
+	opts := defaultCuckooOptions()
+	noCreate := false
+	itemsIdx := -1
+	var err error
+	for i := 1; i < len(args) && itemsIdx < 0; i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "CAPACITY" && i+1 < len(args):
+			i++
+			if opts.capacity, err = parseCuckooCapacity(args[i]); err != nil {
+				return Encode(err)
+			}
+		case option == "NOCREATE":
+			noCreate = true
+		case option == "ITEMS":
+			itemsIdx = i + 1
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+
+	if itemsIdx < 0 || itemsIdx >= len(args) {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	cf, exist := cuckooStore[key]
+	if !exist {
+		if noCreate {
+			return Encode(errors.New("(error) not found"))
+		}
+		if cf, err = createCuckooFilter(key, opts); err != nil {
+			return Encode(err)
+		}
+	}
+
+	return Encode(addCuckooItems(cf, args[itemsIdx:], nx))
+}
+
+// cmd: CF.RESERVE key capacity [BUCKETSIZE bucketsize] [MAXITERATIONS maxiterations] [EXPANSION expansion]
+func cmdCFRESERVE(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CF.RESERVE' command"))
+	}
+
+	key := args[0]
+	opts := defaultCuckooOptions()
+	var err error
+	if opts.capacity, err = parseCuckooCapacity(args[1]); err != nil {
+		return Encode(err)
+	}
+
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		if i+1 >= len(args) {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		i++
+
+		var v int64
+		switch option {
+		case "BUCKETSIZE":
+			if v, err = parseCuckooParam(args[i], 1, 255, "(error) Bad bucket size"); err != nil {
+				return Encode(err)
+			}
+			opts.bucketSize = uint8(v)
+		case "MAXITERATIONS":
+			if v, err = parseCuckooParam(args[i], 1, 65535, "(error) Bad max iterations"); err != nil {
+				return Encode(err)
+			}
+			opts.maxIterations = uint16(v)
+		case "EXPANSION":
+			if v, err = parseCuckooParam(args[i], 0, 32768, "(error) Bad expansion"); err != nil {
+				return Encode(err)
+			}
+			opts.expansion = uint16(v)
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+
+	if _, exist := cuckooStore[key]; exist {
+		return Encode(errors.New("(error) item exists"))
+	}
+	if _, err := createCuckooFilter(key, opts); err != nil {
+		return Encode(err)
+	}
+
+	return constant.RespOk
+}
+
+// cmd: CF.ADD key item
+func cmdCFADD(args []string) []byte {
+	return cuckooAdd(args, "CF.ADD", false)
+}
+
+// cmd: CF.ADDNX key item
+func cmdCFADDNX(args []string) []byte {
+	return cuckooAdd(args, "CF.ADDNX", true)
+}
+
+// cmd: CF.INSERT key [CAPACITY capacity] [NOCREATE] ITEMS item [item ...]
+func cmdCFINSERT(args []string) []byte {
+	return cuckooInsert(args, "CF.INSERT", false)
+}
+
+// cmd: CF.INSERTNX key [CAPACITY capacity] [NOCREATE] ITEMS item [item ...]
+func cmdCFINSERTNX(args []string) []byte {
+	return cuckooInsert(args, "CF.INSERTNX", true)
+}
+
+// cmd: CF.EXISTS key item
+func cmdCFEXISTS(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CF.EXISTS' command"))
+	}
+
+	cf, exist := cuckooStore[args[0]]
+	if !exist || !cf.Exists(args[1]) {
+		return Encode(0)
+	}
+
+	return Encode(1)
+}
+
+// cmd: CF.MEXISTS key item [item ...]
+func cmdCFMEXISTS(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CF.MEXISTS' command"))
+	}
+
+	cf, exist := cuckooStore[args[0]]
+	res := make([]interface{}, 0, len(args)-1)
+	for _, item := range args[1:] {
+		if exist && cf.Exists(item) {
+			res = append(res, 1)
+		} else {
+			res = append(res, 0)
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: CF.DEL key item
+func cmdCFDEL(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CF.DEL' command"))
+	}
+
+	cf, exist := cuckooStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) not found"))
+	}
+	if !cf.Delete(args[1]) {
+		return Encode(0)
+	}
+
+	return Encode(1)
+}
+
+// cmd: CF.COUNT key item
+func cmdCFCOUNT(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CF.COUNT' command"))
+	}
+
+	cf, exist := cuckooStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(cf.Count(args[1]))
+}
+
+// cmd: CF.INFO key
+func cmdCFINFO(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'CF.INFO' command"))
+	}
+
+	cf, exist := cuckooStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) not found"))
+	}
+
+	var numBuckets uint64 = 0
+	for _, f := range cf.Filters {
+		numBuckets += f.NumBuckets
+	}
+
+	return Encode([]interface{}{
+		"Size", int64(cf.Bytes()),
+		"Number of buckets", int64(numBuckets),
+		"Number of filters", len(cf.Filters),
+		"Number of items inserted", int64(cf.NumItems),
+		"Number of items deleted", int64(cf.NumDeletes),
+		"Bucket size", int(cf.BucketSize),
+		"Expansion rate", int(cf.Expansion),
+		"Max iterations", int(cf.MaxIterations),
+	})
+}
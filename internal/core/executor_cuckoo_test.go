@@ -0,0 +1,114 @@
+package core
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestCuckooCommands(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("CF.EXISTS cf a"), reply(0)},
+		{args("CF.COUNT cf a"), reply(0)},
+		{args("CF.ADD cf a"), reply(1)},
+		{args("CF.ADD cf a"), reply(1)},
+		{args("CF.COUNT cf a"), reply(2)},
+		{args("CF.ADDNX cf a"), reply(0)},
+		{args("CF.ADDNX cf b"), reply(1)},
+		{args("CF.MEXISTS cf a b c"), reply([]interface{}{1, 1, 0})},
+		{args("CF.MEXISTS missing a"), reply([]interface{}{0})},
+		{args("CF.DEL cf a"), reply(1)},
+		{args("CF.EXISTS cf a"), reply(1)},
+		{args("CF.DEL cf a"), reply(1)},
+		{args("CF.DEL cf a"), reply(0)},
+		{args("CF.EXISTS cf a"), reply(0)},
+		{args("CF.DEL missing a"), errReply("not found")},
+		// the filter is created with the default options by CF.ADD
+		{args("CF.INFO cf"), reply([]interface{}{
+			"Size", int64(1024),
+			"Number of buckets", int64(512),
+			"Number of filters", 1,
+			"Number of items inserted", int64(1),
+			"Number of items deleted", int64(2),
+			"Bucket size", 2,
+			"Expansion rate", 1,
+			"Max iterations", 20,
+		})},
+		{args("CF.INFO missing"), errReply("not found")},
+		{args("CF.ADD cf"), errReply("wrong number of arguments for 'CF.ADD' command")},
+	})
+}
+
+func TestCFRESERVE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("CF.RESERVE cf 1000 BUCKETSIZE 4 MAXITERATIONS 50 EXPANSION 3"), okReply},
+		{args("CF.RESERVE cf 1000"), errReply("item exists")},
+		{args("CF.INFO cf"), reply([]interface{}{
+			"Size", int64(1024),
+			"Number of buckets", int64(256),
+			"Number of filters", 1,
+			"Number of items inserted", int64(0),
+			"Number of items deleted", int64(0),
+			"Bucket size", 4,
+			"Expansion rate", 4,
+			"Max iterations", 50,
+		})},
+		{args("CF.RESERVE k 0"), errReply("Bad capacity")},
+		{args("CF.RESERVE k 10 BUCKETSIZE 256"), errReply("Bad bucket size")},
+		{args("CF.RESERVE k 10 MAXITERATIONS 0"), errReply("Bad max iterations")},
+		{args("CF.RESERVE k 10 EXPANSION 32769"), errReply("Bad expansion")},
+		{args("CF.RESERVE k 10 EXPANSION"), errReply("syntax error")},
+		{args("CF.RESERVE k 10 OTHER 1"), errReply("syntax error")},
+		{args("CF.RESERVE k 7 BUCKETSIZE 4"), errReply("Capacity must be at least (BucketSize * 2)")},
+		{args("CF.RESERVE k 536870912"), errReply("filter is too large")},
+		{args("CF.INFO k"), errReply("not found")},
+		{args("CF.RESERVE k"), errReply("wrong number of arguments for 'CF.RESERVE' command")},
+	})
+}
+
+func TestCFINSERT(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("CF.INSERT cf NOCREATE ITEMS a"), errReply("not found")},
+		{args("CF.INSERT cf CAPACITY 100 ITEMS a b a"), reply([]interface{}{1, 1, 1})},
+		{args("CF.INSERTNX cf ITEMS a c"), reply([]interface{}{0, 1})},
+		{args("CF.INSERTNX cf NOCREATE ITEMS d"), reply([]interface{}{1})},
+		{args("CF.COUNT cf a"), reply(2)},
+		{args("CF.INSERT k CAPACITY 0 ITEMS a"), errReply("Bad capacity")},
+		{args("CF.INSERT k CAPACITY 10 a"), errReply("syntax error")},
+		{args("CF.INSERT k CAPACITY 10 ITEMS"), errReply("wrong number of arguments for 'CF.INSERT' command")},
+	})
+}
+
+func TestCFADDFull(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do(args("CF.RESERVE cf 4 BUCKETSIZE 1 EXPANSION 0")...)
+	for i := 0; ; i++ {
+		got := c.do("CF.ADD", "cf", "item"+strconv.Itoa(i))
+		if got == errReply("Filter is full") {
+			break
+		}
+		if got != reply(1) || i > 4 {
+			t.Fatalf("CF.ADD cf item%d = %q in a filter of 4 slots", i, got)
+		}
+	}
+	if got := c.do("CF.INSERT", "cf", "ITEMS", "x", "y"); got != reply([]interface{}{-1, -1}) {
+		t.Fatalf("CF.INSERT into a full filter = %q, want -1 for each item", got)
+	}
+
+	// the sub-filter after 32768 buckets of 1 byte would be larger than the max size of a string
+	c.do(args("CF.RESERVE big 2 BUCKETSIZE 1 EXPANSION 32768")...)
+	var got string
+	for i := 0; got == "" || got == reply(1); i++ {
+		got = c.do("CF.ADD", "big", "item"+strconv.Itoa(i))
+	}
+	if got != errReply("filter is too large to expand") {
+		t.Fatalf("CF.ADD into a filter that can not expand = %q", got)
+	}
+}
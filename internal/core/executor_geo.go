@@ -0,0 +1,468 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"mtredis/internal/data_structure"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the geo commands store the points in sorted sets, the score of a member is the 52-bit geohash of its coordinates
+
+// get the conversion factor from the unit to meters
+func parseGeoUnit(s string) (float64, error) {
+	switch strings.ToLower(s) {
+	case "m":
+		return 1, nil
+	case "km":
+		return 1000, nil
+	case "ft":
+		return 0.3048, nil
+	case "mi":
+		return 1609.34, nil
+	default:
+		return 0, errors.New("(error) unsupported unit provided. please use M, KM, FT, MI")
+	}
+}
+
+func parseLongLat(longArg string, latArg string) (float64, float64, error) {
+	longitude, err := parseFloat(longArg)
+	if err != nil {
+		return 0, 0, errors.New("(error) value is not a valid float")
+	}
+	latitude, err := parseFloat(latArg)
+	if err != nil {
+		return 0, 0, errors.New("(error) value is not a valid float")
+	}
+
+	if !data_structure.GeoValidCoordinates(longitude, latitude) {
+		return 0, 0, fmt.Errorf("(error) invalid longitude,latitude pair %f,%f", longitude, latitude)
+	}
+
+	return longitude, latitude, nil
+}
+
+// format a coordinate with 17 decimals like Redis, without the trailing zeros
+func formatGeoCoord(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 17, 64)
+	s = strings.TrimRight(s, "0")
+
+	return strings.TrimSuffix(s, ".")
+}
+
+func formatGeoDistance(meters float64, conversion float64) string {
+	return strconv.FormatFloat(meters/conversion, 'f', 4, 64)
+}
+
+// get the coordinates of the member, false if the key or the member does not exist
+func getGeoPoint(key string, member string) (float64, float64, bool) {
+	zSet, exist := zSetStore[key]
+	if !exist {
+		return 0, 0, false
+	}
+
+	res, score := zSet.GetScore(member)
+	if res == -1 {
+		return 0, 0, false
+	}
+
+	longitude, latitude := data_structure.GeoHashDecodeToLongLat(uint64(score))
+
+	return longitude, latitude, true
+}
+
+// cmd: GEOADD key [NX | XX] [CH] longitude latitude member [longitude latitude member ...]
+func cmdGEOADD(args []string) []byte {
+	if len(args) < 4 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GEOADD' command"))
+	}
+
+	key := args[0]
+	flags := zAddFlags{}
+	i := 1
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			flags.nx = true
+			continue
+		case "XX":
+			flags.xx = true
+			continue
+		case "CH":
+			flags.ch = true
+			continue
+		}
+		break
+	}
+
+	if (len(args)-i)%3 != 0 || i == len(args) || (flags.nx && flags.xx) {
+		return Encode(errors.New("(error) syntax error"))
+	}
+
+	// validate all the points before adding anything, then add them like ZADD does
+	pairs := make([]string, 0, (len(args)-i)/3*2)
+	for ; i < len(args); i += 3 {
+		longitude, latitude, err := parseLongLat(args[i], args[i+1])
+		if err != nil {
+			return Encode(err)
+		}
+
+		hash, _ := data_structure.GeoHashEncodeWGS84(longitude, latitude)
+		pairs = append(pairs, strconv.FormatUint(hash.Bits, 10), args[i+2])
+	}
+
+	return zAdd(key, pairs, &flags)
+}
+
+// cmd: GEOPOS key [member [member ...]]
+func cmdGEOPOS(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GEOPOS' command"))
+	}
+
+	res := make([]interface{}, 0, len(args)-1)
+	for _, member := range args[1:] {
+		longitude, latitude, exist := getGeoPoint(args[0], member)
+		if !exist {
+			res = append(res, nil)
+			continue
+		}
+
+		res = append(res, []string{formatGeoCoord(longitude), formatGeoCoord(latitude)})
+	}
+
+	return Encode(res)
+}
+
+// cmd: GEODIST key member1 member2 [M | KM | FT | MI]
+func cmdGEODIST(args []string) []byte {
+	if len(args) != 3 && len(args) != 4 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GEODIST' command"))
+	}
+
+	conversion := 1.0
+	if len(args) == 4 {
+		var err error
+		if conversion, err = parseGeoUnit(args[3]); err != nil {
+			return Encode(err)
+		}
+	}
+
+	long1, lat1, exist1 := getGeoPoint(args[0], args[1])
+	long2, lat2, exist2 := getGeoPoint(args[0], args[2])
+	if !exist1 || !exist2 {
+		return Encode(nil)
+	}
+
+	return Encode(formatGeoDistance(data_structure.GeoDistance(long1, lat1, long2, lat2), conversion))
+}
+
+// cmd: GEOHASH key [member [member ...]]
+func cmdGEOHASH(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GEOHASH' command"))
+	}
+
+	zSet, exist := zSetStore[args[0]]
+	res := make([]interface{}, 0, len(args)-1)
+	for _, member := range args[1:] {
+		if !exist {
+			res = append(res, nil)
+			continue
+		}
+
+		found, score := zSet.GetScore(member)
+		if found == -1 {
+			res = append(res, nil)
+			continue
+		}
+
+		res = append(res, data_structure.GeoHashString(uint64(score)))
+	}
+
+	return Encode(res)
+}
+
+const (
+	geoSortNone = iota
+	geoSortAsc
+	geoSortDesc
+)
+
+type geoSearchOptions struct {
+	shape      data_structure.GeoShape
+	conversion float64 // from the unit of the shape to meters
+	fromMember bool
+	fromLonLat bool
+	byRadius   bool
+	byBox      bool
+	sort       int
+	count      int64
+	any        bool
+	withCoord  bool
+	withDist   bool
+	withHash   bool
+	storeDist  bool
+}
+
+// parse a non-negative distance and its unit, return the distance in meters
+func parseGeoDistance(value string, unit string, name string, opts *geoSearchOptions) (float64, error) {
+	distance, err := parseFloat(value)
+	if err != nil {
+		return 0, errors.New("(error) need numeric " + name)
+	}
+
+	conversion, err := parseGeoUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+	opts.conversion = conversion
+
+	return distance * conversion, nil
+}
+
+/*
+Parse the options of GEOSEARCH and GEOSEARCHSTORE, the FROMMEMBER member is looked up in the source key if it exists.
+The STOREDIST option is only accepted when storing.
+*/
+func parseGeoSearchOptions(args []string, srcKey string, cmdName string, store bool) (*geoSearchOptions, error) {
+	opts := &geoSearchOptions{conversion: 1}
+
+	for i := 0; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "FROMMEMBER" && i+1 < len(args):
+			if opts.fromMember || opts.fromLonLat {
+				return nil, errors.New("(error) syntax error")
+			}
+			// a missing source key gives an empty result, the member is only looked up in an existing one
+			if _, exist := zSetStore[srcKey]; exist {
+				longitude, latitude, exist := getGeoPoint(srcKey, args[i+1])
+				if !exist {
+					return nil, errors.New("(error) could not decode requested zset member")
+				}
+				opts.shape.Longitude, opts.shape.Latitude = longitude, latitude
+			}
+			opts.fromMember = true
+			i++
+		case option == "FROMLONLAT" && i+2 < len(args):
+			if opts.fromMember || opts.fromLonLat {
+				return nil, errors.New("(error) syntax error")
+			}
+			longitude, latitude, err := parseLongLat(args[i+1], args[i+2])
+			if err != nil {
+				return nil, err
+			}
+			opts.shape.Longitude, opts.shape.Latitude = longitude, latitude
+			opts.fromLonLat = true
+			i += 2
+		case option == "BYRADIUS" && i+2 < len(args):
+			if opts.byRadius || opts.byBox {
+				return nil, errors.New("(error) syntax error")
+			}
+			radius, err := parseGeoDistance(args[i+1], args[i+2], "radius", opts)
+			if err != nil {
+				return nil, err
+			}
+			if radius < 0 {
+				return nil, errors.New("(error) radius cannot be negative")
+			}
+			opts.shape.Radius = radius
+			opts.byRadius = true
+			i += 2
+		case option == "BYBOX" && i+3 < len(args):
+			if opts.byRadius || opts.byBox {
+				return nil, errors.New("(error) syntax error")
+			}
+			width, err := parseGeoDistance(args[i+1], args[i+3], "width", opts)
+			if err != nil {
+				return nil, err
+			}
+			height, err := parseGeoDistance(args[i+2], args[i+3], "height", opts)
+			if err != nil {
+				return nil, err
+			}
+			if width < 0 || height < 0 {
+				return nil, errors.New("(error) height or width cannot be negative")
+			}
+			opts.shape.IsBox, opts.shape.Width, opts.shape.Height = true, width, height
+			opts.byBox = true
+			i += 3
+		case option == "ASC":
+			opts.sort = geoSortAsc
+		case option == "DESC":
+			opts.sort = geoSortDesc
+		case option == "COUNT" && i+1 < len(args):
+			count, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, errors.New("(error) value is not an integer or out of range")
+			}
+			if count <= 0 {
+				return nil, errors.New("(error) COUNT must be > 0")
+			}
+			opts.count = count
+			i++
+			if i+1 < len(args) && strings.ToUpper(args[i+1]) == "ANY" {
+				opts.any = true
+				i++
+			}
+		case option == "ANY":
+			opts.any = true
+		case option == "WITHCOORD":
+			opts.withCoord = true
+		case option == "WITHDIST":
+			opts.withDist = true
+		case option == "WITHHASH":
+			opts.withHash = true
+		case option == "STOREDIST" && store:
+			opts.storeDist = true
+		default:
+			return nil, errors.New("(error) syntax error")
+		}
+	}
+
+	if opts.any && opts.count == 0 {
+		return nil, errors.New("(error) the ANY argument requires COUNT argument")
+	}
+	if store && (opts.withDist || opts.withHash || opts.withCoord) {
+		return nil, errors.New("(error) " + cmdName + " is not compatible with WITHDIST, WITHHASH and WITHCOORD options")
+	}
+	if !opts.fromMember && !opts.fromLonLat {
+		return nil, errors.New("(error) exactly one of FROMMEMBER or FROMLONLAT can be specified for " + cmdName)
+	}
+	if !opts.byRadius && !opts.byBox {
+		return nil, errors.New("(error) exactly one of BYRADIUS and BYBOX can be specified for " + cmdName)
+	}
+
+	// COUNT needs the results to be sorted to return the closest ones, unless ANY is given
+	if opts.count > 0 && opts.sort == geoSortNone && !opts.any {
+		opts.sort = geoSortAsc
+	}
+
+	return opts, nil
+}
+
+type geoPoint struct {
+	member    string
+	distance  float64 // in meters
+	score     float64
+	longitude float64
+	latitude  float64
+}
+
+/*
+Find the members of the sorted set inside the shape.
+Each cell around the center is a range of scores, only the members of these ranges are checked against the shape.
+With ANY, the search stops once count members are found.
+*/
+func geoSearch(zSet *data_structure.ZSet, opts *geoSearchOptions) []geoPoint {
+	res := make([]geoPoint, 0)
+	for _, area := range data_structure.GeoHashAreasOfShape(&opts.shape) {
+		if opts.any && int64(len(res)) >= opts.count {
+			break
+		}
+
+		min, max := area.ScoreRange()
+		r := &data_structure.ZScoreRange{Min: float64(min), Max: float64(max), MaxExclusive: true}
+		for _, e := range zSet.RangeByScore(r, false, 0, -1) {
+			longitude, latitude := data_structure.GeoHashDecodeToLongLat(uint64(e.Score))
+			distance, inside := opts.shape.Contains(longitude, latitude)
+			if !inside {
+				continue
+			}
+
+			res = append(res, geoPoint{member: e.Element, distance: distance, score: e.Score, longitude: longitude, latitude: latitude})
+			if opts.any && int64(len(res)) >= opts.count {
+				break
+			}
+		}
+	}
+
+	switch opts.sort {
+	case geoSortAsc:
+		sort.SliceStable(res, func(i, j int) bool { return res[i].distance < res[j].distance })
+	case geoSortDesc:
+		sort.SliceStable(res, func(i, j int) bool { return res[i].distance > res[j].distance })
+	}
+
+	if opts.count > 0 && int64(len(res)) > opts.count {
+		res = res[:opts.count]
+	}
+
+	return res
+}
+
+// cmd: GEOSEARCH key <FROMMEMBER member | FROMLONLAT longitude latitude> <BYRADIUS radius <M | KM | FT | MI> | BYBOX width height <M | KM | FT | MI>> [ASC | DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+func cmdGEOSEARCH(args []string) []byte {
+	if len(args) < 6 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GEOSEARCH' command"))
+	}
+
+	key := args[0]
+	opts, err := parseGeoSearchOptions(args[1:], key, "GEOSEARCH", false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	zSet, exist := zSetStore[key]
+	if !exist {
+		return Encode(make([]string, 0))
+	}
+
+	points := geoSearch(zSet, opts)
+	if !opts.withCoord && !opts.withDist && !opts.withHash {
+		res := make([]string, 0, len(points))
+		for _, p := range points {
+			res = append(res, p.member)
+		}
+		return Encode(res)
+	}
+
+	res := make([]interface{}, 0, len(points))
+	for _, p := range points {
+		item := []interface{}{p.member}
+		if opts.withDist {
+			item = append(item, formatGeoDistance(p.distance, opts.conversion))
+		}
+		if opts.withHash {
+			item = append(item, int64(p.score))
+		}
+		if opts.withCoord {
+			item = append(item, []string{formatGeoCoord(p.longitude), formatGeoCoord(p.latitude)})
+		}
+		res = append(res, item)
+	}
+
+	return Encode(res)
+}
+
+// cmd: GEOSEARCHSTORE destination source <FROMMEMBER member | FROMLONLAT longitude latitude> <BYRADIUS radius <M | KM | FT | MI> | BYBOX width height <M | KM | FT | MI>> [ASC | DESC] [COUNT count [ANY]] [STOREDIST]
+func cmdGEOSEARCHSTORE(args []string) []byte {
+	if len(args) < 7 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GEOSEARCHSTORE' command"))
+	}
+
+	dst, src := args[0], args[1]
+	opts, err := parseGeoSearchOptions(args[2:], src, "GEOSEARCHSTORE", true)
+	if err != nil {
+		return Encode(err)
+	}
+
+	zSet, exist := zSetStore[src]
+	if !exist {
+		return storeZSetOp(dst, nil)
+	}
+
+	// the score is the geohash, or the distance in the unit of the search with STOREDIST
+	scores := make(map[string]float64)
+	for _, p := range geoSearch(zSet, opts) {
+		if opts.storeDist {
+			scores[p.member] = p.distance / opts.conversion
+		} else {
+			scores[p.member] = p.score
+		}
+	}
+
+	return storeZSetOp(dst, scores)
+}
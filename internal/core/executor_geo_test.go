@@ -0,0 +1,130 @@
+package core
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+var (
+	palermoPos = []string{"13.36138933897018433", "38.11555639549629859"}
+	cataniaPos = []string{"15.08726745843887329", "37.50266842333162032"}
+)
+
+// the replies are the ones of the Redis documentation
+func TestGeoCommands(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("GEOADD Sicily 13.361389 38.115556 Palermo 15.087269 37.502669 Catania"), reply(2)},
+		{args("ZRANGE Sicily 0 -1 WITHSCORES"), reply([]string{"Palermo", "3479099956230698", "Catania", "3479447370796909"})},
+		{args("GEODIST Sicily Palermo Catania"), reply("166274.1516")},
+		{args("GEODIST Sicily Palermo Catania km"), reply("166.2742")},
+		{args("GEODIST Sicily Palermo Catania MI"), reply("103.3182")},
+		{args("GEODIST Sicily Palermo missing"), nilReply},
+		{args("GEODIST Sicily Palermo Catania yd"), errReply("unsupported unit provided. please use M, KM, FT, MI")},
+		{args("GEOHASH Sicily Palermo Catania missing"), reply([]interface{}{"sqc8b49rny0", "sqdtr74hyu0", nil})},
+		{args("GEOHASH missing Palermo"), reply([]interface{}{nil})},
+		{args("GEOPOS Sicily Palermo Catania missing"), reply([]interface{}{palermoPos, cataniaPos, nil})},
+		{args("GEOPOS Sicily"), reply([]interface{}{})},
+	})
+}
+
+func TestGEOADDFlags(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("GEOADD g 13.361389 38.115556 Palermo"), reply(1)},
+		{args("GEOADD g NX 15 37 Palermo 15.087269 37.502669 Catania"), reply(1)},
+		{args("GEOPOS g Palermo"), reply([]interface{}{palermoPos})},
+		{args("GEOADD g XX CH 15 37 Palermo 1 1 new"), reply(1)},
+		{args("GEOPOS g new"), reply([]interface{}{nil})},
+		{args("GEOADD g NX XX 1 1 a"), errReply("syntax error")},
+		{args("GEOADD g 1 1 a 2"), errReply("syntax error")},
+		{args("GEOADD g CH 1 1"), errReply("syntax error")},
+		{args("GEOADD g 1 x a"), errReply("value is not a valid float")},
+		// nothing is added when a point is invalid
+		{args("GEOADD g 1 1 ok 181 1 bad"), errReply("invalid longitude,latitude pair 181.000000,1.000000")},
+		{args("GEOADD g 1 86 bad"), errReply("invalid longitude,latitude pair 1.000000,86.000000")},
+		{args("ZCARD g"), reply(2)},
+		{args("GEOADD g 1 1"), errReply("wrong number of arguments for 'GEOADD' command")},
+	})
+}
+
+func TestGEOSEARCH(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("GEOADD Sicily 13.361389 38.115556 Palermo 15.087269 37.502669 Catania"), reply(2)},
+		{args("GEOADD Sicily 12.758489 38.788135 edge1 17.241510 38.788135 edge2"), reply(2)},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYRADIUS 200 km ASC"), reply([]string{"Catania", "Palermo"})},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYRADIUS 200 km DESC"), reply([]string{"Palermo", "Catania"})},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYBOX 400 400 km ASC WITHCOORD WITHDIST"), reply([]interface{}{
+			[]interface{}{"Catania", "56.4413", cataniaPos},
+			[]interface{}{"Palermo", "190.4424", palermoPos},
+			[]interface{}{"edge2", "279.7403", []string{"17.24151045083999634", "38.78813451624225195"}},
+			[]interface{}{"edge1", "279.7405", []string{"12.7584877610206604", "38.78813451624225195"}},
+		})},
+		{args("GEOSEARCH Sicily FROMMEMBER Palermo BYRADIUS 200 km DESC WITHDIST WITHHASH"), reply([]interface{}{
+			[]interface{}{"Catania", "166.2742", int64(3479447370796909)},
+			[]interface{}{"edge1", "91.4007", int64(3479273021651468)},
+			[]interface{}{"Palermo", "0.0000", int64(3479099956230698)},
+		})},
+		// COUNT returns the closest members
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYBOX 400 400 km COUNT 2"), reply([]string{"Catania", "Palermo"})},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYRADIUS 1 m"), reply([]string{})},
+		{args("GEOSEARCH Sicily FROMMEMBER missing BYRADIUS 1 km"), errReply("could not decode requested zset member")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYRADIUS 200 km ANY"), errReply("the ANY argument requires COUNT argument")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYRADIUS 200 km COUNT 0"), errReply("COUNT must be > 0")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYRADIUS -1 km"), errReply("radius cannot be negative")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYRADIUS x km"), errReply("need numeric radius")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYBOX 1 -1 km"), errReply("height or width cannot be negative")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 FROMMEMBER Palermo BYRADIUS 1 km"), errReply("syntax error")},
+		{args("GEOSEARCH Sicily BYRADIUS 1 km WITHDIST ASC"), errReply("exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 WITHDIST ASC"), errReply("exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37 BYRADIUS 1 km STOREDIST"), errReply("syntax error")},
+		{args("GEOSEARCH Sicily FROMLONLAT 15 37"), errReply("wrong number of arguments for 'GEOSEARCH' command")},
+	})
+}
+
+// a missing source key gives an empty result, even with FROMMEMBER
+func TestGEOSEARCHMissingKey(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("GEOSEARCH missing FROMMEMBER m BYRADIUS 10 km"), reply([]string{})},
+		{args("GEOSEARCH missing FROMLONLAT 15 37 BYBOX 10 10 km WITHDIST"), reply([]string{})},
+		{args("SET dst v"), okReply},
+		{args("GEOSEARCHSTORE dst missing FROMMEMBER m BYRADIUS 10 km"), reply(0)},
+		{args("GET dst"), nilReply},
+		{args("GEOSEARCH missing FROMMEMBER m BYRADIUS 10 km COUNT 0"), errReply("COUNT must be > 0")},
+	})
+}
+
+func TestGEOSEARCHSTORE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("GEOADD Sicily 13.361389 38.115556 Palermo 15.087269 37.502669 Catania"), reply(2)},
+		{args("GEOSEARCHSTORE dst Sicily FROMLONLAT 15 37 BYRADIUS 200 km"), reply(2)},
+		{args("ZRANGE dst 0 -1 WITHSCORES"), reply([]string{"Palermo", "3479099956230698", "Catania", "3479447370796909"})},
+		{args("GEOSEARCHSTORE dst Sicily FROMLONLAT 15 37 BYRADIUS 200 km STOREDIST"), reply(2)},
+		{args("ZRANGE dst 0 -1"), reply([]string{"Catania", "Palermo"})},
+		{args("GEOSEARCHSTORE dst Sicily FROMLONLAT 15 37 BYRADIUS 200 km COUNT 1"), reply(1)},
+		{args("ZRANGE dst 0 -1"), reply([]string{"Catania"})},
+		// an empty result deletes the destination
+		{args("GEOSEARCHSTORE dst Sicily FROMLONLAT 0 0 BYRADIUS 1 km"), reply(0)},
+		{args("ZCARD dst"), reply(0)},
+		{args("GEOSEARCHSTORE dst Sicily FROMLONLAT 15 37 BYRADIUS 200 km WITHDIST"), errReply("GEOSEARCHSTORE is not compatible with WITHDIST, WITHHASH and WITHCOORD options")},
+	})
+
+	// with STOREDIST, the score is the distance in the unit of the search
+	c := newTestClient(t)
+	c.do(args("GEOSEARCHSTORE dst Sicily FROMLONLAT 15 37 BYRADIUS 200 km STOREDIST")...)
+	for member, want := range map[string]float64{"Catania": 56.4413, "Palermo": 190.4424} {
+		value, _ := Decode([]byte(c.do("ZSCORE", "dst", member)))
+		if score, err := strconv.ParseFloat(value.(string), 64); err != nil || math.Abs(score-want) > 0.0001 {
+			t.Errorf("ZSCORE dst %s = %v, want %v", member, value, want)
+		}
+	}
+}
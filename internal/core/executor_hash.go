@@ -0,0 +1,779 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the hash is removed from the keyspace once its last field is gone
+func deleteHashIfEmpty(key string, hash *data_structure.Hash) {
+	if hash.Len() == 0 {
+		delete(hashStore, key)
+		delete(volatileHashStore, key)
+	}
+}
+
+/*
+Get the hash of the key after lazily deleting its expired fields.
+A hash that loses its last field this way is deleted from the keyspace.
+*/
+func getHash(key string) (*data_structure.Hash, bool) {
+	hash, exist := hashStore[key]
+	if !exist {
+		return nil, false
+	}
+
+	if hash.DeleteExpiredFields() > 0 {
+		deleteHashIfEmpty(key, hash)
+		if hash.Len() == 0 {
+			return nil, false
+		}
+	}
+
+	return hash, true
+}
+
+// get the hash of the key, it is created when it does not exist
+func getOrCreateHash(key string) *data_structure.Hash {
+	hash, exist := getHash(key)
+	if !exist {
+		hash = data_structure.CreateHash()
+		hashStore[key] = hash
+	}
+
+	return hash
+}
+
+func getHashField(key string, field string) (string, bool) {
+	hash, exist := getHash(key)
+	if !exist {
+		return "", false
+	}
+
+	return hash.Get(field)
+}
+
+// cmd: HSET key field value [field value ...]
+func cmdHSET(args []string) []byte {
+	if len(args) < 3 || len(args)%2 == 0 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HSET' command"))
+	}
+
+	hash := getOrCreateHash(args[0])
+	added := 0
+	for i := 1; i < len(args); i += 2 {
+		if hash.Set(args[i], args[i+1]) {
+			added++
+		}
+	}
+
+	return Encode(added)
+}
+
+// cmd: HSETNX key field value
+func cmdHSETNX(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HSETNX' command"))
+	}
+
+	hash := getOrCreateHash(args[0])
+	if hash.Exists(args[1]) {
+		return Encode(0)
+	}
+	hash.Set(args[1], args[2])
+
+	return Encode(1)
+}
+
+// cmd: HGET key field
+func cmdHGET(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HGET' command"))
+	}
+
+	value, exist := getHashField(args[0], args[1])
+	if !exist {
+		return constant.RespNil
+	}
+
+	return Encode(value)
+}
+
+// cmd: HMGET key field [field ...]
+func cmdHMGET(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HMGET' command"))
+	}
+
+	hash, exist := getHash(args[0])
+	res := make([]interface{}, len(args)-1)
+	if !exist {
+		return Encode(res)
+	}
+
+	for i, field := range args[1:] {
+		if value, exist := hash.Get(field); exist {
+			res[i] = value
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: HDEL key field [field ...]
+func cmdHDEL(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HDEL' command"))
+	}
+
+	key := args[0]
+	hash, exist := getHash(key)
+	if !exist {
+		return Encode(0)
+	}
+
+	deleted := 0
+	for _, field := range args[1:] {
+		if hash.Delete(field) {
+			deleted++
+		}
+	}
+	deleteHashIfEmpty(key, hash)
+
+	return Encode(deleted)
+}
+
+// cmd: HEXISTS key field
+func cmdHEXISTS(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HEXISTS' command"))
+	}
+
+	hash, exist := getHash(args[0])
+	if !exist || !hash.Exists(args[1]) {
+		return Encode(0)
+	}
+
+	return Encode(1)
+}
+
+// cmd: HLEN key
+func cmdHLEN(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HLEN' command"))
+	}
+
+	hash, exist := getHash(args[0])
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(hash.Len())
+}
+
+// cmd: HSTRLEN key field
+func cmdHSTRLEN(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HSTRLEN' command"))
+	}
+
+	hash, exist := getHash(args[0])
+	if !exist {
+		return Encode(0)
+	}
+
+	value, _ := hash.Get(args[1])
+
+	return Encode(len(value))
+}
+
+// cmd: HKEYS key
+func cmdHKEYS(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HKEYS' command"))
+	}
+
+	hash, exist := getHash(args[0])
+	if !exist {
+		return Encode(make([]string, 0))
+	}
+
+	return Encode(hash.Fields())
+}
+
+// cmd: HVALS key
+func cmdHVALS(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HVALS' command"))
+	}
+
+	hash, exist := getHash(args[0])
+	if !exist {
+		return Encode(make([]string, 0))
+	}
+
+	return Encode(hash.Values())
+}
+
+// cmd: HGETALL key
+func cmdHGETALL(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HGETALL' command"))
+	}
+
+	hash, exist := getHash(args[0])
+	if !exist {
+		return Encode(make([]string, 0))
+	}
+
+	return Encode(hash.GetAll())
+}
+
+// cmd: HINCRBY key field increment
+func cmdHINCRBY(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HINCRBY' command"))
+	}
+
+	delta, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	var current int64 = 0
+	if value, exist := getHashField(args[0], args[1]); exist {
+		n, ok := newStringValue(value).(int64)
+		if !ok {
+			return Encode(errors.New("(error) hash value is not an integer"))
+		}
+		current = n
+	}
+
+	if (delta < 0 && current < math.MinInt64-delta) || (delta > 0 && current > math.MaxInt64-delta) {
+		return Encode(errors.New("(error) increment or decrement would overflow"))
+	}
+
+	current += delta
+	getOrCreateHash(args[0]).SetKeepTTL(args[1], strconv.FormatInt(current, 10))
+
+	return Encode(current)
+}
+
+// cmd: HINCRBYFLOAT key field increment
+func cmdHINCRBYFLOAT(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HINCRBYFLOAT' command"))
+	}
+
+	delta, err := parseFloat(args[2])
+	if err != nil {
+		return Encode(errors.New("(error) value is not a valid float"))
+	}
+
+	var current float64 = 0
+	if value, exist := getHashField(args[0], args[1]); exist {
+		if current, err = parseFloat(value); err != nil {
+			return Encode(errors.New("(error) hash value is not a float"))
+		}
+	}
+
+	current += delta
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return Encode(errors.New("(error) increment would produce NaN or Infinity"))
+	}
+
+	res := formatScore(current)
+	getOrCreateHash(args[0]).SetKeepTTL(args[1], res)
+
+	return Encode(res)
+}
+
+// cmd: HRANDFIELD key [count [WITHVALUES]]
+func cmdHRANDFIELD(args []string) []byte {
+	if len(args) < 1 || len(args) > 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HRANDFIELD' command"))
+	}
+
+	var count int64 = 0
+	hasCount, withValues := len(args) > 1, false
+	if hasCount {
+		var err error
+		count, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+		if count < -math.MaxInt32 || count > math.MaxInt32 {
+			return Encode(errors.New("(error) value is out of range"))
+		}
+	}
+	if len(args) == 3 {
+		if strings.ToUpper(args[2]) != "WITHVALUES" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		withValues = true
+	}
+
+	hash, exist := getHash(args[0])
+	if !exist {
+		if hasCount {
+			return Encode(make([]string, 0))
+		}
+		return constant.RespNil
+	}
+
+	all := hash.GetAll()
+	numFields := len(all) / 2
+
+	if !hasCount {
+		return Encode(all[2*rand.Intn(numFields)])
+	}
+
+	indexes := randomIndexes(numFields, count)
+	res := make([]string, 0, len(indexes)*2)
+	for _, i := range indexes {
+		res = append(res, all[2*i])
+		if withValues {
+			res = append(res, all[2*i+1])
+		}
+	}
+
+	return Encode(res)
+}
+
+/*
+Pick random indexes in [0, n) following the count semantics of SRANDMEMBER and HRANDFIELD:
+a positive count returns up to count distinct indexes, a negative count returns exactly -count indexes that may repeat.
+Every index has the same probability to be picked.
+*/
+func randomIndexes(n int, count int64) []int {
+	if count < 0 {
+		res := make([]int, -count)
+		for i := range res {
+			res[i] = rand.Intn(n)
+		}
+		return res
+	}
+
+	k := int(min(count, int64(n)))
+	if k == n {
+		res := make([]int, n)
+		for i := range res {
+			res[i] = i
+		}
+		return res
+	}
+
+	// Floyd's sampling algorithm: k distinct indexes in O(k), whatever the size of the collection is
+	picked := make(map[int]struct{}, k)
+	res := make([]int, 0, k)
+	for j := n - k; j < n; j++ {
+		t := rand.Intn(j + 1)
+		if _, exist := picked[t]; exist {
+			t = j
+		}
+
+		picked[t] = struct{}{}
+		res = append(res, t)
+	}
+
+	return res
+}
+
+// cmd: HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+func cmdHSCAN(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HSCAN' command"))
+	}
+
+	opts, err := parseScanOptions(args[1:], true)
+	if err != nil {
+		return Encode(err)
+	}
+
+	hash, exist := getHash(args[0])
+	if !exist {
+		return Encode([]interface{}{"0", make([]string, 0)})
+	}
+
+	res := make([]string, 0)
+	next := hash.Scan(opts.cursor, opts.count, func(field string, value string) {
+		if opts.pattern != "" && !matchPattern(opts.pattern, field) {
+			return
+		}
+		res = append(res, field)
+		if !opts.noValues {
+			res = append(res, value)
+		}
+	})
+
+	return Encode([]interface{}{strconv.FormatUint(next, 10), res})
+}
+
+// parse: FIELDS numfields field [field ...], or FIELDS numfields field value [field value ...] when withValues is true
+func parseHashFields(args []string, withValues bool) ([]string, error) {
+	if len(args) < 2 || strings.ToUpper(args[0]) != "FIELDS" {
+		return nil, errors.New("(error) Mandatory argument FIELDS is missing or not at the right position")
+	}
+
+	numFields, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, errors.New("(error) value is not an integer or out of range")
+	}
+	if numFields <= 0 {
+		return nil, errors.New("(error) Parameter `numFields` should be greater than 0")
+	}
+
+	argsPerField := int64(1)
+	if withValues {
+		argsPerField = 2
+	}
+	if int64(len(args)-2) != numFields*argsPerField {
+		return nil, errors.New("(error) The `numfields` parameter must match the number of arguments")
+	}
+
+	return args[2:], nil
+}
+
+/*
+Convert a field expiration given in one of the units EX, PX, EXAT or PXAT to an absolute unix time in milliseconds.
+Unlike keys, 0 is accepted (the fields are deleted right away), and the result must not exceed HashFieldExpireTimeMax.
+*/
+func parseFieldExpireTime(unit string, value string) (int64, error) {
+	rangeErr := fmt.Errorf("(error) invalid expire time, must be >= 0 and <= %d", constant.HashFieldExpireTimeMax)
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("(error) value is not an integer or out of range")
+	}
+	if n < 0 {
+		return 0, rangeErr
+	}
+
+	if unit == "EX" || unit == "EXAT" {
+		if n > constant.HashFieldExpireTimeMax/1000 {
+			return 0, rangeErr
+		}
+		n *= 1000
+	}
+	if unit == "EX" || unit == "PX" {
+		n += time.Now().UnixMilli()
+	}
+	if n > constant.HashFieldExpireTimeMax {
+		return 0, rangeErr
+	}
+
+	return n, nil
+}
+
+// set the expiration time of a field, a time in the past deletes the field right away
+func expireHashField(key string, hash *data_structure.Hash, field string, expireAtMs int64) bool {
+	if expireAtMs <= time.Now().UnixMilli() {
+		hash.Delete(field)
+		return false
+	}
+
+	hash.SetFieldExpiry(field, expireAtMs)
+	volatileHashStore[key] = hash
+
+	return true
+}
+
+/*
+Shared implementation of HEXPIRE, HPEXPIRE, HEXPIREAT and HPEXPIREAT.
+The reply holds one code per field: -2 no such field, 0 the NX/XX/GT/LT condition is not met, 1 the expiration is set, 2 the field is deleted.
+*/
+func expireHashFields(args []string, cmdName string, unit string) []byte {
+	if len(args) < 4 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	key := args[0]
+	expireAtMs, err := parseFieldExpireTime(unit, args[1])
+	if err != nil {
+		return Encode(err)
+	}
+
+	pos := 2
+	condition := strings.ToUpper(args[pos])
+	switch condition {
+	case "NX", "XX", "GT", "LT":
+		pos++
+	default:
+		condition = ""
+	}
+
+	fields, err := parseHashFields(args[pos:], false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	res := make([]interface{}, len(fields))
+	hash, exist := getHash(key)
+	for i, field := range fields {
+		if !exist || !hash.Exists(field) {
+			res[i] = -2
+			continue
+		}
+
+		// a field without TTL is considered to have an infinite TTL for GT and LT
+		current, hasTTL := hash.GetFieldExpiry(field)
+		ok := true
+		switch condition {
+		case "NX":
+			ok = !hasTTL
+		case "XX":
+			ok = hasTTL
+		case "GT":
+			ok = hasTTL && expireAtMs > current
+		case "LT":
+			ok = !hasTTL || expireAtMs < current
+		}
+		if !ok {
+			res[i] = 0
+			continue
+		}
+
+		if expireHashField(key, hash, field, expireAtMs) {
+			res[i] = 1
+		} else {
+			res[i] = 2
+		}
+	}
+
+	if exist {
+		deleteHashIfEmpty(key, hash)
+	}
+
+	return Encode(res)
+}
+
+// cmd: HEXPIRE key seconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func cmdHEXPIRE(args []string) []byte {
+	return expireHashFields(args, "HEXPIRE", "EX")
+}
+
+// cmd: HPEXPIRE key milliseconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func cmdHPEXPIRE(args []string) []byte {
+	return expireHashFields(args, "HPEXPIRE", "PX")
+}
+
+// cmd: HEXPIREAT key unix-time-seconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func cmdHEXPIREAT(args []string) []byte {
+	return expireHashFields(args, "HEXPIREAT", "EXAT")
+}
+
+// cmd: HPEXPIREAT key unix-time-milliseconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func cmdHPEXPIREAT(args []string) []byte {
+	return expireHashFields(args, "HPEXPIREAT", "PXAT")
+}
+
+// shared implementation of HTTL and HPTTL, -2 means no such field and -1 means that the field has no TTL
+func ttlHashFields(args []string, cmdName string, inMs bool) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	fields, err := parseHashFields(args[1:], false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	res := make([]interface{}, len(fields))
+	hash, exist := getHash(args[0])
+	now := time.Now().UnixMilli()
+	for i, field := range fields {
+		if !exist || !hash.Exists(field) {
+			res[i] = -2
+			continue
+		}
+
+		exp, hasTTL := hash.GetFieldExpiry(field)
+		switch {
+		case !hasTTL:
+			res[i] = -1
+		case inMs:
+			res[i] = exp - now
+		default:
+			res[i] = (exp - now + 500) / 1000
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: HTTL key FIELDS numfields field [field ...]
+func cmdHTTL(args []string) []byte {
+	return ttlHashFields(args, "HTTL", false)
+}
+
+// cmd: HPTTL key FIELDS numfields field [field ...]
+func cmdHPTTL(args []string) []byte {
+	return ttlHashFields(args, "HPTTL", true)
+}
+
+// cmd: HPERSIST key FIELDS numfields field [field ...]
+func cmdHPERSIST(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HPERSIST' command"))
+	}
+
+	fields, err := parseHashFields(args[1:], false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	res := make([]interface{}, len(fields))
+	hash, exist := getHash(args[0])
+	for i, field := range fields {
+		switch {
+		case !exist || !hash.Exists(field):
+			res[i] = -2
+		case hash.PersistField(field):
+			res[i] = 1
+		default:
+			res[i] = -1
+		}
+	}
+
+	return Encode(res)
+}
+
+// parse an expiration option of HGETEX or HSETEX and its value, the time must be positive
+func parseHashExpireOption(unit string, value string, cmdName string) (int64, error) {
+	expireAtMs, err := parseExpireTime(unit, value, cmdName)
+	if err != nil {
+		return 0, err
+	}
+	if expireAtMs > constant.HashFieldExpireTimeMax {
+		return 0, fmt.Errorf("(error) invalid expire time in '%s' command", cmdName)
+	}
+
+	return expireAtMs, nil
+}
+
+// cmd: HGETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST] FIELDS numfields field [field ...]
+func cmdHGETEX(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HGETEX' command"))
+	}
+
+	key := args[0]
+	var expireAtMs int64 = -1
+	persist := false
+
+	pos := 1
+	switch opt := strings.ToUpper(args[pos]); opt {
+	case "PERSIST":
+		persist = true
+		pos++
+	case "EX", "PX", "EXAT", "PXAT":
+		if pos+1 >= len(args) {
+			return Encode(errors.New("(error) syntax error"))
+		}
+
+		var err error
+		if expireAtMs, err = parseHashExpireOption(opt, args[pos+1], "hgetex"); err != nil {
+			return Encode(err)
+		}
+		pos += 2
+	}
+
+	fields, err := parseHashFields(args[pos:], false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	res := make([]interface{}, len(fields))
+	hash, exist := getHash(key)
+	if !exist {
+		return Encode(res)
+	}
+
+	for i, field := range fields {
+		value, exist := hash.Get(field)
+		if !exist {
+			continue
+		}
+		res[i] = value
+
+		if persist {
+			hash.PersistField(field)
+		} else if expireAtMs > 0 {
+			expireHashField(key, hash, field, expireAtMs)
+		}
+	}
+	deleteHashIfEmpty(key, hash)
+
+	return Encode(res)
+}
+
+// cmd: HSETEX key [FNX | FXX] [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL] FIELDS numfields field value [field value ...]
+func cmdHSETEX(args []string) []byte {
+	if len(args) < 4 {
+		return Encode(errors.New("(error) wrong number of arguments for 'HSETEX' command"))
+	}
+
+	key := args[0]
+	condition := ""
+	var expireAtMs int64 = -1
+	keepTTL := false
+
+	pos := 1
+	for pos < len(args) && strings.ToUpper(args[pos]) != "FIELDS" {
+		opt := strings.ToUpper(args[pos])
+		switch {
+		case (opt == "FNX" || opt == "FXX") && condition == "":
+			condition = opt
+			pos++
+		case opt == "KEEPTTL" && expireAtMs < 0 && !keepTTL:
+			keepTTL = true
+			pos++
+		case (opt == "EX" || opt == "PX" || opt == "EXAT" || opt == "PXAT") && expireAtMs < 0 && !keepTTL && pos+1 < len(args):
+			var err error
+			if expireAtMs, err = parseHashExpireOption(opt, args[pos+1], "hsetex"); err != nil {
+				return Encode(err)
+			}
+			pos += 2
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+
+	pairs, err := parseHashFields(args[pos:], true)
+	if err != nil {
+		return Encode(err)
+	}
+
+	// FNX: none of the fields may exist, FXX: all the fields must exist
+	hash, exist := getHash(key)
+	for i := 0; i < len(pairs) && condition != ""; i += 2 {
+		fieldExist := exist && hash.Exists(pairs[i])
+		if (condition == "FNX" && fieldExist) || (condition == "FXX" && !fieldExist) {
+			return Encode(0)
+		}
+	}
+
+	hash = getOrCreateHash(key)
+	for i := 0; i < len(pairs); i += 2 {
+		field, value := pairs[i], pairs[i+1]
+		if keepTTL {
+			hash.SetKeepTTL(field, value)
+		} else {
+			hash.Set(field, value)
+		}
+
+		if expireAtMs > 0 {
+			expireHashField(key, hash, field, expireAtMs)
+		}
+	}
+	deleteHashIfEmpty(key, hash)
+
+	return Encode(1)
+}
@@ -0,0 +1,333 @@
+package core
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashCommands(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("HSET h f1 v1 f2 v2"), reply(2)},
+		{args("HSET h f1 v3 f3 v3"), reply(1)},
+		{args("HSET h f1"), errReply("wrong number of arguments for 'HSET' command")},
+		{args("HSETNX h f1 x"), reply(0)},
+		{args("HSETNX h f4 v4"), reply(1)},
+		{args("HGET h f1"), reply("v3")},
+		{args("HGET h missing"), nilReply},
+		{args("HGET missing f1"), nilReply},
+		{args("HMGET h f1 missing f2"), reply([]interface{}{"v3", nil, "v2"})},
+		{args("HEXISTS h f2"), reply(1)},
+		{args("HEXISTS h missing"), reply(0)},
+		{args("HLEN h"), reply(4)},
+		{args("HSTRLEN h f1"), reply(2)},
+		{args("HSTRLEN h missing"), reply(0)},
+		// the listpack keeps the insertion order
+		{args("HKEYS h"), reply([]string{"f1", "f2", "f3", "f4"})},
+		{args("HVALS h"), reply([]string{"v3", "v2", "v3", "v4"})},
+		{args("HGETALL h"), reply([]string{"f1", "v3", "f2", "v2", "f3", "v3", "f4", "v4"})},
+		{args("HDEL h f1 f2 missing"), reply(2)},
+		{args("HDEL h f3 f4"), reply(2)},
+		// the last deletion removes the key
+		{args("HLEN h"), reply(0)},
+		{args("OBJECT ENCODING h"), nilReply},
+		{args("HKEYS h"), reply([]string{})},
+		{args("HGETALL h"), reply([]string{})},
+	})
+}
+
+func TestHashEncoding(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("HSET", "h", "f", "v")
+	if got := c.do("OBJECT", "ENCODING", "h"); got != reply("listpack") {
+		t.Fatalf("OBJECT ENCODING = %q, want listpack", got)
+	}
+
+	c.do("HSET", "h", "long", strings.Repeat("x", 65))
+	if got := c.do("OBJECT", "ENCODING", "h"); got != reply("hashtable") {
+		t.Fatalf("OBJECT ENCODING = %q with a long value, want hashtable", got)
+	}
+
+	for i := range 129 {
+		c.do("HSET", "many", "f"+strconv.Itoa(i), "v")
+	}
+	if got := c.do("OBJECT", "ENCODING", "many"); got != reply("hashtable") {
+		t.Fatalf("OBJECT ENCODING = %q with 129 fields, want hashtable", got)
+	}
+	if got := c.do("HGET", "many", "f128"); got != reply("v") {
+		t.Fatalf("HGET many f128 = %q, want v", got)
+	}
+}
+
+func TestHINCRBY(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("HINCRBY h f 5"), reply(5)},
+		{args("HINCRBY h f -10"), reply(-5)},
+		{args("HINCRBY h f x"), errReply("value is not an integer or out of range")},
+		{args("HSET h s abc big 9223372036854775807"), reply(2)},
+		{args("HINCRBY h s 1"), errReply("hash value is not an integer")},
+		{args("HINCRBY h big 1"), errReply("increment or decrement would overflow")},
+		{args("HINCRBYFLOAT h g 10.5"), reply("10.5")},
+		{args("HINCRBYFLOAT h g 0.1"), reply("10.6")},
+		{args("HINCRBYFLOAT h g -10.6"), reply("0")},
+		{args("HINCRBYFLOAT h g 5.0e3"), reply("5000")},
+		{args("HINCRBYFLOAT h s 1"), errReply("hash value is not a float")},
+		{args("HINCRBYFLOAT h g x"), errReply("value is not a valid float")},
+		{args("HINCRBYFLOAT h g inf"), errReply("increment would produce NaN or Infinity")},
+		// large results are not written with all their digits
+		{args("HINCRBYFLOAT h l 1e308"), reply("1e+308")},
+		{args("HGET h l"), reply("1e+308")},
+	})
+}
+
+func TestHRANDFIELD(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	runReplyTests(t, []replyTest{
+		{args("HRANDFIELD missing"), nilReply},
+		{args("HRANDFIELD missing 5"), reply([]string{})},
+		{args("HSET h a 1 b 2 c 3"), reply(3)},
+		{args("HRANDFIELD h 0"), reply([]string{})},
+		{args("HRANDFIELD h 1 WITH"), errReply("syntax error")},
+		{args("HRANDFIELD h x"), errReply("value is not an integer or out of range")},
+		{args("HRANDFIELD h 4294967296"), errReply("value is out of range")},
+	})
+
+	values := map[string]string{"a": "1", "b": "2", "c": "3"}
+	for range 20 {
+		v, _ := Decode([]byte(c.do("HRANDFIELD", "h")))
+		if _, ok := values[v.(string)]; !ok {
+			t.Fatalf("HRANDFIELD h = %v", v)
+		}
+	}
+
+	// a positive count returns distinct fields, up to the size of the hash
+	v, _ := Decode([]byte(c.do("HRANDFIELD", "h", "5")))
+	fields := make([]string, 0)
+	for _, f := range v.([]interface{}) {
+		fields = append(fields, f.(string))
+	}
+	sort.Strings(fields)
+	if strings.Join(fields, ",") != "a,b,c" {
+		t.Fatalf("HRANDFIELD h 5 = %v, want all the fields once", fields)
+	}
+
+	// a negative count returns exactly -count fields, that may repeat
+	v, _ = Decode([]byte(c.do("HRANDFIELD", "h", "-10", "WITHVALUES")))
+	pairs := v.([]interface{})
+	if len(pairs) != 20 {
+		t.Fatalf("HRANDFIELD h -10 WITHVALUES returned %d elements, want 20", len(pairs))
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		if values[pairs[i].(string)] != pairs[i+1].(string) {
+			t.Fatalf("HRANDFIELD h -10 WITHVALUES: %v => %v", pairs[i], pairs[i+1])
+		}
+	}
+}
+
+func TestHSCANListPack(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("HSET", "h", "a", "1", "b", "2", "ab", "3")
+
+	// the listpack is returned in a single call whatever COUNT is
+	if got := c.do("HSCAN", "h", "0", "COUNT", "1"); got != reply([]interface{}{"0", []string{"a", "1", "b", "2", "ab", "3"}}) {
+		t.Fatalf("HSCAN h 0 COUNT 1 = %q", got)
+	}
+	if got := c.do("HSCAN", "h", "0", "MATCH", "a*", "NOVALUES"); got != reply([]interface{}{"0", []string{"a", "ab"}}) {
+		t.Fatalf("HSCAN h 0 MATCH a* NOVALUES = %q", got)
+	}
+	if got := c.do("HSCAN", "missing", "0"); got != reply([]interface{}{"0", []string{}}) {
+		t.Fatalf("HSCAN missing 0 = %q", got)
+	}
+
+	runReplyTests(t, []replyTest{
+		{args("HSCAN h x"), errReply("invalid cursor")},
+		{args("HSCAN h 0 COUNT 0"), errReply("syntax error")},
+		{args("HSCAN h 0 COUNT x"), errReply("value is not an integer or out of range")},
+		{args("HSCAN h 0 MATCH"), errReply("syntax error")},
+		{args("HSCAN h"), errReply("wrong number of arguments for 'HSCAN' command")},
+	})
+}
+
+// every field present during the whole scan is returned, while the hash table grows between the calls
+func TestHSCANHashTable(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	for i := range 200 {
+		c.do("HSET", "h", "f"+strconv.Itoa(i), "v"+strconv.Itoa(i))
+	}
+
+	added := 0
+	got := scanAll(t, c, []string{"HSCAN", "h"}, []string{"COUNT", "5"}, func() {
+		for range 20 {
+			c.do("HSET", "h", "new"+strconv.Itoa(added), "v")
+			added++
+		}
+	})
+	if added == 0 {
+		t.Fatalf("the scan completed in a single call")
+	}
+
+	seen := make(map[string]string)
+	for i := 0; i < len(got); i += 2 {
+		seen[got[i]] = got[i+1]
+	}
+	for i := range 200 {
+		field := "f" + strconv.Itoa(i)
+		if seen[field] != "v"+strconv.Itoa(i) {
+			t.Fatalf("%s = %q in the scan, want v%d", field, seen[field], i)
+		}
+	}
+}
+
+// MATCH filters the visited fields, a scan without changes returns each matching field once
+func TestHSCANMatch(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	for i := range 200 {
+		c.do("HSET", "h", "f"+strconv.Itoa(i), "v")
+	}
+
+	fields := scanAll(t, c, []string{"HSCAN", "h"}, []string{"COUNT", "10", "MATCH", "f1*", "NOVALUES"}, nil)
+	sort.Strings(fields)
+	want := make([]string, 0)
+	for i := range 200 {
+		if f := "f" + strconv.Itoa(i); strings.HasPrefix(f, "f1") {
+			want = append(want, f)
+		}
+	}
+	sort.Strings(want)
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Fatalf("HSCAN MATCH f1* returned %v, want %v", fields, want)
+	}
+}
+
+func TestHEXPIRE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("HEXPIRE missing 100 FIELDS 1 a"), reply([]interface{}{-2})},
+		{args("HSET h a 1 b 2 c 3"), reply(3)},
+		{args("HEXPIRE h 100 FIELDS 2 a missing"), reply([]interface{}{1, -2})},
+		{args("OBJECT ENCODING h"), reply("listpackex")},
+		{args("HTTL h FIELDS 3 a b missing"), reply([]interface{}{100, -1, -2})},
+		// NX: only the fields without TTL, XX: only the fields with a TTL
+		{args("HEXPIRE h 200 NX FIELDS 2 a b"), reply([]interface{}{0, 1})},
+		{args("HEXPIRE h 300 XX FIELDS 2 a c"), reply([]interface{}{1, 0})},
+		// GT: a field without TTL has an infinite one, LT: it accepts any TTL
+		{args("HEXPIRE h 400 GT FIELDS 2 a c"), reply([]interface{}{1, 0})},
+		{args("HEXPIRE h 50 LT FIELDS 2 a c"), reply([]interface{}{1, 1})},
+		{args("HTTL h FIELDS 3 a b c"), reply([]interface{}{50, 200, 50})},
+		{args("HPERSIST h FIELDS 3 a a missing"), reply([]interface{}{1, -1, -2})},
+		{args("HTTL h FIELDS 1 a"), reply([]interface{}{-1})},
+		// a time in the past deletes the field, the last one deletes the key
+		{args("HEXPIRE h 0 FIELDS 1 a"), reply([]interface{}{2})},
+		{args("HPEXPIREAT h 1 FIELDS 1 b"), reply([]interface{}{2})},
+		{args("HEXPIREAT h 1 FIELDS 1 c"), reply([]interface{}{2})},
+		{args("HLEN h"), reply(0)},
+		{args("OBJECT ENCODING h"), nilReply},
+	})
+}
+
+func TestHEXPIREErrors(t *testing.T) {
+	resetStores(t)
+
+	rangeErr := errReply("invalid expire time, must be >= 0 and <= 281474976710655")
+	runReplyTests(t, []replyTest{
+		{args("HSET h a 1"), reply(1)},
+		{args("HEXPIRE h 100 FIELD 1 a"), errReply("Mandatory argument FIELDS is missing or not at the right position")},
+		{args("HEXPIRE h 100 FIELDS 0 a"), errReply("Parameter `numFields` should be greater than 0")},
+		{args("HEXPIRE h 100 FIELDS 2 a"), errReply("The `numfields` parameter must match the number of arguments")},
+		{args("HEXPIRE h 100 FIELDS x a"), errReply("value is not an integer or out of range")},
+		{args("HEXPIRE h x FIELDS 1 a"), errReply("value is not an integer or out of range")},
+		{args("HEXPIRE h -1 FIELDS 1 a"), rangeErr},
+		{args("HPEXPIREAT h 281474976710656 FIELDS 1 a"), rangeErr},
+		{args("HEXPIRE h 9223372036854775807 FIELDS 1 a"), rangeErr},
+		{args("HEXPIRE h 100 FIELDS"), errReply("wrong number of arguments for 'HEXPIRE' command")},
+		{args("HTTL h FIELDS 2 a"), errReply("The `numfields` parameter must match the number of arguments")},
+		{args("HPERSIST h"), errReply("wrong number of arguments for 'HPERSIST' command")},
+	})
+}
+
+func TestHPTTL(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("HSET", "h", "a", "1")
+	c.do("HPEXPIRE", "h", "100000", "FIELDS", "1", "a")
+
+	v, _ := Decode([]byte(c.do("HPTTL", "h", "FIELDS", "1", "a")))
+	ttl := v.([]interface{})[0].(int64)
+	if ttl <= 99000 || ttl > 100000 {
+		t.Fatalf("HPTTL = %d, want about 100000", ttl)
+	}
+}
+
+func TestHashFieldsExpire(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("HSET", "lazy", "a", "1", "b", "2")
+	c.do("HPEXPIRE", "lazy", "1", "FIELDS", "1", "a")
+	c.do("HSET", "active", "a", "1")
+	c.do("HPEXPIRE", "active", "1", "FIELDS", "1", "a")
+	time.Sleep(5 * time.Millisecond)
+
+	// expired fields are deleted when the hash is accessed
+	runReplyTests(t, []replyTest{
+		{args("HGET lazy a"), nilReply},
+		{args("HLEN lazy"), reply(1)},
+		{args("HGETALL lazy"), reply([]string{"b", "2"})},
+		{args("OBJECT ENCODING lazy"), reply("listpack")},
+	})
+
+	// and by the active expiration, a hash without fields left is deleted
+	ActiveDeleteExpiredHashFields()
+	if _, exist := hashStore["active"]; exist {
+		t.Fatalf("the hash without fields left is not deleted")
+	}
+	if _, exist := volatileHashStore["active"]; exist {
+		t.Fatalf("the hash is still in the volatile hashes")
+	}
+}
+
+func TestHGETEXAndHSETEX(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("HGETEX missing FIELDS 1 a"), reply([]interface{}{nil})},
+		{args("HSET h a 1 b 2"), reply(2)},
+		{args("HGETEX h EX 100 FIELDS 2 a missing"), reply([]interface{}{"1", nil})},
+		{args("HTTL h FIELDS 2 a b"), reply([]interface{}{100, -1})},
+		{args("HGETEX h PERSIST FIELDS 1 a"), reply([]interface{}{"1"})},
+		{args("HTTL h FIELDS 1 a"), reply([]interface{}{-1})},
+		{args("HGETEX h EX 0 FIELDS 1 a"), errReply("invalid expire time in 'hgetex' command")},
+		{args("HGETEX h EX"), errReply("wrong number of arguments for 'HGETEX' command")},
+		{args("HSETEX h FNX FIELDS 2 a 5 c 6"), reply(0)},
+		{args("HSETEX h FNX EX 100 FIELDS 1 c 6"), reply(1)},
+		{args("HTTL h FIELDS 1 c"), reply([]interface{}{100})},
+		{args("HSETEX h FXX FIELDS 2 a 5 d 6"), reply(0)},
+		{args("HSETEX h FXX KEEPTTL FIELDS 2 a 5 c 7"), reply(1)},
+		{args("HTTL h FIELDS 2 a c"), reply([]interface{}{-1, 100})},
+		// without KEEPTTL, setting a field discards its TTL
+		{args("HSETEX h FIELDS 1 c 8"), reply(1)},
+		{args("HTTL h FIELDS 1 c"), reply([]interface{}{-1})},
+		{args("HMGET h a b c"), reply([]interface{}{"5", "2", "8"})},
+		{args("HSETEX h FNX FXX FIELDS 1 a 1"), errReply("syntax error")},
+		{args("HSETEX h EX 10 KEEPTTL FIELDS 1 a 1"), errReply("syntax error")},
+		{args("HSETEX h FIELDS 1 a"), errReply("The `numfields` parameter must match the number of arguments")},
+	})
+}
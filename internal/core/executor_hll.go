@@ -0,0 +1,140 @@
+package core
+
+import (
+	"errors"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+)
+
+func hllError(err error) error {
+	if errors.Is(err, data_structure.ErrHLLCorrupted) {
+		return errors.New("(error) INVALIDOBJ Corrupted HLL object detected")
+	}
+
+	return errors.New("(error) WRONGTYPE Key is not a valid HyperLogLog string value.")
+}
+
+// get the HyperLogLog of the key, the object is nil if the key does not exist
+func getHLL(key string) (*data_structure.Obj, []byte, error) {
+	obj := dictStore.GetObj(key)
+	if obj == nil {
+		return nil, nil, nil
+	}
+
+	b := bitmapValue(obj)
+	if err := data_structure.HLLValidate(b); err != nil {
+		return nil, nil, hllError(err)
+	}
+
+	return obj, b, nil
+}
+
+// cmd: PFADD key [element [element ...]]
+func cmdPFADD(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'PFADD' command"))
+	}
+
+	key := args[0]
+	obj, b, err := getHLL(key)
+	if err != nil {
+		return Encode(err)
+	}
+
+	created := false
+	if obj == nil {
+		b = data_structure.CreateHLL()
+		obj = dictStore.NewObj(key, b, -1)
+		dictStore.SetObj(key, obj)
+		created = true
+	}
+
+	b, updated, err := data_structure.HLLAdd(b, args[1:])
+	if err != nil {
+		return Encode(hllError(err))
+	}
+	obj.Value = b
+
+	if created || updated {
+		return Encode(1)
+	}
+
+	return Encode(0)
+}
+
+// cmd: PFCOUNT key [key ...]
+func cmdPFCOUNT(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'PFCOUNT' command"))
+	}
+
+	// with a single key, the cached cardinality is used and updated
+	if len(args) == 1 {
+		obj, b, err := getHLL(args[0])
+		if err != nil {
+			return Encode(err)
+		}
+		if obj == nil {
+			return Encode(0)
+		}
+
+		count, err := data_structure.HLLCount(b)
+		if err != nil {
+			return Encode(hllError(err))
+		}
+		return Encode(int64(count))
+	}
+
+	// with several keys, the cardinality of their union is computed on the fly
+	regs := make([]uint8, data_structure.HLLRegisters)
+	for _, key := range args {
+		obj, b, err := getHLL(key)
+		if err != nil {
+			return Encode(err)
+		}
+		if obj == nil {
+			continue
+		}
+		if err := data_structure.HLLMergeRegisters(regs, b); err != nil {
+			return Encode(hllError(err))
+		}
+	}
+
+	return Encode(int64(data_structure.HLLCountRegisters(regs)))
+}
+
+/*
+cmd: PFMERGE destkey [sourcekey [sourcekey ...]]
+The destination is part of the union if it exists, it is stored as dense if any of the inputs is dense.
+*/
+func cmdPFMERGE(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'PFMERGE' command"))
+	}
+
+	regs := make([]uint8, data_structure.HLLRegisters)
+	dense := false
+	for _, key := range args {
+		obj, b, err := getHLL(key)
+		if err != nil {
+			return Encode(err)
+		}
+		if obj == nil {
+			continue
+		}
+		if err := data_structure.HLLMergeRegisters(regs, b); err != nil {
+			return Encode(hllError(err))
+		}
+		dense = dense || data_structure.HLLIsDense(b)
+	}
+
+	// an existing destination keeps its TTL
+	b := data_structure.HLLFromRegisters(regs, dense)
+	if obj := dictStore.GetObj(args[0]); obj != nil {
+		obj.Value = b
+	} else {
+		dictStore.SetObj(args[0], dictStore.NewObj(args[0], b, -1))
+	}
+
+	return constant.RespOk
+}
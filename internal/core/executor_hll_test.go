@@ -0,0 +1,92 @@
+package core
+
+import (
+	"mtredis/internal/data_structure"
+	"strconv"
+	"testing"
+)
+
+// the replies are the ones of the Redis documentation
+func TestHLLCommands(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("PFCOUNT hll"), reply(0)},
+		{args("PFADD hll foo bar zap"), reply(1)},
+		{args("PFADD hll zap zap zap"), reply(0)},
+		{args("PFADD hll foo bar"), reply(0)},
+		{args("PFCOUNT hll"), reply(3)},
+		{args("PFADD some-other-hll 1 2 3"), reply(1)},
+		{args("PFCOUNT hll some-other-hll"), reply(6)},
+		{args("PFCOUNT hll missing"), reply(3)},
+		// a new key is created even without elements
+		{args("PFADD empty"), reply(1)},
+		{args("PFADD empty"), reply(0)},
+		{args("PFCOUNT empty"), reply(0)},
+		{[]string{"GET", "empty"}, reply(string(data_structure.CreateHLL()))},
+		{args("PFMERGE hll3 hll some-other-hll missing"), okReply},
+		{args("PFCOUNT hll3"), reply(6)},
+		// the destination is part of the union
+		{args("PFADD hll4 x"), reply(1)},
+		{args("PFMERGE hll4 hll"), okReply},
+		{args("PFCOUNT hll4"), reply(4)},
+		{args("PFMERGE new"), okReply},
+		{args("PFCOUNT new"), reply(0)},
+		{args("SET str value"), okReply},
+		{args("PFADD str a"), errReply("WRONGTYPE Key is not a valid HyperLogLog string value.")},
+		{args("PFCOUNT hll str"), errReply("WRONGTYPE Key is not a valid HyperLogLog string value.")},
+		{args("PFMERGE hll str"), errReply("WRONGTYPE Key is not a valid HyperLogLog string value.")},
+		{args("PFADD"), errReply("wrong number of arguments for 'PFADD' command")},
+	})
+}
+
+// a HyperLogLog is a string, it can be copied with GET and SET
+func TestHLLGetSet(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	for i := range 5000 {
+		c.do("PFADD", "hll", "item"+strconv.Itoa(i))
+	}
+	count := c.do("PFCOUNT", "hll")
+
+	value, _ := Decode([]byte(c.do("GET", "hll")))
+	b := []byte(value.(string))
+	if !data_structure.HLLIsDense(b) {
+		t.Fatalf("the HyperLogLog of 5000 items is not dense")
+	}
+	// the cached cardinality is valid after PFCOUNT
+	if b[15]&0x80 != 0 {
+		t.Fatalf("the cache is invalid after PFCOUNT")
+	}
+
+	c.do("SET", "copy", string(b))
+	if got := c.do("PFCOUNT", "copy"); got != count {
+		t.Fatalf("PFCOUNT of the copy = %q, want %q", got, count)
+	}
+	if got := c.do("PFADD", "copy", "item0"); got != reply(0) {
+		t.Fatalf("PFADD of an existing item to the copy = %q", got)
+	}
+}
+
+// a dense register above Q + 1 = 51 can not come from an item, the object is corrupted
+func TestHLLCorruptedDenseRegister(t *testing.T) {
+	resetStores(t)
+
+	regs := make([]uint8, data_structure.HLLRegisters)
+	regs[100] = 51
+	valid := data_structure.HLLFromRegisters(regs, true)
+	regs[100] = 63
+	corrupted := data_structure.HLLFromRegisters(regs, true)
+
+	runReplyTests(t, []replyTest{
+		{[]string{"SET", "valid", string(valid)}, okReply},
+		{[]string{"SET", "corrupted", string(corrupted)}, okReply},
+		{args("PFCOUNT valid"), reply(1)},
+		{args("PFCOUNT corrupted"), errReply("INVALIDOBJ Corrupted HLL object detected")},
+		{args("PFCOUNT valid corrupted"), errReply("INVALIDOBJ Corrupted HLL object detected")},
+		{args("PFMERGE dst corrupted"), errReply("INVALIDOBJ Corrupted HLL object detected")},
+		{[]string{"SET", "truncated", string(valid[:100])}, okReply},
+		{args("PFCOUNT truncated"), errReply("WRONGTYPE Key is not a valid HyperLogLog string value.")},
+	})
+}
@@ -0,0 +1,604 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// the list is removed from the keyspace once its last element is gone
+func deleteListIfEmpty(key string, list *data_structure.QuickList) {
+	if list.Len() == 0 {
+		delete(listStore, key)
+	}
+}
+
+func pushList(args []string, cmdName string, toHead bool, onlyIfExist bool) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	key := args[0]
+	list, exist := listStore[key]
+	if !exist {
+		if onlyIfExist {
+			return Encode(0)
+		}
+
+		list = data_structure.CreateQuickList()
+		listStore[key] = list
+	}
+
+	if toHead {
+		list.PushHead(args[1:]...)
+	} else {
+		list.PushTail(args[1:]...)
+	}
+	signalKeyAsReady(key)
+
+	return Encode(list.Len())
+}
+
+// cmd: LPUSH key element [element ...]
+func cmdLPUSH(args []string) []byte {
+	return pushList(args, "LPUSH", true, false)
+}
+
+// cmd: RPUSH key element [element ...]
+func cmdRPUSH(args []string) []byte {
+	return pushList(args, "RPUSH", false, false)
+}
+
+// cmd: LPUSHX key element [element ...]
+func cmdLPUSHX(args []string) []byte {
+	return pushList(args, "LPUSHX", true, true)
+}
+
+// cmd: RPUSHX key element [element ...]
+func cmdRPUSHX(args []string) []byte {
+	return pushList(args, "RPUSHX", false, true)
+}
+
+func popList(args []string, cmdName string, fromHead bool) []byte {
+	if len(args) < 1 || len(args) > 2 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	key := args[0]
+	count := -1 // no count given, reply with a single element
+	if len(args) == 2 {
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || n < 0 {
+			return Encode(errors.New("(error) value is out of range, must be positive"))
+		}
+		count = int(n)
+	}
+
+	list, exist := listStore[key]
+	if !exist {
+		if count >= 0 {
+			return constant.RespNilArray
+		}
+		return constant.RespNil
+	}
+
+	if count < 0 {
+		res, _ := popListElement(list, fromHead)
+		deleteListIfEmpty(key, list)
+		return Encode(res)
+	}
+
+	res := make([]string, 0, min(count, list.Len()))
+	for len(res) < count && list.Len() > 0 {
+		v, _ := popListElement(list, fromHead)
+		res = append(res, v)
+	}
+	deleteListIfEmpty(key, list)
+
+	return Encode(res)
+}
+
+func popListElement(list *data_structure.QuickList, fromHead bool) (string, bool) {
+	if fromHead {
+		return list.PopHead()
+	}
+
+	return list.PopTail()
+}
+
+// cmd: LPOP key [count]
+func cmdLPOP(args []string) []byte {
+	return popList(args, "LPOP", true)
+}
+
+// cmd: RPOP key [count]
+func cmdRPOP(args []string) []byte {
+	return popList(args, "RPOP", false)
+}
+
+// cmd: LLEN key
+func cmdLLEN(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LLEN' command"))
+	}
+
+	list, exist := listStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(list.Len())
+}
+
+/*
+Convert a [start, stop] range with possibly negative indexes to 0-based indexes within [0, length).
+An empty range is returned as start > stop.
+*/
+func normalizeListRange(start int64, stop int64, length int) (int, int) {
+	l := int64(length)
+	if start < 0 {
+		start += l
+	}
+	if stop < 0 {
+		stop += l
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= l {
+		stop = l - 1
+	}
+	if start > stop || start >= l {
+		return 1, 0
+	}
+
+	return int(start), int(stop)
+}
+
+// cmd: LRANGE key start stop
+func cmdLRANGE(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LRANGE' command"))
+	}
+
+	start, err1 := strconv.ParseInt(args[1], 10, 64)
+	stop, err2 := strconv.ParseInt(args[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	list, exist := listStore[args[0]]
+	if !exist {
+		return Encode(make([]string, 0))
+	}
+
+	from, to := normalizeListRange(start, stop, list.Len())
+	if from > to {
+		return Encode(make([]string, 0))
+	}
+
+	return Encode(list.Range(from, to))
+}
+
+// cmd: LINDEX key index
+func cmdLINDEX(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LINDEX' command"))
+	}
+
+	index, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	list, exist := listStore[args[0]]
+	if !exist {
+		return constant.RespNil
+	}
+
+	if index < 0 {
+		index += int64(list.Len())
+	}
+	res, ok := list.Index(int(index))
+	if !ok {
+		return constant.RespNil
+	}
+
+	return Encode(res)
+}
+
+// cmd: LSET key index element
+func cmdLSET(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LSET' command"))
+	}
+
+	index, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	list, exist := listStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) no such key"))
+	}
+
+	if index < 0 {
+		index += int64(list.Len())
+	}
+	if !list.Set(int(index), args[2]) {
+		return Encode(errors.New("(error) index out of range"))
+	}
+
+	return constant.RespOk
+}
+
+// cmd: LINSERT key <BEFORE | AFTER> pivot element
+func cmdLINSERT(args []string) []byte {
+	if len(args) != 4 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LINSERT' command"))
+	}
+
+	var after bool
+	switch strings.ToUpper(args[1]) {
+	case "BEFORE":
+		after = false
+	case "AFTER":
+		after = true
+	default:
+		return Encode(errors.New("(error) syntax error"))
+	}
+
+	list, exist := listStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	if !list.Insert(args[2], args[3], after) {
+		return Encode(-1)
+	}
+
+	return Encode(list.Len())
+}
+
+// cmd: LREM key count element
+func cmdLREM(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LREM' command"))
+	}
+
+	count, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	key := args[0]
+	list, exist := listStore[key]
+	if !exist {
+		return Encode(0)
+	}
+
+	removed := list.Remove(int(count), args[2])
+	deleteListIfEmpty(key, list)
+
+	return Encode(removed)
+}
+
+// cmd: LTRIM key start stop
+func cmdLTRIM(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LTRIM' command"))
+	}
+
+	start, err1 := strconv.ParseInt(args[1], 10, 64)
+	stop, err2 := strconv.ParseInt(args[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	key := args[0]
+	list, exist := listStore[key]
+	if !exist {
+		return constant.RespOk
+	}
+
+	from, to := normalizeListRange(start, stop, list.Len())
+	list.Trim(from, to)
+	deleteListIfEmpty(key, list)
+
+	return constant.RespOk
+}
+
+// cmd: LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func cmdLPOS(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LPOS' command"))
+	}
+
+	var rank, count, maxLen int64 = 1, -1, 0 // count = -1 means that COUNT is not given
+
+	for i := 2; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if (opt != "RANK" && opt != "COUNT" && opt != "MAXLEN") || i+1 >= len(args) {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		i++
+
+		n, err := strconv.ParseInt(args[i], 10, 64)
+		if err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+
+		switch opt {
+		case "RANK":
+			if n == 0 {
+				return Encode(errors.New("(error) RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list"))
+			}
+			rank = n
+		case "COUNT":
+			if n < 0 {
+				return Encode(errors.New("(error) COUNT can't be negative"))
+			}
+			count = n
+		case "MAXLEN":
+			if n < 0 {
+				return Encode(errors.New("(error) MAXLEN can't be negative"))
+			}
+			maxLen = n
+		}
+	}
+
+	list, exist := listStore[args[0]]
+	if !exist {
+		if count >= 0 {
+			return Encode(make([]interface{}, 0))
+		}
+		return constant.RespNil
+	}
+
+	// a negative rank scans from the tail, skipping the first |rank|-1 matches
+	fromTail := rank < 0
+	skip := rank - 1
+	if fromTail {
+		skip = -rank - 1
+	}
+
+	res := make([]interface{}, 0)
+	var scanned int64 = 0
+	list.Iterate(fromTail, func(index int, value string) bool {
+		if maxLen > 0 && scanned >= maxLen {
+			return false
+		}
+		scanned++
+
+		if value != args[1] {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+
+		res = append(res, index)
+
+		switch {
+		case count == 0: // all the matches
+			return true
+		case count < 0: // only the first match
+			return false
+		default:
+			return int64(len(res)) < count
+		}
+	})
+
+	if count >= 0 {
+		return Encode(res)
+	}
+	if len(res) == 0 {
+		return constant.RespNil
+	}
+
+	return Encode(res[0])
+}
+
+/*
+Pop an element from one side of the source list and push it to one side of the destination list.
+Source and destination can be the same list, which rotates it.
+*/
+func moveListElement(srcKey string, dstKey string, fromHead bool, toHead bool) (string, bool) {
+	src, exist := listStore[srcKey]
+	if !exist {
+		return "", false
+	}
+
+	value, _ := popListElement(src, fromHead)
+	deleteListIfEmpty(srcKey, src)
+
+	dst, exist := listStore[dstKey]
+	if !exist {
+		dst = data_structure.CreateQuickList()
+		listStore[dstKey] = dst
+	}
+
+	if toHead {
+		dst.PushHead(value)
+	} else {
+		dst.PushTail(value)
+	}
+	signalKeyAsReady(dstKey)
+
+	return value, true
+}
+
+// parse LEFT | RIGHT, return true for LEFT
+func parseListSide(s string) (bool, error) {
+	switch strings.ToUpper(s) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	default:
+		return false, errors.New("(error) syntax error")
+	}
+}
+
+// cmd: LMOVE source destination <LEFT | RIGHT> <LEFT | RIGHT>
+func cmdLMOVE(args []string) []byte {
+	if len(args) != 4 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LMOVE' command"))
+	}
+
+	fromHead, err := parseListSide(args[2])
+	if err != nil {
+		return Encode(err)
+	}
+	toHead, err := parseListSide(args[3])
+	if err != nil {
+		return Encode(err)
+	}
+
+	value, ok := moveListElement(args[0], args[1], fromHead, toHead)
+	if !ok {
+		return constant.RespNil
+	}
+
+	return Encode(value)
+}
+
+// cmd: RPOPLPUSH source destination
+func cmdRPOPLPUSH(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'RPOPLPUSH' command"))
+	}
+
+	value, ok := moveListElement(args[0], args[1], false, true)
+	if !ok {
+		return constant.RespNil
+	}
+
+	return Encode(value)
+}
+
+func blockingPopList(args []string, cmdName string, fromHead bool, fd int) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	keys := args[:len(args)-1]
+	deadline, err := parseBlockingTimeout(args[len(args)-1])
+	if err != nil {
+		return Encode(err)
+	}
+
+	return blockForKeys(fd, keys, deadline, blockedOnList, func(key string) []byte {
+		list, exist := listStore[key]
+		if !exist {
+			return nil
+		}
+
+		value, _ := popListElement(list, fromHead)
+		deleteListIfEmpty(key, list)
+
+		return Encode([]string{key, value})
+	})
+}
+
+// cmd: BLPOP key [key ...] timeout
+func cmdBLPOP(args []string, fd int) []byte {
+	return blockingPopList(args, "BLPOP", true, fd)
+}
+
+// cmd: BRPOP key [key ...] timeout
+func cmdBRPOP(args []string, fd int) []byte {
+	return blockingPopList(args, "BRPOP", false, fd)
+}
+
+// cmd: BLMOVE source destination <LEFT | RIGHT> <LEFT | RIGHT> timeout
+func cmdBLMOVE(args []string, fd int) []byte {
+	if len(args) != 5 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BLMOVE' command"))
+	}
+
+	srcKey, dstKey := args[0], args[1]
+	fromHead, err := parseListSide(args[2])
+	if err != nil {
+		return Encode(err)
+	}
+	toHead, err := parseListSide(args[3])
+	if err != nil {
+		return Encode(err)
+	}
+	deadline, err := parseBlockingTimeout(args[4])
+	if err != nil {
+		return Encode(err)
+	}
+
+	return blockForKeys(fd, []string{srcKey}, deadline, blockedOnList, func(key string) []byte {
+		value, ok := moveListElement(srcKey, dstKey, fromHead, toHead)
+		if !ok {
+			return nil
+		}
+
+		return Encode(value)
+	})
+}
+
+// cmd: BLMPOP timeout numkeys key [key ...] <LEFT | RIGHT> [COUNT count]
+func cmdBLMPOP(args []string, fd int) []byte {
+	if len(args) < 4 {
+		return Encode(errors.New("(error) wrong number of arguments for 'BLMPOP' command"))
+	}
+
+	deadline, err := parseBlockingTimeout(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	numKeys, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || numKeys <= 0 {
+		return Encode(errors.New("(error) numkeys should be greater than 0"))
+	}
+	if numKeys > int64(len(args)-3) {
+		return Encode(errors.New("(error) syntax error"))
+	}
+
+	keys := args[2 : 2+numKeys]
+	rest := args[2+numKeys:]
+
+	fromHead, err := parseListSide(rest[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	count := 1
+	if len(rest) > 1 {
+		if len(rest) != 3 || strings.ToUpper(rest[1]) != "COUNT" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+
+		n, err := strconv.ParseInt(rest[2], 10, 64)
+		if err != nil || n <= 0 {
+			return Encode(errors.New("(error) count should be greater than 0"))
+		}
+		count = int(min(n, math.MaxInt32))
+	}
+
+	return blockForKeys(fd, keys, deadline, blockedOnList, func(key string) []byte {
+		list, exist := listStore[key]
+		if !exist {
+			return nil
+		}
+
+		values := make([]string, 0, min(count, list.Len()))
+		for len(values) < count && list.Len() > 0 {
+			v, _ := popListElement(list, fromHead)
+			values = append(values, v)
+		}
+		deleteListIfEmpty(key, list)
+
+		return Encode([]interface{}{key, values})
+	})
+}
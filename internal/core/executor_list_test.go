@@ -0,0 +1,142 @@
+package core
+
+import (
+	"mtredis/internal/constant"
+	"testing"
+)
+
+func TestListPushPop(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("RPUSH l b c"), reply(2)},
+		{args("LPUSH l a z"), reply(4)},
+		{args("LRANGE l 0 -1"), reply([]string{"z", "a", "b", "c"})},
+		{args("LPUSHX missing a"), reply(0)},
+		{args("RPUSHX l d"), reply(5)},
+		{args("LLEN l"), reply(5)},
+		{args("LPOP l"), reply("z")},
+		{args("RPOP l"), reply("d")},
+		{args("LPOP l 2"), reply([]string{"a", "b"})},
+		{args("RPOP l 0"), reply([]string{})},
+		{args("LPOP l -1"), errReply("value is out of range, must be positive")},
+		{args("RPOP l 10"), reply([]string{"c"})},
+		// the last pop deletes the key
+		{args("LLEN l"), reply(0)},
+		{args("OBJECT ENCODING l"), nilReply},
+		{args("LPOP l"), nilReply},
+		{args("LPOP l 1"), string(constant.RespNilArray)},
+		{args("RPUSHX l a"), reply(0)},
+		{args("LPUSH l"), errReply("wrong number of arguments for 'LPUSH' command")},
+	})
+}
+
+func TestLRANGEAndLINDEX(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("LRANGE l 0 -1"), reply([]string{})},
+		{args("RPUSH l one two three"), reply(3)},
+		{args("OBJECT ENCODING l"), reply("quicklist")},
+		{args("LRANGE l 0 0"), reply([]string{"one"})},
+		{args("LRANGE l -3 2"), reply([]string{"one", "two", "three"})},
+		{args("LRANGE l -100 100"), reply([]string{"one", "two", "three"})},
+		{args("LRANGE l 5 10"), reply([]string{})},
+		{args("LRANGE l 2 1"), reply([]string{})},
+		{args("LRANGE l a 1"), errReply("value is not an integer or out of range")},
+		{args("LINDEX l 0"), reply("one")},
+		{args("LINDEX l -1"), reply("three")},
+		{args("LINDEX l 3"), nilReply},
+		{args("LINDEX missing 0"), nilReply},
+	})
+}
+
+func TestLSETAndLINSERT(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("LSET l 0 x"), errReply("no such key")},
+		{args("RPUSH l Hello World"), reply(2)},
+		{args("LINSERT l BEFORE World There"), reply(3)},
+		{args("LINSERT l AFTER World !"), reply(4)},
+		{args("LRANGE l 0 -1"), reply([]string{"Hello", "There", "World", "!"})},
+		{args("LINSERT l BEFORE missing x"), reply(-1)},
+		{args("LINSERT missing BEFORE a x"), reply(0)},
+		{args("LINSERT l MIDDLE World x"), errReply("syntax error")},
+		{args("LSET l 0 four"), okReply},
+		{args("LSET l -2 five"), okReply},
+		{args("LRANGE l 0 -1"), reply([]string{"four", "There", "five", "!"})},
+		{args("LSET l 4 x"), errReply("index out of range")},
+		{args("LSET l -5 x"), errReply("index out of range")},
+	})
+}
+
+func TestLREMAndLTRIM(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("RPUSH l hello hello foo hello"), reply(4)},
+		{args("LREM l -2 hello"), reply(2)},
+		{args("LRANGE l 0 -1"), reply([]string{"hello", "foo"})},
+		{args("RPUSH l hello hello"), reply(4)},
+		{args("LREM l 1 hello"), reply(1)},
+		{args("LRANGE l 0 -1"), reply([]string{"foo", "hello", "hello"})},
+		{args("LREM l 0 hello"), reply(2)},
+		{args("LREM missing 0 hello"), reply(0)},
+		{args("LREM l 0 foo"), reply(1)},
+		{args("LLEN l"), reply(0)},
+		{args("RPUSH t one two three four"), reply(4)},
+		{args("LTRIM t 1 -2"), okReply},
+		{args("LRANGE t 0 -1"), reply([]string{"two", "three"})},
+		{args("LTRIM t -100 100"), okReply},
+		{args("LRANGE t 0 -1"), reply([]string{"two", "three"})},
+		// an empty range deletes the key
+		{args("LTRIM t 5 10"), okReply},
+		{args("OBJECT ENCODING t"), nilReply},
+		{args("LTRIM missing 0 1"), okReply},
+	})
+}
+
+func TestLPOS(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("RPUSH l a b c d 1 2 3 4 3 3 3"), reply(11)},
+		{args("LPOS l 3"), reply(6)},
+		{args("LPOS l 3 COUNT 0 RANK 2"), reply([]interface{}{8, 9, 10})},
+		{args("LPOS l 3 RANK -1"), reply(10)},
+		{args("LPOS l 3 RANK -2 COUNT 2"), reply([]interface{}{9, 8})},
+		{args("LPOS l 3 COUNT 0 MAXLEN 7"), reply([]interface{}{6})},
+		{args("LPOS l 3 MAXLEN 6"), nilReply},
+		{args("LPOS l x"), nilReply},
+		{args("LPOS l x COUNT 2"), reply([]interface{}{})},
+		{args("LPOS missing x"), nilReply},
+		{args("LPOS missing x COUNT 1"), reply([]interface{}{})},
+		{args("LPOS l 3 RANK 0"), errReply("RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")},
+		{args("LPOS l 3 COUNT -1"), errReply("COUNT can't be negative")},
+		{args("LPOS l 3 MAXLEN -1"), errReply("MAXLEN can't be negative")},
+		{args("LPOS l 3 BOGUS 1"), errReply("syntax error")},
+		{args("LPOS l 3 RANK"), errReply("syntax error")},
+	})
+}
+
+func TestLMOVEAndRPOPLPUSH(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("RPUSH src one two three"), reply(3)},
+		{args("LMOVE src dst RIGHT LEFT"), reply("three")},
+		{args("LMOVE src dst LEFT RIGHT"), reply("one")},
+		{args("LRANGE src 0 -1"), reply([]string{"two"})},
+		{args("LRANGE dst 0 -1"), reply([]string{"three", "one"})},
+		// the same list as source and destination rotates it
+		{args("LMOVE dst dst LEFT RIGHT"), reply("three")},
+		{args("LRANGE dst 0 -1"), reply([]string{"one", "three"})},
+		{args("RPOPLPUSH src dst"), reply("two")},
+		{args("LRANGE dst 0 -1"), reply([]string{"two", "one", "three"})},
+		{args("OBJECT ENCODING src"), nilReply},
+		{args("RPOPLPUSH src dst"), nilReply},
+		{args("LMOVE missing dst LEFT LEFT"), nilReply},
+		{args("LMOVE dst src UP LEFT"), errReply("syntax error")},
+	})
+}
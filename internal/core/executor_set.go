@@ -0,0 +1,368 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the set is removed from the keyspace once its last member is gone
+func deleteSetIfEmpty(key string, set *data_structure.SimpleSet) {
+	if set.Len() == 0 {
+		delete(setStore, key)
+	}
+}
+
+// get the sets of the keys, a missing key gives a nil set (an empty set)
+func getSets(keys []string) []*data_structure.SimpleSet {
+	sets := make([]*data_structure.SimpleSet, len(keys))
+	for i, key := range keys {
+		sets[i] = setStore[key]
+	}
+
+	return sets
+}
+
+/*
+Intersect the sets, stopping once limit members are found (0 means no limit).
+The smallest set is iterated and its members are looked up in the other sets, from the smallest to the largest,
+so the cost depends on the size of the smallest set rather than on the size of the largest ones.
+*/
+func intersectSets(sets []*data_structure.SimpleSet, limit int) []string {
+	for _, set := range sets {
+		if set == nil {
+			return make([]string, 0)
+		}
+	}
+
+	sorted := append([]*data_structure.SimpleSet(nil), sets...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Len() < sorted[j].Len()
+	})
+
+	res := make([]string, 0)
+	for _, member := range sorted[0].Members() {
+		inAll := true
+		for _, other := range sorted[1:] {
+			if other.IsMember(member) == 0 {
+				inAll = false
+				break
+			}
+		}
+
+		if inAll {
+			res = append(res, member)
+			if limit > 0 && len(res) >= limit {
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+func unionSets(sets []*data_structure.SimpleSet) []string {
+	union := data_structure.CreateSimpleSet("")
+	for _, set := range sets {
+		if set != nil {
+			union.Add(set.Members()...)
+		}
+	}
+
+	return union.Members()
+}
+
+// members of the first set that are in none of the other sets
+func diffSets(sets []*data_structure.SimpleSet) []string {
+	res := make([]string, 0)
+	if sets[0] == nil {
+		return res
+	}
+
+	for _, member := range sets[0].Members() {
+		inOther := false
+		for _, other := range sets[1:] {
+			if other != nil && other.IsMember(member) == 1 {
+				inOther = true
+				break
+			}
+		}
+
+		if !inOther {
+			res = append(res, member)
+		}
+	}
+
+	return res
+}
+
+// overwrite the destination with a new set holding the members, an empty result deletes the destination
+func storeSet(destKey string, members []string) []byte {
+	deleteKey(destKey)
+	if len(members) == 0 {
+		return Encode(0)
+	}
+
+	set := data_structure.CreateSimpleSet(destKey)
+	set.Add(members...)
+	setStore[destKey] = set
+
+	return Encode(set.Len())
+}
+
+// cmd: SINTER key [key ...]
+func cmdSINTER(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SINTER' command"))
+	}
+
+	return Encode(intersectSets(getSets(args), 0))
+}
+
+// cmd: SUNION key [key ...]
+func cmdSUNION(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SUNION' command"))
+	}
+
+	return Encode(unionSets(getSets(args)))
+}
+
+// cmd: SDIFF key [key ...]
+func cmdSDIFF(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SDIFF' command"))
+	}
+
+	return Encode(diffSets(getSets(args)))
+}
+
+// cmd: SINTERSTORE destination key [key ...]
+func cmdSINTERSTORE(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SINTERSTORE' command"))
+	}
+
+	return storeSet(args[0], intersectSets(getSets(args[1:]), 0))
+}
+
+// cmd: SUNIONSTORE destination key [key ...]
+func cmdSUNIONSTORE(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SUNIONSTORE' command"))
+	}
+
+	return storeSet(args[0], unionSets(getSets(args[1:])))
+}
+
+// cmd: SDIFFSTORE destination key [key ...]
+func cmdSDIFFSTORE(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SDIFFSTORE' command"))
+	}
+
+	return storeSet(args[0], diffSets(getSets(args[1:])))
+}
+
+// cmd: SINTERCARD numkeys key [key ...] [LIMIT limit]
+func cmdSINTERCARD(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SINTERCARD' command"))
+	}
+
+	numKeys, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+	if numKeys <= 0 {
+		return Encode(errors.New("(error) numkeys should be greater than 0"))
+	}
+	if numKeys > int64(len(args)-1) {
+		return Encode(errors.New("(error) Number of keys can't be greater than number of args"))
+	}
+
+	keys := args[1 : 1+numKeys]
+	rest := args[1+numKeys:]
+	var limit int64 = 0
+	for i := 0; i < len(rest); i++ {
+		if strings.ToUpper(rest[i]) != "LIMIT" || i+1 >= len(rest) {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		i++
+
+		if limit, err = strconv.ParseInt(rest[i], 10, 64); err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+		if limit < 0 {
+			return Encode(errors.New("(error) LIMIT can't be negative"))
+		}
+	}
+
+	return Encode(len(intersectSets(getSets(keys), int(limit))))
+}
+
+// cmd: SMISMEMBER key member [member ...]
+func cmdSMISMEMBER(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SMISMEMBER' command"))
+	}
+
+	set, exist := setStore[args[0]]
+	res := make([]interface{}, len(args)-1)
+	for i, member := range args[1:] {
+		res[i] = 0
+		if exist {
+			res[i] = set.IsMember(member)
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: SMOVE source destination member
+func cmdSMOVE(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SMOVE' command"))
+	}
+
+	srcKey, dstKey, member := args[0], args[1], args[2]
+	src, exist := setStore[srcKey]
+	if !exist || src.IsMember(member) == 0 {
+		return Encode(0)
+	}
+
+	if srcKey == dstKey {
+		return Encode(1)
+	}
+
+	src.Remove(member)
+	deleteSetIfEmpty(srcKey, src)
+
+	dst, exist := setStore[dstKey]
+	if !exist {
+		dst = data_structure.CreateSimpleSet(dstKey)
+		setStore[dstKey] = dst
+	}
+	dst.Add(member)
+
+	return Encode(1)
+}
+
+// cmd: SCARD key
+func cmdSCARD(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SCARD' command"))
+	}
+
+	set, exist := setStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(set.Len())
+}
+
+// cmd: SPOP key [count]
+func cmdSPOP(args []string) []byte {
+	if len(args) < 1 || len(args) > 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SPOP' command"))
+	}
+
+	key := args[0]
+	count := -1 // no count given, reply with a single member
+	if len(args) == 2 {
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || n < 0 {
+			return Encode(errors.New("(error) value is out of range, must be positive"))
+		}
+		count = int(min(n, math.MaxInt32))
+	}
+
+	set, exist := setStore[key]
+	if !exist {
+		if count >= 0 {
+			return Encode(make([]string, 0))
+		}
+		return constant.RespNil
+	}
+
+	if count < 0 {
+		res := set.Pop()
+		deleteSetIfEmpty(key, set)
+		return Encode(res)
+	}
+
+	res := make([]string, 0, min(count, set.Len()))
+	for len(res) < count && set.Len() > 0 {
+		res = append(res, set.Pop())
+	}
+	deleteSetIfEmpty(key, set)
+
+	return Encode(res)
+}
+
+// cmd: SRANDMEMBER key [count]
+func cmdSRANDMEMBER(args []string) []byte {
+	if len(args) < 1 || len(args) > 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SRANDMEMBER' command"))
+	}
+
+	set, exist := setStore[args[0]]
+	if len(args) == 1 {
+		if !exist {
+			return constant.RespNil
+		}
+		return Encode(set.RandomMember())
+	}
+
+	count, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+	if count < -math.MaxInt32 || count > math.MaxInt32 {
+		return Encode(errors.New("(error) value is out of range"))
+	}
+
+	if !exist {
+		return Encode(make([]string, 0))
+	}
+
+	// a positive count gives distinct members, a negative count allows the same member to be returned several times
+	indexes := randomIndexes(set.Len(), count)
+	res := make([]string, len(indexes))
+	for i, idx := range indexes {
+		res[i] = set.MemberAt(idx)
+	}
+
+	return Encode(res)
+}
+
+// cmd: SSCAN key cursor [MATCH pattern] [COUNT count]
+func cmdSSCAN(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SSCAN' command"))
+	}
+
+	opts, err := parseScanOptions(args[1:], false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	set, exist := setStore[args[0]]
+	if !exist {
+		return Encode([]interface{}{"0", make([]string, 0)})
+	}
+
+	members := make([]string, 0)
+	next := set.Scan(opts.cursor, opts.count, func(member string) {
+		if opts.pattern == "" || matchPattern(opts.pattern, member) {
+			members = append(members, member)
+		}
+	})
+
+	return Encode([]interface{}{strconv.FormatUint(next, 10), members})
+}
@@ -0,0 +1,309 @@
+package core
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// check that the members of the set are the expected ones, in any order
+func checkMembers(t *testing.T, c *testClient, key string, want ...string) {
+	t.Helper()
+
+	if got := c.sorted("SMEMBERS", key); !reflect.DeepEqual(got, want) && (len(got) != 0 || len(want) != 0) {
+		t.Errorf("SMEMBERS %s = %v, want %v", key, got, want)
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("SADD", "key1", "a", "b", "c", "d")
+	c.do("SADD", "key2", "c")
+	c.do("SADD", "key3", "a", "c", "e")
+
+	for _, tt := range []struct {
+		line string
+		want []string
+	}{
+		{"SINTER key1 key2 key3", []string{"c"}},
+		{"SINTER key1 key3", []string{"a", "c"}},
+		{"SINTER key1 missing", []string{}},
+		{"SINTER key1", []string{"a", "b", "c", "d"}},
+		{"SUNION key1 key2 key3", []string{"a", "b", "c", "d", "e"}},
+		{"SUNION missing key2", []string{"c"}},
+		{"SDIFF key1 key2 key3", []string{"b", "d"}},
+		{"SDIFF key1 missing", []string{"a", "b", "c", "d"}},
+		{"SDIFF missing key1", []string{}},
+	} {
+		if got := c.sorted(args(tt.line)...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+
+	runReplyTests(t, []replyTest{
+		{args("SINTER"), errReply("wrong number of arguments for 'SINTER' command")},
+		{args("SUNIONSTORE dest"), errReply("wrong number of arguments for 'SUNIONSTORE' command")},
+	})
+}
+
+func TestSetStore(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("SADD", "key1", "a", "b", "c")
+	c.do("SADD", "key2", "c", "d")
+
+	runReplyTests(t, []replyTest{
+		{args("SINTERSTORE dest key1 key2"), reply(1)},
+		{args("SUNIONSTORE dest key1 key2"), reply(4)},
+		{args("SDIFFSTORE dest key1 key2"), reply(2)},
+	})
+	checkMembers(t, c, "dest", "a", "b")
+
+	// the destination can be one of the sources
+	runReplyTests(t, []replyTest{
+		{args("SUNIONSTORE key1 key1 key2"), reply(4)},
+	})
+	checkMembers(t, c, "key1", "a", "b", "c", "d")
+
+	// an empty result deletes the destination
+	runReplyTests(t, []replyTest{
+		{args("SINTERSTORE dest key1 missing"), reply(0)},
+		{args("SCARD dest"), reply(0)},
+		{args("OBJECT ENCODING dest"), nilReply},
+	})
+}
+
+// the destination is overwritten whatever the type of the value it holds
+func TestSetStoreOverwritesOtherTypes(t *testing.T) {
+	for _, create := range []string{
+		"SET dest v",
+		"RPUSH dest v",
+		"HSET dest f v",
+		"ZADD dest 1 v",
+	} {
+		t.Run(create, func(t *testing.T) {
+			resetStores(t)
+
+			c := newTestClient(t)
+			c.do("SADD", "src", "a")
+			c.do(args(create)...)
+			if got := c.do("SUNIONSTORE", "dest", "src"); got != reply(1) {
+				t.Fatalf("SUNIONSTORE = %q, want 1", got)
+			}
+			if got := c.do("OBJECT", "ENCODING", "dest"); got != reply("hashtable") {
+				t.Fatalf("OBJECT ENCODING dest = %q, want the encoding of a set", got)
+			}
+			checkMembers(t, c, "dest", "a")
+			for _, line := range []string{"GET dest", "ZSCORE dest v", "HGET dest f", "LINDEX dest 0"} {
+				if got := c.do(args(line)...); got != nilReply {
+					t.Fatalf("%s = %q, the old value is still there", line, got)
+				}
+			}
+
+			// an empty result deletes the old value too
+			c.do(args(create)...)
+			if got := c.do("SINTERSTORE", "dest", "src", "missing"); got != reply(0) {
+				t.Fatalf("SINTERSTORE = %q, want 0", got)
+			}
+			if got := c.do("OBJECT", "ENCODING", "dest"); got != nilReply {
+				t.Fatalf("OBJECT ENCODING dest = %q after an empty result, want nil", got)
+			}
+		})
+	}
+}
+
+func TestSINTERCARD(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("SADD key1 a b c d"), reply(4)},
+		{args("SADD key2 c d e"), reply(3)},
+		{args("SINTERCARD 2 key1 key2"), reply(2)},
+		{args("SINTERCARD 2 key1 key2 LIMIT 1"), reply(1)},
+		{args("SINTERCARD 2 key1 key2 LIMIT 0"), reply(2)},
+		{args("SINTERCARD 1 key1"), reply(4)},
+		{args("SINTERCARD 2 key1 missing"), reply(0)},
+		{args("SINTERCARD 0 key1"), errReply("numkeys should be greater than 0")},
+		{args("SINTERCARD 3 key1 key2"), errReply("Number of keys can't be greater than number of args")},
+		{args("SINTERCARD 2 key1 key2 LIMIT -1"), errReply("LIMIT can't be negative")},
+		{args("SINTERCARD 2 key1 key2 LIMIT"), errReply("syntax error")},
+		{args("SINTERCARD x key1"), errReply("value is not an integer or out of range")},
+	})
+}
+
+func TestSMISMEMBERAndSMOVE(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	runReplyTests(t, []replyTest{
+		{args("SADD src a b"), reply(2)},
+		{args("SMISMEMBER src a x b"), reply([]interface{}{1, 0, 1})},
+		{args("SMISMEMBER missing a"), reply([]interface{}{0})},
+		{args("SMOVE src dst a"), reply(1)},
+		{args("SMOVE src dst missing"), reply(0)},
+		{args("SMOVE missing dst a"), reply(0)},
+		{args("SMOVE src src b"), reply(1)},
+		{args("SMOVE src dst b"), reply(1)},
+		{args("SCARD src"), reply(0)},
+	})
+	checkMembers(t, c, "dst", "a", "b")
+}
+
+func TestSREMAndSCARD(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("SADD s 1 2 a"), reply(3)},
+		{args("SCARD s"), reply(3)},
+		{args("SCARD missing"), reply(0)},
+		{args("SREM s 1 x 1"), reply(1)},
+		{args("SREM missing a"), reply(0)},
+		{args("SREM s 2 a"), reply(2)},
+		{args("SCARD s"), reply(0)},
+		{args("OBJECT ENCODING s"), nilReply},
+	})
+}
+
+func TestSPOP(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	runReplyTests(t, []replyTest{
+		{args("SPOP missing"), nilReply},
+		{args("SPOP missing 2"), reply([]string{})},
+		{args("SADD s a"), reply(1)},
+		{args("SPOP s 0"), reply([]string{})},
+		{args("SPOP s -1"), errReply("value is out of range, must be positive")},
+		{args("SPOP s"), reply("a")},
+		{args("OBJECT ENCODING s"), nilReply},
+	})
+
+	c.do("SADD", "s", "a", "b", "c", "d")
+	popped := c.sorted("SPOP", "s", "3")
+	rest := c.sorted("SMEMBERS", "s")
+	if len(popped) != 3 || len(rest) != 1 {
+		t.Fatalf("SPOP s 3 = %v, %v left", popped, rest)
+	}
+	all := append(append([]string{}, popped...), rest...)
+	sort.Strings(all)
+	if !reflect.DeepEqual(all, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("SPOP s 3 = %v, %v left, want a, b, c and d", popped, rest)
+	}
+	if got := c.sorted("SPOP", "s", "10"); !reflect.DeepEqual(got, rest) {
+		t.Fatalf("SPOP s 10 = %v, want %v", got, rest)
+	}
+}
+
+func TestSRANDMEMBER(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	runReplyTests(t, []replyTest{
+		{args("SRANDMEMBER missing"), nilReply},
+		{args("SRANDMEMBER missing 3"), reply([]string{})},
+		{args("SADD s a b c"), reply(3)},
+		{args("SRANDMEMBER s 0"), reply([]string{})},
+		{args("SRANDMEMBER s x"), errReply("value is not an integer or out of range")},
+		{args("SRANDMEMBER s -4294967296"), errReply("value is out of range")},
+	})
+
+	if got := c.sorted("SRANDMEMBER", "s", "10"); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("SRANDMEMBER s 10 = %v, want all the members once", got)
+	}
+	if got := c.sorted("SRANDMEMBER", "s", "2"); len(got) != 2 || got[0] == got[1] {
+		t.Fatalf("SRANDMEMBER s 2 = %v, want 2 distinct members", got)
+	}
+	got := c.sorted("SRANDMEMBER", "s", "-20")
+	if len(got) != 20 || got[0] < "a" || got[19] > "c" {
+		t.Fatalf("SRANDMEMBER s -20 = %v, want 20 members", got)
+	}
+	// the set is left untouched
+	if got := c.do("SCARD", "s"); got != reply(3) {
+		t.Fatalf("SCARD s = %q after SRANDMEMBER", got)
+	}
+}
+
+func TestSSCAN(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("SADD", "ints", "3", "1", "2")
+	// the intset is returned in a single call whatever COUNT is
+	if got := c.do("SSCAN", "ints", "0", "COUNT", "1"); got != reply([]interface{}{"0", []string{"1", "2", "3"}}) {
+		t.Fatalf("SSCAN ints 0 COUNT 1 = %q", got)
+	}
+	if got := c.do("SSCAN", "missing", "0"); got != reply([]interface{}{"0", []string{}}) {
+		t.Fatalf("SSCAN missing 0 = %q", got)
+	}
+
+	for i := range 300 {
+		c.do("SADD", "s", "m"+strconv.Itoa(i))
+	}
+	added := 0
+	got := scanAll(t, c, []string{"SSCAN", "s"}, []string{"COUNT", "5", "MATCH", "m1*"}, func() {
+		c.do("SADD", "s", "m1new"+strconv.Itoa(added))
+		added++
+	})
+	seen := make(map[string]bool)
+	for _, m := range got {
+		if !strings.HasPrefix(m, "m1") {
+			t.Fatalf("SSCAN MATCH m1* returned %s", m)
+		}
+		seen[m] = true
+	}
+	for i := range 300 {
+		if m := "m" + strconv.Itoa(i); strings.HasPrefix(m, "m1") && !seen[m] {
+			t.Fatalf("%s is not returned by the scan", m)
+		}
+	}
+
+	runReplyTests(t, []replyTest{
+		{args("SSCAN s 0 NOVALUES"), errReply("syntax error")},
+		{args("SSCAN s -1"), errReply("invalid cursor")},
+	})
+}
+
+func TestSetIntSetEncoding(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	runReplyTests(t, []replyTest{
+		{args("SADD s 3 1 2 -5"), reply(4)},
+		{args("OBJECT ENCODING s"), reply("intset")},
+		// the intset is sorted
+		{args("SMEMBERS s"), reply([]string{"-5", "1", "2", "3"})},
+		{args("SISMEMBER s 01"), reply(0)},
+		{args("SADD s 9223372036854775807"), reply(1)},
+		{args("OBJECT ENCODING s"), reply("intset")},
+		{args("SADD s a"), reply(1)},
+		{args("OBJECT ENCODING s"), reply("hashtable")},
+		{args("SISMEMBER s 9223372036854775807"), reply(1)},
+		// there is no conversion back to an intset
+		{args("SREM s a"), reply(1)},
+		{args("OBJECT ENCODING s"), reply("hashtable")},
+	})
+
+	for i := range 513 {
+		c.do("SADD", "big", strconv.Itoa(i))
+	}
+	if got := c.do("OBJECT", "ENCODING", "big"); got != reply("hashtable") {
+		t.Fatalf("OBJECT ENCODING = %q with 513 integers, want hashtable", got)
+	}
+	if got := c.do("SCARD", "big"); got != reply(513) {
+		t.Fatalf("SCARD big = %q, want 513", got)
+	}
+
+	// the result of the set algebra is an intset when it holds integers only
+	c.do("SADD", "other", "1", "a")
+	if got := c.do("SINTERSTORE", "dest", "s", "other"); got != reply(1) {
+		t.Fatalf("SINTERSTORE = %q, want 1", got)
+	}
+	if got := c.do("OBJECT", "ENCODING", "dest"); got != reply("intset") {
+		t.Fatalf("OBJECT ENCODING dest = %q, want intset", got)
+	}
+}
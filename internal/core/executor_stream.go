@@ -0,0 +1,567 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"mtredis/internal/config"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidStreamID = errors.New("(error) Invalid stream ID specified as stream command argument")
+
+// parse an ID "ms-seq", or "ms" in which case the sequence number is defaultSeq
+func parseStreamID(s string, defaultSeq uint64) (data_structure.StreamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return data_structure.StreamID{}, errInvalidStreamID
+	}
+	if !hasSeq {
+		return data_structure.StreamID{Ms: ms, Seq: defaultSeq}, nil
+	}
+
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return data_structure.StreamID{}, errInvalidStreamID
+	}
+
+	return data_structure.StreamID{Ms: ms, Seq: seq}, nil
+}
+
+/*
+Parse a bound of XRANGE or XREVRANGE: "-" and "+" are the min and max IDs, a missing sequence number is 0 for the start
+and the max for the end, and a '(' prefix makes the bound exclusive.
+*/
+func parseStreamRangeBound(s string, isStart bool) (data_structure.StreamID, error) {
+	switch s {
+	case "-":
+		return data_structure.StreamMinID, nil
+	case "+":
+		return data_structure.StreamMaxID, nil
+	}
+
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+
+	var defaultSeq uint64 = 0
+	if !isStart {
+		defaultSeq = math.MaxUint64
+	}
+	id, err := parseStreamID(s, defaultSeq)
+	if err != nil || !exclusive {
+		return id, err
+	}
+
+	// an exclusive bound is the next (or previous) inclusive one
+	var ok bool
+	if isStart {
+		if id, ok = id.Next(); !ok {
+			return id, errors.New("(error) invalid start ID for the interval")
+		}
+	} else {
+		if id, ok = id.Prev(); !ok {
+			return id, errors.New("(error) invalid end ID for the interval")
+		}
+	}
+
+	return id, nil
+}
+
+func encodeStreamEntry(e data_structure.StreamEntry) []interface{} {
+	return []interface{}{e.ID.String(), e.Fields}
+}
+
+func encodeStreamEntries(entries []data_structure.StreamEntry) []interface{} {
+	res := make([]interface{}, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, encodeStreamEntry(e))
+	}
+
+	return res
+}
+
+type streamTrimOptions struct {
+	strategy string // MAXLEN, MINID or empty when there is no trimming
+	approx   bool
+	maxLen   uint64
+	minID    data_structure.StreamID
+	limit    int64 // 0 means no limit
+	hasLimit bool
+}
+
+/*
+Parse the trimming option at args[i] if there is one: MAXLEN | MINID [= | ~] threshold, or LIMIT count.
+Return the index of the next argument, i itself if args[i] is not a trimming option.
+*/
+func parseStreamTrimOption(args []string, i int, opts *streamTrimOptions) (int, error) {
+	switch option := strings.ToUpper(args[i]); option {
+	case "MAXLEN", "MINID":
+		if opts.strategy != "" && opts.strategy != option {
+			return 0, errors.New("(error) syntax error, MAXLEN and MINID options at the same time are not compatible")
+		}
+		opts.strategy = option
+
+		i++
+		if i < len(args) && (args[i] == "=" || args[i] == "~") {
+			opts.approx = args[i] == "~"
+			i++
+		}
+		if i >= len(args) {
+			return 0, errors.New("(error) syntax error")
+		}
+
+		if option == "MAXLEN" {
+			maxLen, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return 0, errors.New("(error) value is not an integer or out of range")
+			}
+			if maxLen < 0 {
+				return 0, errors.New("(error) The MAXLEN argument must be >= 0.")
+			}
+			opts.maxLen = uint64(maxLen)
+		} else {
+			minID, err := parseStreamID(args[i], 0)
+			if err != nil {
+				return 0, err
+			}
+			opts.minID = minID
+		}
+		return i + 1, nil
+	case "LIMIT":
+		if i+1 >= len(args) {
+			return 0, errors.New("(error) syntax error")
+		}
+		limit, err := strconv.ParseInt(args[i+1], 10, 64)
+		if err != nil {
+			return 0, errors.New("(error) value is not an integer or out of range")
+		}
+		if limit < 0 {
+			return 0, errors.New("(error) The LIMIT argument must be >= 0.")
+		}
+		opts.limit, opts.hasLimit = limit, true
+		return i + 2, nil
+	}
+
+	return i, nil
+}
+
+// check the trimming options once they are all parsed
+func validateStreamTrimOptions(opts *streamTrimOptions) error {
+	if opts.hasLimit && !opts.approx {
+		return errors.New("(error) syntax error, LIMIT cannot be used without the special ~ option")
+	}
+	// like Redis, the approximate trimming removes at most 100 blocks by default
+	if opts.approx && !opts.hasLimit {
+		opts.limit = int64(100 * config.StreamNodeMaxEntries)
+	}
+
+	return nil
+}
+
+func trimStream(stream *data_structure.Stream, opts *streamTrimOptions) int64 {
+	switch opts.strategy {
+	case "MAXLEN":
+		return stream.TrimByMaxLen(opts.maxLen, opts.approx, opts.limit)
+	case "MINID":
+		return stream.TrimByMinID(opts.minID, opts.approx, opts.limit)
+	default:
+		return 0
+	}
+}
+
+/*
+Get the ID of a new entry from the ID argument of XADD: "*" and "ms-*" are generated, the others are explicit.
+The ID must be greater than the last ID of the stream.
+*/
+func nextStreamID(stream *data_structure.Stream, s string) (data_structure.StreamID, error) {
+	last := stream.LastID
+	exhaustedErr := errors.New("(error) The stream has exhausted the last possible ID, unable to add more items")
+	smallerErr := errors.New("(error) The ID specified in XADD is equal or smaller than the target stream top item")
+
+	if s == "*" {
+		now := uint64(time.Now().UnixMilli())
+		if now > last.Ms {
+			return data_structure.StreamID{Ms: now, Seq: 0}, nil
+		}
+		id, ok := last.Next()
+		if !ok {
+			return id, exhaustedErr
+		}
+		return id, nil
+	}
+
+	if msPart, found := strings.CutSuffix(s, "-*"); found {
+		ms, err := strconv.ParseUint(msPart, 10, 64)
+		if err != nil {
+			return data_structure.StreamID{}, errInvalidStreamID
+		}
+		switch {
+		case ms < last.Ms:
+			return data_structure.StreamID{}, smallerErr
+		case ms == last.Ms:
+			if last.Seq == math.MaxUint64 {
+				return data_structure.StreamID{}, smallerErr
+			}
+			return data_structure.StreamID{Ms: ms, Seq: last.Seq + 1}, nil
+		case ms == 0:
+			// 0-0 is not a valid ID
+			return data_structure.StreamID{Ms: 0, Seq: 1}, nil
+		default:
+			return data_structure.StreamID{Ms: ms, Seq: 0}, nil
+		}
+	}
+
+	id, err := parseStreamID(s, 0)
+	if err != nil {
+		return id, err
+	}
+	if id == data_structure.StreamMinID {
+		return id, errors.New("(error) The ID specified in XADD must be greater than 0-0")
+	}
+	if id.Compare(last) <= 0 {
+		return id, smallerErr
+	}
+
+	return id, nil
+}
+
+// cmd: XADD key [NOMKSTREAM] [MAXLEN | MINID [= | ~] threshold [LIMIT count]] * | id field value [field value ...]
+func cmdXADD(args []string) []byte {
+	if len(args) < 4 {
+		return Encode(errors.New("(error) wrong number of arguments for 'XADD' command"))
+	}
+
+	key := args[0]
+	noMkStream := false
+	trimOpts := &streamTrimOptions{}
+	i := 1
+	for i < len(args) {
+		if strings.ToUpper(args[i]) == "NOMKSTREAM" {
+			noMkStream = true
+			i++
+			continue
+		}
+
+		next, err := parseStreamTrimOption(args, i, trimOpts)
+		if err != nil {
+			return Encode(err)
+		}
+		if next == i {
+			break
+		}
+		i = next
+	}
+	if err := validateStreamTrimOptions(trimOpts); err != nil {
+		return Encode(err)
+	}
+
+	// the ID followed by the field value pairs
+	if len(args)-i < 3 || (len(args)-i-1)%2 != 0 {
+		return Encode(errors.New("(error) wrong number of arguments for 'XADD' command"))
+	}
+
+	stream, exist := streamStore[key]
+	if !exist {
+		if noMkStream {
+			return constant.RespNil
+		}
+		stream = data_structure.CreateStream()
+	}
+
+	id, err := nextStreamID(stream, args[i])
+	if err != nil {
+		return Encode(err)
+	}
+
+	streamStore[key] = stream
+	stream.Append(id, args[i+1:])
+	trimStream(stream, trimOpts)
+	signalKeyAsReady(key)
+
+	return Encode(id.String())
+}
+
+// XRANGE and XREVRANGE, the bounds are given from start to end
+func streamRange(args []string, cmdName string, reverse bool) []byte {
+	if len(args) != 3 && len(args) != 5 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	startArg, endArg := args[1], args[2]
+	if reverse {
+		startArg, endArg = endArg, startArg
+	}
+	start, err := parseStreamRangeBound(startArg, true)
+	if err != nil {
+		return Encode(err)
+	}
+	end, err := parseStreamRangeBound(endArg, false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	count := -1
+	if len(args) == 5 {
+		if strings.ToUpper(args[3]) != "COUNT" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		n, err := strconv.ParseInt(args[4], 10, 64)
+		if err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+		count = int(min(max(n, 0), math.MaxInt32))
+	}
+
+	stream, exist := streamStore[args[0]]
+	if !exist || count == 0 {
+		return Encode([]interface{}{})
+	}
+
+	return Encode(encodeStreamEntries(stream.Range(start, end, count, reverse)))
+}
+
+// cmd: XRANGE key start end [COUNT count]
+func cmdXRANGE(args []string) []byte {
+	return streamRange(args, "XRANGE", false)
+}
+
+// cmd: XREVRANGE key end start [COUNT count]
+func cmdXREVRANGE(args []string) []byte {
+	return streamRange(args, "XREVRANGE", true)
+}
+
+// cmd: XLEN key
+func cmdXLEN(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'XLEN' command"))
+	}
+
+	stream, exist := streamStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(int64(stream.Length))
+}
+
+// cmd: XDEL key id [id ...]
+func cmdXDEL(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'XDEL' command"))
+	}
+
+	// parse all the IDs before deleting anything
+	ids := make([]data_structure.StreamID, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		id, err := parseStreamID(arg, 0)
+		if err != nil {
+			return Encode(err)
+		}
+		ids = append(ids, id)
+	}
+
+	stream, exist := streamStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		if stream.Delete(id) {
+			deleted++
+		}
+	}
+
+	return Encode(deleted)
+}
+
+// cmd: XTRIM key MAXLEN | MINID [= | ~] threshold [LIMIT count]
+func cmdXTRIM(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'XTRIM' command"))
+	}
+
+	opts := &streamTrimOptions{}
+	for i := 1; i < len(args); {
+		next, err := parseStreamTrimOption(args, i, opts)
+		if err != nil {
+			return Encode(err)
+		}
+		if next == i {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		i = next
+	}
+	if opts.strategy == "" {
+		return Encode(errors.New("(error) syntax error"))
+	}
+	if err := validateStreamTrimOptions(opts); err != nil {
+		return Encode(err)
+	}
+
+	stream, exist := streamStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(trimStream(stream, opts))
+}
+
+// cmd: XINFO STREAM key
+func cmdXINFO(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'XINFO' command"))
+	}
+
+	if strings.ToUpper(args[0]) != "STREAM" {
+		return Encode(errors.New("(error) unknown subcommand '" + args[0] + "'. Try XINFO HELP."))
+	}
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'XINFO|STREAM' command"))
+	}
+
+	stream, exist := streamStore[args[1]]
+	if !exist {
+		return Encode(errors.New("(error) no such key"))
+	}
+
+	recordedFirstID := data_structure.StreamMinID
+	var firstEntry, lastEntry interface{} = nil, nil
+	if e, ok := stream.First(); ok {
+		recordedFirstID = e.ID
+		firstEntry = encodeStreamEntry(e)
+	}
+	if e, ok := stream.Last(); ok {
+		lastEntry = encodeStreamEntry(e)
+	}
+
+	return Encode([]interface{}{
+		"length", int64(stream.Length),
+		"radix-tree-keys", stream.Blocks.Len(),
+		"radix-tree-nodes", stream.Blocks.NodeCount(),
+		"last-generated-id", stream.LastID.String(),
+		"max-deleted-entry-id", stream.MaxDeletedID.String(),
+		"entries-added", int64(stream.EntriesAdded),
+		"recorded-first-entry-id", recordedFirstID.String(),
+		"groups", 0,
+		"first-entry", firstEntry,
+		"last-entry", lastEntry,
+	})
+}
+
+// the entries of the stream of the key with an ID greater than after, nil if there is none
+func readStream(key string, after data_structure.StreamID, count int) []interface{} {
+	stream, exist := streamStore[key]
+	if !exist {
+		return nil
+	}
+
+	start, ok := after.Next()
+	if !ok {
+		return nil
+	}
+	entries := stream.Range(start, data_structure.StreamMaxID, count, false)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return []interface{}{key, encodeStreamEntries(entries)}
+}
+
+/*
+cmd: XREAD [COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]
+The reply holds the streams having entries with an ID greater than the given one, "$" being the last ID of the stream.
+With BLOCK, if there is none, the client is blocked until an entry is added to one of the streams.
+*/
+func cmdXREAD(args []string, fd int) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'XREAD' command"))
+	}
+
+	count := 0
+	block := false
+	var deadline time.Time
+	streamsIdx := -1
+	for i := 0; i < len(args) && streamsIdx < 0; i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "COUNT" && i+1 < len(args):
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return Encode(errors.New("(error) value is not an integer or out of range"))
+			}
+			count = int(min(max(n, 0), math.MaxInt32))
+		case option == "BLOCK" && i+1 < len(args):
+			i++
+			ms, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return Encode(errors.New("(error) timeout is not an integer or out of range"))
+			}
+			if ms < 0 {
+				return Encode(errors.New("(error) timeout is negative"))
+			}
+			block = true
+			if ms > 0 {
+				deadline = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		case option == "STREAMS":
+			streamsIdx = i + 1
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+
+	if streamsIdx < 0 || streamsIdx >= len(args) || (len(args)-streamsIdx)%2 != 0 {
+		return Encode(errors.New("(error) Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified."))
+	}
+
+	numKeys := (len(args) - streamsIdx) / 2
+	keys := args[streamsIdx : streamsIdx+numKeys]
+	afterIDs := make(map[string]data_structure.StreamID, numKeys)
+	for i, key := range keys {
+		idArg := args[streamsIdx+numKeys+i]
+		if idArg == "$" {
+			if stream, exist := streamStore[key]; exist {
+				afterIDs[key] = stream.LastID
+			} else {
+				afterIDs[key] = data_structure.StreamMinID
+			}
+			continue
+		}
+
+		id, err := parseStreamID(idArg, 0)
+		if err != nil {
+			return Encode(err)
+		}
+		afterIDs[key] = id
+	}
+
+	res := make([]interface{}, 0)
+	for _, key := range keys {
+		if entries := readStream(key, afterIDs[key], count); entries != nil {
+			res = append(res, entries)
+		}
+	}
+	if len(res) > 0 {
+		return Encode(res)
+	}
+	if !block {
+		return constant.RespNilArray
+	}
+
+	return blockForKeys(fd, keys, deadline, blockedOnStream, func(key string) []byte {
+		entries := readStream(key, afterIDs[key], count)
+		if entries == nil {
+			return nil
+		}
+
+		return Encode([]interface{}{entries})
+	})
+}
@@ -0,0 +1,276 @@
+package core
+
+import (
+	"mtredis/internal/constant"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// the reply of stream entries, given as ID followed by its field value pairs
+func entriesReply(entries ...[]string) []interface{} {
+	res := make([]interface{}, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, []interface{}{e[0], e[1:]})
+	}
+
+	return res
+}
+
+func TestXADD(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("XADD s 1-1 f v"), reply("1-1")},
+		{args("XADD s 1-* f v"), reply("1-2")},
+		{args("XADD s 5-* f v"), reply("5-0")},
+		// a missing sequence number is 0
+		{args("XADD s 5 f v"), errReply("The ID specified in XADD is equal or smaller than the target stream top item")},
+		{args("XADD s 5-1 f v"), reply("5-1")},
+		{args("XADD s 4-* f v"), errReply("The ID specified in XADD is equal or smaller than the target stream top item")},
+		{args("XADD t 0-0 f v"), errReply("The ID specified in XADD must be greater than 0-0")},
+		{args("XADD t 0-* f v"), reply("0-1")},
+		{args("XADD t abc f v"), errReply("Invalid stream ID specified as stream command argument")},
+		{args("XADD t 18446744073709551615-18446744073709551615 f v"), reply("18446744073709551615-18446744073709551615")},
+		{args("XADD t * f v"), errReply("The stream has exhausted the last possible ID, unable to add more items")},
+		{args("XADD t 18446744073709551615-* f v"), errReply("The ID specified in XADD is equal or smaller than the target stream top item")},
+		{args("XADD s 6-0 f"), errReply("wrong number of arguments for 'XADD' command")},
+		{args("XADD s 6-0 f v g"), errReply("wrong number of arguments for 'XADD' command")},
+		{args("XADD missing NOMKSTREAM * f v"), nilReply},
+		{args("XLEN missing"), reply(0)},
+		{args("XLEN s"), reply(4)},
+		{args("XRANGE s - +"), reply(entriesReply(
+			[]string{"1-1", "f", "v"}, []string{"1-2", "f", "v"}, []string{"5-0", "f", "v"}, []string{"5-1", "f", "v"},
+		))},
+	})
+
+	// a generated ID is the current time, or the next ID if the clock is behind the last ID
+	c := newTestClient(t)
+	before := time.Now().UnixMilli()
+	value, _ := Decode([]byte(c.do("XADD", "now", "*", "f", "v")))
+	ms, err := strconv.ParseInt(value.(string)[:len(value.(string))-2], 10, 64)
+	if err != nil || ms < before || ms > time.Now().UnixMilli() {
+		t.Fatalf("XADD * = %v, want the current time", value)
+	}
+	if got := c.do("XADD", "s", "99999999999999-5", "f", "v"); got != reply("99999999999999-5") {
+		t.Fatalf("XADD = %q", got)
+	}
+	if got := c.do("XADD", "s", "*", "f", "v"); got != reply("99999999999999-6") {
+		t.Fatalf("XADD * behind the last ID = %q, want 99999999999999-6", got)
+	}
+}
+
+func TestXRANGE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("XADD s 1-0 a 1"), reply("1-0")},
+		{args("XADD s 2-0 a 2 b 2"), reply("2-0")},
+		{args("XADD s 2-1 a 3"), reply("2-1")},
+		{args("XADD s 3-0 a 4"), reply("3-0")},
+		{args("XRANGE s - +"), reply(entriesReply(
+			[]string{"1-0", "a", "1"}, []string{"2-0", "a", "2", "b", "2"}, []string{"2-1", "a", "3"}, []string{"3-0", "a", "4"},
+		))},
+		// a missing sequence number is 0 for the start and the max for the end
+		{args("XRANGE s 2 2"), reply(entriesReply([]string{"2-0", "a", "2", "b", "2"}, []string{"2-1", "a", "3"}))},
+		{args("XRANGE s (1-0 (3-0"), reply(entriesReply([]string{"2-0", "a", "2", "b", "2"}, []string{"2-1", "a", "3"}))},
+		{args("XRANGE s - + COUNT 1"), reply(entriesReply([]string{"1-0", "a", "1"}))},
+		{args("XRANGE s - + COUNT 0"), reply([]interface{}{})},
+		{args("XRANGE s 3 1"), reply([]interface{}{})},
+		{args("XREVRANGE s + - COUNT 2"), reply(entriesReply([]string{"3-0", "a", "4"}, []string{"2-1", "a", "3"}))},
+		{args("XREVRANGE s 2 -"), reply(entriesReply(
+			[]string{"2-1", "a", "3"}, []string{"2-0", "a", "2", "b", "2"}, []string{"1-0", "a", "1"},
+		))},
+		{args("XREVRANGE s (2-1 (1-0"), reply(entriesReply([]string{"2-0", "a", "2", "b", "2"}))},
+		{args("XRANGE missing - +"), reply([]interface{}{})},
+		{args("XRANGE s (18446744073709551615-18446744073709551615 +"), errReply("invalid start ID for the interval")},
+		{args("XRANGE s - (0-0"), errReply("invalid end ID for the interval")},
+		{args("XRANGE s x +"), errReply("Invalid stream ID specified as stream command argument")},
+		{args("XRANGE s - + LIMIT 1"), errReply("syntax error")},
+		{args("XRANGE s - + COUNT x"), errReply("value is not an integer or out of range")},
+		{args("XRANGE s -"), errReply("wrong number of arguments for 'XRANGE' command")},
+	})
+}
+
+func TestXDEL(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("XADD s 1-0 a 1"), reply("1-0")},
+		{args("XADD s 2-0 a 2"), reply("2-0")},
+		{args("XADD s 3-0 a 3"), reply("3-0")},
+		{args("XDEL s 2-0 2-0 4-0"), reply(1)},
+		// nothing is deleted when an ID is invalid
+		{args("XDEL s 1-0 x"), errReply("Invalid stream ID specified as stream command argument")},
+		{args("XLEN s"), reply(2)},
+		{args("XRANGE s - +"), reply(entriesReply([]string{"1-0", "a", "1"}, []string{"3-0", "a", "3"}))},
+		{args("XDEL s 3-0"), reply(1)},
+		// the last ID stays, a new entry must be greater than it
+		{args("XADD s 3-0 a 4"), errReply("The ID specified in XADD is equal or smaller than the target stream top item")},
+		{args("XDEL missing 1-0"), reply(0)},
+		{args("XDEL s"), errReply("wrong number of arguments for 'XDEL' command")},
+	})
+}
+
+func TestXTRIM(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	for i := 1; i <= 250; i++ {
+		c.do("XADD", "s", strconv.Itoa(i)+"-0", "f", "v")
+	}
+
+	runReplyTests(t, []replyTest{
+		// the approximate trimming only removes whole blocks of 100 entries
+		{args("XTRIM s MAXLEN ~ 120"), reply(100)},
+		{args("XTRIM s MAXLEN = 120"), reply(30)},
+		{args("XTRIM s MAXLEN 120"), reply(0)},
+		{args("XTRIM s MINID 200"), reply(69)},
+		{args("XLEN s"), reply(51)},
+		{args("XRANGE s - + COUNT 1"), reply(entriesReply([]string{"200-0", "f", "v"}))},
+		// the block left with 200-0 fits in the limit, the last one does not
+		{args("XTRIM s MINID ~ 300 LIMIT 10"), reply(1)},
+		{args("XTRIM s MINID ~ 300 LIMIT 0"), reply(50)},
+		{args("XTRIM s MAXLEN 10 LIMIT 10"), errReply("syntax error, LIMIT cannot be used without the special ~ option")},
+		{args("XTRIM s MAXLEN 10 MINID 5"), errReply("syntax error, MAXLEN and MINID options at the same time are not compatible")},
+		{args("XTRIM s MAXLEN -1"), errReply("The MAXLEN argument must be >= 0.")},
+		{args("XTRIM s MAXLEN ~ 1 LIMIT -1"), errReply("The LIMIT argument must be >= 0.")},
+		{args("XTRIM s MAXLEN x"), errReply("value is not an integer or out of range")},
+		{args("XTRIM s LIMIT 10"), errReply("syntax error")},
+		{args("XTRIM s MAXLEN 1 NOMKSTREAM"), errReply("syntax error")},
+		{args("XTRIM missing MAXLEN 0"), reply(0)},
+		// XADD trims after adding the entry
+		{args("XADD t 1-0 f v"), reply("1-0")},
+		{args("XADD t MAXLEN 1 2-0 f v"), reply("2-0")},
+		{args("XADD t MINID 5 3-0 f v"), reply("3-0")},
+		{args("XLEN t"), reply(0)},
+		{args("XADD t LIMIT 5 4-0 f v"), errReply("syntax error, LIMIT cannot be used without the special ~ option")},
+	})
+}
+
+func TestXINFO(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("XADD s 1-0 a 1"), reply("1-0")},
+		{args("XADD s 2-0 b 2"), reply("2-0")},
+		{args("XADD s 3-0 c 3"), reply("3-0")},
+		{args("XDEL s 2-0"), reply(1)},
+		{args("XINFO STREAM s"), reply([]interface{}{
+			"length", int64(2),
+			"radix-tree-keys", int64(1),
+			"radix-tree-nodes", int64(2),
+			"last-generated-id", "3-0",
+			"max-deleted-entry-id", "2-0",
+			"entries-added", int64(3),
+			"recorded-first-entry-id", "1-0",
+			"groups", int64(0),
+			"first-entry", []interface{}{"1-0", []string{"a", "1"}},
+			"last-entry", []interface{}{"3-0", []string{"c", "3"}},
+		})},
+		{args("XDEL s 1-0 3-0"), reply(2)},
+		{args("XINFO STREAM s"), reply([]interface{}{
+			"length", int64(0),
+			"radix-tree-keys", int64(0),
+			"radix-tree-nodes", int64(1),
+			"last-generated-id", "3-0",
+			"max-deleted-entry-id", "3-0",
+			"entries-added", int64(3),
+			"recorded-first-entry-id", "0-0",
+			"groups", int64(0),
+			"first-entry", nil,
+			"last-entry", nil,
+		})},
+		{args("XINFO STREAM missing"), errReply("no such key")},
+		{args("XINFO GROUPS s"), errReply("unknown subcommand 'GROUPS'. Try XINFO HELP.")},
+		{args("XINFO STREAM"), errReply("wrong number of arguments for 'XINFO|STREAM' command")},
+	})
+}
+
+func TestXREAD(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("XADD a 1-0 f 1"), reply("1-0")},
+		{args("XADD a 2-0 f 2"), reply("2-0")},
+		{args("XADD b 1-0 g 1"), reply("1-0")},
+		{args("XREAD STREAMS a b 0 1"), reply([]interface{}{
+			[]interface{}{"a", entriesReply([]string{"1-0", "f", "1"}, []string{"2-0", "f", "2"})},
+		})},
+		{args("XREAD COUNT 1 STREAMS a b 0 0"), reply([]interface{}{
+			[]interface{}{"a", entriesReply([]string{"1-0", "f", "1"})},
+			[]interface{}{"b", entriesReply([]string{"1-0", "g", "1"})},
+		})},
+		{args("XREAD STREAMS a b $ $"), string(constant.RespNilArray)},
+		{args("XREAD BLOCK 0 STREAMS a 1-0"), reply([]interface{}{
+			[]interface{}{"a", entriesReply([]string{"2-0", "f", "2"})},
+		})},
+		{args("XREAD STREAMS a b 0"), errReply("Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.")},
+		{args("XREAD COUNT 1 a 0"), errReply("syntax error")},
+		{args("XREAD BLOCK -1 STREAMS a 0"), errReply("timeout is negative")},
+		{args("XREAD STREAMS a x"), errReply("Invalid stream ID specified as stream command argument")},
+	})
+}
+
+func TestXREADBlocking(t *testing.T) {
+	resetStores(t)
+
+	c, other, adder := newTestClient(t), newTestClient(t), newTestClient(t)
+	if got := c.do(args("XREAD COUNT 1 BLOCK 0 STREAMS a b $ $")...); got != "" {
+		t.Fatalf("XREAD on missing streams = %q, want the client blocked", got)
+	}
+	if got := other.do(args("XREAD BLOCK 0 STREAMS b $")...); got != "" {
+		t.Fatalf("XREAD on a missing stream = %q, want the client blocked", got)
+	}
+
+	// XREAD does not consume the entries, all the clients blocked on the stream are served
+	adder.do("XADD", "b", "1-0", "f", "v")
+	want := reply([]interface{}{[]interface{}{"b", entriesReply([]string{"1-0", "f", "v"})}})
+	if got := c.read(); got != want {
+		t.Errorf("first client got %q, want %q", got, want)
+	}
+	if got := other.read(); got != want {
+		t.Errorf("second client got %q, want %q", got, want)
+	}
+	if len(blockedClients) != 0 || len(blockingKeys) != 0 {
+		t.Fatalf("blockedClients = %v, blockingKeys = %v, want both empty", blockedClients, blockingKeys)
+	}
+
+	c.do(args("XREAD BLOCK 10 STREAMS b $")...)
+	time.Sleep(20 * time.Millisecond)
+	HandleBlockedClientsTimeout()
+	if got := c.read(); got != string(constant.RespNilArray) {
+		t.Fatalf("the client got %q after its timeout, want a nil array", got)
+	}
+}
+
+func TestXREADClientNotServedIsSkipped(t *testing.T) {
+	resetStores(t)
+
+	later, reader, popper, adder := newTestClient(t), newTestClient(t), newTestClient(t), newTestClient(t)
+	later.do(args("XREAD BLOCK 0 STREAMS s 100-0")...)
+	reader.do(args("XREAD BLOCK 0 STREAMS s $")...)
+
+	// the entry is not after the ID of the first client, the next client in the queue is served
+	adder.do("XADD", "s", "5-0", "f", "v")
+	if got, want := reader.read(), reply([]interface{}{[]interface{}{"s", entriesReply([]string{"5-0", "f", "v"})}}); got != want {
+		t.Fatalf("second client got %q, want %q", got, want)
+	}
+	if got := later.read(); got != "" {
+		t.Fatalf("first client got %q for an entry before its ID", got)
+	}
+
+	// a client of another type does not stop the queue either
+	later.do("PING")
+	popper.do("BLPOP", "l", "0")
+	reader.do(args("XREAD BLOCK 0 STREAMS l $")...)
+	adder.do("RPUSH", "l", "x")
+	if got := popper.read(); got != reply([]string{"l", "x"}) {
+		t.Fatalf("BLPOP client got %q, want [l x]", got)
+	}
+
+	adder.do("XADD", "s", "100-1", "f", "w")
+	if got, want := later.read(), reply([]interface{}{[]interface{}{"s", entriesReply([]string{"100-1", "f", "w"})}})+reply("PONG"); got != want {
+		t.Fatalf("first client got %q, want %q", got, want)
+	}
+}
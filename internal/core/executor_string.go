@@ -0,0 +1,630 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+/*
+Store the value as an int64 when it is the canonical representation of an integer ("123", "-5", but not "0123" or "+5").
+Counters are the most common string values, this saves memory and avoids re-parsing them on every INCR.
+*/
+func newStringValue(s string) interface{} {
+	if len(s) == 0 || len(s) > 20 {
+		return s
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != s {
+		return s
+	}
+
+	return n
+}
+
+// get the string representation of a string object, whatever its internal encoding is
+func stringValue(obj *data_structure.Obj) string {
+	switch v := obj.Value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return ""
+	}
+}
+
+// create a new string object (without expiration) or overwrite the existing one and discard its TTL
+func setStringValue(key string, value string) {
+	dictStore.SetObj(key, dictStore.NewObj(key, newStringValue(value), -1))
+	dictStore.DeleteExpiry(key)
+}
+
+/*
+Add delta to the integer value of the key, the key is created with value 0 if it does not exist.
+The TTL of an existing key is kept.
+*/
+func incrDecrBy(key string, delta int64) []byte {
+	var current int64 = 0
+
+	obj := dictStore.GetObj(key)
+	if obj != nil {
+		n, ok := obj.Value.(int64)
+		if !ok {
+			// a raw string (e.g. modified by a bit operation) may still hold an integer
+			n, ok = newStringValue(stringValue(obj)).(int64)
+		}
+		if !ok {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+		current = n
+	}
+
+	if (delta < 0 && current < math.MinInt64-delta) || (delta > 0 && current > math.MaxInt64-delta) {
+		return Encode(errors.New("(error) increment or decrement would overflow"))
+	}
+
+	current += delta
+	if obj != nil {
+		obj.Value = current
+	} else {
+		dictStore.SetObj(key, dictStore.NewObj(key, current, -1))
+	}
+
+	return Encode(current)
+}
+
+// cmd: INCR key
+func cmdINCR(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'INCR' command"))
+	}
+
+	return incrDecrBy(args[0], 1)
+}
+
+// cmd: DECR key
+func cmdDECR(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'DECR' command"))
+	}
+
+	return incrDecrBy(args[0], -1)
+}
+
+// cmd: INCRBY key increment
+func cmdINCRBY(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'INCRBY' command"))
+	}
+
+	delta, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	return incrDecrBy(args[0], delta)
+}
+
+// cmd: DECRBY key decrement
+func cmdDECRBY(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'DECRBY' command"))
+	}
+
+	delta, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+	if delta == math.MinInt64 {
+		return Encode(errors.New("(error) decrement would overflow"))
+	}
+
+	return incrDecrBy(args[0], -delta)
+}
+
+// cmd: INCRBYFLOAT key increment
+func cmdINCRBYFLOAT(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'INCRBYFLOAT' command"))
+	}
+
+	key := args[0]
+	delta, err := parseFloat(args[1])
+	if err != nil {
+		return Encode(errors.New("(error) value is not a valid float"))
+	}
+
+	var current float64 = 0
+	obj := dictStore.GetObj(key)
+	if obj != nil {
+		current, err = parseFloat(stringValue(obj))
+		if err != nil {
+			return Encode(errors.New("(error) value is not a valid float"))
+		}
+	}
+
+	current += delta
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return Encode(errors.New("(error) increment would produce NaN or Infinity"))
+	}
+
+	res := formatScore(current)
+	if obj != nil {
+		obj.Value = newStringValue(res)
+	} else {
+		dictStore.SetObj(key, dictStore.NewObj(key, newStringValue(res), -1))
+	}
+
+	return Encode(res)
+}
+
+// parse a float the way Redis does: no spaces, NaN is rejected but "inf", "+inf" and "-inf" are accepted
+func parseFloat(s string) (float64, error) {
+	if len(s) == 0 || strings.TrimSpace(s) != s {
+		return 0, errors.New("not a valid float")
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, err
+	}
+	if math.IsNaN(f) {
+		return 0, errors.New("not a valid float")
+	}
+
+	return f, nil
+}
+
+// cmd: APPEND key value
+func cmdAPPEND(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'APPEND' command"))
+	}
+
+	key, value := args[0], args[1]
+	obj := dictStore.GetObj(key)
+	if obj == nil {
+		setStringValue(key, value)
+		return Encode(len(value))
+	}
+
+	current := stringValue(obj)
+	if len(current)+len(value) > constant.StringMaxSize {
+		return Encode(errors.New("(error) string exceeds maximum allowed size (proto-max-bulk-len)"))
+	}
+
+	res := current + value
+	obj.Value = newStringValue(res)
+
+	return Encode(len(res))
+}
+
+// cmd: STRLEN key
+func cmdSTRLEN(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'STRLEN' command"))
+	}
+
+	obj := dictStore.GetObj(args[0])
+	if obj == nil {
+		return Encode(0)
+	}
+
+	return Encode(len(stringValue(obj)))
+}
+
+// cmd: GETRANGE key start end
+func cmdGETRANGE(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GETRANGE' command"))
+	}
+
+	start, err1 := strconv.ParseInt(args[1], 10, 64)
+	end, err2 := strconv.ParseInt(args[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	obj := dictStore.GetObj(args[0])
+	if obj == nil {
+		return Encode("")
+	}
+
+	s := stringValue(obj)
+	strLen := int64(len(s))
+
+	// negative indexes count from the end of the string
+	if start < 0 && end < 0 && start > end {
+		return Encode("")
+	}
+	if start < 0 {
+		start += strLen
+	}
+	if end < 0 {
+		end += strLen
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= strLen {
+		end = strLen - 1
+	}
+	if strLen == 0 || start > end {
+		return Encode("")
+	}
+
+	return Encode(s[start : end+1])
+}
+
+// cmd: SETRANGE key offset value
+func cmdSETRANGE(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SETRANGE' command"))
+	}
+
+	key, value := args[0], args[2]
+	offset, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+	if offset < 0 {
+		return Encode(errors.New("(error) offset is out of range"))
+	}
+
+	obj := dictStore.GetObj(key)
+	current := ""
+	if obj != nil {
+		current = stringValue(obj)
+	}
+
+	// nothing to write, the key is not created
+	if len(value) == 0 {
+		return Encode(len(current))
+	}
+
+	if offset > constant.StringMaxSize-int64(len(value)) {
+		return Encode(errors.New("(error) string exceeds maximum allowed size (proto-max-bulk-len)"))
+	}
+
+	// pad the string with zero bytes when the offset is past its end
+	buf := []byte(current)
+	if newLen := int(offset) + len(value); newLen > len(buf) {
+		buf = append(buf, make([]byte, newLen-len(buf))...)
+	}
+	copy(buf[offset:], value)
+
+	if obj != nil {
+		obj.Value = newStringValue(string(buf))
+	} else {
+		dictStore.SetObj(key, dictStore.NewObj(key, newStringValue(string(buf)), -1))
+	}
+
+	return Encode(len(buf))
+}
+
+// cmd: GETDEL key
+func cmdGETDEL(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GETDEL' command"))
+	}
+
+	key := args[0]
+	obj := dictStore.GetObj(key)
+	if obj == nil {
+		return constant.RespNil
+	}
+
+	dictStore.DeleteObj(key)
+
+	return Encode(stringValue(obj))
+}
+
+// cmd: GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]
+func cmdGETEX(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'GETEX' command"))
+	}
+
+	key := args[0]
+	var expireAtMs int64 = -1
+	persist := false
+
+	for i := 1; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch opt {
+		case "PERSIST":
+			if expireAtMs > 0 || persist {
+				return Encode(errors.New("(error) syntax error"))
+			}
+			persist = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if expireAtMs > 0 || persist || i+1 >= len(args) {
+				return Encode(errors.New("(error) syntax error"))
+			}
+			i++
+
+			var err error
+			expireAtMs, err = parseExpireTime(opt, args[i], "getex")
+			if err != nil {
+				return Encode(err)
+			}
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+
+	obj := dictStore.GetObj(key)
+	if obj == nil {
+		return constant.RespNil
+	}
+
+	if expireAtMs > 0 {
+		dictStore.SetExpiryAt(key, uint64(expireAtMs))
+	} else if persist {
+		dictStore.DeleteExpiry(key)
+	}
+
+	return Encode(stringValue(obj))
+}
+
+// cmd: MGET key [key ...]
+func cmdMGET(args []string) []byte {
+	if len(args) < 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'MGET' command"))
+	}
+
+	res := make([]interface{}, len(args))
+	for i, key := range args {
+		obj := dictStore.GetObj(key)
+		if obj != nil {
+			res[i] = stringValue(obj)
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: MSET key value [key value ...]
+func cmdMSET(args []string) []byte {
+	if len(args) == 0 || len(args)%2 == 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'MSET' command"))
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		setStringValue(args[i], args[i+1])
+	}
+
+	return constant.RespOk
+}
+
+// cmd: MSETNX key value [key value ...]
+func cmdMSETNX(args []string) []byte {
+	if len(args) == 0 || len(args)%2 == 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'MSETNX' command"))
+	}
+
+	// nothing is set if at least one key already exists
+	for i := 0; i < len(args); i += 2 {
+		if dictStore.GetObj(args[i]) != nil {
+			return Encode(0)
+		}
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		setStringValue(args[i], args[i+1])
+	}
+
+	return Encode(1)
+}
+
+// cmd: SETNX key value
+func cmdSETNX(args []string) []byte {
+	if len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SETNX' command"))
+	}
+
+	key := args[0]
+	if dictStore.GetObj(key) != nil {
+		return Encode(0)
+	}
+
+	setStringValue(key, args[1])
+
+	return Encode(1)
+}
+
+// cmd: SETEX key seconds value
+func cmdSETEX(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'SETEX' command"))
+	}
+
+	return setWithExpire(args[0], args[2], "EX", args[1], "setex")
+}
+
+// cmd: PSETEX key milliseconds value
+func cmdPSETEX(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'PSETEX' command"))
+	}
+
+	return setWithExpire(args[0], args[2], "PX", args[1], "psetex")
+}
+
+func setWithExpire(key string, value string, unit string, ttl string, cmdName string) []byte {
+	expireAtMs, err := parseExpireTime(unit, ttl, cmdName)
+	if err != nil {
+		return Encode(err)
+	}
+
+	setStringValue(key, value)
+	dictStore.SetExpiryAt(key, uint64(expireAtMs))
+
+	return constant.RespOk
+}
+
+// the length of the LCS of a and b, only two rows of the table are kept since the LCS itself is not needed
+func lcsLength(a string, b string) int {
+	prev, cur := make([]uint32, len(b)+1), make([]uint32, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	return int(prev[len(b)])
+}
+
+// cmd: LCS key1 key2 [LEN] [IDX] [MINMATCHLEN min-match-len] [WITHMATCHLEN]
+func cmdLCS(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'LCS' command"))
+	}
+
+	getLen, getIdx, withMatchLen := false, false, false
+	var minMatchLen int64 = 0
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "LEN":
+			getLen = true
+		case "IDX":
+			getIdx = true
+		case "WITHMATCHLEN":
+			withMatchLen = true
+		case "MINMATCHLEN":
+			if i+1 >= len(args) {
+				return Encode(errors.New("(error) syntax error"))
+			}
+			i++
+
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return Encode(errors.New("(error) value is not an integer or out of range"))
+			}
+			if n > 0 {
+				minMatchLen = n
+			}
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+
+	if getLen && getIdx {
+		return Encode(errors.New("(error) If you want both the length and indexes, please just use IDX."))
+	}
+
+	var a, b string
+	if obj := dictStore.GetObj(args[0]); obj != nil {
+		a = stringValue(obj)
+	}
+	if obj := dictStore.GetObj(args[1]); obj != nil {
+		b = stringValue(obj)
+	}
+
+	if getLen {
+		return Encode(lcsLength(a, b))
+	}
+
+	aLen, bLen := len(a), len(b)
+	if uint64(aLen+1)*uint64(bLen+1)*4 > constant.StringMaxSize {
+		return Encode(errors.New("(error) Insufficient memory, transient memory for LCS exceeds proto-max-bulk-len"))
+	}
+
+	// dp[i][j] is the length of the LCS of a[:i] and b[:j], stored in a flat array
+	dp := make([]uint32, (aLen+1)*(bLen+1))
+	at := func(i, j int) *uint32 { return &dp[i*(bLen+1)+j] }
+	for i := 1; i <= aLen; i++ {
+		for j := 1; j <= bLen; j++ {
+			if a[i-1] == b[j-1] {
+				*at(i, j) = *at(i-1, j-1) + 1
+			} else {
+				*at(i, j) = max(*at(i-1, j), *at(i, j-1))
+			}
+		}
+	}
+
+	lcsLen := int(*at(aLen, bLen))
+
+	/*
+		Walk the table backward from the end of both strings to build the LCS string.
+		When IDX is given, also collect the ranges of contiguous matches (from the last one to the first one).
+	*/
+	res := make([]byte, lcsLen)
+	matches := make([]interface{}, 0)
+	idx := lcsLen
+	i, j := aLen, bLen
+	aStart, aEnd, bStart, bEnd := aLen, 0, 0, 0 // aStart == aLen means there is no ongoing range
+
+	for i > 0 && j > 0 {
+		emitRange := false
+		if a[i-1] == b[j-1] {
+			res[idx-1] = a[i-1]
+
+			if aStart == aLen { // start a new range
+				aStart, aEnd, bStart, bEnd = i-1, i-1, j-1, j-1
+			} else if aStart == i && bStart == j { // extend the current range backward
+				aStart--
+				bStart--
+			} else {
+				emitRange = true
+			}
+
+			// matched with the first byte of one of the strings, the loop is about to stop
+			if aStart == 0 || bStart == 0 {
+				emitRange = true
+			}
+
+			idx--
+			i--
+			j--
+		} else {
+			if *at(i-1, j) > *at(i, j-1) {
+				i--
+			} else {
+				j--
+			}
+
+			if aStart != aLen {
+				emitRange = true
+			}
+		}
+
+		if emitRange {
+			matchLen := aEnd - aStart + 1
+			if getIdx && (minMatchLen == 0 || int64(matchLen) >= minMatchLen) {
+				match := []interface{}{
+					[]interface{}{aStart, aEnd},
+					[]interface{}{bStart, bEnd},
+				}
+				if withMatchLen {
+					match = append(match, matchLen)
+				}
+				matches = append(matches, match)
+			}
+
+			aStart = aLen
+		}
+	}
+
+	if getIdx {
+		return Encode([]interface{}{"matches", matches, "len", lcsLen})
+	}
+
+	return Encode(string(res))
+}
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"mtredis/internal/constant"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIntegerEncoding(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("SET k 123"), okReply},
+		{args("OBJECT ENCODING k"), reply("int")},
+		{args("SET k -5"), okReply},
+		{args("OBJECT ENCODING k"), reply("int")},
+		// not the canonical representation of an integer
+		{args("SET k 0123"), okReply},
+		{args("OBJECT ENCODING k"), reply("embstr")},
+		{args("SET k +5"), okReply},
+		{args("OBJECT ENCODING k"), reply("embstr")},
+		{args("SET k " + strings.Repeat("x", 45)), okReply},
+		{args("OBJECT ENCODING k"), reply("raw")},
+		{args("OBJECT ENCODING missing"), nilReply},
+		{args("APPEND n 12"), reply(2)},
+		{args("APPEND n 34"), reply(4)},
+		{args("OBJECT ENCODING n"), reply("int")},
+		{args("INCR n"), reply(1235)},
+	})
+}
+
+func TestINCRDECR(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("INCR k"), reply(1)},
+		{args("INCRBY k 10"), reply(11)},
+		{args("DECR k"), reply(10)},
+		{args("DECRBY k 20"), reply(-10)},
+		{args("GET k"), reply("-10")},
+		{args("INCRBY k abc"), errReply("value is not an integer or out of range")},
+		{args("SET k 9223372036854775807"), okReply},
+		{args("INCR k"), errReply("increment or decrement would overflow")},
+		{args("DECRBY k -9223372036854775808"), errReply("decrement would overflow")},
+		{args("SET k -9223372036854775808"), okReply},
+		{args("DECR k"), errReply("increment or decrement would overflow")},
+		{args("SET k hello"), okReply},
+		{args("INCR k"), errReply("value is not an integer or out of range")},
+		{args("SET k 0123"), okReply},
+		{args("INCR k"), errReply("value is not an integer or out of range")},
+	})
+}
+
+func TestINCRBYFLOAT(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("INCRBYFLOAT k 10.5"), reply("10.5")},
+		{args("INCRBYFLOAT k 0.1"), reply("10.6")},
+		{args("INCRBYFLOAT k -5.6"), reply("5")},
+		{args("OBJECT ENCODING k"), reply("int")},
+		{args("INCRBYFLOAT k 5.0e3"), reply("5005")},
+		{args("INCRBYFLOAT k abc"), errReply("value is not a valid float")},
+		{args("INCRBYFLOAT k nan"), errReply("value is not a valid float")},
+		{args("INCRBYFLOAT k inf"), errReply("increment would produce NaN or Infinity")},
+		{args("SET big 1e308"), okReply},
+		{args("INCRBYFLOAT big 0"), reply("1e+308")},
+		{args("INCRBYFLOAT big 1e308"), errReply("increment would produce NaN or Infinity")},
+		{args("SET s hello"), okReply},
+		{args("INCRBYFLOAT s 1"), errReply("value is not a valid float")},
+	})
+}
+
+func TestAPPENDAndSTRLEN(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("STRLEN k"), reply(0)},
+		{args("APPEND k Hello"), reply(5)},
+		{args("APPEND k World"), reply(10)},
+		{args("GET k"), reply("HelloWorld")},
+		{args("STRLEN k"), reply(10)},
+	})
+}
+
+func TestGETRANGE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("GETRANGE k 0 -1"), reply("")},
+		{args("SET k This_is_a_string"), okReply},
+		{args("GETRANGE k 0 3"), reply("This")},
+		{args("GETRANGE k -3 -1"), reply("ing")},
+		{args("GETRANGE k 0 -1"), reply("This_is_a_string")},
+		{args("GETRANGE k 10 100"), reply("string")},
+		{args("GETRANGE k -100 3"), reply("This")},
+		{args("GETRANGE k -1 -3"), reply("")},
+		{args("GETRANGE k 5 2"), reply("")},
+		{args("GETRANGE k a 2"), errReply("value is not an integer or out of range")},
+	})
+}
+
+func TestSETRANGE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("SET k Hello_World"), okReply},
+		{args("SETRANGE k 6 Redis"), reply(11)},
+		{args("GET k"), reply("Hello_Redis")},
+		{args("SETRANGE p 3 ab"), reply(5)},
+		{args("GET p"), reply("\x00\x00\x00ab")},
+		{args("SETRANGE e 10"), errReply("wrong number of arguments for 'SETRANGE' command")},
+		{args("SETRANGE k -1 x"), errReply("offset is out of range")},
+		{args("SETRANGE k " + strconv.Itoa(constant.StringMaxSize-1) + " ab"),
+			errReply("string exceeds maximum allowed size (proto-max-bulk-len)")},
+		{args("SETRANGE k 9223372036854775807 ab"),
+			errReply("string exceeds maximum allowed size (proto-max-bulk-len)")},
+		{args("GET k"), reply("Hello_Redis")},
+	})
+
+	// an empty value does not create the key
+	c := newTestClient(t)
+	if got := c.do("SETRANGE", "e", "10", ""); got != reply(0) {
+		t.Errorf("SETRANGE e 10 \"\" = %q, want 0", got)
+	}
+	if got := c.do("GET", "e"); got != nilReply {
+		t.Errorf("GET e = %q after an empty SETRANGE, want nil", got)
+	}
+}
+
+func TestGETDELAndGETEX(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	runReplyTests(t, []replyTest{
+		{args("SET k v"), okReply},
+		{args("GETDEL k"), reply("v")},
+		{args("GETDEL k"), nilReply},
+		{args("GETEX k"), nilReply},
+		{args("SET k v"), okReply},
+		{args("GETEX k EX 100"), reply("v")},
+	})
+	checkTTL(t, c, "k", 100)
+
+	runReplyTests(t, []replyTest{
+		{args("GETEX k PERSIST"), reply("v")},
+		{args("TTL k"), string(constant.TtlKeyExistNotExpired)},
+		{args("GETEX k EX 10 PERSIST"), errReply("syntax error")},
+		{args("GETEX k EX 0"), errReply("invalid expire time in 'getex' command")},
+		{args("GETEX k BOGUS"), errReply("syntax error")},
+	})
+}
+
+func TestMultiKeyStringCommands(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	runReplyTests(t, []replyTest{
+		{args("MSET a 1 b 2"), okReply},
+		{args("MGET a b c"), reply([]interface{}{"1", "2", nil})},
+		{args("MSET a"), errReply("wrong number of arguments for 'MSET' command")},
+		// nothing is set when one of the keys exists
+		{args("MSETNX c 3 a 4"), reply(0)},
+		{args("MGET a c"), reply([]interface{}{"1", nil})},
+		{args("MSETNX c 3 d 4"), reply(1)},
+		{args("SETNX c 5"), reply(0)},
+		{args("SETNX e 5"), reply(1)},
+		{args("GET e"), reply("5")},
+		{args("SETEX f 100 v"), okReply},
+		{args("PSETEX g 100000 v"), okReply},
+		{args("SETEX h 0 v"), errReply("invalid expire time in 'setex' command")},
+		{args("PSETEX h -1 v"), errReply("invalid expire time in 'psetex' command")},
+	})
+	checkTTL(t, c, "f", 100)
+	checkTTL(t, c, "g", 100)
+}
+
+func TestLCS(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("MSET a ohmytext b mynewtext"), okReply},
+		{args("LCS a b"), reply("mytext")},
+		{args("LCS a b LEN"), reply(6)},
+		{args("LCS a b IDX"), reply([]interface{}{
+			"matches", []interface{}{
+				[]interface{}{[]interface{}{4, 7}, []interface{}{5, 8}},
+				[]interface{}{[]interface{}{2, 3}, []interface{}{0, 1}},
+			},
+			"len", 6,
+		})},
+		{args("LCS a b IDX MINMATCHLEN 4 WITHMATCHLEN"), reply([]interface{}{
+			"matches", []interface{}{
+				[]interface{}{[]interface{}{4, 7}, []interface{}{5, 8}, 4},
+			},
+			"len", 6,
+		})},
+		{args("LCS a b LEN IDX"), errReply("If you want both the length and indexes, please just use IDX.")},
+		{args("LCS a missing"), reply("")},
+		{args("LCS a b BOGUS"), errReply("syntax error")},
+	})
+}
+
+func TestLCSMemoryLimit(t *testing.T) {
+	resetStores(t)
+
+	// (24000+1) * (6000+1) cells of 4 bytes are more than proto-max-bulk-len
+	c := newTestClient(t)
+	c.do("SET", "a", strings.Repeat("ab", 12000))
+	c.do("SET", "b", strings.Repeat("b", 6000))
+
+	want := errReply("Insufficient memory, transient memory for LCS exceeds proto-max-bulk-len")
+	if got := c.do("LCS", "a", "b"); got != want {
+		t.Errorf("LCS a b = %q, want %q", got, want)
+	}
+	if got := c.do("LCS", "a", "b", "IDX"); got != want {
+		t.Errorf("LCS a b IDX = %q, want %q", got, want)
+	}
+	// the length alone only needs two rows of the table
+	if got := c.do("LCS", "a", "b", "LEN"); got != reply(6000) {
+		t.Errorf("LCS a b LEN = %q, want 6000", got)
+	}
+}
@@ -0,0 +1,427 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// format a double reply of a t-digest, an empty digest gives nan
+func formatTDigestValue(v float64) string {
+	if math.IsNaN(v) {
+		return "nan"
+	}
+
+	return formatScore(v)
+}
+
+func parseTDigestCompression(s string) (float64, error) {
+	compression, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errors.New("(error) T-Digest: error parsing compression parameter")
+	}
+	if compression <= 0 {
+		return 0, errors.New("(error) T-Digest: compression parameter needs to be a positive integer")
+	}
+	// each centroid takes 16 bytes and there are at most 6 * compression + 10 of them, see TDigestCapacity
+	if compression > (constant.StringMaxSize/16-10)/6 {
+		return 0, errors.New("(error) T-Digest: compression parameter is too large")
+	}
+
+	return float64(compression), nil
+}
+
+// parse the finite values of the arguments, msg is the error if one of them is not
+func parseTDigestValues(args []string, msg string) ([]float64, error) {
+	res := make([]float64, 0, len(args))
+	for _, arg := range args {
+		v, err := parseFloat(arg)
+		if err != nil || math.IsInf(v, 0) {
+			return nil, errors.New(msg)
+		}
+		res = append(res, v)
+	}
+
+	return res, nil
+}
+
+func getTDigest(key string) (*data_structure.TDigest, error) {
+	t, exist := tDigestStore[key]
+	if !exist {
+		return nil, errors.New("(error) T-Digest: key does not exist")
+	}
+
+	return t, nil
+}
+
+// round x to the nearest integer, halves are rounded down
+func roundHalfDown(x float64) int64 {
+	return int64(math.Ceil(x - 0.5))
+}
+
+// cmd: TDIGEST.CREATE key [COMPRESSION compression]
+func cmdTDIGESTCREATE(args []string) []byte {
+	if len(args) != 1 && len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.CREATE' command"))
+	}
+
+	compression := float64(constant.TDigestDefaultCompression)
+	if len(args) == 3 {
+		if strings.ToUpper(args[1]) != "COMPRESSION" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		var err error
+		if compression, err = parseTDigestCompression(args[2]); err != nil {
+			return Encode(err)
+		}
+	}
+
+	if _, exist := tDigestStore[args[0]]; exist {
+		return Encode(errors.New("(error) T-Digest: key already exists"))
+	}
+	tDigestStore[args[0]] = data_structure.CreateTDigest(compression)
+
+	return constant.RespOk
+}
+
+// cmd: TDIGEST.ADD key value [value ...]
+func cmdTDIGESTADD(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.ADD' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	// parse all the values before adding anything
+	values, err := parseTDigestValues(args[1:], "(error) T-Digest: error parsing val parameter")
+	if err != nil {
+		return Encode(err)
+	}
+	for _, v := range values {
+		t.Add(v)
+	}
+
+	return constant.RespOk
+}
+
+// cmd: TDIGEST.QUANTILE key quantile [quantile ...]
+func cmdTDIGESTQUANTILE(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.QUANTILE' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	quantiles, err := parseTDigestValues(args[1:], "(error) T-Digest: error parsing quantile")
+	if err != nil {
+		return Encode(err)
+	}
+
+	res := make([]interface{}, 0, len(quantiles))
+	for _, q := range quantiles {
+		if q < 0 || q > 1 {
+			return Encode(errors.New("(error) T-Digest: quantile should be in [0,1]"))
+		}
+		res = append(res, formatTDigestValue(t.Quantile(q)))
+	}
+
+	return Encode(res)
+}
+
+// cmd: TDIGEST.CDF key value [value ...]
+func cmdTDIGESTCDF(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.CDF' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	values, err := parseTDigestValues(args[1:], "(error) T-Digest: error parsing cdf")
+	if err != nil {
+		return Encode(err)
+	}
+
+	res := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		res = append(res, formatTDigestValue(t.CDF(v)))
+	}
+
+	return Encode(res)
+}
+
+/*
+The estimated rank of each value: the number of values lower than it, plus half the values equal to it.
+It is -1 for a value below the min (above the max with reverse), the number of values for a value beyond the other end,
+and -2 for every value if the digest is empty.
+*/
+func tDigestRank(args []string, cmdName string, reverse bool) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	values, err := parseTDigestValues(args[1:], "(error) T-Digest: error parsing value")
+	if err != nil {
+		return Encode(err)
+	}
+
+	size := t.Size()
+	res := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		switch {
+		case size == 0:
+			res = append(res, int64(-2))
+		case (!reverse && v < t.Min) || (reverse && v > t.Max):
+			res = append(res, int64(-1))
+		case (!reverse && v > t.Max) || (reverse && v < t.Min):
+			res = append(res, int64(size))
+		case reverse:
+			res = append(res, roundHalfDown(size-t.CDF(v)*size))
+		default:
+			res = append(res, roundHalfDown(t.CDF(v)*size))
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: TDIGEST.RANK key value [value ...]
+func cmdTDIGESTRANK(args []string) []byte {
+	return tDigestRank(args, "TDIGEST.RANK", false)
+}
+
+// cmd: TDIGEST.REVRANK key value [value ...]
+func cmdTDIGESTREVRANK(args []string) []byte {
+	return tDigestRank(args, "TDIGEST.REVRANK", true)
+}
+
+/*
+The estimated value of each rank, rank 0 being the min (the max with reverse).
+It is inf (-inf with reverse) for a rank not lower than the number of values, and nan if the digest is empty.
+*/
+func tDigestByRank(args []string, cmdName string, reverse bool) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	ranks := make([]int64, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		rank, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return Encode(errors.New("(error) T-Digest: error parsing rank"))
+		}
+		if rank < 0 {
+			return Encode(errors.New("(error) T-Digest: rank needs to be non negative"))
+		}
+		ranks = append(ranks, rank)
+	}
+
+	size := t.Size()
+	res := make([]interface{}, 0, len(ranks))
+	for _, rank := range ranks {
+		switch {
+		case size == 0:
+			res = append(res, formatTDigestValue(math.NaN()))
+		case float64(rank) >= size && reverse:
+			res = append(res, formatTDigestValue(math.Inf(-1)))
+		case float64(rank) >= size:
+			res = append(res, formatTDigestValue(math.Inf(1)))
+		case reverse:
+			res = append(res, formatTDigestValue(t.Quantile(1-(float64(rank)+0.5)/size)))
+		default:
+			res = append(res, formatTDigestValue(t.Quantile((float64(rank)+0.5)/size)))
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: TDIGEST.BYRANK key rank [rank ...]
+func cmdTDIGESTBYRANK(args []string) []byte {
+	return tDigestByRank(args, "TDIGEST.BYRANK", false)
+}
+
+// cmd: TDIGEST.BYREVRANK key reverse_rank [reverse_rank ...]
+func cmdTDIGESTBYREVRANK(args []string) []byte {
+	return tDigestByRank(args, "TDIGEST.BYREVRANK", true)
+}
+
+// cmd: TDIGEST.MIN key
+func cmdTDIGESTMIN(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.MIN' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+	if t.Size() == 0 {
+		return Encode(formatTDigestValue(math.NaN()))
+	}
+
+	return Encode(formatTDigestValue(t.Min))
+}
+
+// cmd: TDIGEST.MAX key
+func cmdTDIGESTMAX(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.MAX' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+	if t.Size() == 0 {
+		return Encode(formatTDigestValue(math.NaN()))
+	}
+
+	return Encode(formatTDigestValue(t.Max))
+}
+
+// cmd: TDIGEST.TRIMMED_MEAN key low_cut_quantile high_cut_quantile
+func cmdTDIGESTTRIMMEDMEAN(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.TRIMMED_MEAN' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	cuts, err := parseTDigestValues(args[1:], "(error) T-Digest: error parsing cut quantile")
+	if err != nil {
+		return Encode(err)
+	}
+	low, high := cuts[0], cuts[1]
+	if low < 0 || low > 1 || high < 0 || high > 1 {
+		return Encode(errors.New("(error) T-Digest: low_cut_percentile and high_cut_percentile should be in [0,1]"))
+	}
+	if low >= high {
+		return Encode(errors.New("(error) T-Digest: low_cut_percentile should be lower than high_cut_percentile"))
+	}
+
+	return Encode(formatTDigestValue(t.TrimmedMean(low, high)))
+}
+
+/*
+cmd: TDIGEST.MERGE destination numkeys source [source ...] [COMPRESSION compression] [OVERRIDE]
+An existing destination is part of the merge unless OVERRIDE is given.
+Without COMPRESSION, the compression is the highest one of the inputs.
+*/
+func cmdTDIGESTMERGE(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.MERGE' command"))
+	}
+
+	numKeys, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || numKeys <= 0 || numKeys > int64(len(args)-2) {
+		return Encode(errors.New("(error) T-Digest: invalid numkeys"))
+	}
+
+	var compression float64 = 0
+	override := false
+	for i := 2 + int(numKeys); i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COMPRESSION":
+			if i+1 >= len(args) {
+				return Encode(errors.New("(error) syntax error"))
+			}
+			i++
+			if compression, err = parseTDigestCompression(args[i]); err != nil {
+				return Encode(err)
+			}
+		case "OVERRIDE":
+			override = true
+		default:
+			return Encode(errors.New("(error) syntax error"))
+		}
+	}
+
+	sources := make([]*data_structure.TDigest, 0, numKeys+1)
+	for _, key := range args[2 : 2+numKeys] {
+		src, err := getTDigest(key)
+		if err != nil {
+			return Encode(err)
+		}
+		sources = append(sources, src)
+	}
+	if dst, exist := tDigestStore[args[0]]; exist && !override {
+		sources = append(sources, dst)
+	}
+
+	if compression == 0 {
+		for _, src := range sources {
+			compression = max(compression, src.Compression)
+		}
+	}
+
+	// merge into a new digest since the destination can be one of the sources
+	res := data_structure.CreateTDigest(compression)
+	res.Merge(sources)
+	tDigestStore[args[0]] = res
+
+	return constant.RespOk
+}
+
+// cmd: TDIGEST.RESET key
+func cmdTDIGESTRESET(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.RESET' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+	t.Reset()
+
+	return constant.RespOk
+}
+
+// cmd: TDIGEST.INFO key
+func cmdTDIGESTINFO(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TDIGEST.INFO' command"))
+	}
+
+	t, err := getTDigest(args[0])
+	if err != nil {
+		return Encode(err)
+	}
+
+	return Encode([]interface{}{
+		"Compression", int64(t.Compression),
+		"Capacity", t.Capacity,
+		"Merged nodes", t.MergedNodes,
+		"Unmerged nodes", t.UnmergedNodes,
+		"Merged weight", int64(t.MergedWeight),
+		"Unmerged weight", int64(t.UnmergedWeight),
+		"Observations", int64(t.Size()),
+		"Total compressions", t.TotalCompressions,
+		"Memory usage", int64(t.Capacity * 16),
+	})
+}
@@ -0,0 +1,118 @@
+package core
+
+import "testing"
+
+func TestTDigestCommands(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("TDIGEST.CREATE t"), okReply},
+		{args("TDIGEST.CREATE t"), errReply("T-Digest: key already exists")},
+		{args("TDIGEST.MIN t"), reply("nan")},
+		{args("TDIGEST.MAX t"), reply("nan")},
+		{args("TDIGEST.QUANTILE t 0.5"), reply([]interface{}{"nan"})},
+		{args("TDIGEST.CDF t 1"), reply([]interface{}{"nan"})},
+		{args("TDIGEST.RANK t 1"), reply([]interface{}{int64(-2)})},
+		{args("TDIGEST.BYRANK t 0"), reply([]interface{}{"nan"})},
+		{args("TDIGEST.ADD t 1 2 3 4 5 6 7 8 9 10"), okReply},
+		{args("TDIGEST.MIN t"), reply("1")},
+		{args("TDIGEST.MAX t"), reply("10")},
+		{args("TDIGEST.QUANTILE t 0 0.5 1"), reply([]interface{}{"1", "6", "10"})},
+		{args("TDIGEST.CDF t 0 5 11"), reply([]interface{}{"0", "0.45", "1"})},
+		{args("TDIGEST.TRIMMED_MEAN t 0.1 0.6"), reply("4")},
+		{args("TDIGEST.TRIMMED_MEAN t 0.3 0.9"), reply("6.5")},
+		{args("TDIGEST.TRIMMED_MEAN t 0 1"), reply("5.5")},
+		{args("TDIGEST.INFO t"), reply([]interface{}{
+			"Compression", int64(100), "Capacity", int64(610), "Merged nodes", int64(10), "Unmerged nodes", int64(0),
+			"Merged weight", int64(10), "Unmerged weight", int64(0), "Observations", int64(10),
+			"Total compressions", int64(1), "Memory usage", int64(9760),
+		})},
+		// nothing is added when a value is invalid
+		{args("TDIGEST.ADD t 11 abc"), errReply("T-Digest: error parsing val parameter")},
+		{args("TDIGEST.ADD t inf"), errReply("T-Digest: error parsing val parameter")},
+		{args("TDIGEST.MAX t"), reply("10")},
+		{args("TDIGEST.QUANTILE t 1.5"), errReply("T-Digest: quantile should be in [0,1]")},
+		{args("TDIGEST.TRIMMED_MEAN t 0.5 0.5"), errReply("T-Digest: low_cut_percentile should be lower than high_cut_percentile")},
+		{args("TDIGEST.TRIMMED_MEAN t -1 0.5"), errReply("T-Digest: low_cut_percentile and high_cut_percentile should be in [0,1]")},
+		{args("TDIGEST.RESET t"), okReply},
+		{args("TDIGEST.MIN t"), reply("nan")},
+		{args("TDIGEST.ADD t"), errReply("wrong number of arguments for 'TDIGEST.ADD' command")},
+		{args("TDIGEST.ADD missing 1"), errReply("T-Digest: key does not exist")},
+		{args("TDIGEST.QUANTILE missing 0.5"), errReply("T-Digest: key does not exist")},
+		{args("TDIGEST.INFO missing"), errReply("T-Digest: key does not exist")},
+	})
+}
+
+func TestTDigestRank(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("TDIGEST.CREATE s COMPRESSION 1000"), okReply},
+		{args("TDIGEST.ADD s 10 20 30 40 50 60"), okReply},
+		{args("TDIGEST.RANK s 0 10 20 30 40 50 60 70"), reply([]interface{}{
+			int64(-1), int64(0), int64(1), int64(2), int64(3), int64(4), int64(5), int64(6),
+		})},
+		{args("TDIGEST.REVRANK s 0 10 20 30 40 50 60 70"), reply([]interface{}{
+			int64(6), int64(5), int64(4), int64(3), int64(2), int64(1), int64(0), int64(-1),
+		})},
+		{args("TDIGEST.BYRANK s 0 1 2 3 4 5 6 7"), reply([]interface{}{"10", "20", "30", "40", "50", "60", "inf", "inf"})},
+		{args("TDIGEST.BYREVRANK s 0 1 2 3 4 5 6"), reply([]interface{}{"60", "50", "40", "30", "20", "10", "-inf"})},
+		{args("TDIGEST.BYRANK s -1"), errReply("T-Digest: rank needs to be non negative")},
+		{args("TDIGEST.BYRANK s x"), errReply("T-Digest: error parsing rank")},
+		{args("TDIGEST.RANK s x"), errReply("T-Digest: error parsing value")},
+	})
+}
+
+func TestTDIGESTMERGE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("TDIGEST.CREATE a COMPRESSION 50"), okReply},
+		{args("TDIGEST.CREATE b COMPRESSION 200"), okReply},
+		{args("TDIGEST.ADD a 1 2 3"), okReply},
+		{args("TDIGEST.ADD b 4 5"), okReply},
+		// without COMPRESSION, the highest one of the inputs
+		{args("TDIGEST.MERGE m 2 a b"), okReply},
+		{args("TDIGEST.INFO m"), reply([]interface{}{
+			"Compression", int64(200), "Capacity", int64(1210), "Merged nodes", int64(5), "Unmerged nodes", int64(0),
+			"Merged weight", int64(5), "Unmerged weight", int64(0), "Observations", int64(5),
+			"Total compressions", int64(1), "Memory usage", int64(19360),
+		})},
+		{args("TDIGEST.MIN m"), reply("1")},
+		{args("TDIGEST.MAX m"), reply("5")},
+		// the existing destination is part of the merge
+		{args("TDIGEST.MERGE m 1 a"), okReply},
+		{args("TDIGEST.RANK m 6"), reply([]interface{}{int64(8)})},
+		{args("TDIGEST.MERGE m 1 a COMPRESSION 10 OVERRIDE"), okReply},
+		{args("TDIGEST.RANK m 6"), reply([]interface{}{int64(3)})},
+		{args("TDIGEST.MAX m"), reply("3")},
+		// a source can be the destination
+		{args("TDIGEST.MERGE a 2 a a"), okReply},
+		{args("TDIGEST.RANK a 6"), reply([]interface{}{int64(9)})},
+		{args("TDIGEST.MERGE m 0 a"), errReply("T-Digest: invalid numkeys")},
+		{args("TDIGEST.MERGE m 3 a b"), errReply("T-Digest: invalid numkeys")},
+		{args("TDIGEST.MERGE m 1 a COMPRESSION"), errReply("syntax error")},
+		{args("TDIGEST.MERGE m 1 a REPLACE"), errReply("syntax error")},
+		{args("TDIGEST.MERGE m 2 a missing"), errReply("T-Digest: key does not exist")},
+	})
+}
+
+func TestTDigestCompression(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("TDIGEST.CREATE t COMPRESSION 0"), errReply("T-Digest: compression parameter needs to be a positive integer")},
+		{args("TDIGEST.CREATE t COMPRESSION -5"), errReply("T-Digest: compression parameter needs to be a positive integer")},
+		{args("TDIGEST.CREATE t COMPRESSION 1.5"), errReply("T-Digest: error parsing compression parameter")},
+		{args("TDIGEST.CREATE t COMPRESSIONS 100"), errReply("syntax error")},
+		// the centroids of 5592404 take more than 512MB
+		{args("TDIGEST.CREATE t COMPRESSION 5592404"), errReply("T-Digest: compression parameter is too large")},
+		// 6 * compression overflows the capacity, the size was checked after it
+		{args("TDIGEST.CREATE t COMPRESSION 1537228672809129302"), errReply("T-Digest: compression parameter is too large")},
+		{args("TDIGEST.CREATE t COMPRESSION 9223372036854775807"), errReply("T-Digest: compression parameter is too large")},
+		{args("TDIGEST.ADD a 1"), errReply("T-Digest: key does not exist")},
+		{args("TDIGEST.CREATE a"), okReply},
+		{args("TDIGEST.MERGE m 1 a COMPRESSION 9223372036854775807"), errReply("T-Digest: compression parameter is too large")},
+		{args("TDIGEST.INFO m"), errReply("T-Digest: key does not exist")},
+	})
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"mtredis/internal/constant"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var (
+	okReply  = string(constant.RespOk)
+	nilReply = string(constant.RespNil)
+)
+
+// check that the TTL of the key, in seconds, is within a second of the expected one
+func checkTTL(t *testing.T, c *testClient, key string, want int) {
+	t.Helper()
+
+	got := c.do("TTL", key)
+	if got != reply(want) && got != reply(want-1) {
+		t.Errorf("TTL %s = %q, want %d", key, got, want)
+	}
+}
+
+func TestSETConditions(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("SET k v1 XX"), nilReply},
+		{args("GET k"), nilReply},
+		{args("SET k v1 NX"), okReply},
+		{args("SET k v2 NX"), nilReply},
+		{args("GET k"), reply("v1")},
+		{args("SET k v2 XX"), okReply},
+		{args("GET k"), reply("v2")},
+		{args("SET k v3 NX XX"), errReply("syntax error")},
+		{args("SET k v3 BOGUS"), errReply("syntax error")},
+		{args("SET k"), errReply("wrong number of arguments for 'SET' command")},
+	})
+}
+
+func TestSETGet(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("SET k v1 GET"), nilReply},
+		{args("SET k v2 GET"), reply("v1")},
+		// the old value is returned even when NX keeps the key untouched
+		{args("SET k v3 NX GET"), reply("v2")},
+		{args("GET k"), reply("v2")},
+		{args("SET missing v XX GET"), nilReply},
+		{args("GET missing"), nilReply},
+	})
+}
+
+func TestSETExpiration(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	future := time.Now().Add(time.Hour)
+	for _, tt := range []struct {
+		line string
+		ttl  int
+	}{
+		{"SET k v EX 100", 100},
+		{"SET k v PX 100000", 100},
+		{"SET k v EXAT " + strconv.FormatInt(future.Unix(), 10), 3600},
+		{"SET k v PXAT " + strconv.FormatInt(future.UnixMilli(), 10), 3600},
+		{"SET k v KEEPTTL", 3600},
+	} {
+		if got := c.do(args(tt.line)...); got != okReply {
+			t.Fatalf("%s = %q", tt.line, got)
+		}
+		checkTTL(t, c, "k", tt.ttl)
+	}
+
+	runReplyTests(t, []replyTest{
+		// a plain SET discards the TTL
+		{args("SET k v"), okReply},
+		{args("TTL k"), string(constant.TtlKeyExistNotExpired)},
+		{args("SET k v EX 0"), errReply("invalid expire time in 'set' command")},
+		{args("SET k v EX -5"), errReply("invalid expire time in 'set' command")},
+		{args("SET k v EX abc"), errReply("value is not an integer or out of range")},
+		{args("SET k v EX 9223372036854775807"), errReply("invalid expire time in 'set' command")},
+		{args("SET k v EX 10 PX 10"), errReply("syntax error")},
+		{args("SET k v EX 10 KEEPTTL"), errReply("syntax error")},
+		{args("SET k v KEEPTTL EX 10"), errReply("syntax error")},
+		{args("SET k v EX"), errReply("syntax error")},
+	})
+}
+
+func TestSETExpiredKey(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	c.do("SET", "k", "v", "PX", "1")
+	time.Sleep(5 * time.Millisecond)
+	if got := c.do("GET", "k"); got != nilReply {
+		t.Errorf("GET k = %q after expiration, want nil", got)
+	}
+	if got := c.do("SET", "k", "v2", "NX"); got != okReply {
+		t.Errorf("SET k v2 NX = %q on an expired key, want OK", got)
+	}
+}
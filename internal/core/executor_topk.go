@@ -0,0 +1,201 @@
+package core
+
+import (
+	"errors"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// the max increment of TOPK.INCRBY, bigger increments would take too long to decay
+const topKMaxIncrement = 100000
+
+// increment the items and reply with the items expelled from the leaders, nil for an item which expels nothing
+func topKIncrBy(topK *data_structure.TopK, items []string, increments []uint32) []byte {
+	res := make([]interface{}, 0, len(items))
+	for i, item := range items {
+		if expelled, ok := topK.IncrBy(item, increments[i]); ok {
+			res = append(res, expelled)
+		} else {
+			res = append(res, nil)
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: TOPK.RESERVE key topk [width depth decay]
+func cmdTOPKRESERVE(args []string) []byte {
+	if len(args) != 2 && len(args) != 5 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TOPK.RESERVE' command"))
+	}
+
+	k, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil || k == 0 {
+		return Encode(errors.New("(error) TopK: invalid k"))
+	}
+
+	var width, depth uint64 = constant.TopKDefaultWidth, constant.TopKDefaultDepth
+	decay := constant.TopKDefaultDecay
+	if len(args) == 5 {
+		if width, err = strconv.ParseUint(args[2], 10, 32); err != nil || width == 0 {
+			return Encode(errors.New("(error) TopK: invalid width"))
+		}
+		if depth, err = strconv.ParseUint(args[3], 10, 32); err != nil || depth == 0 {
+			return Encode(errors.New("(error) TopK: invalid depth"))
+		}
+		if decay, err = parseFloat(args[4]); err != nil || decay <= 0 || decay > 1 {
+			return Encode(errors.New("(error) TopK: invalid decay value. must be '<= 1' & '> 0'"))
+		}
+	}
+
+	if _, exist := topKStore[args[0]]; exist {
+		return Encode(errors.New("(error) TopK: key already exists"))
+	}
+	// each bucket takes 8 bytes, divide the bound so that width * depth can not overflow
+	if width > constant.StringMaxSize/8/depth || k > constant.StringMaxSize/16 {
+		return Encode(errors.New("(error) TopK: width * depth is too large"))
+	}
+
+	topKStore[args[0]] = data_structure.CreateTopK(uint32(k), uint32(width), uint32(depth), decay)
+
+	return constant.RespOk
+}
+
+// cmd: TOPK.ADD key item [item ...]
+func cmdTOPKADD(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TOPK.ADD' command"))
+	}
+
+	topK, exist := topKStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) TopK: key does not exist"))
+	}
+
+	increments := make([]uint32, len(args)-1)
+	for i := range increments {
+		increments[i] = 1
+	}
+
+	return topKIncrBy(topK, args[1:], increments)
+}
+
+// cmd: TOPK.INCRBY key item increment [item increment ...]
+func cmdTOPKINCRBY(args []string) []byte {
+	if len(args) < 3 || len(args)%2 == 0 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TOPK.INCRBY' command"))
+	}
+
+	topK, exist := topKStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) TopK: key does not exist"))
+	}
+
+	// parse all the increments before changing anything
+	items := make([]string, 0, len(args)/2)
+	increments := make([]uint32, 0, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		value, err := strconv.ParseUint(args[i+1], 10, 32)
+		if err != nil || value == 0 || value > topKMaxIncrement {
+			return Encode(errors.New("(error) TopK: increment must be an integer between 1 and 100000"))
+		}
+		items = append(items, args[i])
+		increments = append(increments, uint32(value))
+	}
+
+	return topKIncrBy(topK, items, increments)
+}
+
+// cmd: TOPK.QUERY key item [item ...]
+func cmdTOPKQUERY(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TOPK.QUERY' command"))
+	}
+
+	topK, exist := topKStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) TopK: key does not exist"))
+	}
+
+	res := make([]interface{}, 0, len(args)-1)
+	for _, item := range args[1:] {
+		if topK.Query(item) {
+			res = append(res, 1)
+		} else {
+			res = append(res, 0)
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: TOPK.COUNT key item [item ...]
+func cmdTOPKCOUNT(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TOPK.COUNT' command"))
+	}
+
+	topK, exist := topKStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) TopK: key does not exist"))
+	}
+
+	res := make([]interface{}, 0, len(args)-1)
+	for _, item := range args[1:] {
+		res = append(res, int64(topK.Count(item)))
+	}
+
+	return Encode(res)
+}
+
+// cmd: TOPK.LIST key [WITHCOUNT]
+func cmdTOPKLIST(args []string) []byte {
+	if len(args) != 1 && len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TOPK.LIST' command"))
+	}
+
+	topK, exist := topKStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) TopK: key does not exist"))
+	}
+
+	withCount := false
+	if len(args) == 2 {
+		if strings.ToUpper(args[1]) != "WITHCOUNT" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		withCount = true
+	}
+
+	leaders := topK.List()
+	res := make([]interface{}, 0, 2*len(leaders))
+	for _, it := range leaders {
+		res = append(res, it.Item)
+		if withCount {
+			res = append(res, int64(it.Count))
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: TOPK.INFO key
+func cmdTOPKINFO(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'TOPK.INFO' command"))
+	}
+
+	topK, exist := topKStore[args[0]]
+	if !exist {
+		return Encode(errors.New("(error) TopK: key does not exist"))
+	}
+
+	return Encode([]interface{}{
+		"k", int64(topK.K),
+		"width", int64(topK.Width),
+		"depth", int64(topK.Depth),
+		"decay", strconv.FormatFloat(topK.Decay, 'g', -1, 64),
+	})
+}
@@ -0,0 +1,53 @@
+package core
+
+import "testing"
+
+func TestTopKCommands(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("TOPK.RESERVE tk 2 1000 4 0.9"), okReply},
+		{args("TOPK.RESERVE tk 2"), errReply("TopK: key already exists")},
+		{args("TOPK.INFO tk"), reply([]interface{}{"k", int64(2), "width", int64(1000), "depth", int64(4), "decay", "0.9"})},
+		{args("TOPK.ADD tk a a b"), reply([]interface{}{nil, nil, nil})},
+		{args("TOPK.INCRBY tk a 3 b 1"), reply([]interface{}{nil, nil})},
+		{args("TOPK.LIST tk WITHCOUNT"), reply([]interface{}{"a", int64(5), "b", int64(2)})},
+		// c expels the smallest leader
+		{args("TOPK.INCRBY tk c 4"), reply([]interface{}{"b"})},
+		{args("TOPK.LIST tk"), reply([]interface{}{"a", "c"})},
+		{args("TOPK.QUERY tk a b c d"), reply([]interface{}{1, 0, 1, 0})},
+		{args("TOPK.COUNT tk a b c d"), reply([]interface{}{int64(5), int64(2), int64(4), int64(0)})},
+		{args("TOPK.LIST tk WITHCOUNTS"), errReply("syntax error")},
+		// nothing is incremented when an increment is invalid
+		{args("TOPK.INCRBY tk d 1 e 0"), errReply("TopK: increment must be an integer between 1 and 100000")},
+		{args("TOPK.INCRBY tk d 100001"), errReply("TopK: increment must be an integer between 1 and 100000")},
+		{args("TOPK.COUNT tk d"), reply([]interface{}{int64(0)})},
+		{args("TOPK.INCRBY tk d"), errReply("wrong number of arguments for 'TOPK.INCRBY' command")},
+		{args("TOPK.ADD missing a"), errReply("TopK: key does not exist")},
+		{args("TOPK.QUERY missing a"), errReply("TopK: key does not exist")},
+		{args("TOPK.LIST missing"), errReply("TopK: key does not exist")},
+		{args("TOPK.INFO missing"), errReply("TopK: key does not exist")},
+	})
+}
+
+func TestTOPKRESERVE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("TOPK.RESERVE tk 10"), okReply},
+		{args("TOPK.INFO tk"), reply([]interface{}{"k", int64(10), "width", int64(8), "depth", int64(7), "decay", "0.9"})},
+		{args("TOPK.LIST tk"), reply([]interface{}{})},
+		{args("TOPK.RESERVE k 0"), errReply("TopK: invalid k")},
+		{args("TOPK.RESERVE k 10 0 7 0.9"), errReply("TopK: invalid width")},
+		{args("TOPK.RESERVE k 10 8 x 0.9"), errReply("TopK: invalid depth")},
+		{args("TOPK.RESERVE k 10 8 7 0"), errReply("TopK: invalid decay value. must be '<= 1' & '> 0'")},
+		{args("TOPK.RESERVE k 10 8 7 1.5"), errReply("TopK: invalid decay value. must be '<= 1' & '> 0'")},
+		// 8 * width * depth is the size of the buckets, 2^31 * 2^30 * 8 overflows to 0 with 64 bits
+		{args("TOPK.RESERVE k 10 2147483648 1073741824 0.9"), errReply("TopK: width * depth is too large")},
+		{args("TOPK.RESERVE k 10 4294967295 4294967295 0.9"), errReply("TopK: width * depth is too large")},
+		{args("TOPK.RESERVE k 10 67108865 1 0.9"), errReply("TopK: width * depth is too large")},
+		{args("TOPK.RESERVE k 33554433"), errReply("TopK: width * depth is too large")},
+		{args("TOPK.INFO k"), errReply("TopK: key does not exist")},
+		{args("TOPK.RESERVE k 10 8 7"), errReply("wrong number of arguments for 'TOPK.RESERVE' command")},
+	})
+}
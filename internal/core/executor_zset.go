@@ -0,0 +1,1191 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"mtredis/internal/constant"
+	"mtredis/internal/data_structure"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Format a score the way Redis does: the shortest representation that round-trips to the same float64,
+in fixed-point notation unless the exponent is too small or too large (like printf's %.17g).
+*/
+func formatScore(score float64) string {
+	if math.IsInf(score, 1) {
+		return "inf"
+	}
+	if math.IsInf(score, -1) {
+		return "-inf"
+	}
+
+	s := strconv.FormatFloat(score, 'e', -1, 64)
+	exp, _ := strconv.Atoi(s[strings.IndexByte(s, 'e')+1:])
+	if exp < -4 || exp >= 17 {
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// the sorted set is removed from the keyspace once its last member is gone
+func deleteZSetIfEmpty(key string, zSet *data_structure.ZSet) {
+	if zSet.Len() == 0 {
+		delete(zSetStore, key)
+	}
+}
+
+func blockingPopZSet(args []string, cmdName string, max bool, fd int) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	keys := args[:len(args)-1]
+	deadline, err := parseBlockingTimeout(args[len(args)-1])
+	if err != nil {
+		return Encode(err)
+	}
+
+	return blockForKeys(fd, keys, deadline, blockedOnZSet, func(key string) []byte {
+		zSet, exist := zSetStore[key]
+		if !exist {
+			return nil
+		}
+
+		e := popZSetElements(key, zSet, max, 1)[0]
+
+		return Encode([]string{key, e.Element, formatScore(e.Score)})
+	})
+}
+
+// cmd: BZPOPMIN key [key ...] timeout
+func cmdBZPOPMIN(args []string, fd int) []byte {
+	return blockingPopZSet(args, "BZPOPMIN", false, fd)
+}
+
+// cmd: BZPOPMAX key [key ...] timeout
+func cmdBZPOPMAX(args []string, fd int) []byte {
+	return blockingPopZSet(args, "BZPOPMAX", true, fd)
+}
+
+// parse a score bound of a range: a float, optionally prefixed by '(' to make it exclusive
+func parseScoreBound(s string) (float64, bool, error) {
+	exclusive := false
+	if strings.HasPrefix(s, "(") {
+		exclusive = true
+		s = s[1:]
+	}
+
+	score, err := parseFloat(s)
+	if err != nil {
+		return 0, false, errors.New("(error) min or max is not a float")
+	}
+
+	return score, exclusive, nil
+}
+
+func parseScoreRange(min string, max string) (*data_structure.ZScoreRange, error) {
+	r := &data_structure.ZScoreRange{}
+
+	var err error
+	if r.Min, r.MinExclusive, err = parseScoreBound(min); err != nil {
+		return nil, err
+	}
+	if r.Max, r.MaxExclusive, err = parseScoreBound(max); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// parse a lexicographical bound of a range: '-', '+', or a string prefixed by '[' (inclusive) or '(' (exclusive)
+func parseLexBound(s string) (data_structure.ZLexBound, error) {
+	switch {
+	case s == "-":
+		return data_structure.ZLexBound{Inf: -1}, nil
+	case s == "+":
+		return data_structure.ZLexBound{Inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return data_structure.ZLexBound{Value: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return data_structure.ZLexBound{Value: s[1:], Exclusive: true}, nil
+	default:
+		return data_structure.ZLexBound{}, errors.New("(error) min or max not valid string range item")
+	}
+}
+
+func parseLexRange(min string, max string) (*data_structure.ZLexRange, error) {
+	r := &data_structure.ZLexRange{}
+
+	var err error
+	if r.Min, err = parseLexBound(min); err != nil {
+		return nil, err
+	}
+	if r.Max, err = parseLexBound(max); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+const (
+	zRangeByRank = iota
+	zRangeByScore
+	zRangeByLex
+)
+
+type zRangeOptions struct {
+	rangeType  int
+	reverse    bool
+	withScores bool
+	hasLimit   bool
+	offset     int
+	limit      int
+}
+
+/*
+Parse the optional arguments of the range commands, only the options listed in allowed are accepted.
+The options are BYSCORE, BYLEX, REV, WITHSCORES and LIMIT offset count.
+*/
+func parseZRangeOptions(args []string, opts *zRangeOptions, allowed ...string) error {
+	opts.limit = -1
+
+	for i := 0; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		isAllowed := false
+		for _, a := range allowed {
+			if a == option {
+				isAllowed = true
+				break
+			}
+		}
+		if !isAllowed {
+			return errors.New("(error) syntax error")
+		}
+
+		switch option {
+		case "BYSCORE", "BYLEX":
+			rangeType := zRangeByScore
+			if option == "BYLEX" {
+				rangeType = zRangeByLex
+			}
+			if opts.rangeType != zRangeByRank && opts.rangeType != rangeType {
+				return errors.New("(error) syntax error")
+			}
+			opts.rangeType = rangeType
+		case "REV":
+			opts.reverse = true
+		case "WITHSCORES":
+			opts.withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return errors.New("(error) syntax error")
+			}
+			offset, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return errors.New("(error) value is not an integer or out of range")
+			}
+			limit, err := strconv.ParseInt(args[i+2], 10, 64)
+			if err != nil {
+				return errors.New("(error) value is not an integer or out of range")
+			}
+			opts.hasLimit = true
+			opts.offset = int(max(min(offset, math.MaxInt32), math.MinInt32))
+			opts.limit = int(max(min(limit, math.MaxInt32), math.MinInt32))
+			i += 2
+		}
+	}
+
+	if opts.hasLimit && opts.rangeType == zRangeByRank {
+		return errors.New("(error) syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if opts.withScores && opts.rangeType == zRangeByLex {
+		return errors.New("(error) syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+
+	return nil
+}
+
+/*
+Get the elements of the sorted set between start and stop according to the options.
+For the reversed score and lex ranges, start is the upper bound and stop the lower one.
+*/
+func zRange(key string, start string, stop string, opts *zRangeOptions) ([]data_structure.ZElement, error) {
+	zSet, exist := zSetStore[key]
+
+	switch opts.rangeType {
+	case zRangeByScore:
+		minArg, maxArg := start, stop
+		if opts.reverse {
+			minArg, maxArg = stop, start
+		}
+		r, err := parseScoreRange(minArg, maxArg)
+		if err != nil {
+			return nil, err
+		}
+		if !exist || opts.offset < 0 {
+			return nil, nil
+		}
+
+		return zSet.RangeByScore(r, opts.reverse, opts.offset, opts.limit), nil
+	case zRangeByLex:
+		minArg, maxArg := start, stop
+		if opts.reverse {
+			minArg, maxArg = stop, start
+		}
+		r, err := parseLexRange(minArg, maxArg)
+		if err != nil {
+			return nil, err
+		}
+		if !exist || opts.offset < 0 {
+			return nil, nil
+		}
+
+		return zSet.RangeByLex(r, opts.reverse, opts.offset, opts.limit), nil
+	default:
+		startIdx, err := strconv.ParseInt(start, 10, 64)
+		if err != nil {
+			return nil, errors.New("(error) value is not an integer or out of range")
+		}
+		stopIdx, err := strconv.ParseInt(stop, 10, 64)
+		if err != nil {
+			return nil, errors.New("(error) value is not an integer or out of range")
+		}
+		if !exist {
+			return nil, nil
+		}
+
+		from, to := normalizeListRange(startIdx, stopIdx, zSet.Len())
+		if from > to {
+			return nil, nil
+		}
+
+		return zSet.RangeByRank(from, to, opts.reverse), nil
+	}
+}
+
+// encode the elements of a range, interleaved with their scores if withScores is true
+func encodeZElements(elements []data_structure.ZElement, withScores bool) []byte {
+	res := make([]string, 0, len(elements))
+	for _, e := range elements {
+		res = append(res, e.Element)
+		if withScores {
+			res = append(res, formatScore(e.Score))
+		}
+	}
+
+	return Encode(res)
+}
+
+func zRangeCommand(args []string, cmdName string, opts *zRangeOptions, allowed ...string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	if err := parseZRangeOptions(args[3:], opts, allowed...); err != nil {
+		return Encode(err)
+	}
+
+	elements, err := zRange(args[0], args[1], args[2], opts)
+	if err != nil {
+		return Encode(err)
+	}
+
+	return encodeZElements(elements, opts.withScores)
+}
+
+// cmd: ZRANGE key start stop [BYSCORE | BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func cmdZRANGE(args []string) []byte {
+	return zRangeCommand(args, "ZRANGE", &zRangeOptions{}, "BYSCORE", "BYLEX", "REV", "LIMIT", "WITHSCORES")
+}
+
+// cmd: ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+func cmdZRANGEBYSCORE(args []string) []byte {
+	return zRangeCommand(args, "ZRANGEBYSCORE", &zRangeOptions{rangeType: zRangeByScore}, "WITHSCORES", "LIMIT")
+}
+
+// cmd: ZREVRANGE key start stop [WITHSCORES]
+func cmdZREVRANGE(args []string) []byte {
+	return zRangeCommand(args, "ZREVRANGE", &zRangeOptions{reverse: true}, "WITHSCORES")
+}
+
+// cmd: ZRANGEBYLEX key min max [LIMIT offset count]
+func cmdZRANGEBYLEX(args []string) []byte {
+	return zRangeCommand(args, "ZRANGEBYLEX", &zRangeOptions{rangeType: zRangeByLex}, "LIMIT")
+}
+
+// cmd: ZRANGESTORE dst src min max [BYSCORE | BYLEX] [REV] [LIMIT offset count]
+func cmdZRANGESTORE(args []string) []byte {
+	if len(args) < 4 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZRANGESTORE' command"))
+	}
+
+	dst := args[0]
+	opts := &zRangeOptions{}
+	if err := parseZRangeOptions(args[4:], opts, "BYSCORE", "BYLEX", "REV", "LIMIT"); err != nil {
+		return Encode(err)
+	}
+
+	elements, err := zRange(args[1], args[2], args[3], opts)
+	if err != nil {
+		return Encode(err)
+	}
+
+	deleteKey(dst)
+	if len(elements) == 0 {
+		return Encode(0)
+	}
+
+	zSet := data_structure.CreatZSet()
+	for _, e := range elements {
+		zSet.Add(e.Score, e.Element)
+	}
+	zSetStore[dst] = zSet
+	signalKeyAsReady(dst)
+
+	return Encode(len(elements))
+}
+
+// cmd: ZREM key member [member ...]
+func cmdZREM(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZREM' command"))
+	}
+
+	key := args[0]
+	zSet, exist := zSetStore[key]
+	if !exist {
+		return Encode(0)
+	}
+
+	count := 0
+	for _, member := range args[1:] {
+		count += zSet.Remove(member)
+	}
+	deleteZSetIfEmpty(key, zSet)
+
+	return Encode(count)
+}
+
+// cmd: ZREMRANGEBYRANK key start stop
+func cmdZREMRANGEBYRANK(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZREMRANGEBYRANK' command"))
+	}
+
+	key := args[0]
+	start, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+	stop, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+
+	zSet, exist := zSetStore[key]
+	if !exist {
+		return Encode(0)
+	}
+
+	from, to := normalizeListRange(start, stop, zSet.Len())
+	if from > to {
+		return Encode(0)
+	}
+
+	removed := zSet.RemoveRangeByRank(from, to)
+	deleteZSetIfEmpty(key, zSet)
+
+	return Encode(removed)
+}
+
+// cmd: ZREMRANGEBYSCORE key min max
+func cmdZREMRANGEBYSCORE(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZREMRANGEBYSCORE' command"))
+	}
+
+	key := args[0]
+	r, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return Encode(err)
+	}
+
+	zSet, exist := zSetStore[key]
+	if !exist || r.IsEmpty() {
+		return Encode(0)
+	}
+
+	removed := zSet.RemoveRangeByScore(r)
+	deleteZSetIfEmpty(key, zSet)
+
+	return Encode(removed)
+}
+
+// cmd: ZREMRANGEBYLEX key min max
+func cmdZREMRANGEBYLEX(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZREMRANGEBYLEX' command"))
+	}
+
+	key := args[0]
+	r, err := parseLexRange(args[1], args[2])
+	if err != nil {
+		return Encode(err)
+	}
+
+	zSet, exist := zSetStore[key]
+	if !exist || r.IsEmpty() {
+		return Encode(0)
+	}
+
+	removed := zSet.RemoveRangeByLex(r)
+	deleteZSetIfEmpty(key, zSet)
+
+	return Encode(removed)
+}
+
+// cmd: ZCARD key
+func cmdZCARD(args []string) []byte {
+	if len(args) != 1 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZCARD' command"))
+	}
+
+	zSet, exist := zSetStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(zSet.Len())
+}
+
+// cmd: ZCOUNT key min max
+func cmdZCOUNT(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZCOUNT' command"))
+	}
+
+	r, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return Encode(err)
+	}
+
+	zSet, exist := zSetStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(zSet.CountInScoreRange(r))
+}
+
+// cmd: ZLEXCOUNT key min max
+func cmdZLEXCOUNT(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZLEXCOUNT' command"))
+	}
+
+	r, err := parseLexRange(args[1], args[2])
+	if err != nil {
+		return Encode(err)
+	}
+
+	zSet, exist := zSetStore[args[0]]
+	if !exist {
+		return Encode(0)
+	}
+
+	return Encode(zSet.CountInLexRange(r))
+}
+
+type zAddFlags struct {
+	nx   bool // only add new elements
+	xx   bool // only update existing elements
+	gt   bool // only update when the new score is greater than the current one
+	lt   bool // only update when the new score is less than the current one
+	ch   bool // reply with the number of added and updated elements
+	incr bool // increment the score like ZINCRBY
+}
+
+/*
+Add or update the (score, member) pairs of the sorted set according to the flags.
+The reply is the number of added (or changed with CH) elements, or the new score with INCR (nil if the operation was aborted).
+*/
+func zAdd(key string, pairs []string, flags *zAddFlags) []byte {
+	if len(pairs)%2 == 1 || len(pairs) == 0 {
+		return Encode(errors.New("(error) syntax error"))
+	}
+	if flags.incr && len(pairs) > 2 {
+		return Encode(errors.New("(error) INCR option supports a single increment-element pair"))
+	}
+	if flags.nx && flags.xx {
+		return Encode(errors.New("(error) XX and NX options at the same time are not compatible"))
+	}
+	if (flags.gt && flags.nx) || (flags.lt && flags.nx) || (flags.gt && flags.lt) {
+		return Encode(errors.New("(error) GT, LT, and/or NX options at the same time are not compatible"))
+	}
+
+	// parse all the scores before changing anything
+	scores := make([]float64, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		score, err := parseFloat(pairs[i])
+		if err != nil {
+			return Encode(errors.New("(error) value is not a valid float"))
+		}
+		scores = append(scores, score)
+	}
+
+	zSet, exist := zSetStore[key]
+	if !exist {
+		if flags.xx {
+			if flags.incr {
+				return constant.RespNil
+			}
+			return Encode(0)
+		}
+
+		zSet = data_structure.CreatZSet()
+		zSetStore[key] = zSet
+	}
+
+	added, updated, processed := 0, 0, 0
+	var newScore float64
+	for i, score := range scores {
+		member := pairs[2*i+1]
+
+		res, currentScore := zSet.GetScore(member)
+		if res == -1 {
+			if flags.xx {
+				continue
+			}
+
+			zSet.Add(score, member)
+			added++
+			processed++
+			newScore = score
+			continue
+		}
+
+		if flags.nx {
+			continue
+		}
+		if flags.incr {
+			score += currentScore
+			if math.IsNaN(score) {
+				deleteZSetIfEmpty(key, zSet)
+				return Encode(errors.New("(error) resulting score is not a number (NaN)"))
+			}
+		}
+		if (flags.lt && score >= currentScore) || (flags.gt && score <= currentScore) {
+			continue
+		}
+
+		if score != currentScore {
+			zSet.Add(score, member)
+			updated++
+		}
+		processed++
+		newScore = score
+	}
+	deleteZSetIfEmpty(key, zSet)
+
+	if added+updated > 0 {
+		signalKeyAsReady(key)
+	}
+
+	if flags.incr {
+		if processed == 0 {
+			return constant.RespNil
+		}
+		return Encode(formatScore(newScore))
+	}
+	if flags.ch {
+		return Encode(added + updated)
+	}
+
+	return Encode(added)
+}
+
+// cmd: ZINCRBY key increment member
+func cmdZINCRBY(args []string) []byte {
+	if len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZINCRBY' command"))
+	}
+
+	return zAdd(args[0], args[1:], &zAddFlags{incr: true})
+}
+
+/*
+Reply the 0-based rank of the member, counted from the highest score if reverse is true.
+With WITHSCORE, the reply is an array of the rank and the score.
+*/
+func zRank(args []string, cmdName string, reverse bool) []byte {
+	if len(args) != 2 && len(args) != 3 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	withScore := false
+	if len(args) == 3 {
+		if strings.ToUpper(args[2]) != "WITHSCORE" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		withScore = true
+	}
+
+	nilReply := constant.RespNil
+	if withScore {
+		nilReply = constant.RespNilArray
+	}
+
+	key, member := args[0], args[1]
+	zSet, exist := zSetStore[key]
+	if !exist {
+		return nilReply
+	}
+
+	rank, score := zSet.GetRank(member, reverse)
+	if rank < 0 {
+		return nilReply
+	}
+
+	if withScore {
+		return Encode([]interface{}{rank, formatScore(score)})
+	}
+
+	return Encode(rank)
+}
+
+// cmd: ZREVRANK key member [WITHSCORE]
+func cmdZREVRANK(args []string) []byte {
+	return zRank(args, "ZREVRANK", true)
+}
+
+// cmd: ZMSCORE key member [member ...]
+func cmdZMSCORE(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZMSCORE' command"))
+	}
+
+	zSet, exist := zSetStore[args[0]]
+	res := make([]interface{}, 0, len(args)-1)
+	for _, member := range args[1:] {
+		if !exist {
+			res = append(res, nil)
+			continue
+		}
+
+		found, score := zSet.GetScore(member)
+		if found == -1 {
+			res = append(res, nil)
+		} else {
+			res = append(res, formatScore(score))
+		}
+	}
+
+	return Encode(res)
+}
+
+// cmd: ZRANDMEMBER key [count [WITHSCORES]]
+func cmdZRANDMEMBER(args []string) []byte {
+	if len(args) < 1 || len(args) > 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZRANDMEMBER' command"))
+	}
+
+	var count int64 = 0
+	hasCount, withScores := len(args) > 1, false
+	if hasCount {
+		var err error
+		count, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+		if count < -math.MaxInt32 || count > math.MaxInt32 {
+			return Encode(errors.New("(error) value is out of range"))
+		}
+	}
+	if len(args) == 3 {
+		if strings.ToUpper(args[2]) != "WITHSCORES" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		withScores = true
+	}
+
+	zSet, exist := zSetStore[args[0]]
+	if !exist {
+		if hasCount {
+			return Encode(make([]string, 0))
+		}
+		return constant.RespNil
+	}
+
+	if !hasCount {
+		return Encode(zSet.ElementAt(rand.Intn(zSet.Len())).Element)
+	}
+
+	indexes := randomIndexes(zSet.Len(), count)
+	elements := make([]data_structure.ZElement, 0, len(indexes))
+	for _, i := range indexes {
+		elements = append(elements, zSet.ElementAt(i))
+	}
+
+	return encodeZElements(elements, withScores)
+}
+
+// pop up to count elements from the lowest scores (or the highest if max is true), the emptied sorted set is removed
+func popZSetElements(key string, zSet *data_structure.ZSet, max bool, count int) []data_structure.ZElement {
+	elements := make([]data_structure.ZElement, 0, min(count, zSet.Len()))
+	for len(elements) < count {
+		member, score, ok := zSet.Pop(max)
+		if !ok {
+			break
+		}
+		elements = append(elements, data_structure.ZElement{Element: member, Score: score})
+	}
+	deleteZSetIfEmpty(key, zSet)
+
+	return elements
+}
+
+func popZSet(args []string, cmdName string, max bool) []byte {
+	if len(args) != 1 && len(args) != 2 {
+		return Encode(errors.New("(error) wrong number of arguments for '" + cmdName + "' command"))
+	}
+
+	count := 1
+	if len(args) == 2 {
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+		if n < 0 {
+			return Encode(errors.New("(error) value is out of range, must be positive"))
+		}
+		count = int(min(n, math.MaxInt32))
+	}
+
+	key := args[0]
+	zSet, exist := zSetStore[key]
+	if !exist {
+		return Encode(make([]string, 0))
+	}
+
+	return encodeZElements(popZSetElements(key, zSet, max, count), true)
+}
+
+// cmd: ZPOPMIN key [count]
+func cmdZPOPMIN(args []string) []byte {
+	return popZSet(args, "ZPOPMIN", false)
+}
+
+// cmd: ZPOPMAX key [count]
+func cmdZPOPMAX(args []string) []byte {
+	return popZSet(args, "ZPOPMAX", true)
+}
+
+// cmd: ZMPOP numkeys key [key ...] <MIN | MAX> [COUNT count]
+func cmdZMPOP(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZMPOP' command"))
+	}
+
+	numKeys, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || numKeys <= 0 {
+		return Encode(errors.New("(error) numkeys should be greater than 0"))
+	}
+	if numKeys > int64(len(args)-2) {
+		return Encode(errors.New("(error) syntax error"))
+	}
+
+	keys := args[1 : 1+numKeys]
+	rest := args[1+numKeys:]
+
+	var max bool
+	switch strings.ToUpper(rest[0]) {
+	case "MIN":
+		max = false
+	case "MAX":
+		max = true
+	default:
+		return Encode(errors.New("(error) syntax error"))
+	}
+
+	count := 1
+	if len(rest) > 1 {
+		if len(rest) != 3 || strings.ToUpper(rest[1]) != "COUNT" {
+			return Encode(errors.New("(error) syntax error"))
+		}
+
+		n, err := strconv.ParseInt(rest[2], 10, 64)
+		if err != nil || n <= 0 {
+			return Encode(errors.New("(error) count should be greater than 0"))
+		}
+		count = int(min(n, math.MaxInt32))
+	}
+
+	// pop from the first non-empty sorted set
+	for _, key := range keys {
+		zSet, exist := zSetStore[key]
+		if !exist {
+			continue
+		}
+
+		elements := popZSetElements(key, zSet, max, count)
+		res := make([]interface{}, 0, len(elements))
+		for _, e := range elements {
+			res = append(res, []string{e.Element, formatScore(e.Score)})
+		}
+
+		return Encode([]interface{}{key, res})
+	}
+
+	return constant.RespNilArray
+}
+
+const (
+	aggregateSum = iota
+	aggregateMin
+	aggregateMax
+)
+
+type zSetOpOptions struct {
+	weights    []float64
+	aggregate  int
+	withScores bool
+}
+
+/*
+Parse "numkeys key [key ...]" followed by the options of the set operations.
+WEIGHTS and AGGREGATE are only accepted by union and intersection, WITHSCORES only by the commands that reply the elements.
+*/
+func parseZSetOpArgs(args []string, cmdName string, allowWeights bool, allowWithScores bool) ([]string, *zSetOpOptions, error) {
+	numKeys, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return nil, nil, errors.New("(error) value is not an integer or out of range")
+	}
+	if numKeys < 1 {
+		return nil, nil, errors.New("(error) at least 1 input key is needed for '" + cmdName + "' command")
+	}
+	if numKeys > int64(len(args)-1) {
+		return nil, nil, errors.New("(error) syntax error")
+	}
+
+	keys := args[1 : 1+numKeys]
+	rest := args[1+numKeys:]
+	opts := &zSetOpOptions{}
+	for i := 0; i < len(rest); i++ {
+		switch option := strings.ToUpper(rest[i]); {
+		case option == "WEIGHTS" && allowWeights && i+len(keys) < len(rest):
+			opts.weights = make([]float64, len(keys))
+			for j := range keys {
+				i++
+				weight, err := parseFloat(rest[i])
+				if err != nil {
+					return nil, nil, errors.New("(error) weight value is not a float")
+				}
+				opts.weights[j] = weight
+			}
+		case option == "AGGREGATE" && allowWeights && i+1 < len(rest):
+			i++
+			switch strings.ToUpper(rest[i]) {
+			case "SUM":
+				opts.aggregate = aggregateSum
+			case "MIN":
+				opts.aggregate = aggregateMin
+			case "MAX":
+				opts.aggregate = aggregateMax
+			default:
+				return nil, nil, errors.New("(error) syntax error")
+			}
+		case option == "WITHSCORES" && allowWithScores:
+			opts.withScores = true
+		default:
+			return nil, nil, errors.New("(error) syntax error")
+		}
+	}
+
+	return keys, opts, nil
+}
+
+// get the scores of the input keys, a plain set gives a score of 1 to each member and a missing key gives an empty input
+func getZSetInputs(keys []string) []map[string]float64 {
+	inputs := make([]map[string]float64, len(keys))
+	for i, key := range keys {
+		if zSet, exist := zSetStore[key]; exist {
+			inputs[i] = zSet.Scores()
+			continue
+		}
+
+		if set, exist := setStore[key]; exist {
+			scores := make(map[string]float64, set.Len())
+			for _, member := range set.Members() {
+				scores[member] = 1
+			}
+			inputs[i] = scores
+		}
+	}
+
+	return inputs
+}
+
+// the score of an input element multiplied by its weight, 0 * inf gives 0 instead of NaN
+func weightedScore(score float64, i int, opts *zSetOpOptions) float64 {
+	if opts.weights == nil {
+		return score
+	}
+
+	res := score * opts.weights[i]
+	if math.IsNaN(res) {
+		return 0
+	}
+
+	return res
+}
+
+// combine two scores of the same element, inf + -inf gives 0 instead of NaN
+func aggregateScores(a float64, b float64, aggregate int) float64 {
+	switch aggregate {
+	case aggregateMin:
+		return min(a, b)
+	case aggregateMax:
+		return max(a, b)
+	default:
+		res := a + b
+		if math.IsNaN(res) {
+			return 0
+		}
+		return res
+	}
+}
+
+func unionZSets(inputs []map[string]float64, opts *zSetOpOptions) map[string]float64 {
+	res := make(map[string]float64)
+	for i, input := range inputs {
+		for member, score := range input {
+			score = weightedScore(score, i, opts)
+			if current, exist := res[member]; exist {
+				res[member] = aggregateScores(current, score, opts.aggregate)
+			} else {
+				res[member] = score
+			}
+		}
+	}
+
+	return res
+}
+
+/*
+Intersect the inputs, stopping once limit elements are found (0 means no limit).
+Like intersectSets, the smallest input is iterated and its members are looked up in the other inputs.
+*/
+func intersectZSets(inputs []map[string]float64, opts *zSetOpOptions, limit int) map[string]float64 {
+	res := make(map[string]float64)
+	order := make([]int, len(inputs))
+	for i, input := range inputs {
+		if len(input) == 0 {
+			return res
+		}
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return len(inputs[order[i]]) < len(inputs[order[j]])
+	})
+
+	for member, score := range inputs[order[0]] {
+		score = weightedScore(score, order[0], opts)
+		inAll := true
+		for _, i := range order[1:] {
+			other, exist := inputs[i][member]
+			if !exist {
+				inAll = false
+				break
+			}
+			score = aggregateScores(score, weightedScore(other, i, opts), opts.aggregate)
+		}
+
+		if inAll {
+			res[member] = score
+			if limit > 0 && len(res) >= limit {
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+// elements of the first input that are in none of the other inputs, with their score in the first input
+func diffZSets(inputs []map[string]float64) map[string]float64 {
+	res := make(map[string]float64)
+	for member, score := range inputs[0] {
+		inOther := false
+		for _, other := range inputs[1:] {
+			if _, exist := other[member]; exist {
+				inOther = true
+				break
+			}
+		}
+
+		if !inOther {
+			res[member] = score
+		}
+	}
+
+	return res
+}
+
+func createZSetFromScores(scores map[string]float64) *data_structure.ZSet {
+	zSet := data_structure.CreatZSet()
+	for member, score := range scores {
+		zSet.Add(score, member)
+	}
+
+	return zSet
+}
+
+// reply the result of a set operation ordered by score
+func replyZSetOp(scores map[string]float64, withScores bool) []byte {
+	if len(scores) == 0 {
+		return Encode(make([]string, 0))
+	}
+
+	zSet := createZSetFromScores(scores)
+
+	return encodeZElements(zSet.RangeByRank(0, zSet.Len()-1, false), withScores)
+}
+
+// overwrite the destination with a new sorted set holding the result, an empty result deletes the destination
+func storeZSetOp(destKey string, scores map[string]float64) []byte {
+	deleteKey(destKey)
+	if len(scores) == 0 {
+		return Encode(0)
+	}
+
+	zSetStore[destKey] = createZSetFromScores(scores)
+	signalKeyAsReady(destKey)
+
+	return Encode(len(scores))
+}
+
+// cmd: ZUNION numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE <SUM | MIN | MAX>] [WITHSCORES]
+func cmdZUNION(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZUNION' command"))
+	}
+
+	keys, opts, err := parseZSetOpArgs(args, "ZUNION", true, true)
+	if err != nil {
+		return Encode(err)
+	}
+
+	return replyZSetOp(unionZSets(getZSetInputs(keys), opts), opts.withScores)
+}
+
+// cmd: ZINTER numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE <SUM | MIN | MAX>] [WITHSCORES]
+func cmdZINTER(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZINTER' command"))
+	}
+
+	keys, opts, err := parseZSetOpArgs(args, "ZINTER", true, true)
+	if err != nil {
+		return Encode(err)
+	}
+
+	return replyZSetOp(intersectZSets(getZSetInputs(keys), opts, 0), opts.withScores)
+}
+
+// cmd: ZDIFF numkeys key [key ...] [WITHSCORES]
+func cmdZDIFF(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZDIFF' command"))
+	}
+
+	keys, opts, err := parseZSetOpArgs(args, "ZDIFF", false, true)
+	if err != nil {
+		return Encode(err)
+	}
+
+	return replyZSetOp(diffZSets(getZSetInputs(keys)), opts.withScores)
+}
+
+// cmd: ZUNIONSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE <SUM | MIN | MAX>]
+func cmdZUNIONSTORE(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZUNIONSTORE' command"))
+	}
+
+	keys, opts, err := parseZSetOpArgs(args[1:], "ZUNIONSTORE", true, false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	return storeZSetOp(args[0], unionZSets(getZSetInputs(keys), opts))
+}
+
+// cmd: ZINTERSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE <SUM | MIN | MAX>]
+func cmdZINTERSTORE(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZINTERSTORE' command"))
+	}
+
+	keys, opts, err := parseZSetOpArgs(args[1:], "ZINTERSTORE", true, false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	return storeZSetOp(args[0], intersectZSets(getZSetInputs(keys), opts, 0))
+}
+
+// cmd: ZDIFFSTORE destination numkeys key [key ...]
+func cmdZDIFFSTORE(args []string) []byte {
+	if len(args) < 3 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZDIFFSTORE' command"))
+	}
+
+	keys, _, err := parseZSetOpArgs(args[1:], "ZDIFFSTORE", false, false)
+	if err != nil {
+		return Encode(err)
+	}
+
+	return storeZSetOp(args[0], diffZSets(getZSetInputs(keys)))
+}
+
+// cmd: ZINTERCARD numkeys key [key ...] [LIMIT limit]
+func cmdZINTERCARD(args []string) []byte {
+	if len(args) < 2 {
+		return Encode(errors.New("(error) wrong number of arguments for 'ZINTERCARD' command"))
+	}
+
+	numKeys, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return Encode(errors.New("(error) value is not an integer or out of range"))
+	}
+	if numKeys <= 0 {
+		return Encode(errors.New("(error) numkeys should be greater than 0"))
+	}
+	if numKeys > int64(len(args)-1) {
+		return Encode(errors.New("(error) Number of keys can't be greater than number of args"))
+	}
+
+	keys := args[1 : 1+numKeys]
+	rest := args[1+numKeys:]
+	var limit int64 = 0
+	for i := 0; i < len(rest); i++ {
+		if strings.ToUpper(rest[i]) != "LIMIT" || i+1 >= len(rest) {
+			return Encode(errors.New("(error) syntax error"))
+		}
+		i++
+
+		if limit, err = strconv.ParseInt(rest[i], 10, 64); err != nil {
+			return Encode(errors.New("(error) value is not an integer or out of range"))
+		}
+		if limit < 0 {
+			return Encode(errors.New("(error) LIMIT can't be negative"))
+		}
+	}
+
+	return Encode(len(intersectZSets(getZSetInputs(keys), &zSetOpOptions{}, int(limit))))
+}
@@ -0,0 +1,489 @@
+package core
+
+import (
+	"mtredis/internal/config"
+	"mtredis/internal/constant"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestZRANGEByRank(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZRANGE z 0 -1"), reply([]string{})},
+		{args("ZADD z 1 one 2 two 3 three"), reply(3)},
+		{args("ZRANGE z 0 -1"), reply([]string{"one", "two", "three"})},
+		{args("ZRANGE z 2 3"), reply([]string{"three"})},
+		{args("ZRANGE z -2 -1"), reply([]string{"two", "three"})},
+		{args("ZRANGE z -100 100"), reply([]string{"one", "two", "three"})},
+		{args("ZRANGE z 2 1"), reply([]string{})},
+		{args("ZRANGE z 0 1 WITHSCORES"), reply([]string{"one", "1", "two", "2"})},
+		{args("ZRANGE z 0 0 REV"), reply([]string{"three"})},
+		{args("ZREVRANGE z 0 -1"), reply([]string{"three", "two", "one"})},
+		{args("ZREVRANGE z 0 0 WITHSCORES"), reply([]string{"three", "3"})},
+		{args("ZRANGE z a 1"), errReply("value is not an integer or out of range")},
+		{args("ZRANGE z 0 1 LIMIT 0 1"), errReply("syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")},
+		{args("ZRANGE z 0 1 BOGUS"), errReply("syntax error")},
+		{args("ZREVRANGE z 0 1 REV"), errReply("syntax error")},
+		{args("ZRANGE z 0"), errReply("wrong number of arguments for 'ZRANGE' command")},
+	})
+}
+
+func TestZRANGEByScore(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD z 1 one 2 two 3 three 1.5 half"), reply(4)},
+		{args("ZRANGEBYSCORE z -inf +inf"), reply([]string{"one", "half", "two", "three"})},
+		{args("ZRANGEBYSCORE z 1 2"), reply([]string{"one", "half", "two"})},
+		{args("ZRANGEBYSCORE z (1 2"), reply([]string{"half", "two"})},
+		{args("ZRANGEBYSCORE z (1 (2"), reply([]string{"half"})},
+		{args("ZRANGEBYSCORE z (2 (2"), reply([]string{})},
+		{args("ZRANGEBYSCORE z 3 1"), reply([]string{})},
+		{args("ZRANGEBYSCORE z 1 2 WITHSCORES"), reply([]string{"one", "1", "half", "1.5", "two", "2"})},
+		{args("ZRANGEBYSCORE z -inf +inf LIMIT 1 2"), reply([]string{"half", "two"})},
+		{args("ZRANGEBYSCORE z -inf +inf LIMIT 1 -1"), reply([]string{"half", "two", "three"})},
+		{args("ZRANGEBYSCORE z -inf +inf LIMIT -1 2"), reply([]string{})},
+		{args("ZRANGE z (1 +inf BYSCORE LIMIT 1 1"), reply([]string{"two"})},
+		{args("ZRANGE z +inf (1 BYSCORE REV"), reply([]string{"three", "two", "half"})},
+		{args("ZRANGE z +inf -inf BYSCORE REV LIMIT 0 1 WITHSCORES"), reply([]string{"three", "3"})},
+		{args("ZRANGEBYSCORE z a 2"), errReply("min or max is not a float")},
+		{args("ZRANGEBYSCORE z 1 nan"), errReply("min or max is not a float")},
+		{args("ZRANGEBYSCORE z 1 2 LIMIT 0"), errReply("syntax error")},
+		{args("ZRANGEBYSCORE z 1 2 LIMIT a 1"), errReply("value is not an integer or out of range")},
+		{args("ZRANGEBYSCORE missing 1 2"), reply([]string{})},
+		{args("ZRANGEBYSCORE missing a 2"), errReply("min or max is not a float")},
+	})
+}
+
+func TestZRANGEByLex(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD z 0 a 0 b 0 c 0 d 0 e 0 f 0 g"), reply(7)},
+		{args("ZRANGEBYLEX z - [c"), reply([]string{"a", "b", "c"})},
+		{args("ZRANGEBYLEX z - (c"), reply([]string{"a", "b"})},
+		{args("ZRANGEBYLEX z [aaa (g"), reply([]string{"b", "c", "d", "e", "f"})},
+		{args("ZRANGEBYLEX z - + LIMIT 2 3"), reply([]string{"c", "d", "e"})},
+		{args("ZRANGEBYLEX z + -"), reply([]string{})},
+		{args("ZRANGE z (g [aaa BYLEX REV"), reply([]string{"f", "e", "d", "c", "b"})},
+		{args("ZRANGE z + - BYLEX REV LIMIT 0 2"), reply([]string{"g", "f"})},
+		{args("ZRANGEBYLEX z a c"), errReply("min or max not valid string range item")},
+		{args("ZRANGE z - + BYLEX WITHSCORES"), errReply("syntax error, WITHSCORES not supported in combination with BYLEX")},
+		{args("ZRANGE z - + BYLEX BYSCORE"), errReply("syntax error")},
+		{args("ZRANGEBYLEX z - + WITHSCORES"), errReply("syntax error")},
+	})
+}
+
+func TestZRANGESTORE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD src 1 one 2 two 3 three 4 four"), reply(4)},
+		{args("ZRANGESTORE dst src 2 -1"), reply(2)},
+		{args("ZRANGE dst 0 -1 WITHSCORES"), reply([]string{"three", "3", "four", "4"})},
+		{args("ZRANGESTORE dst src (1 3 BYSCORE LIMIT 0 1"), reply(1)},
+		{args("ZRANGE dst 0 -1"), reply([]string{"two"})},
+		{args("ZRANGESTORE dst src 0 0 REV"), reply(1)},
+		{args("ZRANGE dst 0 -1"), reply([]string{"four"})},
+		// an empty result deletes the destination
+		{args("ZRANGESTORE dst src 10 20"), reply(0)},
+		{args("ZCARD dst"), reply(0)},
+		{args("ZRANGESTORE dst missing 0 -1"), reply(0)},
+		{args("ZRANGESTORE dst src 0 -1 WITHSCORES"), errReply("syntax error")},
+		{args("ZRANGESTORE dst src 0"), errReply("wrong number of arguments for 'ZRANGESTORE' command")},
+	})
+}
+
+func TestZREM(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZREM z a"), reply(0)},
+		{args("ZADD z 1 a 2 b 3 c"), reply(3)},
+		{args("ZREM z a x a"), reply(1)},
+		{args("ZCARD z"), reply(2)},
+		{args("ZREM z b c"), reply(2)},
+		// the last removal deletes the key
+		{args("ZCARD z"), reply(0)},
+		{args("OBJECT ENCODING z"), nilReply},
+		{args("ZREM z"), errReply("wrong number of arguments for 'ZREM' command")},
+		{args("ZCARD"), errReply("wrong number of arguments for 'ZCARD' command")},
+	})
+}
+
+func TestZREMRANGE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD z 1 one 2 two 3 three 4 four 5 five"), reply(5)},
+		{args("ZREMRANGEBYRANK z 3 1"), reply(0)},
+		{args("ZREMRANGEBYRANK z -2 -1"), reply(2)},
+		{args("ZRANGE z 0 -1"), reply([]string{"one", "two", "three"})},
+		{args("ZREMRANGEBYSCORE z (1 2"), reply(1)},
+		{args("ZREMRANGEBYSCORE z (3 +inf"), reply(0)},
+		{args("ZREMRANGEBYSCORE z 3 1"), reply(0)},
+		{args("ZRANGE z 0 -1"), reply([]string{"one", "three"})},
+		{args("ZREMRANGEBYRANK z 0 100"), reply(2)},
+		{args("ZCARD z"), reply(0)},
+		{args("ZADD l 0 a 0 b 0 c 0 d"), reply(4)},
+		{args("ZREMRANGEBYLEX l [b (d"), reply(2)},
+		{args("ZREMRANGEBYLEX l (d +"), reply(0)},
+		{args("ZRANGE l 0 -1"), reply([]string{"a", "d"})},
+		{args("ZREMRANGEBYLEX l - +"), reply(2)},
+		{args("ZCARD l"), reply(0)},
+		{args("ZREMRANGEBYRANK z a 1"), errReply("value is not an integer or out of range")},
+		{args("ZREMRANGEBYSCORE z 1 b"), errReply("min or max is not a float")},
+		{args("ZREMRANGEBYLEX z b c"), errReply("min or max not valid string range item")},
+		{args("ZREMRANGEBYRANK z 0"), errReply("wrong number of arguments for 'ZREMRANGEBYRANK' command")},
+	})
+}
+
+func TestZCOUNT(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZCOUNT z -inf +inf"), reply(0)},
+		{args("ZADD z 1 one 2 two 3 three"), reply(3)},
+		{args("ZCOUNT z -inf +inf"), reply(3)},
+		{args("ZCOUNT z (1 3"), reply(2)},
+		{args("ZCOUNT z (1 (3"), reply(1)},
+		{args("ZCOUNT z 3 1"), reply(0)},
+		{args("ZCOUNT z a 1"), errReply("min or max is not a float")},
+		{args("ZADD l 0 a 0 b 0 c 0 d 0 e 0 f 0 g"), reply(7)},
+		{args("ZLEXCOUNT l - +"), reply(7)},
+		{args("ZLEXCOUNT l [b [f"), reply(5)},
+		{args("ZLEXCOUNT l (b (f"), reply(3)},
+		{args("ZLEXCOUNT l [f [b"), reply(0)},
+		{args("ZLEXCOUNT l b f"), errReply("min or max not valid string range item")},
+		{args("ZLEXCOUNT missing - +"), reply(0)},
+	})
+}
+
+// the destination of ZRANGESTORE is overwritten whatever its type, also by an empty result
+func TestZRANGESTOREOverwritesDestination(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		setup replyTest
+		check replyTest // the old value is gone
+	}{
+		{"string", replyTest{args("SET dst v"), okReply}, replyTest{args("GET dst"), nilReply}},
+		{"list", replyTest{args("RPUSH dst a b"), reply(2)}, replyTest{args("LLEN dst"), reply(0)}},
+		{"hash", replyTest{args("HSET dst f v"), reply(1)}, replyTest{args("HLEN dst"), reply(0)}},
+		{"set", replyTest{args("SADD dst a b"), reply(2)}, replyTest{args("SCARD dst"), reply(0)}},
+		{"zset", replyTest{args("ZADD dst 1 a"), reply(1)}, replyTest{args("ZSCORE dst a"), nilReply}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resetStores(t)
+			runReplyTests(t, []replyTest{
+				{args("ZADD src 1 one 2 two"), reply(2)},
+				tt.setup,
+				{args("ZRANGESTORE dst src 0 -1"), reply(2)},
+				tt.check,
+				{args("ZRANGE dst 0 -1"), reply([]string{"one", "two"})},
+				{args("OBJECT ENCODING dst"), reply("listpack")},
+			})
+
+			resetStores(t)
+			runReplyTests(t, []replyTest{
+				{args("ZADD src 1 one 2 two"), reply(2)},
+				tt.setup,
+				{args("ZRANGESTORE dst src 5 10"), reply(0)},
+				tt.check,
+				{args("OBJECT ENCODING dst"), nilReply},
+			})
+		})
+	}
+}
+
+func TestZADDFlags(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD z XX 1 a"), reply(0)},
+		{args("ZCARD z"), reply(0)},
+		{args("ZADD z 1 a 2 b"), reply(2)},
+		{args("ZADD z 10 a 3 c"), reply(1)},
+		{args("ZADD z CH 20 a 20 a 3 c 4 d"), reply(2)},
+		{args("ZADD z NX 100 a 5 e"), reply(1)},
+		{args("ZSCORE z a"), reply("20")},
+		{args("ZADD z XX CH 30 a 6 f"), reply(1)},
+		{args("ZSCORE z f"), nilReply},
+		{args("ZADD z GT CH 10 a 40 b"), reply(1)},
+		{args("ZADD z LT CH 10 a 50 b"), reply(1)},
+		{args("ZRANGE z 0 -1 WITHSCORES"), reply([]string{"c", "3", "d", "4", "e", "5", "a", "10", "b", "40"})},
+		// GT and LT still add new members
+		{args("ZADD z GT 1 g"), reply(1)},
+		{args("ZADD z INCR 5 a"), reply("15")},
+		{args("ZADD z INCR -inf new"), reply("-inf")},
+		{args("ZADD z NX INCR 5 a"), nilReply},
+		{args("ZADD z XX INCR 5 missing"), nilReply},
+		{args("ZADD z GT INCR -1 a"), nilReply},
+		{args("ZADD z INCR +inf new"), errReply("resulting score is not a number (NaN)")},
+		{args("ZADD z INCR 1 a 2 b"), errReply("INCR option supports a single increment-element pair")},
+		{args("ZADD z NX XX 1 a"), errReply("XX and NX options at the same time are not compatible")},
+		{args("ZADD z NX GT 1 a"), errReply("GT, LT, and/or NX options at the same time are not compatible")},
+		{args("ZADD z GT LT 1 a"), errReply("GT, LT, and/or NX options at the same time are not compatible")},
+		{args("ZADD z 1 a 2"), errReply("syntax error")},
+		{args("ZADD z NX CH"), errReply("syntax error")},
+		// nothing is added when a score is invalid
+		{args("ZADD z 1 x nan y"), errReply("value is not a valid float")},
+		{args("ZSCORE z x"), nilReply},
+		{args("ZADD z XX 1 a"), reply(0)},
+		{args("ZADD z"), errReply("wrong number of arguments for 'ZADD' command")},
+	})
+}
+
+func TestZINCRBY(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZINCRBY z 2 a"), reply("2")},
+		{args("ZINCRBY z 0.5 a"), reply("2.5")},
+		{args("ZINCRBY z -3 a"), reply("-0.5")},
+		{args("ZINCRBY z 0.00001 b"), reply("1e-05")},
+		{args("ZINCRBY z 1e17 c"), reply("1e+17")},
+		{args("ZINCRBY z +inf a"), reply("inf")},
+		{args("ZINCRBY z -inf a"), errReply("resulting score is not a number (NaN)")},
+		{args("ZSCORE z a"), reply("inf")},
+		{args("ZINCRBY z x a"), errReply("value is not a valid float")},
+		{args("ZINCRBY z 1"), errReply("wrong number of arguments for 'ZINCRBY' command")},
+	})
+}
+
+func TestZRANK(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZRANK z a"), nilReply},
+		{args("ZRANK z a WITHSCORE"), string(constant.RespNilArray)},
+		{args("ZADD z 1 a 2 b 3.5 c"), reply(3)},
+		{args("ZRANK z a"), reply(0)},
+		{args("ZRANK z c"), reply(2)},
+		{args("ZREVRANK z a"), reply(2)},
+		{args("ZREVRANK z c"), reply(0)},
+		{args("ZRANK z b WITHSCORE"), reply([]interface{}{1, "2"})},
+		{args("ZREVRANK z c WITHSCORE"), reply([]interface{}{0, "3.5"})},
+		{args("ZRANK z x"), nilReply},
+		{args("ZREVRANK z x WITHSCORE"), string(constant.RespNilArray)},
+		{args("ZRANK z a WITHSCORES"), errReply("syntax error")},
+		{args("ZREVRANK z"), errReply("wrong number of arguments for 'ZREVRANK' command")},
+	})
+}
+
+func TestZMSCORE(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZMSCORE z a b"), reply([]interface{}{nil, nil})},
+		{args("ZADD z 1 a 2.5 b -inf c"), reply(3)},
+		{args("ZMSCORE z a x b c"), reply([]interface{}{"1", nil, "2.5", "-inf"})},
+		{args("ZMSCORE z"), errReply("wrong number of arguments for 'ZMSCORE' command")},
+	})
+}
+
+func TestZRANDMEMBER(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZRANDMEMBER z"), nilReply},
+		{args("ZRANDMEMBER z 3"), reply([]string{})},
+		{args("ZRANDMEMBER z 1 WITHSCORE"), errReply("syntax error")},
+		{args("ZRANDMEMBER z x"), errReply("value is not an integer or out of range")},
+		{args("ZRANDMEMBER z -2147483648"), errReply("value is out of range")},
+		{args("ZRANDMEMBER z 1 WITHSCORES x"), errReply("wrong number of arguments for 'ZRANDMEMBER' command")},
+		{args("ZADD z 1 a 2 b 3 c"), reply(3)},
+		{args("ZRANDMEMBER z 0"), reply([]string{})},
+		// the whole set when the count is not less than its size
+		{args("ZRANDMEMBER z 5 WITHSCORES"), reply([]string{"a", "1", "b", "2", "c", "3"})},
+	})
+
+	c := newTestClient(t)
+	members := map[string]bool{"a": true, "b": true, "c": true}
+	if value, _ := Decode([]byte(c.do("ZRANDMEMBER", "z"))); !members[value.(string)] {
+		t.Fatalf("ZRANDMEMBER z = %v, want a member", value)
+	}
+
+	// distinct members for a positive count
+	for range 20 {
+		got := c.sorted("ZRANDMEMBER", "z", "2")
+		if len(got) != 2 || got[0] == got[1] || !members[got[0]] || !members[got[1]] {
+			t.Fatalf("ZRANDMEMBER z 2 = %v, want 2 distinct members", got)
+		}
+	}
+
+	// a negative count may repeat the members
+	got := c.sorted("ZRANDMEMBER", "z", "-10")
+	if len(got) != 10 {
+		t.Fatalf("ZRANDMEMBER z -10 returned %d members, want 10", len(got))
+	}
+	for _, m := range got {
+		if !members[m] {
+			t.Fatalf("ZRANDMEMBER z -10 returned %q", m)
+		}
+	}
+
+	// each member is followed by its own score
+	value, _ := Decode([]byte(c.do("ZRANDMEMBER", "z", "-10", "WITHSCORES")))
+	pairs := value.([]interface{})
+	scores := map[string]string{"a": "1", "b": "2", "c": "3"}
+	if len(pairs) != 20 {
+		t.Fatalf("ZRANDMEMBER z -10 WITHSCORES returned %d elements, want 20", len(pairs))
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		if member := pairs[i].(string); pairs[i+1] != scores[member] {
+			t.Fatalf("ZRANDMEMBER WITHSCORES returned %v with the score %v", member, pairs[i+1])
+		}
+	}
+}
+
+func TestZPOP(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZPOPMIN z"), reply([]string{})},
+		{args("ZADD z 1 a 2 b 3 c 4 d"), reply(4)},
+		{args("ZPOPMIN z"), reply([]string{"a", "1"})},
+		{args("ZPOPMAX z 2"), reply([]string{"d", "4", "c", "3"})},
+		{args("ZPOPMIN z 0"), reply([]string{})},
+		{args("ZPOPMIN z 10"), reply([]string{"b", "2"})},
+		{args("OBJECT ENCODING z"), nilReply},
+		{args("ZPOPMIN z -1"), errReply("value is out of range, must be positive")},
+		{args("ZPOPMAX z x"), errReply("value is not an integer or out of range")},
+		{args("ZPOPMAX z 1 2"), errReply("wrong number of arguments for 'ZPOPMAX' command")},
+	})
+}
+
+func TestZMPOP(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZMPOP 2 a b MIN"), string(constant.RespNilArray)},
+		{args("ZADD b 1 x 2 y 3 z"), reply(3)},
+		{args("ZMPOP 2 a b MIN"), reply([]interface{}{"b", []interface{}{[]string{"x", "1"}}})},
+		{args("ZMPOP 2 a b max COUNT 5"), reply([]interface{}{"b", []interface{}{[]string{"z", "3"}, []string{"y", "2"}}})},
+		{args("ZCARD b"), reply(0)},
+		{args("ZMPOP 0 a MIN"), errReply("numkeys should be greater than 0")},
+		{args("ZMPOP 3 a b MIN"), errReply("syntax error")},
+		{args("ZMPOP 1 a UP"), errReply("syntax error")},
+		{args("ZMPOP 1 a MIN COUNT"), errReply("syntax error")},
+		{args("ZMPOP 1 a MIN COUNT 0"), errReply("count should be greater than 0")},
+		{args("ZMPOP 1 a"), errReply("wrong number of arguments for 'ZMPOP' command")},
+	})
+}
+
+func TestZSetOperations(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD z1 1 one 2 two"), reply(2)},
+		{args("ZADD z2 1 one 2 two 3 three"), reply(3)},
+		{args("SADD s one four"), reply(2)},
+		{args("ZUNION 2 z1 z2 WITHSCORES"), reply([]string{"one", "2", "three", "3", "two", "4"})},
+		{args("ZUNION 2 z1 z2 WEIGHTS 2 3 WITHSCORES"), reply([]string{"one", "5", "three", "9", "two", "10"})},
+		{args("ZUNION 2 z1 z2 AGGREGATE MIN WITHSCORES"), reply([]string{"one", "1", "two", "2", "three", "3"})},
+		{args("ZUNION 2 z1 z2 WEIGHTS 1 -1 AGGREGATE max WITHSCORES"), reply([]string{"three", "-3", "one", "1", "two", "2"})},
+		{args("ZINTER 2 z1 z2"), reply([]string{"one", "two"})},
+		{args("ZINTER 2 z1 z2 WITHSCORES"), reply([]string{"one", "2", "two", "4"})},
+		{args("ZINTER 2 z2 missing"), reply([]string{})},
+		{args("ZDIFF 2 z2 z1 WITHSCORES"), reply([]string{"three", "3"})},
+		{args("ZDIFF 2 z1 z2"), reply([]string{})},
+		// the members of a plain set have a score of 1
+		{args("ZUNION 2 z2 s WITHSCORES"), reply([]string{"four", "1", "one", "2", "two", "2", "three", "3"})},
+		{args("ZINTER 2 s z2 WITHSCORES"), reply([]string{"one", "2"})},
+		// inf * 0 and inf + -inf give 0 instead of NaN
+		{args("ZADD inf +inf a"), reply(1)},
+		{args("ZADD ninf -inf a"), reply(1)},
+		{args("ZUNION 1 inf WEIGHTS 0 WITHSCORES"), reply([]string{"a", "0"})},
+		{args("ZINTER 2 inf ninf WITHSCORES"), reply([]string{"a", "0"})},
+		{args("ZUNION 0 z1"), errReply("at least 1 input key is needed for 'ZUNION' command")},
+		{args("ZUNION x z1"), errReply("value is not an integer or out of range")},
+		{args("ZINTER 3 z1 z2"), errReply("syntax error")},
+		{args("ZUNION 2 z1 z2 WEIGHTS 1"), errReply("syntax error")},
+		{args("ZUNION 2 z1 z2 WEIGHTS 1 x"), errReply("weight value is not a float")},
+		{args("ZUNION 2 z1 z2 AGGREGATE AVG"), errReply("syntax error")},
+		{args("ZDIFF 2 z1 z2 WEIGHTS 1 1"), errReply("syntax error")},
+		{args("ZUNION 1"), errReply("wrong number of arguments for 'ZUNION' command")},
+	})
+}
+
+func TestZSetOperationsStore(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD z1 1 one 2 two"), reply(2)},
+		{args("ZADD z2 1 one 2 two 3 three"), reply(3)},
+		{args("ZUNIONSTORE out 2 z1 z2 WEIGHTS 2 3"), reply(3)},
+		{args("ZRANGE out 0 -1 WITHSCORES"), reply([]string{"one", "5", "three", "9", "two", "10"})},
+		{args("ZINTERSTORE out 2 z1 z2 AGGREGATE MAX"), reply(2)},
+		{args("ZRANGE out 0 -1 WITHSCORES"), reply([]string{"one", "1", "two", "2"})},
+		{args("ZDIFFSTORE out 2 z2 z1"), reply(1)},
+		{args("ZRANGE out 0 -1 WITHSCORES"), reply([]string{"three", "3"})},
+		// the destination can be one of the inputs
+		{args("ZUNIONSTORE z1 2 z1 z2"), reply(3)},
+		{args("ZRANGE z1 0 -1 WITHSCORES"), reply([]string{"one", "2", "three", "3", "two", "4"})},
+		// the destination is overwritten whatever its type, an empty result deletes it
+		{args("SET str v"), okReply},
+		{args("ZINTERSTORE str 2 z2 str"), reply(0)},
+		{args("GET str"), nilReply},
+		{args("RPUSH list a"), reply(1)},
+		{args("ZDIFFSTORE list 1 z2"), reply(3)},
+		{args("LLEN list"), reply(0)},
+		{args("ZCARD list"), reply(3)},
+		{args("HSET hash f v"), reply(1)},
+		{args("ZUNIONSTORE hash 1 missing"), reply(0)},
+		{args("HLEN hash"), reply(0)},
+		{args("ZUNIONSTORE out 2 z1 z2 WITHSCORES"), errReply("syntax error")},
+		{args("ZDIFFSTORE out 0 z1"), errReply("at least 1 input key is needed for 'ZDIFFSTORE' command")},
+		{args("ZINTERSTORE out 1"), errReply("wrong number of arguments for 'ZINTERSTORE' command")},
+	})
+}
+
+func TestZINTERCARD(t *testing.T) {
+	resetStores(t)
+
+	runReplyTests(t, []replyTest{
+		{args("ZADD z1 1 a 2 b 3 c"), reply(3)},
+		{args("ZADD z2 1 a 2 b 3 c 4 d"), reply(4)},
+		{args("ZINTERCARD 2 z1 z2"), reply(3)},
+		{args("ZINTERCARD 2 z1 z2 LIMIT 2"), reply(2)},
+		{args("ZINTERCARD 2 z1 z2 LIMIT 0"), reply(3)},
+		{args("ZINTERCARD 2 z1 missing"), reply(0)},
+		{args("ZINTERCARD 0 z1"), errReply("numkeys should be greater than 0")},
+		{args("ZINTERCARD 3 z1 z2"), errReply("Number of keys can't be greater than number of args")},
+		{args("ZINTERCARD 2 z1 z2 LIMIT -1"), errReply("LIMIT can't be negative")},
+		{args("ZINTERCARD 2 z1 z2 LIMIT"), errReply("syntax error")},
+		{args("ZINTERCARD 2 z1 z2 LIMIT x"), errReply("value is not an integer or out of range")},
+	})
+}
+
+func TestZSetObjectEncoding(t *testing.T) {
+	resetStores(t)
+
+	c := newTestClient(t)
+	for i := range config.ZSetMaxListPackEntries {
+		c.do("ZADD", "z", strconv.Itoa(i), "m"+strconv.Itoa(i))
+	}
+	if got := c.do("OBJECT", "ENCODING", "z"); got != reply("listpack") {
+		t.Fatalf("OBJECT ENCODING = %q with %d elements, want listpack", got, config.ZSetMaxListPackEntries)
+	}
+	c.do("ZADD", "z", "0", "one-more")
+	if got := c.do("OBJECT", "ENCODING", "z"); got != reply("skiplist") {
+		t.Fatalf("OBJECT ENCODING = %q with one more element, want skiplist", got)
+	}
+	if got := c.do("ZRANGE", "z", "0", "1"); got != reply([]string{"m0", "one-more"}) {
+		t.Fatalf("ZRANGE after the conversion = %q", got)
+	}
+
+	runReplyTests(t, []replyTest{
+		{[]string{"ZADD", "long", "1", strings.Repeat("x", config.ZSetMaxListPackValue+1)}, reply(1)},
+		{args("OBJECT ENCODING long"), reply("skiplist")},
+		// a result stored by a set operation gets the encoding of its size
+		{args("ZUNIONSTORE small 1 z"), reply(config.ZSetMaxListPackEntries + 1)},
+		{args("OBJECT ENCODING small"), reply("skiplist")},
+		{args("ZRANGESTORE small z 0 9"), reply(10)},
+		{args("OBJECT ENCODING small"), reply("listpack")},
+	})
+}
@@ -27,3 +27,31 @@ func ActiveDeleteExpiredKeys() {
 		}
 	}
 }
+
+/*
+Same sampling approach as ActiveDeleteExpiredKeys, applied to the hashes that have fields with a TTL.
+A hash that loses its last field is deleted from the keyspace.
+*/
+func ActiveDeleteExpiredHashFields() {
+	for {
+		var expiredFieldCount = 0
+		var sampleCountRemain = constant.ActiveDeleteExpiredKeySampleSize
+
+		for key, hash := range volatileHashStore {
+			sampleCountRemain--
+			if sampleCountRemain < 0 {
+				break
+			}
+
+			expiredFieldCount += hash.DeleteExpiredFields()
+			if !hash.HasExpiringFields() {
+				delete(volatileHashStore, key)
+			}
+			deleteHashIfEmpty(key, hash)
+		}
+
+		if float64(expiredFieldCount)/float64(constant.ActiveDeleteExpiredKeySampleSize) <= constant.ThresholdToStopActiveDelete {
+			break
+		}
+	}
+}
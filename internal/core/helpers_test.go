@@ -0,0 +1,182 @@
+package core
+
+import (
+	"errors"
+	"mtredis/internal/data_structure"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+/*
+A client connected to the executor through a non-blocking pipe: the executor writes the replies to the write end
+and the test reads them from the read end, a client blocked on keys simply has nothing to read yet.
+*/
+type testClient struct {
+	t  *testing.T
+	rd int
+	wr int
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_NONBLOCK); err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+
+	c := &testClient{t: t, rd: p[0], wr: p[1]}
+	t.Cleanup(func() {
+		RemoveBlockedClient(c.wr)
+		syscall.Close(c.rd)
+		syscall.Close(c.wr)
+	})
+
+	return c
+}
+
+// send a command to the executor and return the replies written to the client, "" when the client blocked
+func (c *testClient) do(args ...string) string {
+	c.t.Helper()
+
+	cmd := &Command{Cmd: strings.ToUpper(args[0]), Args: args[1:]}
+	if err := ExecuteAndResponse(cmd, c.wr); err != nil {
+		c.t.Fatalf("%v: %v", args, err)
+	}
+
+	return c.read()
+}
+
+// return the replies written to the client since the last read
+func (c *testClient) read() string {
+	c.t.Helper()
+
+	var sb strings.Builder
+	buf := make([]byte, 4096)
+	for {
+		n, err := syscall.Read(c.rd, buf)
+		if n > 0 {
+			sb.Write(buf[:n])
+		}
+		if err == syscall.EAGAIN || n < len(buf) {
+			return sb.String()
+		}
+		if err != nil {
+			c.t.Fatalf("read: %v", err)
+		}
+	}
+}
+
+// start the test with empty stores and no blocked client
+func resetStores(t *testing.T) {
+	t.Helper()
+
+	dictStore = data_structure.CreateDict()
+	listStore = make(map[string]*data_structure.QuickList)
+	hashStore = make(map[string]*data_structure.Hash)
+	volatileHashStore = make(map[string]*data_structure.Hash)
+	setStore = make(map[string]*data_structure.SimpleSet)
+	zSetStore = make(map[string]*data_structure.ZSet)
+	streamStore = make(map[string]*data_structure.Stream)
+	bloomStore = make(map[string]*data_structure.BloomFilter)
+	cmsStore = make(map[string]*data_structure.CMS)
+	cuckooStore = make(map[string]*data_structure.CuckooFilter)
+	topKStore = make(map[string]*data_structure.TopK)
+	tDigestStore = make(map[string]*data_structure.TDigest)
+
+	blockedClients = make(map[int]*blockedClient)
+	blockingKeys = make(map[string][]*blockedClient)
+	readyKeys = make([]string, 0)
+	readyKeySet = make(map[string]struct{})
+	pendingCommands = make(map[int][]*Command)
+	unblockedClients = make([]int, 0)
+}
+
+// an error reply with the "(error) " prefix of the executor
+func errReply(msg string) string {
+	return string(Encode(errors.New("(error) " + msg)))
+}
+
+// the reply of a value, encoded like the executor does
+func reply(v interface{}) string {
+	return string(Encode(v))
+}
+
+type replyTest struct {
+	args []string
+	want string
+}
+
+// run the commands in order on a single client, checking each reply
+func runReplyTests(t *testing.T, tests []replyTest) {
+	t.Helper()
+
+	c := newTestClient(t)
+	for _, tt := range tests {
+		if got := c.do(tt.args...); got != tt.want {
+			t.Errorf("%s = %q, want %q", strings.Join(tt.args, " "), got, tt.want)
+		}
+	}
+}
+
+func args(line string) []string {
+	return strings.Fields(line)
+}
+
+/*
+Iterate over a collection with a SCAN family command (e.g. "HSCAN key") until the cursor is back to 0,
+and return the elements of all the calls. between is called after every call that is not the last one.
+*/
+func scanAll(t *testing.T, c *testClient, cmd []string, opts []string, between func()) []string {
+	t.Helper()
+
+	res := make([]string, 0)
+	cursor := "0"
+	for {
+		line := append(append(append([]string{}, cmd...), cursor), opts...)
+		value, err := Decode([]byte(c.do(line...)))
+		if err != nil {
+			t.Fatalf("%v: %v", line, err)
+		}
+
+		page, ok := value.([]interface{})
+		if !ok || len(page) != 2 {
+			t.Fatalf("%v = %v, want a cursor and the elements", line, value)
+		}
+		for _, e := range page[1].([]interface{}) {
+			res = append(res, e.(string))
+		}
+
+		cursor = page[0].(string)
+		if cursor == "0" {
+			return res
+		}
+		if between != nil {
+			between()
+		}
+	}
+}
+
+// send a command replying with an array of strings in no particular order (e.g. SMEMBERS), and return it sorted
+func (c *testClient) sorted(args ...string) []string {
+	c.t.Helper()
+
+	value, err := Decode([]byte(c.do(args...)))
+	if err != nil {
+		c.t.Fatalf("%v: %v", args, err)
+	}
+	array, ok := value.([]interface{})
+	if !ok {
+		c.t.Fatalf("%v = %v, want an array", args, value)
+	}
+
+	res := make([]string, len(array))
+	for i, e := range array {
+		res[i] = e.(string)
+	}
+	sort.Strings(res)
+
+	return res
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"mtredis/internal/config"
 	"syscall"
+	"time"
 )
 
 type Epoll struct {
@@ -26,8 +27,14 @@ func CreateIOMultiplexer() (*Epoll, error) {
 	}, nil
 }
 
-func (ep *Epoll) Wait() ([]Event, error) {
-	n, err := syscall.EpollWait(ep.Fd, ep.EpollEvents, -1)
+func (ep *Epoll) Wait(timeout time.Duration) ([]Event, error) {
+	// epoll's timeout is in milliseconds, round up so that we don't wake up right before a timer is due
+	timeoutMs := -1
+	if timeout >= 0 {
+		timeoutMs = int((timeout + time.Millisecond - 1) / time.Millisecond)
+	}
+
+	n, err := syscall.EpollWait(ep.Fd, ep.EpollEvents, timeoutMs)
 	if err != nil {
 		log.Printf("failed to handle event: %v", err)
 		return nil, err
@@ -152,6 +152,8 @@ func EncodeOne(value interface{}) []byte {
 		return encodeBulkString(v)
 	case int64:
 		return encodeInt64(v)
+	case int:
+		return encodeInt64(int64(v))
 	case error:
 		return encodeError(v.Error())
 	case []string:
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+Glob-style pattern matching used by the MATCH option of the SCAN family:
+* matches any sequence, ? matches any character, [abc], [^abc] and [a-z] match character classes and \ escapes the next character.
+The matching is iterative and only backtracks to the last *: a * that matched can only be extended, which keeps
+patterns such as *a*a*a*b linear in the length of the string times the length of the pattern.
+*/
+func matchPattern(pattern string, s string) bool {
+	p, i := 0, 0
+	starP, starI := -1, 0 // the position after the last *, and the position in s it is matched up to
+	for i < len(s) {
+		if p < len(pattern) && pattern[p] == '*' {
+			for p < len(pattern) && pattern[p] == '*' {
+				p++
+			}
+			if p == len(pattern) {
+				return true
+			}
+			starP, starI = p, i
+			continue
+		}
+
+		if p < len(pattern) {
+			if next, ok := matchPatternChar(pattern, p, s[i]); ok {
+				p = next
+				i++
+				continue
+			}
+		}
+
+		// extend the last * by one character and retry the rest of the pattern
+		if starP < 0 {
+			return false
+		}
+		starI++
+		p, i = starP, starI
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// match the character against the token of the pattern at p (a character, ?, a class or an escape), return the position after the token
+func matchPatternChar(pattern string, p int, c byte) (int, bool) {
+	switch pattern[p] {
+	case '?':
+		return p + 1, true
+	case '[':
+		p++
+		not := p < len(pattern) && pattern[p] == '^'
+		if not {
+			p++
+		}
+
+		match := false
+		for p < len(pattern) && pattern[p] != ']' {
+			switch {
+			case pattern[p] == '\\' && p+1 < len(pattern):
+				p++
+				match = match || pattern[p] == c
+			case p+2 < len(pattern) && pattern[p+1] == '-':
+				start, end := pattern[p], pattern[p+2]
+				if start > end {
+					start, end = end, start
+				}
+				match = match || (c >= start && c <= end)
+				p += 2
+			default:
+				match = match || pattern[p] == c
+			}
+			p++
+		}
+		if p < len(pattern) { // skip the closing bracket
+			p++
+		}
+
+		return p, match != not
+	case '\\':
+		if p+1 < len(pattern) {
+			p++
+		}
+	}
+
+	return p + 1, pattern[p] == c
+}
+
+type scanOptions struct {
+	cursor   uint64
+	pattern  string // empty when MATCH is not given
+	count    int
+	noValues bool
+}
+
+// parse: cursor [MATCH pattern] [COUNT count] [NOVALUES], NOVALUES is only accepted when allowNoValues is true
+func parseScanOptions(args []string, allowNoValues bool) (*scanOptions, error) {
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("(error) invalid cursor")
+	}
+
+	opts := &scanOptions{cursor: cursor, count: 10}
+	for i := 1; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch {
+		case opt == "MATCH" && i+1 < len(args):
+			i++
+			opts.pattern = args[i]
+		case opt == "COUNT" && i+1 < len(args):
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return nil, errors.New("(error) value is not an integer or out of range")
+			}
+			if n < 1 {
+				return nil, errors.New("(error) syntax error")
+			}
+			opts.count = int(min(n, math.MaxInt32))
+		case opt == "NOVALUES" && allowNoValues:
+			opts.noValues = true
+		default:
+			return nil, errors.New("(error) syntax error")
+		}
+	}
+
+	return opts, nil
+}
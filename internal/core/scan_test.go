@@ -0,0 +1,71 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchPattern(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "anything", true},
+		{"hello", "hello", true},
+		{"hello", "hello!", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "hllo", true},
+		{"h*llo", "heeeello", true},
+		{"h*llo", "heeeelo", false},
+		{"h[ae]llo", "hello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{"h[b-a]llo", "hallo", true},
+		{"h[\\]]llo", "h]llo", true},
+		{"\\*", "*", true},
+		{"\\*", "a", false},
+		{"a\\", "a\\", true},
+		{"*.txt", "notes.txt", true},
+		{"*.txt", "notes.txt.bak", false},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "aXcYb", false},
+		{"a**", "a", true},
+		{"*a*a*b", "aaab", true},
+		{"*a*a*b", "aab", true},
+		{"*a*a*b", "ab", false},
+		// an unterminated class matches the characters listed so far
+		{"[ab", "a", true},
+		{"[ab", "c", false},
+	} {
+		if got := matchPattern(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+// a pattern with many * can not make the matching exponential (CVE-2022-36021)
+func TestMatchPatternManyStars(t *testing.T) {
+	pattern := strings.Repeat("*a", 30) + "*b"
+	s := strings.Repeat("a", 10000)
+
+	start := time.Now()
+	if matchPattern(pattern, s) {
+		t.Fatalf("matchPattern matched a string without b")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("matchPattern took %v", elapsed)
+	}
+	if !matchPattern(pattern, s+"b") {
+		t.Fatalf("matchPattern did not match the string ending with b")
+	}
+}
@@ -3,11 +3,45 @@ package core
 import "mtredis/internal/data_structure"
 
 var dictStore *data_structure.Dict
+var listStore map[string]*data_structure.QuickList
+var hashStore map[string]*data_structure.Hash
+var volatileHashStore map[string]*data_structure.Hash // hashes having at least one field with a TTL
 var setStore map[string]*data_structure.SimpleSet
 var zSetStore map[string]*data_structure.ZSet
+var streamStore map[string]*data_structure.Stream
+var bloomStore map[string]*data_structure.BloomFilter
+var cmsStore map[string]*data_structure.CMS
+var cuckooStore map[string]*data_structure.CuckooFilter
+var topKStore map[string]*data_structure.TopK
+var tDigestStore map[string]*data_structure.TDigest
 
 func init() {
 	dictStore = data_structure.CreateDict()
+	listStore = make(map[string]*data_structure.QuickList)
+	hashStore = make(map[string]*data_structure.Hash)
+	volatileHashStore = make(map[string]*data_structure.Hash)
 	setStore = make(map[string]*data_structure.SimpleSet)
 	zSetStore = make(map[string]*data_structure.ZSet)
+	streamStore = make(map[string]*data_structure.Stream)
+	bloomStore = make(map[string]*data_structure.BloomFilter)
+	cmsStore = make(map[string]*data_structure.CMS)
+	cuckooStore = make(map[string]*data_structure.CuckooFilter)
+	topKStore = make(map[string]*data_structure.TopK)
+	tDigestStore = make(map[string]*data_structure.TDigest)
+}
+
+// remove the key from every store whatever the type of its value, e.g. to overwrite the destination of a store command
+func deleteKey(key string) {
+	dictStore.DeleteObj(key)
+	delete(listStore, key)
+	delete(hashStore, key)
+	delete(volatileHashStore, key)
+	delete(setStore, key)
+	delete(zSetStore, key)
+	delete(streamStore, key)
+	delete(bloomStore, key)
+	delete(cmsStore, key)
+	delete(cuckooStore, key)
+	delete(topKStore, key)
+	delete(tDigestStore, key)
 }
@@ -0,0 +1,164 @@
+package data_structure
+
+import (
+	"errors"
+	"math"
+	"mtredis/internal/constant"
+)
+
+// the error rate of every new sub-filter is the error rate of the previous one multiplied by this ratio
+const bloomErrorTighteningRatio = 0.5
+
+var ErrBloomFull = errors.New("non scaling filter is full")
+var ErrBloomTooLarge = errors.New("filter is too large to scale")
+
+/*
+A sub-filter of a scalable bloom filter: a bit array sized for Capacity items with the given error rate.
+With bpe = -ln(error) / ln(2)^2 bits per item, the optimal number of hash functions is ceil(ln(2) * bpe).
+*/
+type bloomLink struct {
+	Bits      []byte
+	NumBits   uint64
+	Hashes    uint32
+	Capacity  uint64
+	ErrorRate float64
+	Size      uint64 // number of items added to this sub-filter
+}
+
+// the size in bytes of the bit array of a sub-filter, it is computed as a float64 so that it can not overflow
+func BloomLinkBytes(capacity uint64, errorRate float64) float64 {
+	return float64(capacity) * -math.Log(errorRate) / (math.Ln2 * math.Ln2) / 8
+}
+
+func createBloomLink(capacity uint64, errorRate float64) *bloomLink {
+	bpe := -math.Log(errorRate) / (math.Ln2 * math.Ln2)
+
+	// round the number of bits up to a multiple of 64
+	numBits := uint64(math.Ceil(float64(capacity) * bpe))
+	numBits = (numBits + 63) / 64 * 64
+
+	return &bloomLink{
+		Bits:      make([]byte, numBits/8),
+		NumBits:   numBits,
+		Hashes:    uint32(math.Ceil(math.Ln2 * bpe)),
+		Capacity:  capacity,
+		ErrorRate: errorRate,
+	}
+}
+
+// the positions of the bits of an item are a + i * b (double hashing), where a and b are computed once per item
+func (l *bloomLink) check(a uint64, b uint64) bool {
+	for i := uint64(0); i < uint64(l.Hashes); i++ {
+		x := (a + i*b) % l.NumBits
+		if l.Bits[x/8]&(1<<(x%8)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (l *bloomLink) add(a uint64, b uint64) {
+	for i := uint64(0); i < uint64(l.Hashes); i++ {
+		x := (a + i*b) % l.NumBits
+		l.Bits[x/8] |= 1 << (x % 8)
+	}
+	l.Size++
+}
+
+/*
+A scalable bloom filter tells if an item may have been added, or has definitely not been added.
+It is a chain of sub-filters: once the last one is full, a new one is stacked with Expansion times its capacity
+and half its error rate, so the overall error rate stays bounded by about twice the error rate of the first one.
+A non scaling filter refuses new items once its only sub-filter is full.
+*/
+type BloomFilter struct {
+	Filters    []*bloomLink
+	Size       uint64 // number of items added
+	Expansion  uint32
+	NonScaling bool
+}
+
+func CreateBloomFilter(capacity uint64, errorRate float64, expansion uint32, nonScaling bool) *BloomFilter {
+	return &BloomFilter{
+		Filters:    []*bloomLink{createBloomLink(capacity, errorRate)},
+		Size:       0,
+		Expansion:  expansion,
+		NonScaling: nonScaling,
+	}
+}
+
+func bloomHash(item string) (uint64, uint64) {
+	a := murmurHash64A([]byte(item), 0xc6a4a7935bd1e995)
+	b := murmurHash64A([]byte(item), a)
+
+	return a, b
+}
+
+func (bf *BloomFilter) Exists(item string) bool {
+	a, b := bloomHash(item)
+	for _, l := range bf.Filters {
+		if l.check(a, b) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Add the item to the last sub-filter, a new sub-filter is stacked if it is full.
+Return false if the item may already exist. An error is returned if the filter is full and can not scale:
+it is non scaling, or the new sub-filter would be larger than the max size of a string.
+*/
+func (bf *BloomFilter) Add(item string) (bool, error) {
+	a, b := bloomHash(item)
+	for _, l := range bf.Filters {
+		if l.check(a, b) {
+			return false, nil
+		}
+	}
+
+	last := bf.Filters[len(bf.Filters)-1]
+	if last.Size >= last.Capacity {
+		if bf.NonScaling {
+			return false, ErrBloomFull
+		}
+
+		if last.Capacity > math.MaxUint64/uint64(bf.Expansion) {
+			return false, ErrBloomTooLarge
+		}
+		capacity, errorRate := last.Capacity*uint64(bf.Expansion), last.ErrorRate*bloomErrorTighteningRatio
+		if BloomLinkBytes(capacity, errorRate) > constant.StringMaxSize {
+			return false, ErrBloomTooLarge
+		}
+
+		last = createBloomLink(capacity, errorRate)
+		bf.Filters = append(bf.Filters, last)
+	}
+
+	last.add(a, b)
+	bf.Size++
+
+	return true, nil
+}
+
+// the total capacity of the sub-filters
+func (bf *BloomFilter) Capacity() uint64 {
+	var res uint64 = 0
+	for _, l := range bf.Filters {
+		res += l.Capacity
+	}
+
+	return res
+}
+
+// the memory used by the bit arrays, in bytes
+func (bf *BloomFilter) Bytes() uint64 {
+	var res uint64 = 0
+	for _, l := range bf.Filters {
+		res += uint64(len(l.Bits))
+	}
+
+	return res
+}
@@ -0,0 +1,95 @@
+package data_structure
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestBloomFilterNoFalseNegatives(t *testing.T) {
+	bf := CreateBloomFilter(100, 0.01, 2, false)
+	for i := range 1000 {
+		item := "item" + strconv.Itoa(i)
+		bf.Add(item)
+		if !bf.Exists(item) {
+			t.Fatalf("%s does not exist after it was added", item)
+		}
+	}
+	for i := range 1000 {
+		if !bf.Exists("item" + strconv.Itoa(i)) {
+			t.Fatalf("item%d does not exist after the filter scaled", i)
+		}
+	}
+}
+
+// a new sub-filter is stacked with expansion times the capacity of the last one once it is full
+func TestBloomFilterScaling(t *testing.T) {
+	bf := CreateBloomFilter(100, 0.01, 2, false)
+	for i := 0; bf.Size < 700; i++ {
+		if _, err := bf.Add("item" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	if len(bf.Filters) != 3 || bf.Capacity() != 700 {
+		t.Fatalf("%d sub-filters with a capacity of %d, want 3 and 700", len(bf.Filters), bf.Capacity())
+	}
+	for i, l := range bf.Filters {
+		if want := 0.01 * math.Pow(bloomErrorTighteningRatio, float64(i)); l.ErrorRate != want {
+			t.Fatalf("the error rate of the sub-filter %d is %v, want %v", i, l.ErrorRate, want)
+		}
+		if l.NumBits%64 != 0 || uint64(len(l.Bits))*8 != l.NumBits {
+			t.Fatalf("the sub-filter %d has %d bits in %d bytes", i, l.NumBits, len(l.Bits))
+		}
+	}
+
+	// the overall error rate stays below twice the error rate of the first sub-filter
+	falsePositives := 0
+	for i := range 10000 {
+		if bf.Exists("other" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 10000; rate > 0.02 {
+		t.Fatalf("false positive rate %v, want at most 0.02", rate)
+	}
+}
+
+func TestBloomFilterFull(t *testing.T) {
+	bf := CreateBloomFilter(10, 0.01, 2, true)
+	for i := range 10 {
+		if added, err := bf.Add("item" + strconv.Itoa(i)); !added || err != nil {
+			t.Fatalf("Add(item%d) = %v, %v", i, added, err)
+		}
+	}
+	if _, err := bf.Add("one-more"); err != ErrBloomFull {
+		t.Fatalf("Add to a full non scaling filter: %v, want ErrBloomFull", err)
+	}
+	// an existing item is still reported as such
+	if added, err := bf.Add("item0"); added || err != nil {
+		t.Fatalf("Add(item0) = %v, %v, want false", added, err)
+	}
+	if bf.Size != 10 || len(bf.Filters) != 1 {
+		t.Fatalf("Size = %d with %d sub-filters after the filter is full", bf.Size, len(bf.Filters))
+	}
+}
+
+// the next sub-filter would be larger than the max size of a string
+func TestBloomFilterTooLargeToScale(t *testing.T) {
+	bf := CreateBloomFilter(1, 0.01, math.MaxUint32, false)
+	if _, err := bf.Add("a"); err != nil {
+		t.Fatalf("Add(a): %v", err)
+	}
+	if _, err := bf.Add("b"); err != ErrBloomTooLarge {
+		t.Fatalf("Add(b): %v, want ErrBloomTooLarge", err)
+	}
+	if len(bf.Filters) != 1 {
+		t.Fatalf("%d sub-filters, the too large one was created", len(bf.Filters))
+	}
+
+	// the capacity of the next sub-filter overflows
+	huge := &BloomFilter{Filters: []*bloomLink{{Capacity: math.MaxUint64 / 2, Size: math.MaxUint64 / 2, NumBits: 64, Bits: make([]byte, 8), Hashes: 1}}, Expansion: 4}
+	if _, err := huge.Add("a"); err != ErrBloomTooLarge {
+		t.Fatalf("Add with an overflowing capacity: %v, want ErrBloomTooLarge", err)
+	}
+}
@@ -0,0 +1,87 @@
+package data_structure
+
+import "math"
+
+/*
+A Count-Min Sketch estimates the frequency of items in a fixed amount of memory.
+It is a matrix of depth rows and width counters: an item increments one counter per row, chosen by a hash seeded with the row index.
+The estimated count of an item is the minimum of its counters, which is never lower than the real count.
+With width = ceil(2 / error) and depth = ceil(log(probability) / log(1/2)), the overestimation is at most error * total count
+with a probability of 1 - probability.
+The hash functions only depend on the item and the row, so sketches with the same dimensions can be merged.
+*/
+type CMS struct {
+	Width   uint32
+	Depth   uint32
+	Counter uint64   // total count of all the increments
+	Array   []uint32 // depth rows of width counters
+}
+
+func CreateCMS(width uint32, depth uint32) *CMS {
+	return &CMS{
+		Width:   width,
+		Depth:   depth,
+		Counter: 0,
+		Array:   make([]uint32, uint64(width)*uint64(depth)),
+	}
+}
+
+// compute the dimensions of a sketch for the given error rate and probability of exceeding it
+func CMSDimByProb(errorRate float64, probability float64) (uint32, uint32) {
+	width := math.Ceil(2 / errorRate)
+	depth := math.Ceil(math.Log(probability) / math.Log(0.5))
+
+	return uint32(min(width, math.MaxUint32)), uint32(min(depth, math.MaxUint32))
+}
+
+// the position of the counter of the item in the row
+func (c *CMS) index(item string, row uint32) uint64 {
+	return uint64(row)*uint64(c.Width) + uint64(murmurHash2([]byte(item), row)%c.Width)
+}
+
+// increment the counters of the item, saturating at the max uint32, return the new estimated count of the item
+func (c *CMS) IncrBy(item string, value uint32) uint32 {
+	var res uint32 = math.MaxUint32
+	for row := uint32(0); row < c.Depth; row++ {
+		idx := c.index(item, row)
+		c.Array[idx] = uint32(min(uint64(c.Array[idx])+uint64(value), math.MaxUint32))
+		res = min(res, c.Array[idx])
+	}
+	c.Counter += uint64(value)
+
+	return res
+}
+
+// the estimated count of the item, the minimum of its counters
+func (c *CMS) Query(item string) uint32 {
+	var res uint32 = math.MaxUint32
+	for row := uint32(0); row < c.Depth; row++ {
+		res = min(res, c.Array[c.index(item, row)])
+	}
+
+	return res
+}
+
+/*
+Overwrite the sketch with the weighted sum of the sources, the counters are clamped to the range of uint32.
+All the sketches must have the same dimensions.
+*/
+func (c *CMS) Merge(sources []*CMS, weights []int64) {
+	// sum into a new array since the destination can be one of the sources
+	array := make([]uint32, len(c.Array))
+	for i := range array {
+		var sum int64 = 0
+		for j, src := range sources {
+			sum += int64(src.Array[i]) * weights[j]
+		}
+		array[i] = uint32(min(max(sum, 0), math.MaxUint32))
+	}
+
+	var counter int64 = 0
+	for j, src := range sources {
+		counter += int64(src.Counter) * weights[j]
+	}
+
+	c.Array = array
+	c.Counter = uint64(max(counter, 0))
+}
@@ -0,0 +1,87 @@
+package data_structure
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestCMSDimByProb(t *testing.T) {
+	if width, depth := CMSDimByProb(0.001, 0.01); width != 2000 || depth != 7 {
+		t.Fatalf("CMSDimByProb(0.001, 0.01) = %d, %d, want 2000, 7", width, depth)
+	}
+	if width, _ := CMSDimByProb(1e-12, 0.5); width != math.MaxUint32 {
+		t.Fatalf("CMSDimByProb(1e-12, 0.5) = %d, want the width clamped to the max uint32", width)
+	}
+}
+
+// the estimate is never below the real count, and above it by at most error * total count with a high probability
+func TestCMSEstimate(t *testing.T) {
+	errorRate := 0.01
+	cms := CreateCMS(CMSDimByProb(errorRate, 0.001))
+
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[string]uint32)
+	for range 10000 {
+		// a skewed distribution like the heavy hitters of a real stream
+		item := strconv.Itoa(int(rng.ExpFloat64() * 100))
+		counts[item]++
+		cms.IncrBy(item, 1)
+	}
+	if cms.Counter != 10000 {
+		t.Fatalf("Counter = %d, want 10000", cms.Counter)
+	}
+
+	for item, count := range counts {
+		got := cms.Query(item)
+		if got < count {
+			t.Fatalf("Query(%s) = %d, below the real count %d", item, got, count)
+		}
+		if float64(got-count) > errorRate*10000 {
+			t.Fatalf("Query(%s) = %d, more than the error bound above the real count %d", item, got, count)
+		}
+	}
+	if got := cms.Query("missing"); float64(got) > errorRate*10000 {
+		t.Fatalf("Query(missing) = %d", got)
+	}
+}
+
+func TestCMSSaturation(t *testing.T) {
+	cms := CreateCMS(10, 2)
+	cms.IncrBy("a", math.MaxUint32-1)
+	if got := cms.IncrBy("a", 10); got != math.MaxUint32 {
+		t.Fatalf("IncrBy past the max uint32 = %d, want the counter saturated", got)
+	}
+	if cms.Counter != math.MaxUint32+9 {
+		t.Fatalf("Counter = %d, the total count is not saturated", cms.Counter)
+	}
+}
+
+func TestCMSMerge(t *testing.T) {
+	a, b := CreateCMS(100, 4), CreateCMS(100, 4)
+	a.IncrBy("x", 5)
+	a.IncrBy("y", 1)
+	b.IncrBy("x", 2)
+
+	dst := CreateCMS(100, 4)
+	dst.Merge([]*CMS{a, b}, []int64{1, 3})
+	if got := dst.Query("x"); got != 11 {
+		t.Fatalf("Query(x) after the merge = %d, want 5 + 3 * 2", got)
+	}
+	if dst.Counter != 12 {
+		t.Fatalf("Counter after the merge = %d, want 12", dst.Counter)
+	}
+
+	// the destination can be a source, negative sums are clamped to 0
+	a.Merge([]*CMS{a, b}, []int64{1, -10})
+	if got := a.Query("x"); got != 0 {
+		t.Fatalf("Query(x) = %d after a negative merge, want 0", got)
+	}
+	if got := a.Query("y"); got != 1 {
+		t.Fatalf("Query(y) = %d after a negative merge, want 1", got)
+	}
+	if a.Counter != 0 {
+		t.Fatalf("Counter = %d after a negative merge, want 0", a.Counter)
+	}
+}
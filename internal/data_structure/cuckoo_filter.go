@@ -0,0 +1,242 @@
+package data_structure
+
+import (
+	"errors"
+	"math/rand"
+	"mtredis/internal/constant"
+)
+
+var ErrCuckooFull = errors.New("filter is full")
+var ErrCuckooTooLarge = errors.New("filter is too large to expand")
+
+// a sub-filter of a cuckoo filter: NumBuckets buckets (a power of 2) of BucketSize fingerprints, 0 is an empty slot
+type cuckooSubFilter struct {
+	NumBuckets uint64
+	Data       []uint8
+}
+
+/*
+A cuckoo filter tells if an item may have been added, like a bloom filter, but items can also be deleted.
+It stores a 1-byte fingerprint of each item in one of its two candidate buckets: i1 = hash % numBuckets and
+i2 = (i1 ^ (fingerprint * 0x5bd1e995)) % numBuckets, so that each bucket can be computed from the other one and the fingerprint.
+When both buckets are full, a random fingerprint is kicked out to its other bucket, up to MaxIterations times.
+If that fails, a new sub-filter with Expansion times more buckets is added, unless Expansion is 0.
+*/
+type CuckooFilter struct {
+	Filters       []*cuckooSubFilter
+	BucketSize    uint8
+	MaxIterations uint16
+	Expansion     uint16
+	NumItems      uint64
+	NumDeletes    uint64
+}
+
+func nextPowerOf2(n uint64) uint64 {
+	res := uint64(1)
+	for res < n {
+		res <<= 1
+	}
+
+	return res
+}
+
+func CreateCuckooFilter(capacity uint64, bucketSize uint8, maxIterations uint16, expansion uint16) *CuckooFilter {
+	cf := &CuckooFilter{
+		BucketSize:    bucketSize,
+		MaxIterations: maxIterations,
+		Expansion:     uint16(nextPowerOf2(uint64(expansion))),
+	}
+	if expansion == 0 {
+		cf.Expansion = 0
+	}
+	cf.addSubFilter(nextPowerOf2(max(capacity/uint64(bucketSize), 1)))
+
+	return cf
+}
+
+func (cf *CuckooFilter) addSubFilter(numBuckets uint64) *cuckooSubFilter {
+	f := &cuckooSubFilter{
+		NumBuckets: numBuckets,
+		Data:       make([]uint8, numBuckets*uint64(cf.BucketSize)),
+	}
+	cf.Filters = append(cf.Filters, f)
+
+	return f
+}
+
+// the hash and the fingerprint of an item, the fingerprint is never 0
+func cuckooHash(item string) (uint64, uint8) {
+	h := murmurHash64A([]byte(item), 0)
+
+	return h, uint8(h%255 + 1)
+}
+
+func cuckooAltIndex(index uint64, fp uint8) uint64 {
+	return index ^ (uint64(fp) * 0x5bd1e995)
+}
+
+// the slots of the bucket
+func (f *cuckooSubFilter) bucket(index uint64, bucketSize uint8) []uint8 {
+	i := index % f.NumBuckets * uint64(bucketSize)
+
+	return f.Data[i : i+uint64(bucketSize)]
+}
+
+// the two candidate buckets of the fingerprint, the second one is nil if they are the same
+func (f *cuckooSubFilter) buckets(h uint64, fp uint8, bucketSize uint8) ([]uint8, []uint8) {
+	i1 := h % f.NumBuckets
+	i2 := cuckooAltIndex(i1, fp) % f.NumBuckets
+	if i1 == i2 {
+		return f.bucket(i1, bucketSize), nil
+	}
+
+	return f.bucket(i1, bucketSize), f.bucket(i2, bucketSize)
+}
+
+// put the fingerprint in an empty slot of the bucket, return false if the bucket is full
+func bucketInsert(bucket []uint8, fp uint8) bool {
+	for i := range bucket {
+		if bucket[i] == 0 {
+			bucket[i] = fp
+			return true
+		}
+	}
+
+	return false
+}
+
+func bucketCount(bucket []uint8, fp uint8) int {
+	res := 0
+	for _, v := range bucket {
+		if v == fp {
+			res++
+		}
+	}
+
+	return res
+}
+
+func bucketDelete(bucket []uint8, fp uint8) bool {
+	for i := range bucket {
+		if bucket[i] == fp {
+			bucket[i] = 0
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Insert the fingerprint into the sub-filter, kicking out other fingerprints if both candidate buckets are full.
+If no place is found after MaxIterations kicks, the kicks are undone so that the sub-filter is unchanged, and false is returned.
+*/
+func (cf *CuckooFilter) insertInto(f *cuckooSubFilter, h uint64, fp uint8) bool {
+	b1, b2 := f.buckets(h, fp, cf.BucketSize)
+	if bucketInsert(b1, fp) || (b2 != nil && bucketInsert(b2, fp)) {
+		return true
+	}
+
+	type kick struct {
+		index uint64
+		slot  int
+		fp    uint8
+	}
+	kicks := make([]kick, 0, cf.MaxIterations)
+
+	index := h % f.NumBuckets
+	if rand.Intn(2) == 0 {
+		index = cuckooAltIndex(index, fp) % f.NumBuckets
+	}
+	for i := uint16(0); i < cf.MaxIterations; i++ {
+		// swap the fingerprint with a random one of the bucket, then move the victim to its other bucket
+		slot := rand.Intn(int(cf.BucketSize))
+		bucket := f.bucket(index, cf.BucketSize)
+		kicks = append(kicks, kick{index: index, slot: slot, fp: bucket[slot]})
+		fp, bucket[slot] = bucket[slot], fp
+
+		index = cuckooAltIndex(index, fp) % f.NumBuckets
+		if bucketInsert(f.bucket(index, cf.BucketSize), fp) {
+			return true
+		}
+	}
+
+	for i := len(kicks) - 1; i >= 0; i-- {
+		f.bucket(kicks[i].index, cf.BucketSize)[kicks[i].slot] = kicks[i].fp
+	}
+
+	return false
+}
+
+/*
+Add the item, even if it may already exist. An error is returned if the filter is full and can not expand:
+its expansion is 0, or the new sub-filter would be larger than the max size of a string.
+*/
+func (cf *CuckooFilter) Add(item string) error {
+	h, fp := cuckooHash(item)
+
+	last := cf.Filters[len(cf.Filters)-1]
+	if !cf.insertInto(last, h, fp) {
+		if cf.Expansion == 0 {
+			return ErrCuckooFull
+		}
+		// each bucket takes BucketSize bytes, divide the bound so that the size can not overflow
+		if last.NumBuckets > constant.StringMaxSize/uint64(cf.Expansion)/uint64(cf.BucketSize) {
+			return ErrCuckooTooLarge
+		}
+
+		last = cf.addSubFilter(last.NumBuckets * uint64(cf.Expansion))
+		if !cf.insertInto(last, h, fp) {
+			return ErrCuckooFull
+		}
+	}
+	cf.NumItems++
+
+	return nil
+}
+
+// the number of times the fingerprint of the item is found, it can be more than the real count because of collisions
+func (cf *CuckooFilter) Count(item string) int {
+	h, fp := cuckooHash(item)
+
+	res := 0
+	for _, f := range cf.Filters {
+		b1, b2 := f.buckets(h, fp, cf.BucketSize)
+		res += bucketCount(b1, fp)
+		if b2 != nil {
+			res += bucketCount(b2, fp)
+		}
+	}
+
+	return res
+}
+
+func (cf *CuckooFilter) Exists(item string) bool {
+	return cf.Count(item) > 0
+}
+
+// delete one occurrence of the fingerprint of the item, starting from the newest sub-filter, return false if it is not found
+func (cf *CuckooFilter) Delete(item string) bool {
+	h, fp := cuckooHash(item)
+
+	for i := len(cf.Filters) - 1; i >= 0; i-- {
+		b1, b2 := cf.Filters[i].buckets(h, fp, cf.BucketSize)
+		if bucketDelete(b1, fp) || (b2 != nil && bucketDelete(b2, fp)) {
+			cf.NumItems--
+			cf.NumDeletes++
+			return true
+		}
+	}
+
+	return false
+}
+
+// the memory used by the buckets, in bytes
+func (cf *CuckooFilter) Bytes() uint64 {
+	var res uint64 = 0
+	for _, f := range cf.Filters {
+		res += uint64(len(f.Data))
+	}
+
+	return res
+}
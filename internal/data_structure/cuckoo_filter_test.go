@@ -0,0 +1,115 @@
+package data_structure
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestCuckooFilterAddDelete(t *testing.T) {
+	cf := CreateCuckooFilter(1000, 2, 20, 1)
+	for i := range 500 {
+		if err := cf.Add("item" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("Add(item%d): %v", i, err)
+		}
+	}
+	// an item can be added more than once
+	cf.Add("item0")
+	if n := cf.Count("item0"); n < 2 {
+		t.Fatalf("Count(item0) = %d after 2 adds", n)
+	}
+	if cf.NumItems != 501 {
+		t.Fatalf("NumItems = %d, want 501", cf.NumItems)
+	}
+
+	for i := range 500 {
+		if !cf.Exists("item" + strconv.Itoa(i)) {
+			t.Fatalf("item%d does not exist after it was added", i)
+		}
+	}
+
+	for i := range 250 {
+		if !cf.Delete("item" + strconv.Itoa(i)) {
+			t.Fatalf("Delete(item%d) did not find the item", i)
+		}
+	}
+	if cf.NumItems != 251 || cf.NumDeletes != 250 {
+		t.Fatalf("NumItems = %d, NumDeletes = %d after the deletes", cf.NumItems, cf.NumDeletes)
+	}
+	// deleting an item does not delete the other ones
+	for i := 250; i < 500; i++ {
+		if !cf.Exists("item" + strconv.Itoa(i)) {
+			t.Fatalf("item%d does not exist after other items were deleted", i)
+		}
+	}
+	// the second occurrence of item0 is kept by the first delete
+	if !cf.Exists("item0") {
+		t.Fatalf("item0 was added twice and deleted once, it does not exist")
+	}
+}
+
+func TestCuckooFilterFull(t *testing.T) {
+	cf := CreateCuckooFilter(64, 2, 20, 0)
+	added := make([]string, 0)
+	for i := 0; ; i++ {
+		item := "item" + strconv.Itoa(i)
+		if err := cf.Add(item); err != nil {
+			if err != ErrCuckooFull {
+				t.Fatalf("Add(%s): %v, want ErrCuckooFull", item, err)
+			}
+			break
+		}
+		added = append(added, item)
+	}
+
+	if len(cf.Filters) != 1 || len(added) > 64 || cf.NumItems != uint64(len(added)) {
+		t.Fatalf("%d items added to %d sub-filters of 64 slots, NumItems = %d", len(added), len(cf.Filters), cf.NumItems)
+	}
+	// the kicks of the failed insert are undone, no item is lost
+	for _, item := range added {
+		if !cf.Exists(item) {
+			t.Fatalf("%s does not exist once the filter is full", item)
+		}
+	}
+}
+
+func TestCuckooFilterExpansion(t *testing.T) {
+	cf := CreateCuckooFilter(8, 2, 20, 3)
+	if cf.Expansion != 4 {
+		t.Fatalf("Expansion = %d, want the next power of 2", cf.Expansion)
+	}
+
+	for i := range 200 {
+		if err := cf.Add("item" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("Add(item%d): %v", i, err)
+		}
+	}
+	if len(cf.Filters) < 2 {
+		t.Fatalf("%d sub-filters for 200 items in 8 slots", len(cf.Filters))
+	}
+	for i := 1; i < len(cf.Filters); i++ {
+		if cf.Filters[i].NumBuckets != cf.Filters[i-1].NumBuckets*4 {
+			t.Fatalf("the sub-filter %d has %d buckets, the previous one %d", i, cf.Filters[i].NumBuckets, cf.Filters[i-1].NumBuckets)
+		}
+	}
+	for i := range 200 {
+		if !cf.Exists("item" + strconv.Itoa(i)) {
+			t.Fatalf("item%d does not exist after the filter expanded", i)
+		}
+	}
+}
+
+// the sub-filter after 32768 buckets of 1 byte would be larger than the max size of a string
+func TestCuckooFilterTooLargeToExpand(t *testing.T) {
+	cf := CreateCuckooFilter(1, 1, 20, 32768)
+
+	var err error
+	for i := 0; err == nil; i++ {
+		err = cf.Add("item" + strconv.Itoa(i))
+	}
+	if err != ErrCuckooTooLarge {
+		t.Fatalf("Add: %v, want ErrCuckooTooLarge", err)
+	}
+	if len(cf.Filters) != 2 || cf.Filters[1].NumBuckets != 32768 {
+		t.Fatalf("%d sub-filters, the too large one was created", len(cf.Filters))
+	}
+}
@@ -28,6 +28,14 @@ func (d *Dict) SetExpiry(key string, ttlMs int64) {
 	d.ExpiredDictStore[key] = uint64(time.Now().UnixMilli()) + uint64(ttlMs)
 }
 
+func (d *Dict) SetExpiryAt(key string, expireAtMs uint64) {
+	d.ExpiredDictStore[key] = expireAtMs
+}
+
+func (d *Dict) DeleteExpiry(key string) {
+	delete(d.ExpiredDictStore, key)
+}
+
 func (d *Dict) GetExpiry(key string) (uint64, bool) {
 	exp, isExpired := d.ExpiredDictStore[key]
 
@@ -0,0 +1,356 @@
+package data_structure
+
+import "math"
+
+/*
+A geohash interleaves the bits of the longitude and the latitude of a point: the latitude bits are on the even positions
+and the longitude bits on the odd positions. With 26 bits per coordinate, the 52-bit hash fits exactly in the mantissa
+of a float64, so it can be stored as the score of a sorted set element.
+Points close to each other share a common prefix, so the points of an area are a range of scores.
+*/
+
+const (
+	GeoStepMax  = 26 // 26 * 2 = 52 bits
+	GeoLongMin  = -180.0
+	GeoLongMax  = 180.0
+	GeoLatMin   = -85.05112878 // the limits of the Web Mercator projection
+	GeoLatMax   = 85.05112878
+	EarthRadius = 6372797.560856 // in meters, same as Redis
+
+	mercatorMax = 20037726.37
+)
+
+type GeoHashBits struct {
+	Bits uint64
+	Step uint8 // number of bits per coordinate
+}
+
+type GeoHashRange struct {
+	Min float64
+	Max float64
+}
+
+// the cell of a geohash
+type GeoHashArea struct {
+	Hash      GeoHashBits
+	Longitude GeoHashRange
+	Latitude  GeoHashRange
+}
+
+var geoLongRange = GeoHashRange{Min: GeoLongMin, Max: GeoLongMax}
+var geoLatRange = GeoHashRange{Min: GeoLatMin, Max: GeoLatMax}
+
+// spread the 32 bits of v to the even positions of a 64-bit integer
+func spreadBits(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+
+	return x
+}
+
+// the inverse of spreadBits: gather the even bits of x
+func squashBits(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+
+	return uint32(x)
+}
+
+func GeoValidCoordinates(longitude float64, latitude float64) bool {
+	return longitude >= GeoLongMin && longitude <= GeoLongMax && latitude >= GeoLatMin && latitude <= GeoLatMax
+}
+
+// encode the point with step bits per coordinate, the point must be within the ranges and the Web Mercator limits
+func GeoHashEncode(longRange GeoHashRange, latRange GeoHashRange, longitude float64, latitude float64, step uint8) (GeoHashBits, bool) {
+	if !GeoValidCoordinates(longitude, latitude) ||
+		longitude < longRange.Min || longitude > longRange.Max || latitude < latRange.Min || latitude > latRange.Max {
+		return GeoHashBits{}, false
+	}
+
+	latOffset := (latitude - latRange.Min) / (latRange.Max - latRange.Min)
+	longOffset := (longitude - longRange.Min) / (longRange.Max - longRange.Min)
+
+	// convert to fixed point based on the step
+	latOffset *= float64(uint64(1) << step)
+	longOffset *= float64(uint64(1) << step)
+
+	return GeoHashBits{
+		Bits: spreadBits(uint32(latOffset)) | spreadBits(uint32(longOffset))<<1,
+		Step: step,
+	}, true
+}
+
+// encode the point to the 52-bit geohash used as the score of the sorted set
+func GeoHashEncodeWGS84(longitude float64, latitude float64) (GeoHashBits, bool) {
+	return GeoHashEncode(geoLongRange, geoLatRange, longitude, latitude, GeoStepMax)
+}
+
+func GeoHashDecode(longRange GeoHashRange, latRange GeoHashRange, hash GeoHashBits) GeoHashArea {
+	latScale := latRange.Max - latRange.Min
+	longScale := longRange.Max - longRange.Min
+	ilato := float64(squashBits(hash.Bits))
+	ilono := float64(squashBits(hash.Bits >> 1))
+	cells := float64(uint64(1) << hash.Step)
+
+	return GeoHashArea{
+		Hash: hash,
+		Latitude: GeoHashRange{
+			Min: latRange.Min + (ilato/cells)*latScale,
+			Max: latRange.Min + ((ilato+1)/cells)*latScale,
+		},
+		Longitude: GeoHashRange{
+			Min: longRange.Min + (ilono/cells)*longScale,
+			Max: longRange.Min + ((ilono+1)/cells)*longScale,
+		},
+	}
+}
+
+// decode a 52-bit geohash to the center of its cell
+func GeoHashDecodeToLongLat(bits uint64) (float64, float64) {
+	area := GeoHashDecode(geoLongRange, geoLatRange, GeoHashBits{Bits: bits, Step: GeoStepMax})
+
+	longitude := (area.Longitude.Min + area.Longitude.Max) / 2
+	latitude := (area.Latitude.Min + area.Latitude.Max) / 2
+
+	return min(max(longitude, GeoLongMin), GeoLongMax), min(max(latitude, GeoLatMin), GeoLatMax)
+}
+
+/*
+Get the standard 11-character base32 geohash of a 52-bit geohash.
+The standard geohash uses a latitude range of [-90, 90], so the point is decoded and encoded again with this range.
+*/
+func GeoHashString(bits uint64) string {
+	const alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+	longitude, latitude := GeoHashDecodeToLongLat(bits)
+	hash, _ := GeoHashEncode(GeoHashRange{Min: -180, Max: 180}, GeoHashRange{Min: -90, Max: 90}, longitude, latitude, GeoStepMax)
+
+	buf := make([]byte, 11)
+	for i := range buf {
+		// the 52 bits only fill 10 characters and 2 bits, the last character is 0 like in Redis
+		idx := 0
+		if i < 10 {
+			idx = int(hash.Bits>>(52-(i+1)*5)) & 0x1f
+		}
+		buf[i] = alphabet[idx]
+	}
+
+	return string(buf)
+}
+
+// the score range [min, max) of the points inside the cell of the geohash
+func (h GeoHashBits) ScoreRange() (uint64, uint64) {
+	shift := 2 * (GeoStepMax - uint(h.Step))
+
+	return h.Bits << shift, (h.Bits + 1) << shift
+}
+
+func (h GeoHashBits) isZero() bool {
+	return h.Bits == 0 && h.Step == 0
+}
+
+// move the cell by one in the longitude direction (east if d > 0, west otherwise)
+func (h GeoHashBits) moveX(d int) GeoHashBits {
+	x := h.Bits & 0xaaaaaaaaaaaaaaaa
+	y := h.Bits & 0x5555555555555555
+	zz := uint64(0x5555555555555555) >> (64 - 2*uint(h.Step))
+
+	if d > 0 {
+		x = x + (zz + 1)
+	} else {
+		x = x | zz
+		x = x - (zz + 1)
+	}
+	x &= uint64(0xaaaaaaaaaaaaaaaa) >> (64 - 2*uint(h.Step))
+
+	return GeoHashBits{Bits: x | y, Step: h.Step}
+}
+
+// move the cell by one in the latitude direction (north if d > 0, south otherwise)
+func (h GeoHashBits) moveY(d int) GeoHashBits {
+	x := h.Bits & 0xaaaaaaaaaaaaaaaa
+	y := h.Bits & 0x5555555555555555
+	zz := uint64(0xaaaaaaaaaaaaaaaa) >> (64 - 2*uint(h.Step))
+
+	if d > 0 {
+		y = y + (zz + 1)
+	} else {
+		y = y | zz
+		y = y - (zz + 1)
+	}
+	y &= uint64(0x5555555555555555) >> (64 - 2*uint(h.Step))
+
+	return GeoHashBits{Bits: x | y, Step: h.Step}
+}
+
+func degToRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radToDeg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+func geoLatDistance(lat1 float64, lat2 float64) float64 {
+	return EarthRadius * math.Abs(degToRad(lat2)-degToRad(lat1))
+}
+
+// the distance in meters between two points with the haversine formula
+func GeoDistance(long1 float64, lat1 float64, long2 float64, lat2 float64) float64 {
+	v := math.Sin((degToRad(long2) - degToRad(long1)) / 2)
+	// the points are on the same meridian
+	if v == 0 {
+		return geoLatDistance(lat1, lat2)
+	}
+
+	lat1r, lat2r := degToRad(lat1), degToRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := u*u + math.Cos(lat1r)*math.Cos(lat2r)*v*v
+
+	return 2 * EarthRadius * math.Asin(math.Sqrt(a))
+}
+
+// the area of a search: a circle of Radius meters, or a box of Width x Height meters if IsBox is true
+type GeoShape struct {
+	Longitude float64
+	Latitude  float64
+	IsBox     bool
+	Radius    float64
+	Width     float64
+	Height    float64
+}
+
+// check if the point is inside the shape, return its distance in meters to the center of the shape
+func (s *GeoShape) Contains(longitude float64, latitude float64) (float64, bool) {
+	if !s.IsBox {
+		distance := GeoDistance(s.Longitude, s.Latitude, longitude, latitude)
+		return distance, distance <= s.Radius
+	}
+
+	// the latitude distance is cheaper to compute, so it is checked first
+	if geoLatDistance(latitude, s.Latitude) > s.Height/2 {
+		return 0, false
+	}
+	if GeoDistance(longitude, latitude, s.Longitude, latitude) > s.Width/2 {
+		return 0, false
+	}
+
+	return GeoDistance(s.Longitude, s.Latitude, longitude, latitude), true
+}
+
+// the bounding box of the shape: min longitude, min latitude, max longitude, max latitude
+func (s *GeoShape) boundingBox() (float64, float64, float64, float64) {
+	height, width := s.Radius, s.Radius
+	if s.IsBox {
+		height, width = s.Height/2, s.Width/2
+	}
+
+	latDelta := radToDeg(height / EarthRadius)
+	longDeltaTop := radToDeg(width / EarthRadius / math.Cos(degToRad(s.Latitude+latDelta)))
+	longDeltaBottom := radToDeg(width / EarthRadius / math.Cos(degToRad(s.Latitude-latDelta)))
+
+	// the directions of the northern and southern hemispheres are opposite
+	longDelta := longDeltaTop
+	if s.Latitude < 0 {
+		longDelta = longDeltaBottom
+	}
+
+	return s.Longitude - longDelta, s.Latitude - latDelta, s.Longitude + longDelta, s.Latitude + latDelta
+}
+
+// the number of bits per coordinate so that a cell is about as large as the radius
+func geoEstimateStepsByRadius(radius float64, latitude float64) uint8 {
+	if radius == 0 {
+		return GeoStepMax
+	}
+
+	step := 1
+	for radius < mercatorMax {
+		radius *= 2
+		step++
+	}
+	// make sure the range is included in most of the base cases
+	step -= 2
+
+	// the cells are narrower towards the poles
+	if latitude > 66 || latitude < -66 {
+		step--
+		if latitude > 80 || latitude < -80 {
+			step--
+		}
+	}
+
+	return uint8(min(max(step, 1), GeoStepMax))
+}
+
+/*
+Get the cells to scan for a search: the cell of the center of the shape and its 8 neighbors.
+The step is estimated from the size of the shape so that the 9 cells cover it, and the neighbors
+that do not intersect the bounding box of the shape are skipped.
+*/
+func GeoHashAreasOfShape(s *GeoShape) []GeoHashBits {
+	minLong, minLat, maxLong, maxLat := s.boundingBox()
+
+	radius := s.Radius
+	if s.IsBox {
+		// the distance from the center to a corner
+		radius = math.Sqrt((s.Width/2)*(s.Width/2) + (s.Height/2)*(s.Height/2))
+	}
+	step := geoEstimateStepsByRadius(radius, s.Latitude)
+
+	hash, _ := GeoHashEncode(geoLongRange, geoLatRange, s.Longitude, s.Latitude, step)
+	north, south, east, west := hash.moveY(1), hash.moveY(-1), hash.moveX(1), hash.moveX(-1)
+
+	// the estimated step may not be small enough when the shape is near the edge of the cell
+	if step > 1 &&
+		(GeoHashDecode(geoLongRange, geoLatRange, north).Latitude.Max < maxLat ||
+			GeoHashDecode(geoLongRange, geoLatRange, south).Latitude.Min > minLat ||
+			GeoHashDecode(geoLongRange, geoLatRange, east).Longitude.Max < maxLong ||
+			GeoHashDecode(geoLongRange, geoLatRange, west).Longitude.Min > minLong) {
+		step--
+		hash, _ = GeoHashEncode(geoLongRange, geoLatRange, s.Longitude, s.Latitude, step)
+		north, south, east, west = hash.moveY(1), hash.moveY(-1), hash.moveX(1), hash.moveX(-1)
+	}
+
+	// center, north, south, east, west, north-east, north-west, south-east, south-west
+	areas := []GeoHashBits{hash, north, south, east, west, east.moveY(1), west.moveY(1), east.moveY(-1), west.moveY(-1)}
+
+	// exclude the cells that are useless
+	if step >= 2 {
+		area := GeoHashDecode(geoLongRange, geoLatRange, hash)
+		if area.Latitude.Min < minLat {
+			areas[2], areas[7], areas[8] = GeoHashBits{}, GeoHashBits{}, GeoHashBits{}
+		}
+		if area.Latitude.Max > maxLat {
+			areas[1], areas[5], areas[6] = GeoHashBits{}, GeoHashBits{}, GeoHashBits{}
+		}
+		if area.Longitude.Min < minLong {
+			areas[4], areas[6], areas[8] = GeoHashBits{}, GeoHashBits{}, GeoHashBits{}
+		}
+		if area.Longitude.Max > maxLong {
+			areas[3], areas[5], areas[7] = GeoHashBits{}, GeoHashBits{}, GeoHashBits{}
+		}
+	}
+
+	res := make([]GeoHashBits, 0, len(areas))
+	for _, a := range areas {
+		if a.isZero() {
+			continue
+		}
+		// with a huge radius, adjacent neighbors can be the same cell
+		if len(res) > 0 && res[len(res)-1] == a {
+			continue
+		}
+		res = append(res, a)
+	}
+
+	return res
+}
@@ -0,0 +1,105 @@
+package data_structure
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestGeoHashBits(t *testing.T) {
+	for _, v := range []uint32{0, 1, 0x3ffffff, 0xdeadbeef, math.MaxUint32} {
+		spread := spreadBits(v)
+		if spread&0xaaaaaaaaaaaaaaaa != 0 || squashBits(spread) != v {
+			t.Fatalf("spreadBits(%#x) = %#x, squashed back to %#x", v, spread, squashBits(spread))
+		}
+	}
+}
+
+// the scores and the geohash strings are the ones of Redis
+func TestGeoHashEncodeWGS84(t *testing.T) {
+	for _, tt := range []struct {
+		longitude float64
+		latitude  float64
+		score     uint64
+		hash      string
+	}{
+		{13.361389, 38.115556, 3479099956230698, "sqc8b49rny0"},
+		{15.087269, 37.502669, 3479447370796909, "sqdtr74hyu0"},
+	} {
+		hash, ok := GeoHashEncodeWGS84(tt.longitude, tt.latitude)
+		if !ok || hash.Bits != tt.score {
+			t.Fatalf("GeoHashEncodeWGS84(%v, %v) = %d, %v, want %d", tt.longitude, tt.latitude, hash.Bits, ok, tt.score)
+		}
+		if s := GeoHashString(hash.Bits); s != tt.hash {
+			t.Fatalf("GeoHashString(%d) = %s, want %s", hash.Bits, s, tt.hash)
+		}
+
+		// the center of the cell is within a few centimeters of the point
+		longitude, latitude := GeoHashDecodeToLongLat(hash.Bits)
+		if d := GeoDistance(tt.longitude, tt.latitude, longitude, latitude); d > 1 {
+			t.Fatalf("the decoded point is %vm away from (%v, %v)", d, tt.longitude, tt.latitude)
+		}
+	}
+
+	for _, p := range [][2]float64{{180.1, 0}, {0, 85.06}, {0, -85.06}} {
+		if _, ok := GeoHashEncodeWGS84(p[0], p[1]); ok {
+			t.Fatalf("GeoHashEncodeWGS84(%v, %v) accepted invalid coordinates", p[0], p[1])
+		}
+	}
+}
+
+func TestGeoDistance(t *testing.T) {
+	// like GEODIST, the distance between the stored points of Palermo and Catania
+	long1, lat1 := GeoHashDecodeToLongLat(3479099956230698)
+	long2, lat2 := GeoHashDecodeToLongLat(3479447370796909)
+	if d := GeoDistance(long1, lat1, long2, lat2); math.Abs(d-166274.1516) > 0.0001 {
+		t.Fatalf("GeoDistance(Palermo, Catania) = %v, want 166274.1516", d)
+	}
+	// on the same meridian
+	if d := GeoDistance(10, 0, 10, 1); math.Abs(d-EarthRadius*math.Pi/180) > 1e-6 {
+		t.Fatalf("GeoDistance of 1 degree of latitude = %v", d)
+	}
+}
+
+// every point inside a shape must be in one of the cells to scan
+func TestGeoHashAreasOfShape(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 200 {
+		shape := &GeoShape{
+			Longitude: rng.Float64()*340 - 170,
+			Latitude:  rng.Float64()*140 - 70,
+			Radius:    math.Pow(10, 2+rng.Float64()*4),
+		}
+		if rng.Intn(2) == 0 {
+			shape.IsBox = true
+			shape.Width = math.Pow(10, 2+rng.Float64()*4)
+			shape.Height = math.Pow(10, 2+rng.Float64()*4)
+		}
+		areas := GeoHashAreasOfShape(shape)
+
+		// random points around the shape, in degrees of about its size
+		spread := radToDeg(max(shape.Radius, shape.Width, shape.Height) / EarthRadius)
+		for range 200 {
+			longitude := shape.Longitude + (rng.Float64()*2-1)*spread/math.Cos(degToRad(shape.Latitude))
+			latitude := shape.Latitude + (rng.Float64()*2-1)*spread
+			hash, ok := GeoHashEncodeWGS84(longitude, latitude)
+			if !ok {
+				continue
+			}
+			if _, inside := shape.Contains(GeoHashDecodeToLongLat(hash.Bits)); !inside {
+				continue
+			}
+
+			found := false
+			for _, area := range areas {
+				if min, max := area.ScoreRange(); hash.Bits >= min && hash.Bits < max {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Fatalf("the point (%v, %v) inside %+v is in none of the cells to scan", longitude, latitude, *shape)
+			}
+		}
+	}
+}
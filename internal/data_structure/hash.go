@@ -0,0 +1,271 @@
+package data_structure
+
+import (
+	"math"
+	"mtredis/internal/constant"
+	"time"
+)
+
+/*
+A hash starts with the compact listpack encoding (field1, value1, field2, value2, ...)
+and is converted to a hash table once it holds too many fields or a too long field or value.
+The conversion is one way, a hash never goes back to the listpack encoding.
+Fields can have their own expiration time, expired fields are removed by DeleteExpiredFields.
+*/
+type Hash struct {
+	ListPack   *ListPack         // listpack encoding, nil once the hash is converted
+	Dict       map[string]string // hash table encoding
+	Buckets    *HashBuckets      // the fields of the hash table encoding, for HSCAN
+	Expires    map[string]int64  // field => expiration time (unix time in milliseconds), only for fields with a TTL
+	NextExpire int64             // lower bound of the expiration times, there is nothing to delete before this time
+}
+
+func CreateHash() *Hash {
+	return &Hash{
+		ListPack: CreateListPack(),
+	}
+}
+
+func (h *Hash) Encoding() string {
+	if h.ListPack != nil {
+		if len(h.Expires) > 0 {
+			return "listpackex"
+		}
+		return "listpack"
+	}
+
+	return "hashtable"
+}
+
+func (h *Hash) convertToDict() {
+	values := h.ListPack.Values()
+	h.Dict = make(map[string]string, len(values)/2)
+	h.Buckets = CreateHashBuckets()
+	for i := 0; i < len(values); i += 2 {
+		h.Dict[values[i]] = values[i+1]
+		h.Buckets.Add(values[i])
+	}
+
+	h.ListPack = nil
+}
+
+// find the index of the field in the listpack, -1 if the field does not exist
+func (h *Hash) listPackIndex(field string) int {
+	res := -1
+	h.ListPack.Iterate(func(index int, entry []byte) bool {
+		// only the even entries are fields
+		if index%2 == 0 && string(entry) == field {
+			res = index
+			return false
+		}
+
+		return true
+	})
+
+	return res
+}
+
+func (h *Hash) Get(field string) (string, bool) {
+	if h.ListPack == nil {
+		value, exist := h.Dict[field]
+		return value, exist
+	}
+
+	idx := h.listPackIndex(field)
+	if idx < 0 {
+		return "", false
+	}
+
+	return h.ListPack.Values()[idx+1], true
+}
+
+func (h *Hash) Exists(field string) bool {
+	_, exist := h.Get(field)
+
+	return exist
+}
+
+// set the value of the field and discard its TTL, return true if the field is new
+func (h *Hash) Set(field string, value string) bool {
+	delete(h.Expires, field)
+
+	return h.SetKeepTTL(field, value)
+}
+
+// set the value of the field without touching its TTL, return true if the field is new
+func (h *Hash) SetKeepTTL(field string, value string) bool {
+	if h.ListPack != nil && (len(field) > constant.HashMaxListPackValue || len(value) > constant.HashMaxListPackValue) {
+		h.convertToDict()
+	}
+
+	if h.ListPack == nil {
+		_, exist := h.Dict[field]
+		h.Dict[field] = value
+		if !exist {
+			h.Buckets.Add(field)
+		}
+		return !exist
+	}
+
+	idx := h.listPackIndex(field)
+	if idx >= 0 {
+		values := h.ListPack.Values()
+		values[idx+1] = value
+		h.ListPack.SetValues(values)
+		return false
+	}
+
+	if h.ListPack.Count/2+1 > constant.HashMaxListPackEntries {
+		h.convertToDict()
+		h.Dict[field] = value
+		h.Buckets.Add(field)
+		return true
+	}
+
+	h.ListPack.Append(field, value)
+
+	return true
+}
+
+// remove the field, return true if it existed
+func (h *Hash) Delete(field string) bool {
+	delete(h.Expires, field)
+
+	if h.ListPack == nil {
+		_, exist := h.Dict[field]
+		if exist {
+			delete(h.Dict, field)
+			h.Buckets.Remove(field)
+		}
+		return exist
+	}
+
+	idx := h.listPackIndex(field)
+	if idx < 0 {
+		return false
+	}
+
+	values := h.ListPack.Values()
+	h.ListPack.SetValues(append(values[:idx], values[idx+2:]...))
+
+	return true
+}
+
+func (h *Hash) Len() int {
+	if h.ListPack == nil {
+		return len(h.Dict)
+	}
+
+	return h.ListPack.Count / 2
+}
+
+// get all the fields and values as a flat list: field1, value1, field2, value2, ...
+func (h *Hash) GetAll() []string {
+	if h.ListPack != nil {
+		return h.ListPack.Values()
+	}
+
+	res := make([]string, 0, 2*len(h.Dict))
+	for field, value := range h.Dict {
+		res = append(res, field, value)
+	}
+
+	return res
+}
+
+func (h *Hash) Fields() []string {
+	all := h.GetAll()
+	res := make([]string, 0, len(all)/2)
+	for i := 0; i < len(all); i += 2 {
+		res = append(res, all[i])
+	}
+
+	return res
+}
+
+func (h *Hash) Values() []string {
+	all := h.GetAll()
+	res := make([]string, 0, len(all)/2)
+	for i := 1; i < len(all); i += 2 {
+		res = append(res, all[i])
+	}
+
+	return res
+}
+
+/*
+Call fn on the fields and their values from the cursor on, until at least count fields are visited,
+and return the next cursor, 0 once all the fields are visited.
+The cursor of the hash table encoding is a bucket cursor (see HashBuckets.Scan). The listpack encoding is small,
+it is visited up to its end in a single call like Redis does, so that a cursor other than 0 always belongs to the hash table
+and stays valid if the hash is converted during the iteration. Its cursor is the position of the first field to visit.
+*/
+func (h *Hash) Scan(cursor uint64, count int, fn func(field string, value string)) uint64 {
+	if h.ListPack == nil {
+		return h.Buckets.Scan(cursor, count, func(field string) {
+			fn(field, h.Dict[field])
+		})
+	}
+
+	values := h.ListPack.Values()
+	for i := cursor; i < uint64(len(values)/2); i++ {
+		fn(values[2*i], values[2*i+1])
+	}
+
+	return 0
+}
+
+// set the expiration time (unix time in milliseconds) of an existing field
+func (h *Hash) SetFieldExpiry(field string, expireAtMs int64) {
+	if h.Expires == nil {
+		h.Expires = make(map[string]int64)
+		h.NextExpire = math.MaxInt64
+	}
+
+	h.Expires[field] = expireAtMs
+	h.NextExpire = min(h.NextExpire, expireAtMs)
+}
+
+// get the expiration time of the field, false if the field has no TTL
+func (h *Hash) GetFieldExpiry(field string) (int64, bool) {
+	exp, exist := h.Expires[field]
+
+	return exp, exist
+}
+
+// remove the TTL of the field, return true if the field had one
+func (h *Hash) PersistField(field string) bool {
+	_, exist := h.Expires[field]
+	delete(h.Expires, field)
+
+	return exist
+}
+
+func (h *Hash) HasExpiringFields() bool {
+	return len(h.Expires) > 0
+}
+
+/*
+Delete the fields whose expiration time is reached and return the number of deleted fields.
+It is cheap when nothing can have expired yet, thanks to NextExpire.
+*/
+func (h *Hash) DeleteExpiredFields() int {
+	now := time.Now().UnixMilli()
+	if len(h.Expires) == 0 || now < h.NextExpire {
+		return 0
+	}
+
+	deleted := 0
+	next := int64(math.MaxInt64)
+	for field, exp := range h.Expires {
+		if exp <= now {
+			h.Delete(field)
+			deleted++
+		} else {
+			next = min(next, exp)
+		}
+	}
+	h.NextExpire = next
+
+	return deleted
+}
@@ -0,0 +1,90 @@
+package data_structure
+
+import "math/bits"
+
+const hashBucketsMinSize = 4
+
+/*
+The keys of a hash table encoding grouped in buckets by their hash, it gives the SCAN family a stable iteration order.
+The number of buckets is a power of 2 that follows the number of keys: the table doubles when it is full
+and halves when it is less than 1/8 full.
+*/
+type HashBuckets struct {
+	buckets [][]string
+	count   int
+}
+
+func CreateHashBuckets() *HashBuckets {
+	return &HashBuckets{
+		buckets: make([][]string, hashBucketsMinSize),
+		count:   0,
+	}
+}
+
+func (b *HashBuckets) index(key string) uint64 {
+	return murmurHash64A([]byte(key), 0) & uint64(len(b.buckets)-1)
+}
+
+func (b *HashBuckets) resize(size int) {
+	old := b.buckets
+	b.buckets = make([][]string, size)
+	for _, bucket := range old {
+		for _, key := range bucket {
+			i := b.index(key)
+			b.buckets[i] = append(b.buckets[i], key)
+		}
+	}
+}
+
+// the key must not be in the buckets yet
+func (b *HashBuckets) Add(key string) {
+	if b.count >= len(b.buckets) {
+		b.resize(2 * len(b.buckets))
+	}
+
+	i := b.index(key)
+	b.buckets[i] = append(b.buckets[i], key)
+	b.count++
+}
+
+func (b *HashBuckets) Remove(key string) {
+	i := b.index(key)
+	bucket := b.buckets[i]
+	for j, k := range bucket {
+		if k == key {
+			bucket[j] = bucket[len(bucket)-1]
+			bucket[len(bucket)-1] = ""
+			b.buckets[i] = bucket[:len(bucket)-1]
+			b.count--
+			break
+		}
+	}
+
+	if len(b.buckets) > hashBucketsMinSize && b.count*8 < len(b.buckets) {
+		b.resize(len(b.buckets) / 2)
+	}
+}
+
+/*
+Call fn on the keys of the buckets from the cursor on, until at least count keys (or 10 * count buckets) are visited,
+and return the next cursor, 0 once all the buckets are visited.
+Like in Redis, the cursor is incremented on its reversed bits: when the table doubles, the buckets already visited
+map to buckets that are still before the cursor, and when it halves, the cursor is masked, so a key that stays
+in the table during the whole iteration is returned at least once.
+*/
+func (b *HashBuckets) Scan(cursor uint64, count int, fn func(key string)) uint64 {
+	mask := uint64(len(b.buckets) - 1)
+	visited := 0
+	for steps := 0; ; steps++ {
+		for _, key := range b.buckets[cursor&mask] {
+			fn(key)
+			visited++
+		}
+
+		cursor |= ^mask
+		cursor = bits.Reverse64(bits.Reverse64(cursor) + 1)
+		if cursor == 0 || visited >= count || steps+1 >= 10*count {
+			return cursor
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package data_structure
+
+import (
+	"strconv"
+	"testing"
+)
+
+// scan all the buckets with the given count, calling between after every call with the cursor it returned
+func scanBuckets(b *HashBuckets, count int, between func(cursor uint64)) map[string]int {
+	seen := make(map[string]int)
+	var cursor uint64 = 0
+	for {
+		cursor = b.Scan(cursor, count, func(key string) {
+			seen[key]++
+		})
+		if cursor == 0 {
+			return seen
+		}
+		between(cursor)
+	}
+}
+
+func TestHashBucketsScan(t *testing.T) {
+	b := CreateHashBuckets()
+	for i := range 1000 {
+		b.Add(strconv.Itoa(i))
+	}
+
+	calls := 0
+	seen := scanBuckets(b, 10, func(cursor uint64) { calls++ })
+	if len(seen) != 1000 {
+		t.Fatalf("the scan returned %d keys, want 1000", len(seen))
+	}
+	for key, n := range seen {
+		if n != 1 {
+			t.Fatalf("key %s returned %d times by a scan without changes", key, n)
+		}
+	}
+	// about 10 keys per call
+	if calls < 50 || calls > 200 {
+		t.Fatalf("the scan took %d calls with COUNT 10", calls)
+	}
+}
+
+func TestHashBucketsScanEmpty(t *testing.T) {
+	b := CreateHashBuckets()
+	b.Add("a")
+	b.Remove("a")
+
+	steps := 0
+	next := b.Scan(0, 1, func(key string) { t.Fatalf("the scan of empty buckets returned %q", key) })
+	for next != 0 {
+		next = b.Scan(next, 1, func(string) {})
+		steps++
+	}
+	if steps > hashBucketsMinSize {
+		t.Fatalf("the scan of empty buckets took %d calls", steps)
+	}
+}
+
+// the keys present during the whole iteration are returned, while the table grows or shrinks between the calls
+func TestHashBucketsScanWhileResizing(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		change func(b *HashBuckets, i int)
+	}{
+		{"grow", func(b *HashBuckets, i int) {
+			// stop at some point, or the table grows faster than the scan visits it
+			if i >= 40 {
+				return
+			}
+			for j := range 50 {
+				b.Add("new" + strconv.Itoa(i*50+j))
+			}
+		}},
+		{"shrink", func(b *HashBuckets, i int) {
+			for j := range 50 {
+				b.Remove("tmp" + strconv.Itoa(i*50+j))
+			}
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := CreateHashBuckets()
+			for i := range 200 {
+				b.Add("stable" + strconv.Itoa(i))
+			}
+			for i := range 2000 {
+				b.Add("tmp" + strconv.Itoa(i))
+			}
+
+			i := 0
+			seen := scanBuckets(b, 5, func(cursor uint64) {
+				tt.change(b, i)
+				i++
+			})
+			if i == 0 {
+				t.Fatalf("the scan completed in a single call")
+			}
+			for j := range 200 {
+				if seen["stable"+strconv.Itoa(j)] == 0 {
+					t.Fatalf("stable%d is not returned by the scan", j)
+				}
+			}
+		})
+	}
+}
+
+func TestHashBucketsResize(t *testing.T) {
+	b := CreateHashBuckets()
+	for i := range 100 {
+		b.Add(strconv.Itoa(i))
+	}
+	if len(b.buckets) != 128 {
+		t.Fatalf("%d buckets for 100 keys, want 128", len(b.buckets))
+	}
+
+	// the table halves while it is less than 1/8 full
+	for i := range 99 {
+		b.Remove(strconv.Itoa(i))
+	}
+	if len(b.buckets) != 8 {
+		t.Fatalf("%d buckets for 1 key, want 8", len(b.buckets))
+	}
+
+	// removing a missing key does nothing
+	b.Remove("missing")
+	seen := scanBuckets(b, 10, func(uint64) {})
+	if len(seen) != 1 || seen["99"] != 1 {
+		t.Fatalf("the scan returned %v, want only 99", seen)
+	}
+
+	b.Remove("99")
+	if len(b.buckets) != hashBucketsMinSize {
+		t.Fatalf("%d buckets without keys, want %d", len(b.buckets), hashBucketsMinSize)
+	}
+}
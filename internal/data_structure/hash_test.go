@@ -0,0 +1,153 @@
+package data_structure
+
+import (
+	"mtredis/internal/constant"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashEncodingConversion(t *testing.T) {
+	h := CreateHash()
+	for i := range constant.HashMaxListPackEntries {
+		h.Set("f"+strconv.Itoa(i), "v")
+	}
+	if h.Encoding() != "listpack" {
+		t.Fatalf("Encoding() = %s with %d fields, want listpack", h.Encoding(), h.Len())
+	}
+	h.Set("f0", "updated")
+	if h.Encoding() != "listpack" {
+		t.Fatalf("updating a field converted the hash")
+	}
+
+	h.Set("one-more", "v")
+	if h.Encoding() != "hashtable" {
+		t.Fatalf("Encoding() = %s with %d fields, want hashtable", h.Encoding(), h.Len())
+	}
+	if v, _ := h.Get("f0"); v != "updated" || h.Len() != constant.HashMaxListPackEntries+1 {
+		t.Fatalf("the fields are not kept by the conversion")
+	}
+
+	long := CreateHash()
+	long.Set("f", strings.Repeat("x", constant.HashMaxListPackValue))
+	if long.Encoding() != "listpack" {
+		t.Fatalf("a value of the max size converted the hash")
+	}
+	long.Set("f", strings.Repeat("x", constant.HashMaxListPackValue+1))
+	if long.Encoding() != "hashtable" {
+		t.Fatalf("Encoding() = %s with a long value, want hashtable", long.Encoding())
+	}
+}
+
+func TestHashOperations(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		hash func() *Hash
+	}{
+		{"listpack", CreateHash},
+		{"hashtable", func() *Hash {
+			h := CreateHash()
+			h.convertToDict()
+			return h
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.hash()
+			if !h.Set("a", "1") || !h.Set("b", "2") || h.Set("a", "3") {
+				t.Fatalf("Set does not report the new fields")
+			}
+			if v, ok := h.Get("a"); !ok || v != "3" {
+				t.Fatalf("Get(a) = %q, %v, want 3", v, ok)
+			}
+			if h.Exists("c") || !h.Exists("b") {
+				t.Fatalf("Exists is wrong")
+			}
+			if h.Delete("c") || !h.Delete("a") || h.Len() != 1 {
+				t.Fatalf("Delete is wrong, Len() = %d", h.Len())
+			}
+			if !reflect.DeepEqual(h.GetAll(), []string{"b", "2"}) {
+				t.Fatalf("GetAll() = %v, want [b 2]", h.GetAll())
+			}
+		})
+	}
+}
+
+func TestHashFieldExpiration(t *testing.T) {
+	h := CreateHash()
+	h.Set("a", "1")
+	h.Set("b", "2")
+	h.SetFieldExpiry("a", time.Now().UnixMilli()-1)
+	h.SetFieldExpiry("b", time.Now().Add(time.Hour).UnixMilli())
+	if h.Encoding() != "listpackex" {
+		t.Fatalf("Encoding() = %s with field TTLs, want listpackex", h.Encoding())
+	}
+
+	if n := h.DeleteExpiredFields(); n != 1 || h.Exists("a") || !h.Exists("b") {
+		t.Fatalf("DeleteExpiredFields() = %d, want only a deleted", n)
+	}
+	if !h.PersistField("b") || h.PersistField("b") || h.HasExpiringFields() {
+		t.Fatalf("PersistField does not remove the TTL")
+	}
+
+	// Set discards the TTL, SetKeepTTL keeps it
+	h.SetFieldExpiry("b", time.Now().Add(time.Hour).UnixMilli())
+	h.SetKeepTTL("b", "3")
+	if _, ok := h.GetFieldExpiry("b"); !ok {
+		t.Fatalf("SetKeepTTL discarded the TTL")
+	}
+	h.Set("b", "4")
+	if _, ok := h.GetFieldExpiry("b"); ok {
+		t.Fatalf("Set kept the TTL")
+	}
+}
+
+func TestHashScanListPack(t *testing.T) {
+	h := CreateHash()
+	for i := range 20 {
+		h.Set("f"+strconv.Itoa(i), "v"+strconv.Itoa(i))
+	}
+
+	// the listpack is returned whole by a single call, whatever the count is
+	got := make([]string, 0)
+	next := h.Scan(0, 1, func(field string, value string) {
+		got = append(got, field, value)
+	})
+	if next != 0 {
+		t.Fatalf("Scan of a listpack returned the cursor %d, want 0", next)
+	}
+	if !reflect.DeepEqual(got, h.GetAll()) {
+		t.Fatalf("Scan returned %v, want %v", got, h.GetAll())
+	}
+}
+
+// a cursor returned by the hash table stays valid while fields are added, since a hash is never converted back
+func TestHashScanHashTable(t *testing.T) {
+	h := CreateHash()
+	for i := range 300 {
+		h.Set("f"+strconv.Itoa(i), "v"+strconv.Itoa(i))
+	}
+	if h.Encoding() != "hashtable" {
+		t.Fatalf("Encoding() = %s, want hashtable", h.Encoding())
+	}
+
+	seen := make(map[string]string)
+	var cursor uint64 = 0
+	for calls := 0; ; calls++ {
+		cursor = h.Scan(cursor, 10, func(field string, value string) {
+			seen[field] = value
+		})
+		if cursor == 0 {
+			break
+		}
+		h.Set("new"+strconv.Itoa(calls), "v")
+	}
+
+	for i := range 300 {
+		field := "f" + strconv.Itoa(i)
+		if seen[field] != "v"+strconv.Itoa(i) {
+			t.Fatalf("%s = %q in the scan", field, seen[field])
+		}
+	}
+}
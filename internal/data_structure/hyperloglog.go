@@ -0,0 +1,361 @@
+package data_structure
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+/*
+A HyperLogLog estimates the number of distinct items with a standard error of 1.04 / sqrt(16384) = 0.81%.
+An item is hashed to 64 bits: the low 14 bits select one of the 16384 registers, and the register keeps the maximum
+position of the first set bit in the other 50 bits.
+
+The layout is the same as Redis's, so that the value can be read with GET and written back with SET:
+  - a 16-byte header: "HYLL", the encoding (0 dense, 1 sparse), 3 unused bytes, and the cached cardinality
+    (8 bytes, little endian), the most significant bit of the last byte is set when the cache is invalid
+  - dense: 16384 registers of 6 bits, register 0 in the lowest bits of the first byte
+  - sparse: a run-length encoding of the registers with 3 opcodes:
+    ZERO 00xxxxxx: xxxxxx + 1 registers set to 0 (1 to 64)
+    XZERO 01xxxxxx yyyyyyyy: xxxxxxyyyyyyyy + 1 registers set to 0 (1 to 16384)
+    VAL 1vvvvvxx: xx + 1 registers set to vvvvv + 1 (1 to 4 registers, value 1 to 32)
+
+A new HyperLogLog is sparse, it is converted to dense when a register exceeds 32 or the sparse encoding exceeds HLLSparseMaxBytes.
+*/
+
+const (
+	hllP         = 14
+	hllQ         = 64 - hllP
+	HLLRegisters = 1 << hllP
+	hllBits      = 6
+	hllMaxValue  = 1<<hllBits - 1
+	hllHeaderLen = 16
+	hllDenseLen  = hllHeaderLen + (HLLRegisters*hllBits+7)/8
+
+	hllDense  = 0
+	hllSparse = 1
+
+	hllSparseValMaxValue = 32
+	hllSparseValMaxLen   = 4
+	hllSparseZeroMaxLen  = 64
+	hllSparseXZeroMaxLen = 16384
+
+	hllAlphaInf = 0.721347520444481703680 // constant for 0.5 / ln(2)
+)
+
+// the sparse encoding is converted to dense above this size, same as Redis's default hll-sparse-max-bytes
+var HLLSparseMaxBytes = 3000
+
+var ErrHLLWrongType = errors.New("not a valid HyperLogLog string value")
+var ErrHLLCorrupted = errors.New("corrupted HLL object detected")
+
+// create an empty sparse HyperLogLog, its cached cardinality is 0
+func CreateHLL() []byte {
+	regs := make([]uint8, HLLRegisters)
+
+	return HLLFromRegisters(regs, false)
+}
+
+// check the header of a HyperLogLog
+func HLLValidate(b []byte) error {
+	if len(b) < hllHeaderLen || string(b[:4]) != "HYLL" || b[4] > hllSparse {
+		return ErrHLLWrongType
+	}
+	if b[4] == hllDense && len(b) != hllDenseLen {
+		return ErrHLLWrongType
+	}
+
+	return nil
+}
+
+func HLLIsDense(b []byte) bool {
+	return b[4] == hllDense
+}
+
+func hllInvalidateCache(b []byte) {
+	b[15] |= 0x80
+}
+
+// the register index and the value of an item
+func hllPatLen(item string) (int, uint8) {
+	h := murmurHash64A([]byte(item), 0xadc83b19)
+	index := int(h & (HLLRegisters - 1))
+
+	// the bit Q is set so that the count is at most Q + 1
+	h >>= hllP
+	h |= 1 << hllQ
+
+	return index, uint8(bits.TrailingZeros64(h) + 1)
+}
+
+func hllDenseGet(regs []byte, i int) uint8 {
+	byteIdx, fb := i*hllBits/8, uint(i*hllBits&7)
+	v := regs[byteIdx] >> fb
+	if fb > 8-hllBits {
+		v |= regs[byteIdx+1] << (8 - fb)
+	}
+
+	return v & hllMaxValue
+}
+
+func hllDenseSet(regs []byte, i int, v uint8) {
+	byteIdx, fb := i*hllBits/8, uint(i*hllBits&7)
+	regs[byteIdx] &^= hllMaxValue << fb
+	regs[byteIdx] |= v << fb
+	if fb > 8-hllBits {
+		regs[byteIdx+1] &^= hllMaxValue >> (8 - fb)
+		regs[byteIdx+1] |= v >> (8 - fb)
+	}
+}
+
+// decode the sparse opcodes into the registers
+func hllSparseDecode(data []byte, regs []uint8) error {
+	idx := 0
+	for i := 0; i < len(data); i++ {
+		op := data[i]
+		var runLen int
+		var v uint8 = 0
+		switch {
+		case op&0xc0 == 0x00:
+			runLen = int(op&0x3f) + 1
+		case op&0xc0 == 0x40:
+			if i+1 >= len(data) {
+				return ErrHLLCorrupted
+			}
+			i++
+			runLen = (int(op&0x3f)<<8 | int(data[i])) + 1
+		default:
+			runLen = int(op&0x3) + 1
+			v = (op>>2)&0x1f + 1
+		}
+
+		if idx+runLen > HLLRegisters {
+			return ErrHLLCorrupted
+		}
+		for j := 0; j < runLen; j++ {
+			regs[idx+j] = v
+		}
+		idx += runLen
+	}
+
+	if idx != HLLRegisters {
+		return ErrHLLCorrupted
+	}
+
+	return nil
+}
+
+// encode the registers as sparse opcodes, return false if a register does not fit in a VAL opcode
+func hllSparseEncode(regs []uint8) ([]byte, bool) {
+	res := make([]byte, 0, 32)
+	for i := 0; i < len(regs); {
+		v := regs[i]
+		runLen := 1
+		for i+runLen < len(regs) && regs[i+runLen] == v {
+			runLen++
+		}
+		i += runLen
+
+		if v > hllSparseValMaxValue {
+			return nil, false
+		}
+		for runLen > 0 {
+			switch {
+			case v > 0:
+				n := min(runLen, hllSparseValMaxLen)
+				res = append(res, 0x80|(v-1)<<2|uint8(n-1))
+				runLen -= n
+			case runLen > hllSparseZeroMaxLen:
+				n := min(runLen, hllSparseXZeroMaxLen)
+				res = append(res, 0x40|uint8((n-1)>>8), uint8(n-1))
+				runLen -= n
+			default:
+				res = append(res, uint8(runLen-1))
+				runLen = 0
+			}
+		}
+	}
+
+	return res, true
+}
+
+// get one register per byte, the HyperLogLog must be valid
+func HLLRegistersOf(b []byte) ([]uint8, error) {
+	regs := make([]uint8, HLLRegisters)
+	if err := HLLMergeRegisters(regs, b); err != nil {
+		return nil, err
+	}
+
+	return regs, nil
+}
+
+/*
+Set each register to the max of itself and the register of the HyperLogLog.
+A dense register can hold up to 63 while a count is at most Q + 1, a larger one means the object is corrupted.
+*/
+func HLLMergeRegisters(regs []uint8, b []byte) error {
+	if HLLIsDense(b) {
+		for i := range regs {
+			v := hllDenseGet(b[hllHeaderLen:], i)
+			if v > hllQ+1 {
+				return ErrHLLCorrupted
+			}
+			regs[i] = max(regs[i], v)
+		}
+		return nil
+	}
+
+	other := make([]uint8, HLLRegisters)
+	if err := hllSparseDecode(b[hllHeaderLen:], other); err != nil {
+		return err
+	}
+	for i := range regs {
+		regs[i] = max(regs[i], other[i])
+	}
+
+	return nil
+}
+
+// encode the registers with an invalid cached cardinality, as sparse unless dense is set or it does not fit
+func HLLFromRegisters(regs []uint8, dense bool) []byte {
+	header := []byte{'H', 'Y', 'L', 'L', hllSparse, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	if !dense {
+		if data, ok := hllSparseEncode(regs); ok && len(data)+hllHeaderLen <= HLLSparseMaxBytes {
+			res := append(header, data...)
+			if !hllAllZero(regs) {
+				hllInvalidateCache(res)
+			}
+			return res
+		}
+	}
+
+	res := make([]byte, hllDenseLen)
+	copy(res, header)
+	res[4] = hllDense
+	for i, v := range regs {
+		hllDenseSet(res[hllHeaderLen:], i, v)
+	}
+	hllInvalidateCache(res)
+
+	return res
+}
+
+func hllAllZero(regs []uint8) bool {
+	for _, v := range regs {
+		if v != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+Add the items to a valid HyperLogLog, return the new HyperLogLog (it may be reallocated, or converted to dense)
+and whether a register has changed.
+*/
+func HLLAdd(b []byte, items []string) ([]byte, bool, error) {
+	updated := false
+
+	if HLLIsDense(b) {
+		for _, item := range items {
+			index, count := hllPatLen(item)
+			if count > hllDenseGet(b[hllHeaderLen:], index) {
+				hllDenseSet(b[hllHeaderLen:], index, count)
+				updated = true
+			}
+		}
+	} else {
+		regs, err := HLLRegistersOf(b)
+		if err != nil {
+			return b, false, err
+		}
+		for _, item := range items {
+			index, count := hllPatLen(item)
+			if count > regs[index] {
+				regs[index] = count
+				updated = true
+			}
+		}
+		if updated {
+			b = HLLFromRegisters(regs, false)
+		}
+	}
+
+	if updated {
+		hllInvalidateCache(b)
+	}
+
+	return b, updated, nil
+}
+
+// the estimated cardinality of a valid HyperLogLog, the cache of the header is used if it is valid, else it is updated
+func HLLCount(b []byte) (uint64, error) {
+	if b[15]&0x80 == 0 {
+		return binary.LittleEndian.Uint64(b[8:16]), nil
+	}
+
+	regs, err := HLLRegistersOf(b)
+	if err != nil {
+		return 0, err
+	}
+	res := HLLCountRegisters(regs)
+	binary.LittleEndian.PutUint64(b[8:16], res)
+
+	return res, nil
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+
+	y, z := 1.0, x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
+
+// the cardinality estimator of Otmar Ertl, "New cardinality estimation algorithms for HyperLogLog sketches", as Redis does
+func HLLCountRegisters(regs []uint8) uint64 {
+	// a register fits in hllBits bits, larger than the max count Q + 1 if the object is corrupted
+	var histogram [hllMaxValue + 1]int
+	for _, v := range regs {
+		histogram[v]++
+	}
+
+	m := float64(HLLRegisters)
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
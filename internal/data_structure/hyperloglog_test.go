@@ -0,0 +1,209 @@
+package data_structure
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// the empty HyperLogLog of Redis: the sparse header with a valid cache of 0, and a single XZERO of 16384 registers
+func TestCreateHLL(t *testing.T) {
+	want := append([]byte("HYLL\x01"), make([]byte, 11)...)
+	want = append(want, 0x7f, 0xff)
+	if b := CreateHLL(); !bytes.Equal(b, want) {
+		t.Fatalf("CreateHLL() = %x, want %x", b, want)
+	}
+	if count, err := HLLCount(CreateHLL()); err != nil || count != 0 {
+		t.Fatalf("HLLCount of an empty HyperLogLog = %d, %v", count, err)
+	}
+}
+
+func TestHLLSparseEncoding(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		set  map[int]uint8
+		want []byte
+	}{
+		// XZERO 1000, VAL 3, XZERO 15383
+		{"xzero", map[int]uint8{1000: 3}, []byte{0x43, 0xe7, 0x88, 0x7c, 0x16}},
+		// ZERO 10, VAL 1, XZERO 16373
+		{"zero", map[int]uint8{10: 1}, []byte{0x09, 0x80, 0x7f, 0xf4}},
+		// VAL 32 on 4 registers then on 1 register, XZERO 16379
+		{"val", map[int]uint8{0: 32, 1: 32, 2: 32, 3: 32, 4: 32}, []byte{0xff, 0xfc, 0x7f, 0xfa}},
+		// ZERO 64 is the longest ZERO, a run of 65 needs XZERO
+		{"zero max", map[int]uint8{64: 2, 130: 2}, []byte{0x3f, 0x84, 0x40, 0x40, 0x84, 0x7f, 0x7c}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			regs := make([]uint8, HLLRegisters)
+			for i, v := range tt.set {
+				regs[i] = v
+			}
+
+			data, ok := hllSparseEncode(regs)
+			if !ok || !bytes.Equal(data, tt.want) {
+				t.Fatalf("hllSparseEncode = %x, %v, want %x", data, ok, tt.want)
+			}
+
+			decoded := make([]uint8, HLLRegisters)
+			if err := hllSparseDecode(data, decoded); err != nil || !reflect.DeepEqual(decoded, regs) {
+				t.Fatalf("hllSparseDecode(%x) does not give the registers back: %v", data, err)
+			}
+		})
+	}
+
+	// a register above 32 does not fit in a VAL opcode
+	regs := make([]uint8, HLLRegisters)
+	regs[5] = 33
+	if _, ok := hllSparseEncode(regs); ok {
+		t.Fatalf("hllSparseEncode encoded a register of 33")
+	}
+}
+
+func TestHLLSparseDecodeCorrupted(t *testing.T) {
+	for _, data := range [][]byte{
+		{0x7f},             // truncated XZERO
+		{0x7f, 0xfe},       // 16383 registers
+		{0x7f, 0xff, 0x80}, // 16385 registers
+	} {
+		if err := hllSparseDecode(data, make([]uint8, HLLRegisters)); err != ErrHLLCorrupted {
+			t.Fatalf("hllSparseDecode(%x): %v, want ErrHLLCorrupted", data, err)
+		}
+	}
+}
+
+// 6-bit registers in little endian order, register 0 in the lowest bits of the first byte
+func TestHLLDenseLayout(t *testing.T) {
+	regs := make([]byte, 6)
+	for i, v := range []uint8{5, 3, 63, 1, 0x2a, 0x15, 0x3f, 0x10} {
+		hllDenseSet(regs, i, v)
+	}
+	want := []byte{0xc5, 0xf0, 0x07, 0x6a, 0xf5, 0x43}
+	if !bytes.Equal(regs, want) {
+		t.Fatalf("dense registers = %x, want %x", regs, want)
+	}
+	for i, v := range []uint8{5, 3, 63, 1, 0x2a, 0x15, 0x3f, 0x10} {
+		if got := hllDenseGet(regs, i); got != v {
+			t.Fatalf("hllDenseGet(%d) = %d, want %d", i, got, v)
+		}
+	}
+
+	// setting a register does not change its neighbors
+	hllDenseSet(regs, 2, 0)
+	if hllDenseGet(regs, 1) != 3 || hllDenseGet(regs, 2) != 0 || hllDenseGet(regs, 3) != 1 {
+		t.Fatalf("hllDenseSet changed the neighbors of the register")
+	}
+}
+
+// the registers are the same after a round trip through the sparse and the dense encodings
+func TestHLLSparseDenseRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	regs := make([]uint8, HLLRegisters)
+	for range 200 {
+		regs[rng.Intn(HLLRegisters)] = uint8(rng.Intn(hllSparseValMaxValue) + 1)
+	}
+
+	sparse := HLLFromRegisters(regs, false)
+	if HLLIsDense(sparse) || HLLValidate(sparse) != nil {
+		t.Fatalf("HLLFromRegisters did not create a valid sparse HyperLogLog")
+	}
+	dense := HLLFromRegisters(regs, true)
+	if !HLLIsDense(dense) || len(dense) != hllDenseLen || HLLValidate(dense) != nil {
+		t.Fatalf("HLLFromRegisters did not create a valid dense HyperLogLog")
+	}
+	if sparse[15]&0x80 == 0 || dense[15]&0x80 == 0 {
+		t.Fatalf("the cached cardinality of a new HyperLogLog is valid")
+	}
+
+	fromSparse, err := HLLRegistersOf(sparse)
+	if err != nil || !reflect.DeepEqual(fromSparse, regs) {
+		t.Fatalf("the sparse registers differ: %v", err)
+	}
+	fromDense, err := HLLRegistersOf(dense)
+	if err != nil || !reflect.DeepEqual(fromDense, regs) {
+		t.Fatalf("the dense registers differ: %v", err)
+	}
+	if !bytes.Equal(HLLFromRegisters(fromDense, false), sparse) {
+		t.Fatalf("dense to sparse does not give the same bytes")
+	}
+
+	c1, _ := HLLCount(sparse)
+	c2, _ := HLLCount(dense)
+	if c1 != c2 {
+		t.Fatalf("HLLCount = %d sparse and %d dense", c1, c2)
+	}
+}
+
+func TestHLLSparseToDenseConversion(t *testing.T) {
+	// a register above 32
+	regs := make([]uint8, HLLRegisters)
+	regs[0] = hllSparseValMaxValue + 1
+	if !HLLIsDense(HLLFromRegisters(regs, false)) {
+		t.Fatalf("a register of 33 is kept sparse")
+	}
+
+	// the sparse encoding grows past HLLSparseMaxBytes
+	b := CreateHLL()
+	for i := 0; !HLLIsDense(b); i++ {
+		var err error
+		if b, _, err = HLLAdd(b, []string{"item" + strconv.Itoa(i)}); err != nil {
+			t.Fatalf("HLLAdd: %v", err)
+		}
+		if !HLLIsDense(b) && len(b) > HLLSparseMaxBytes {
+			t.Fatalf("a sparse HyperLogLog of %d bytes", len(b))
+		}
+	}
+}
+
+// a dense register can hold up to 63, above Q + 1 it can not come from an item
+func TestHLLDenseRegisterAboveMax(t *testing.T) {
+	regs := make([]uint8, HLLRegisters)
+	regs[7] = hllQ + 1
+	b := HLLFromRegisters(regs, true)
+	if _, err := HLLRegistersOf(b); err != nil {
+		t.Fatalf("a register of Q + 1 is rejected: %v", err)
+	}
+
+	hllDenseSet(b[hllHeaderLen:], 7, hllQ+2)
+	if _, err := HLLRegistersOf(b); err != ErrHLLCorrupted {
+		t.Fatalf("HLLRegistersOf with a register of Q + 2: %v, want ErrHLLCorrupted", err)
+	}
+	hllDenseSet(b[hllHeaderLen:], 7, hllMaxValue)
+	if _, err := HLLCount(b); err != ErrHLLCorrupted {
+		t.Fatalf("HLLCount with a register of 63: %v, want ErrHLLCorrupted", err)
+	}
+	// the estimator does not panic on such registers
+	regs[7] = hllMaxValue
+	HLLCountRegisters(regs)
+}
+
+func TestHLLCount(t *testing.T) {
+	b := CreateHLL()
+	for _, n := range []int{10, 1000, 100000} {
+		items := make([]string, 0, n)
+		for i := range n {
+			items = append(items, "item"+strconv.Itoa(i))
+		}
+		b, _, _ = HLLAdd(b, items)
+
+		count, err := HLLCount(b)
+		if err != nil {
+			t.Fatalf("HLLCount: %v", err)
+		}
+		// 5 times the standard error
+		if relErr := math.Abs(float64(count)-float64(n)) / float64(n); relErr > 0.05 {
+			t.Fatalf("HLLCount = %d for %d items", count, n)
+		}
+		if b[15]&0x80 != 0 {
+			t.Fatalf("HLLCount did not update the cache")
+		}
+
+		// adding an existing item changes nothing, the cache stays valid
+		var updated bool
+		if b, updated, _ = HLLAdd(b, items[:1]); updated || b[15]&0x80 != 0 {
+			t.Fatalf("adding an existing item updated the HyperLogLog")
+		}
+	}
+}
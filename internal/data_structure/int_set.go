@@ -0,0 +1,192 @@
+package data_structure
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+/*
+An intset is a sorted array of integers packed in a byte slice (little endian).
+All the integers use the same width (2, 4 or 8 bytes): the smallest one that can hold every member.
+Adding a member that does not fit in the current width upgrades the whole intset to a wider encoding, there is no downgrade.
+*/
+type IntSet struct {
+	Encoding int    // width in bytes of every integer: 2, 4 or 8
+	Length   int    // number of integers
+	Contents []byte // Length * Encoding bytes
+}
+
+func CreateIntSet() *IntSet {
+	return &IntSet{
+		Encoding: 2,
+		Length:   0,
+		Contents: make([]byte, 0),
+	}
+}
+
+// get the smallest width able to hold the value
+func intSetValueEncoding(v int64) int {
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return 8
+	}
+	if v < math.MinInt16 || v > math.MaxInt16 {
+		return 4
+	}
+
+	return 2
+}
+
+// parse a member that is the canonical representation of an int64 ("12", "-3", but not "012" or "+3")
+func parseCanonicalInt(s string) (int64, bool) {
+	if len(s) == 0 || len(s) > 20 {
+		return 0, false
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || strconv.FormatInt(v, 10) != s {
+		return 0, false
+	}
+
+	return v, true
+}
+
+func (is *IntSet) getEncoded(pos int, encoding int) int64 {
+	b := is.Contents[pos*encoding:]
+	switch encoding {
+	case 2:
+		return int64(int16(binary.LittleEndian.Uint16(b)))
+	case 4:
+		return int64(int32(binary.LittleEndian.Uint32(b)))
+	default:
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+}
+
+func (is *IntSet) set(pos int, v int64) {
+	b := is.Contents[pos*is.Encoding:]
+	switch is.Encoding {
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(b, uint64(v))
+	}
+}
+
+// get the integer at the given position, 0 <= pos < Length
+func (is *IntSet) Get(pos int) int64 {
+	return is.getEncoded(pos, is.Encoding)
+}
+
+// binary search, return the position of the value and true when found, otherwise the position where it should be inserted
+func (is *IntSet) search(v int64) (int, bool) {
+	low, high := 0, is.Length-1
+	for low <= high {
+		mid := int(uint(low+high) >> 1)
+		cur := is.Get(mid)
+		switch {
+		case cur < v:
+			low = mid + 1
+		case cur > v:
+			high = mid - 1
+		default:
+			return mid, true
+		}
+	}
+
+	return low, false
+}
+
+func (is *IntSet) resize(length int) {
+	size := length * is.Encoding
+	if size > cap(is.Contents) {
+		contents := make([]byte, size, size+size/2)
+		copy(contents, is.Contents)
+		is.Contents = contents
+	} else {
+		is.Contents = is.Contents[:size]
+	}
+}
+
+/*
+Re-encode every integer with a wider width, then add v.
+As v does not fit in the old width, it is either smaller or larger than all the members: it goes to the head or to the tail.
+*/
+func (is *IntSet) upgradeAndAdd(v int64) {
+	oldEncoding := is.Encoding
+	old := is.Contents
+	is.Encoding = intSetValueEncoding(v)
+	is.Contents = make([]byte, 0)
+	is.resize(is.Length + 1)
+
+	prepend := 0
+	if v < 0 {
+		prepend = 1
+	}
+
+	oldSet := IntSet{Encoding: oldEncoding, Length: is.Length, Contents: old}
+	for i := 0; i < is.Length; i++ {
+		is.set(i+prepend, oldSet.getEncoded(i, oldEncoding))
+	}
+
+	if prepend == 1 {
+		is.set(0, v)
+	} else {
+		is.set(is.Length, v)
+	}
+	is.Length++
+}
+
+// add the value, return false if it is already a member
+func (is *IntSet) Add(v int64) bool {
+	if intSetValueEncoding(v) > is.Encoding {
+		is.upgradeAndAdd(v)
+		return true
+	}
+
+	pos, found := is.search(v)
+	if found {
+		return false
+	}
+
+	is.resize(is.Length + 1)
+	copy(is.Contents[(pos+1)*is.Encoding:], is.Contents[pos*is.Encoding:is.Length*is.Encoding])
+	is.set(pos, v)
+	is.Length++
+
+	return true
+}
+
+// remove the value, return false if it is not a member
+func (is *IntSet) Remove(v int64) bool {
+	if intSetValueEncoding(v) > is.Encoding {
+		return false
+	}
+
+	pos, found := is.search(v)
+	if !found {
+		return false
+	}
+
+	copy(is.Contents[pos*is.Encoding:], is.Contents[(pos+1)*is.Encoding:])
+	is.Length--
+	is.resize(is.Length)
+
+	return true
+}
+
+func (is *IntSet) Contains(v int64) bool {
+	if intSetValueEncoding(v) > is.Encoding {
+		return false
+	}
+
+	_, found := is.search(v)
+
+	return found
+}
+
+func (is *IntSet) Len() int {
+	return is.Length
+}
@@ -0,0 +1,137 @@
+package data_structure
+
+import (
+	"math"
+	"math/rand"
+	"mtredis/internal/constant"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestIntSetLayout(t *testing.T) {
+	is := CreateIntSet()
+	is.Add(5)
+	is.Add(-2)
+	is.Add(300)
+
+	// sorted 16-bit little endian integers, like the intset of Redis
+	want := []byte{0xfe, 0xff, 0x05, 0x00, 0x2c, 0x01}
+	if is.Encoding != 2 || !slices.Equal(is.Contents, want) {
+		t.Fatalf("Encoding = %d, Contents = %x, want 2, %x", is.Encoding, is.Contents, want)
+	}
+}
+
+func TestIntSetUpgrade(t *testing.T) {
+	is := CreateIntSet()
+	is.Add(1)
+	is.Add(-1)
+
+	is.Add(math.MaxInt16 + 1)
+	if is.Encoding != 4 || len(is.Contents) != 3*4 {
+		t.Fatalf("Encoding = %d with %d bytes after adding a 32-bit value", is.Encoding, len(is.Contents))
+	}
+
+	// a negative value that does not fit goes first, a positive one goes last
+	is.Add(math.MinInt64)
+	if is.Encoding != 8 || is.Get(0) != math.MinInt64 {
+		t.Fatalf("Encoding = %d, Get(0) = %d after adding MinInt64", is.Encoding, is.Get(0))
+	}
+	is.Add(math.MaxInt64)
+
+	want := []int64{math.MinInt64, -1, 1, math.MaxInt16 + 1, math.MaxInt64}
+	for i, v := range want {
+		if is.Get(i) != v {
+			t.Fatalf("Get(%d) = %d, want %d", i, is.Get(i), v)
+		}
+	}
+
+	// there is no downgrade
+	is.Remove(math.MinInt64)
+	is.Remove(math.MaxInt64)
+	if is.Encoding != 8 || is.Len() != 3 {
+		t.Fatalf("Encoding = %d, Len() = %d after the removals", is.Encoding, is.Len())
+	}
+}
+
+// compare the intset with a sorted slice after random additions and removals
+func TestIntSetModel(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	values := []int64{0, 1, -1, math.MaxInt16, math.MinInt16, math.MaxInt32, math.MinInt32, math.MaxInt64, math.MinInt64}
+
+	is := CreateIntSet()
+	model := make([]int64, 0)
+	for range 5000 {
+		v := values[r.Intn(len(values))] + int64(r.Intn(5)) - 2
+		i, found := slices.BinarySearch(model, v)
+
+		if r.Intn(3) == 0 {
+			if got := is.Remove(v); got != found {
+				t.Fatalf("Remove(%d) = %v, want %v", v, got, found)
+			}
+			if found {
+				model = slices.Delete(model, i, i+1)
+			}
+		} else {
+			if got := is.Add(v); got == found {
+				t.Fatalf("Add(%d) = %v, want %v", v, got, !found)
+			}
+			if !found {
+				model = slices.Insert(model, i, v)
+			}
+		}
+
+		if is.Len() != len(model) || len(is.Contents) != is.Len()*is.Encoding {
+			t.Fatalf("Len() = %d with %d bytes, want %d", is.Len(), len(is.Contents), len(model))
+		}
+		for j, want := range model {
+			if is.Get(j) != want {
+				t.Fatalf("Get(%d) = %d, want %d", j, is.Get(j), want)
+			}
+		}
+		if _, want := slices.BinarySearch(model, v); is.Contains(v) != want {
+			t.Fatalf("Contains(%d) = %v, want %v", v, !want, want)
+		}
+	}
+}
+
+func TestSimpleSetConversion(t *testing.T) {
+	s := CreateSimpleSet("s")
+	for i := range constant.SetMaxIntSetEntries {
+		s.Add(strconv.Itoa(i))
+	}
+	if s.Encoding() != "intset" {
+		t.Fatalf("Encoding() = %s with %d integers, want intset", s.Encoding(), s.Len())
+	}
+	// adding an existing member does not convert the set
+	s.Add("0")
+	if s.Encoding() != "intset" {
+		t.Fatalf("re-adding a member converted the set")
+	}
+
+	s.Add(strconv.Itoa(constant.SetMaxIntSetEntries))
+	if s.Encoding() != "hashtable" || s.Len() != constant.SetMaxIntSetEntries+1 {
+		t.Fatalf("Encoding() = %s, Len() = %d, want hashtable", s.Encoding(), s.Len())
+	}
+	if s.IsMember("511") != 1 || s.IsMember("512") != 1 {
+		t.Fatalf("members are lost by the conversion")
+	}
+
+	for _, member := range []string{"a", "012", "+1", "1.5", "99999999999999999999"} {
+		s := CreateSimpleSet("s")
+		s.Add("1", member)
+		if s.Encoding() != "hashtable" {
+			t.Fatalf("Encoding() = %s after adding %q, want hashtable", s.Encoding(), member)
+		}
+		if s.IsMember(member) != 1 || s.IsMember("1") != 1 {
+			t.Fatalf("members are lost by the conversion to add %q", member)
+		}
+	}
+
+	// members that are not integers are never found in an intset
+	s = CreateSimpleSet("s")
+	s.Add("1")
+	if s.IsMember("01") != 0 || s.Remove("01") != 0 || s.Encoding() != "intset" {
+		t.Fatalf("01 is found in the intset holding 1")
+	}
+}
@@ -0,0 +1,76 @@
+package data_structure
+
+import "encoding/binary"
+
+/*
+A listpack stores a sequence of strings contiguously in a single byte slice:
+every entry is the uvarint-encoded length of the string followed by its bytes.
+It is the compact encoding of small aggregates, lookups are O(n) but the memory overhead per entry is only a few bytes.
+*/
+type ListPack struct {
+	Data  []byte
+	Count int // number of entries
+}
+
+func CreateListPack() *ListPack {
+	return &ListPack{
+		Data:  make([]byte, 0),
+		Count: 0,
+	}
+}
+
+func packEntry(buf []byte, value string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+
+	return append(buf, value...)
+}
+
+// decode the count packed entries of data
+func unpackEntries(data []byte, count int) []string {
+	res := make([]string, 0, count)
+	for pos := 0; pos < len(data); {
+		l, size := binary.Uvarint(data[pos:])
+		pos += size
+		res = append(res, string(data[pos:pos+int(l)]))
+		pos += int(l)
+	}
+
+	return res
+}
+
+func (lp *ListPack) Append(values ...string) {
+	for _, v := range values {
+		lp.Data = packEntry(lp.Data, v)
+	}
+	lp.Count += len(values)
+}
+
+func (lp *ListPack) Values() []string {
+	return unpackEntries(lp.Data, lp.Count)
+}
+
+// re-encode the whole listpack with the given values
+func (lp *ListPack) SetValues(values []string) {
+	buf := make([]byte, 0, len(lp.Data))
+	for _, v := range values {
+		buf = packEntry(buf, v)
+	}
+
+	lp.Data = buf
+	lp.Count = len(values)
+}
+
+/*
+Call fn for every entry with its index, without decoding the entries to strings.
+The entry slice must not be retained by fn. The iteration stops when fn returns false.
+*/
+func (lp *ListPack) Iterate(fn func(index int, entry []byte) bool) {
+	for pos, i := 0, 0; pos < len(lp.Data); i++ {
+		l, size := binary.Uvarint(lp.Data[pos:])
+		pos += size
+		if !fn(i, lp.Data[pos:pos+int(l)]) {
+			return
+		}
+		pos += int(l)
+	}
+}
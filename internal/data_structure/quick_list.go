@@ -0,0 +1,494 @@
+package data_structure
+
+import (
+	"encoding/binary"
+	"mtredis/internal/constant"
+)
+
+/*
+A quicklist is a doubly linked list of packed nodes.
+Each node stores its elements contiguously in a single byte slice, packed like a listpack: the length of an entry
+is stored before it and again (backward) after it, so that the node can be read from both ends.
+This saves the per-element overhead of a plain linked list (pointers + string header) while keeping pushes and pops at both ends cheap.
+*/
+type QuickListNode struct {
+	Prev  *QuickListNode
+	Next  *QuickListNode
+	Data  []byte // packed entries in Data[Start:], the space before Start lets PushHead prepend in place
+	Start int
+	Count int // number of entries in the node
+}
+
+type QuickList struct {
+	Head      *QuickListNode
+	Tail      *QuickListNode
+	Length    int // total number of elements
+	NodeCount int
+}
+
+func CreateQuickList() *QuickList {
+	return &QuickList{}
+}
+
+func uvarintSize(v uint64) int {
+	size := 1
+	for v >= 0x80 {
+		v >>= 7
+		size++
+	}
+
+	return size
+}
+
+// append the entry: the uvarint length, the bytes, then the size of both as a uvarint written backward
+func packNodeEntry(buf []byte, value string) []byte {
+	start := len(buf)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	buf = append(buf, value...)
+
+	backLen := binary.AppendUvarint(nil, uint64(len(buf)-start))
+	for i := len(backLen) - 1; i >= 0; i-- {
+		buf = append(buf, backLen[i])
+	}
+
+	return buf
+}
+
+// decode the entry at the start of data, return it with its total size
+func unpackNodeEntry(data []byte) (string, int) {
+	l, size := binary.Uvarint(data)
+	res := string(data[size : size+int(l)])
+
+	return res, size + int(l) + uvarintSize(uint64(size)+l)
+}
+
+// the total size of the last entry of data, read from its backward length
+func lastNodeEntrySize(data []byte) int {
+	var v uint64 = 0
+	k := 0
+	for shift := 0; ; shift += 7 {
+		b := data[len(data)-1-k]
+		v |= uint64(b&0x7f) << shift
+		k++
+		if b < 0x80 {
+			break
+		}
+	}
+
+	return int(v) + k
+}
+
+func (n *QuickListNode) entries() []byte {
+	return n.Data[n.Start:]
+}
+
+// decode all the entries of the node
+func (n *QuickListNode) values() []string {
+	res := make([]string, 0, n.Count)
+	for data := n.entries(); len(data) > 0; {
+		v, size := unpackNodeEntry(data)
+		res = append(res, v)
+		data = data[size:]
+	}
+
+	return res
+}
+
+// re-encode the entries of the node
+func (n *QuickListNode) setValues(values []string) {
+	buf := make([]byte, 0, len(n.entries()))
+	for _, v := range values {
+		buf = packNodeEntry(buf, v)
+	}
+
+	n.Data, n.Start = buf, 0
+	n.Count = len(values)
+}
+
+// add packed entries at the end of the node, the space freed by the pops at the head is reclaimed before growing
+func (n *QuickListNode) appendEntries(entries []byte, count int) {
+	if n.Start > 0 && len(n.Data)+len(entries) > cap(n.Data) {
+		live := n.entries()
+		buf := make([]byte, len(live), 2*(len(live)+len(entries)))
+		copy(buf, live)
+		n.Data, n.Start = buf, 0
+	}
+
+	n.Data = append(n.Data, entries...)
+	n.Count += count
+}
+
+// add packed entries at the start of the node, in the free space before Start when there is enough
+func (n *QuickListNode) prependEntries(entries []byte, count int) {
+	if n.Start < len(entries) {
+		live := n.entries()
+		headroom := len(entries) + len(live)
+		buf := make([]byte, headroom+len(live))
+		copy(buf[headroom:], live)
+		n.Data, n.Start = buf, headroom
+	}
+
+	n.Start -= len(entries)
+	copy(n.Data[n.Start:], entries)
+	n.Count += count
+}
+
+// check if an element of the given size can be added to the node without exceeding the node's size limit
+func (n *QuickListNode) canAdd(size int) bool {
+	return len(n.entries())+size+2*binary.MaxVarintLen64 <= constant.QuickListNodeMaxSize
+}
+
+func (ql *QuickList) linkAfter(prev *QuickListNode, n *QuickListNode) {
+	n.Prev = prev
+	if prev == nil {
+		n.Next = ql.Head
+		ql.Head = n
+	} else {
+		n.Next = prev.Next
+		prev.Next = n
+	}
+
+	if n.Next != nil {
+		n.Next.Prev = n
+	} else {
+		ql.Tail = n
+	}
+
+	ql.NodeCount++
+}
+
+func (ql *QuickList) unlink(n *QuickListNode) {
+	if n.Prev != nil {
+		n.Prev.Next = n.Next
+	} else {
+		ql.Head = n.Next
+	}
+
+	if n.Next != nil {
+		n.Next.Prev = n.Prev
+	} else {
+		ql.Tail = n.Prev
+	}
+
+	ql.NodeCount--
+}
+
+/*
+Update the node with its new values.
+Empty nodes are removed from the list, and nodes that grew over the size limit are split into several nodes.
+*/
+func (ql *QuickList) updateNode(n *QuickListNode, values []string) {
+	if len(values) == 0 {
+		ql.unlink(n)
+		return
+	}
+
+	n.setValues(values)
+	if len(n.entries()) <= constant.QuickListNodeMaxSize || n.Count == 1 {
+		return
+	}
+
+	// split: fill the current node, then move the remaining values to new nodes
+	node := n
+	chunk := make([]string, 0)
+	size := 0
+	for _, v := range values {
+		if len(chunk) > 0 && size+len(v)+2*binary.MaxVarintLen64 > constant.QuickListNodeMaxSize {
+			node.setValues(chunk)
+			next := &QuickListNode{}
+			ql.linkAfter(node, next)
+			node = next
+			chunk = make([]string, 0)
+			size = 0
+		}
+
+		chunk = append(chunk, v)
+		size += len(v) + 2*binary.MaxVarintLen64
+	}
+	node.setValues(chunk)
+}
+
+// merge the adjacent nodes whose entries fit in a single node, so that the deletions do not leave many small nodes
+func (ql *QuickList) mergeNodes() {
+	for n := ql.Head; n != nil && n.Next != nil; {
+		next := n.Next
+		if len(n.entries())+len(next.entries()) > constant.QuickListNodeMaxSize {
+			n = next
+			continue
+		}
+
+		n.appendEntries(next.entries(), next.Count)
+		ql.unlink(next)
+	}
+}
+
+func (ql *QuickList) PushHead(values ...string) {
+	for _, v := range values {
+		if ql.Head == nil || !ql.Head.canAdd(len(v)) {
+			ql.linkAfter(nil, &QuickListNode{})
+		}
+
+		ql.Head.prependEntries(packNodeEntry(nil, v), 1)
+		ql.Length++
+	}
+}
+
+func (ql *QuickList) PushTail(values ...string) {
+	for _, v := range values {
+		if ql.Tail == nil || !ql.Tail.canAdd(len(v)) {
+			ql.linkAfter(ql.Tail, &QuickListNode{})
+		}
+
+		ql.Tail.appendEntries(packNodeEntry(nil, v), 1)
+		ql.Length++
+	}
+}
+
+func (ql *QuickList) PopHead() (string, bool) {
+	if ql.Head == nil {
+		return "", false
+	}
+
+	n := ql.Head
+	res, size := unpackNodeEntry(n.entries())
+	n.Start += size
+	n.Count--
+	if n.Count == 0 {
+		ql.unlink(n)
+	}
+	ql.Length--
+
+	return res, true
+}
+
+func (ql *QuickList) PopTail() (string, bool) {
+	if ql.Tail == nil {
+		return "", false
+	}
+
+	n := ql.Tail
+	size := lastNodeEntrySize(n.entries())
+	res, _ := unpackNodeEntry(n.Data[len(n.Data)-size:])
+	n.Data = n.Data[:len(n.Data)-size]
+	n.Count--
+	if n.Count == 0 {
+		ql.unlink(n)
+	}
+	ql.Length--
+
+	return res, true
+}
+
+func (ql *QuickList) Len() int {
+	return ql.Length
+}
+
+// find the node holding the element at the 0-based index, and the index of the element inside this node
+func (ql *QuickList) locate(index int) (*QuickListNode, int) {
+	if index < 0 || index >= ql.Length {
+		return nil, 0
+	}
+
+	// walk from the closest end
+	if index < ql.Length/2 {
+		for n := ql.Head; n != nil; n = n.Next {
+			if index < n.Count {
+				return n, index
+			}
+			index -= n.Count
+		}
+	} else {
+		index = ql.Length - 1 - index
+		for n := ql.Tail; n != nil; n = n.Prev {
+			if index < n.Count {
+				return n, n.Count - 1 - index
+			}
+			index -= n.Count
+		}
+	}
+
+	return nil, 0
+}
+
+// get the element at the 0-based index
+func (ql *QuickList) Index(index int) (string, bool) {
+	n, i := ql.locate(index)
+	if n == nil {
+		return "", false
+	}
+
+	return n.values()[i], true
+}
+
+// replace the element at the 0-based index
+func (ql *QuickList) Set(index int, value string) bool {
+	n, i := ql.locate(index)
+	if n == nil {
+		return false
+	}
+
+	values := n.values()
+	values[i] = value
+	ql.updateNode(n, values)
+
+	return true
+}
+
+// get the elements from start to stop (inclusive), both are 0-based and must be within the list's bounds
+func (ql *QuickList) Range(start int, stop int) []string {
+	res := make([]string, 0, stop-start+1)
+	ql.Iterate(false, func(index int, value string) bool {
+		if index > stop {
+			return false
+		}
+		if index >= start {
+			res = append(res, value)
+		}
+
+		return true
+	})
+
+	return res
+}
+
+/*
+Call fn for every element with its 0-based index, from the head or from the tail of the list.
+The iteration stops when fn returns false.
+*/
+func (ql *QuickList) Iterate(fromTail bool, fn func(index int, value string) bool) {
+	if !fromTail {
+		index := 0
+		for n := ql.Head; n != nil; n = n.Next {
+			for _, v := range n.values() {
+				if !fn(index, v) {
+					return
+				}
+				index++
+			}
+		}
+		return
+	}
+
+	index := ql.Length - 1
+	for n := ql.Tail; n != nil; n = n.Prev {
+		values := n.values()
+		for i := len(values) - 1; i >= 0; i-- {
+			if !fn(index, values[i]) {
+				return
+			}
+			index--
+		}
+	}
+}
+
+// insert the value right before or after the first occurrence of pivot, return false if pivot is not found
+func (ql *QuickList) Insert(pivot string, value string, after bool) bool {
+	for n := ql.Head; n != nil; n = n.Next {
+		values := n.values()
+		for i, v := range values {
+			if v != pivot {
+				continue
+			}
+
+			if after {
+				i++
+			}
+			values = append(values[:i], append([]string{value}, values[i:]...)...)
+			ql.updateNode(n, values)
+			ql.Length++
+
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Remove the elements equal to value.
+count > 0: remove at most count elements moving from head to tail.
+count < 0: remove at most -count elements moving from tail to head.
+count = 0: remove all the elements equal to value.
+*/
+func (ql *QuickList) Remove(count int, value string) int {
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+	removed := 0
+
+	n := ql.Head
+	if count < 0 {
+		n = ql.Tail
+	}
+
+	for n != nil && (limit == 0 || removed < limit) {
+		next := n.Next
+		if count < 0 {
+			next = n.Prev
+		}
+
+		values := n.values()
+		kept := make([]string, 0, len(values))
+		if count >= 0 {
+			for _, v := range values {
+				if v == value && (limit == 0 || removed < limit) {
+					removed++
+					continue
+				}
+				kept = append(kept, v)
+			}
+		} else {
+			for i := len(values) - 1; i >= 0; i-- {
+				if values[i] == value && removed < limit {
+					removed++
+					continue
+				}
+				kept = append(kept, values[i])
+			}
+			// restore the original order
+			for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+				kept[i], kept[j] = kept[j], kept[i]
+			}
+		}
+
+		if len(kept) != len(values) {
+			ql.updateNode(n, kept)
+		}
+		n = next
+	}
+
+	ql.Length -= removed
+	if removed > 0 {
+		ql.mergeNodes()
+	}
+
+	return removed
+}
+
+// keep only the elements from start to stop (inclusive), an empty range (start > stop) removes all the elements
+func (ql *QuickList) Trim(start int, stop int) {
+	index := 0
+	for n := ql.Head; n != nil; {
+		next := n.Next
+		first, last := index, index+n.Count-1
+
+		switch {
+		case last < start || first > stop || start > stop: // the whole node is out of the range
+			ql.unlink(n)
+		case first < start || last > stop: // the node is partially out of the range
+			values := n.values()
+			ql.updateNode(n, values[max(start-first, 0):min(stop-first, n.Count-1)+1])
+		}
+
+		index += last - first + 1
+		n = next
+	}
+
+	if start > stop || start >= ql.Length {
+		ql.Length = 0
+	} else {
+		ql.Length = min(stop, ql.Length-1) - start + 1
+	}
+	ql.mergeNodes()
+}
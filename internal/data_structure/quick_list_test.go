@@ -0,0 +1,282 @@
+package data_structure
+
+import (
+	"math/rand"
+	"mtredis/internal/constant"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// check the links, the counters and the node sizes of the quicklist, and that it holds the expected elements
+func checkQuickList(t *testing.T, ql *QuickList, want []string) {
+	t.Helper()
+
+	length, nodes := 0, 0
+	var prev *QuickListNode
+	for n := ql.Head; n != nil; n = n.Next {
+		if n.Prev != prev {
+			t.Fatalf("node %d: broken Prev link", nodes)
+		}
+		if n.Count == 0 {
+			t.Fatalf("node %d: empty node left in the list", nodes)
+		}
+		if len(n.values()) != n.Count {
+			t.Fatalf("node %d: Count = %d, holds %d entries", nodes, n.Count, len(n.values()))
+		}
+		if n.Count > 1 && len(n.entries()) > constant.QuickListNodeMaxSize {
+			t.Fatalf("node %d: %d bytes of entries, more than the max node size", nodes, len(n.entries()))
+		}
+
+		length += n.Count
+		nodes++
+		prev = n
+	}
+	if ql.Tail != prev {
+		t.Fatalf("Tail is not the last node")
+	}
+	if length != ql.Length || length != ql.Len() {
+		t.Fatalf("Length = %d, the nodes hold %d elements", ql.Length, length)
+	}
+	if nodes != ql.NodeCount {
+		t.Fatalf("NodeCount = %d, the list has %d nodes", ql.NodeCount, nodes)
+	}
+
+	got := make([]string, 0, length)
+	ql.Iterate(false, func(index int, value string) bool {
+		if index != len(got) {
+			t.Fatalf("Iterate: index %d for element %d", index, len(got))
+		}
+		got = append(got, value)
+		return true
+	})
+	if len(want) == 0 && len(got) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("elements = %v, want %v", got, want)
+	}
+
+	reversed := make([]string, 0, length)
+	ql.Iterate(true, func(index int, value string) bool {
+		if index != length-1-len(reversed) {
+			t.Fatalf("Iterate from tail: index %d for element %d", index, len(reversed))
+		}
+		reversed = append(reversed, value)
+		return true
+	})
+	for i, v := range reversed {
+		if v != want[length-1-i] {
+			t.Fatalf("Iterate from tail: element %d = %q, want %q", i, v, want[length-1-i])
+		}
+	}
+}
+
+func TestQuickListPushPop(t *testing.T) {
+	ql := CreateQuickList()
+	if _, ok := ql.PopHead(); ok {
+		t.Fatalf("PopHead on an empty list succeeded")
+	}
+	if _, ok := ql.PopTail(); ok {
+		t.Fatalf("PopTail on an empty list succeeded")
+	}
+
+	ql.PushTail("b", "c")
+	ql.PushHead("a")
+	// "z", "y" are pushed one after the other, like LPUSH does
+	ql.PushHead("y", "z")
+	checkQuickList(t, ql, []string{"z", "y", "a", "b", "c"})
+
+	if v, ok := ql.PopHead(); !ok || v != "z" {
+		t.Fatalf("PopHead = %q, %v, want z", v, ok)
+	}
+	if v, ok := ql.PopTail(); !ok || v != "c" {
+		t.Fatalf("PopTail = %q, %v, want c", v, ok)
+	}
+	checkQuickList(t, ql, []string{"y", "a", "b"})
+
+	for range 3 {
+		ql.PopTail()
+	}
+	checkQuickList(t, ql, nil)
+	if ql.Head != nil || ql.Tail != nil {
+		t.Fatalf("the nodes of an empty list are not released")
+	}
+}
+
+func TestQuickListLargeElements(t *testing.T) {
+	ql := CreateQuickList()
+	big := strings.Repeat("x", constant.QuickListNodeMaxSize+1)
+	want := []string{"a", big, "b", big}
+	ql.PushTail(want...)
+	checkQuickList(t, ql, want)
+
+	// an element larger than a node gets a node of its own
+	if ql.NodeCount != 4 {
+		t.Fatalf("NodeCount = %d, want 4", ql.NodeCount)
+	}
+	if v, _ := ql.Index(1); v != big {
+		t.Fatalf("Index(1) is not the large element")
+	}
+}
+
+func TestQuickListNodesAreMerged(t *testing.T) {
+	ql := CreateQuickList()
+	want := make([]string, 0)
+	for i := range 5000 {
+		v := strconv.Itoa(i % 10)
+		ql.PushTail(v)
+		want = append(want, v)
+	}
+	checkQuickList(t, ql, want)
+	if ql.NodeCount < 2 {
+		t.Fatalf("NodeCount = %d, the test needs several nodes", ql.NodeCount)
+	}
+
+	// removing 9 elements out of 10 leaves nodes small enough to fit in a single one
+	kept := make([]string, 0)
+	for _, v := range want {
+		if v == "0" {
+			kept = append(kept, v)
+		}
+	}
+	for i := 1; i < 10; i++ {
+		ql.Remove(0, strconv.Itoa(i))
+	}
+	checkQuickList(t, ql, kept)
+	if ql.NodeCount != 1 {
+		t.Fatalf("NodeCount = %d after the removal, want the nodes merged into 1", ql.NodeCount)
+	}
+}
+
+// apply random operations to a quicklist and to a plain slice, and compare them after every operation
+func TestQuickListModel(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		ql := CreateQuickList()
+		model := make([]string, 0)
+
+		randValue := func() string {
+			// mostly small values, sometimes values large enough to fill a node quickly
+			if r.Intn(20) == 0 {
+				return strings.Repeat(strconv.Itoa(r.Intn(10)), r.Intn(3*constant.QuickListNodeMaxSize/2))
+			}
+			return strconv.Itoa(r.Intn(30))
+		}
+
+		for op := 0; op < 3000; op++ {
+			switch r.Intn(9) {
+			case 0:
+				v := randValue()
+				ql.PushHead(v)
+				model = append([]string{v}, model...)
+			case 1:
+				v := randValue()
+				ql.PushTail(v)
+				model = append(model, v)
+			case 2:
+				v, ok := ql.PopHead()
+				if ok != (len(model) > 0) || (ok && v != model[0]) {
+					t.Fatalf("seed %d op %d: PopHead = %q, %v", seed, op, v, ok)
+				}
+				if ok {
+					model = model[1:]
+				}
+			case 3:
+				v, ok := ql.PopTail()
+				if ok != (len(model) > 0) || (ok && v != model[len(model)-1]) {
+					t.Fatalf("seed %d op %d: PopTail = %q, %v", seed, op, v, ok)
+				}
+				if ok {
+					model = model[:len(model)-1]
+				}
+			case 4:
+				if len(model) == 0 {
+					continue
+				}
+				i, v := r.Intn(len(model)), randValue()
+				if got, _ := ql.Index(i); got != model[i] {
+					t.Fatalf("seed %d op %d: Index(%d) = %q, want %q", seed, op, i, got, model[i])
+				}
+				ql.Set(i, v)
+				model[i] = v
+			case 5:
+				pivot, v, after := strconv.Itoa(r.Intn(30)), randValue(), r.Intn(2) == 0
+				i := indexOf(model, pivot)
+				if ok := ql.Insert(pivot, v, after); ok != (i >= 0) {
+					t.Fatalf("seed %d op %d: Insert(%q) = %v", seed, op, pivot, ok)
+				}
+				if i >= 0 {
+					if after {
+						i++
+					}
+					model = append(model[:i], append([]string{v}, model[i:]...)...)
+				}
+			case 6:
+				count, v := r.Intn(7)-3, strconv.Itoa(r.Intn(30))
+				var want int
+				model, want = removeFromModel(model, count, v)
+				if got := ql.Remove(count, v); got != want {
+					t.Fatalf("seed %d op %d: Remove(%d, %q) = %d, want %d", seed, op, count, v, got, want)
+				}
+			case 7:
+				if r.Intn(10) != 0 || len(model) == 0 {
+					continue
+				}
+				start := r.Intn(len(model))
+				stop := start + r.Intn(len(model)-start)
+				ql.Trim(start, stop)
+				model = append([]string{}, model[start:stop+1]...)
+			case 8:
+				if len(model) == 0 {
+					continue
+				}
+				start := r.Intn(len(model))
+				stop := start + r.Intn(len(model)-start)
+				if got := ql.Range(start, stop); !reflect.DeepEqual(got, model[start:stop+1]) {
+					t.Fatalf("seed %d op %d: Range(%d, %d) = %v", seed, op, start, stop, got)
+				}
+			}
+
+			checkQuickList(t, ql, model)
+		}
+	}
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// remove the elements like LREM does, return the remaining elements and the number of removed ones
+func removeFromModel(values []string, count int, value string) ([]string, int) {
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+
+	removed := make(map[int]bool)
+	for k := 0; k < len(values); k++ {
+		i := k
+		if count < 0 {
+			i = len(values) - 1 - k
+		}
+		if values[i] == value && (limit == 0 || len(removed) < limit) {
+			removed[i] = true
+		}
+	}
+
+	kept := make([]string, 0, len(values))
+	for i, v := range values {
+		if !removed[i] {
+			kept = append(kept, v)
+		}
+	}
+
+	return kept, len(removed)
+}
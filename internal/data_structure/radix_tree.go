@@ -0,0 +1,275 @@
+package data_structure
+
+import "bytes"
+
+/*
+A radix tree (compressed trie) maps byte strings to values, and keeps them ordered like bytes.Compare.
+Every node holds the part of the key (prefix) that follows its parent, so a chain of nodes having a single child
+is stored as one node. The children of a node start with distinct bytes and are sorted by their first byte.
+*/
+type RadixTree struct {
+	root *radixNode
+	size int
+}
+
+type radixNode struct {
+	prefix   []byte
+	children []*radixNode
+	isKey    bool
+	value    interface{}
+}
+
+func CreateRadixTree() *RadixTree {
+	return &RadixTree{
+		root: &radixNode{},
+		size: 0,
+	}
+}
+
+// the number of keys
+func (t *RadixTree) Len() int {
+	return t.size
+}
+
+// the number of nodes, the root included
+func (t *RadixTree) NodeCount() int {
+	var count func(n *radixNode) int
+	count = func(n *radixNode) int {
+		res := 1
+		for _, child := range n.children {
+			res += count(child)
+		}
+		return res
+	}
+
+	return count(t.root)
+}
+
+func commonPrefixLen(a []byte, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+
+	return i
+}
+
+// the index of the child starting with the byte c, or the index where it should be inserted and false
+func (n *radixNode) childIndex(c byte) (int, bool) {
+	for i, child := range n.children {
+		if child.prefix[0] == c {
+			return i, true
+		}
+		if child.prefix[0] > c {
+			return i, false
+		}
+	}
+
+	return len(n.children), false
+}
+
+// set the value of the key, return true if the key is new
+func (t *RadixTree) Insert(key []byte, value interface{}) bool {
+	n := t.root
+	for {
+		// split the node if the key leaves its prefix before its end
+		common := commonPrefixLen(n.prefix, key)
+		if common < len(n.prefix) {
+			child := &radixNode{
+				prefix:   n.prefix[common:],
+				children: n.children,
+				isKey:    n.isKey,
+				value:    n.value,
+			}
+			n.prefix = n.prefix[:common]
+			n.children = []*radixNode{child}
+			n.isKey, n.value = false, nil
+		}
+		key = key[common:]
+
+		if len(key) == 0 {
+			isNew := !n.isKey
+			n.isKey, n.value = true, value
+			if isNew {
+				t.size++
+			}
+			return isNew
+		}
+
+		i, found := n.childIndex(key[0])
+		if !found {
+			leaf := &radixNode{
+				prefix: append([]byte(nil), key...),
+				isKey:  true,
+				value:  value,
+			}
+			n.children = append(n.children, nil)
+			copy(n.children[i+1:], n.children[i:])
+			n.children[i] = leaf
+			t.size++
+			return true
+		}
+		n = n.children[i]
+	}
+}
+
+func (t *RadixTree) Find(key []byte) (interface{}, bool) {
+	n := t.root
+	for {
+		if !bytes.HasPrefix(key, n.prefix) {
+			return nil, false
+		}
+		key = key[len(n.prefix):]
+		if len(key) == 0 {
+			return n.value, n.isKey
+		}
+
+		i, found := n.childIndex(key[0])
+		if !found {
+			return nil, false
+		}
+		n = n.children[i]
+	}
+}
+
+// remove the key, return false if it does not exist
+func (t *RadixTree) Remove(key []byte) bool {
+	if !t.root.remove(key) {
+		return false
+	}
+	t.size--
+
+	return true
+}
+
+/*
+Remove the key from the subtree of the node, key is relative to the node's prefix.
+A child left without key and children is removed, a child left without key and with a single child is merged with it.
+*/
+func (n *radixNode) remove(key []byte) bool {
+	if !bytes.HasPrefix(key, n.prefix) {
+		return false
+	}
+	key = key[len(n.prefix):]
+	if len(key) == 0 {
+		if !n.isKey {
+			return false
+		}
+		n.isKey, n.value = false, nil
+		return true
+	}
+
+	i, found := n.childIndex(key[0])
+	if !found || !n.children[i].remove(key) {
+		return false
+	}
+
+	child := n.children[i]
+	if !child.isKey && len(child.children) == 0 {
+		n.children = append(n.children[:i], n.children[i+1:]...)
+	} else if !child.isKey && len(child.children) == 1 {
+		grandChild := child.children[0]
+		grandChild.prefix = append(append([]byte(nil), child.prefix...), grandChild.prefix...)
+		n.children[i] = grandChild
+	}
+
+	return true
+}
+
+/*
+Call fn on the keys not lower than start in ascending order, until it returns false.
+A nil start walks from the first key.
+*/
+func (t *RadixTree) WalkFrom(start []byte, fn func(key []byte, value interface{}) bool) {
+	t.root.walk(nil, start, fn)
+}
+
+func (n *radixNode) walk(path []byte, start []byte, fn func(key []byte, value interface{}) bool) bool {
+	path = append(append(make([]byte, 0, len(path)+len(n.prefix)), path...), n.prefix...)
+
+	m := min(len(path), len(start))
+	switch bytes.Compare(path[:m], start[:m]) {
+	case -1:
+		// all the keys of the subtree are lower than start
+		return true
+	case 1:
+		// all the keys of the subtree are greater than start
+		start = nil
+	}
+
+	// the path is not lower than start unless it is a proper prefix of it
+	if n.isKey && len(path) >= len(start) && !fn(path, n.value) {
+		return false
+	}
+	for _, child := range n.children {
+		if !child.walk(path, start, fn) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+Call fn on the keys not greater than end in descending order, until it returns false.
+A nil end walks from the last key.
+*/
+func (t *RadixTree) WalkBackFrom(end []byte, fn func(key []byte, value interface{}) bool) {
+	t.root.walkBack(nil, end, fn, end == nil)
+}
+
+func (n *radixNode) walkBack(path []byte, end []byte, fn func(key []byte, value interface{}) bool, unbounded bool) bool {
+	path = append(append(make([]byte, 0, len(path)+len(n.prefix)), path...), n.prefix...)
+
+	if !unbounded {
+		m := min(len(path), len(end))
+		switch bytes.Compare(path[:m], end[:m]) {
+		case 1:
+			// all the keys of the subtree are greater than end
+			return true
+		case -1:
+			// all the keys of the subtree are lower than end
+			unbounded = true
+		}
+	}
+
+	for i := len(n.children) - 1; i >= 0; i-- {
+		if !n.children[i].walkBack(path, end, fn, unbounded) {
+			return false
+		}
+	}
+
+	// the path is not greater than end unless end is a proper prefix of it
+	if n.isKey && (unbounded || len(path) <= len(end)) && !fn(path, n.value) {
+		return false
+	}
+
+	return true
+}
+
+// the greatest key not greater than the given key
+func (t *RadixTree) Floor(key []byte) ([]byte, interface{}, bool) {
+	var resKey []byte
+	var resValue interface{}
+	t.WalkBackFrom(key, func(k []byte, v interface{}) bool {
+		resKey, resValue = k, v
+		return false
+	})
+
+	return resKey, resValue, resKey != nil
+}
+
+func (t *RadixTree) First() ([]byte, interface{}, bool) {
+	var resKey []byte
+	var resValue interface{}
+	t.WalkFrom(nil, func(k []byte, v interface{}) bool {
+		resKey, resValue = k, v
+		return false
+	})
+
+	return resKey, resValue, resKey != nil
+}
+
+func (t *RadixTree) Last() ([]byte, interface{}, bool) {
+	return t.Floor(nil)
+}
@@ -0,0 +1,162 @@
+package data_structure
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func walkKeys(tree *RadixTree, start []byte, limit int) []string {
+	res := make([]string, 0)
+	tree.WalkFrom(start, func(key []byte, value interface{}) bool {
+		res = append(res, string(key))
+		return limit <= 0 || len(res) < limit
+	})
+
+	return res
+}
+
+func walkBackKeys(tree *RadixTree, end []byte, limit int) []string {
+	res := make([]string, 0)
+	tree.WalkBackFrom(end, func(key []byte, value interface{}) bool {
+		res = append(res, string(key))
+		return limit <= 0 || len(res) < limit
+	})
+
+	return res
+}
+
+func TestRadixTreeSplitAndMerge(t *testing.T) {
+	tree := CreateRadixTree()
+	for _, key := range []string{"romane", "romanus", "romulus", "rubens", "ruber", "rubicon", "rubicundus"} {
+		if !tree.Insert([]byte(key), key) {
+			t.Fatalf("Insert(%s) reported an existing key", key)
+		}
+	}
+	if tree.Insert([]byte("ruber"), "ruber") {
+		t.Fatalf("Insert of an existing key reported a new key")
+	}
+	// root, r, om, an, e, us, ulus, ub, e, ns, r, ic, on, undus
+	if tree.Len() != 7 || tree.NodeCount() != 14 {
+		t.Fatalf("Len() = %d, NodeCount() = %d, want 7 and 14", tree.Len(), tree.NodeCount())
+	}
+
+	// a key that is the prefix of others splits their node
+	tree.Insert([]byte("rom"), "rom")
+	if v, ok := tree.Find([]byte("rom")); !ok || v != "rom" {
+		t.Fatalf("Find(rom) = %v, %v", v, ok)
+	}
+	if _, ok := tree.Find([]byte("ro")); ok {
+		t.Fatalf("Find(ro) found a key that was not inserted")
+	}
+
+	// removing the keys merges the nodes left with a single child
+	for _, key := range []string{"rom", "romane", "rubens", "rubicon"} {
+		if !tree.Remove([]byte(key)) {
+			t.Fatalf("Remove(%s) = false", key)
+		}
+	}
+	if tree.Remove([]byte("rom")) || tree.Remove([]byte("rubi")) {
+		t.Fatalf("Remove of a missing key returned true")
+	}
+	// root, r, om, anus, ulus, ub, er, icundus
+	if tree.Len() != 4 || tree.NodeCount() != 8 {
+		t.Fatalf("Len() = %d, NodeCount() = %d, want 4 and 8", tree.Len(), tree.NodeCount())
+	}
+	if got := walkKeys(tree, nil, 0); !slices.Equal(got, []string{"romanus", "romulus", "ruber", "rubicundus"}) {
+		t.Fatalf("the keys are %v after the removals", got)
+	}
+}
+
+// the walks visit the keys like a sorted slice does, from any start or end, including keys that are not in the tree
+func TestRadixTreeWalk(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tree := CreateRadixTree()
+	model := make(map[string]struct{})
+	randomKey := func() []byte {
+		key := make([]byte, rng.Intn(5))
+		for i := range key {
+			key[i] = "abc\x00\xff"[rng.Intn(5)]
+		}
+		return key
+	}
+
+	for range 2000 {
+		key := randomKey()
+		if rng.Intn(3) == 0 {
+			_, exist := model[string(key)]
+			if tree.Remove(key) != exist {
+				t.Fatalf("Remove(%q) = %v", key, !exist)
+			}
+			delete(model, string(key))
+		} else {
+			tree.Insert(key, string(key))
+			model[string(key)] = struct{}{}
+		}
+	}
+
+	sorted := make([]string, 0, len(model))
+	for key := range model {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	if tree.Len() != len(sorted) {
+		t.Fatalf("Len() = %d, want %d", tree.Len(), len(sorted))
+	}
+	if got := walkKeys(tree, nil, 0); !slices.Equal(got, sorted) {
+		t.Fatalf("WalkFrom(nil) = %q, want %q", got, sorted)
+	}
+	for _, key := range sorted {
+		if v, ok := tree.Find([]byte(key)); !ok || v != key {
+			t.Fatalf("Find(%q) = %v, %v", key, v, ok)
+		}
+	}
+
+	for range 200 {
+		bound := randomKey()
+		from := sort.SearchStrings(sorted, string(bound))
+		if got, want := walkKeys(tree, bound, 3), sorted[from:min(from+3, len(sorted))]; !slices.Equal(got, want) {
+			t.Fatalf("WalkFrom(%q) = %q, want %q", bound, got, want)
+		}
+
+		// the keys not greater than the bound, in descending order
+		to := sort.Search(len(sorted), func(i int) bool { return sorted[i] > string(bound) })
+		want := make([]string, 0)
+		for i := to - 1; i >= 0 && len(want) < 3; i-- {
+			want = append(want, sorted[i])
+		}
+		if got := walkBackKeys(tree, bound, 3); !slices.Equal(got, want) {
+			t.Fatalf("WalkBackFrom(%q) = %q, want %q", bound, got, want)
+		}
+
+		key, _, ok := tree.Floor(bound)
+		if ok != (to > 0) || (ok && string(key) != sorted[to-1]) {
+			t.Fatalf("Floor(%q) = %q, %v", bound, key, ok)
+		}
+	}
+}
+
+func TestRadixTreeFirstLast(t *testing.T) {
+	tree := CreateRadixTree()
+	if _, _, ok := tree.First(); ok {
+		t.Fatalf("First() found a key in an empty tree")
+	}
+	if _, _, ok := tree.Last(); ok {
+		t.Fatalf("Last() found a key in an empty tree")
+	}
+
+	// the empty key is the lowest one
+	for _, key := range []string{"b", "", "ba", "a"} {
+		tree.Insert([]byte(key), key)
+	}
+	if key, v, _ := tree.First(); string(key) != "" || v != "" {
+		t.Fatalf("First() = %q, %v, want the empty key", key, v)
+	}
+	if key, v, _ := tree.Last(); string(key) != "ba" || v != "ba" {
+		t.Fatalf("Last() = %q, %v, want ba", key, v)
+	}
+	if key, _, _ := tree.Floor([]byte("az")); string(key) != "a" {
+		t.Fatalf("Floor(az) = %q, want a", key)
+	}
+}
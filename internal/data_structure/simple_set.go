@@ -1,22 +1,71 @@
 package data_structure
 
+import (
+	"math/rand"
+	"mtredis/internal/constant"
+	"strconv"
+)
+
+/*
+A set of integers starts with the compact intset encoding.
+It is converted to a hash table when a member that is not an integer is added, or when it holds too many members.
+*/
 type SimpleSet struct {
-	Key  string
-	Dict map[string]struct{} // no need to care about the value (an empty struct)
+	Key      string
+	IntSet   *IntSet        // intset encoding, nil once the set is converted
+	Dict     map[string]int // hash table encoding: map member to its index in Elements
+	Elements []string       // members stored densely, so that a random member can be picked uniformly in O(1)
+	Buckets  *HashBuckets   // the members of the hash table encoding, for SSCAN
 }
 
 func CreateSimpleSet(key string) *SimpleSet {
 	return &SimpleSet{
-		Key:  key,
-		Dict: make(map[string]struct{}),
+		Key:    key,
+		IntSet: CreateIntSet(),
+	}
+}
+
+func (s *SimpleSet) Encoding() string {
+	if s.IntSet != nil {
+		return "intset"
 	}
+
+	return "hashtable"
+}
+
+func (s *SimpleSet) convertToDict() {
+	s.Dict = make(map[string]int, s.IntSet.Len())
+	s.Elements = make([]string, 0, s.IntSet.Len())
+	s.Buckets = CreateHashBuckets()
+	for i := 0; i < s.IntSet.Len(); i++ {
+		m := strconv.FormatInt(s.IntSet.Get(i), 10)
+		s.Dict[m] = i
+		s.Elements = append(s.Elements, m)
+		s.Buckets.Add(m)
+	}
+
+	s.IntSet = nil
 }
 
 func (s *SimpleSet) Add(members ...string) int {
 	added := 0
 	for _, m := range members {
+		if s.IntSet != nil {
+			v, isInt := parseCanonicalInt(m)
+			if isInt && (s.IntSet.Len() < constant.SetMaxIntSetEntries || s.IntSet.Contains(v)) {
+				if s.IntSet.Add(v) {
+					added++
+				}
+				continue
+			}
+
+			s.convertToDict()
+		}
+
 		if _, exist := s.Dict[m]; !exist {
-			s.Dict[m] = struct{}{}
+			s.Dict[m] = len(s.Elements)
+			s.Elements = append(s.Elements, m)
+			s.Buckets.Add(m)
 			added++
 		}
 	}
@@ -27,8 +76,22 @@ func (s *SimpleSet) Add(members ...string) int {
 func (s *SimpleSet) Remove(members ...string) int {
 	removed := 0
 	for _, m := range members {
-		if _, exist := s.Dict[m]; exist {
+		if s.IntSet != nil {
+			if v, isInt := parseCanonicalInt(m); isInt && s.IntSet.Remove(v) {
+				removed++
+			}
+			continue
+		}
+
+		if idx, exist := s.Dict[m]; exist {
+			// move the last member to the hole to keep Elements dense
+			last := s.Elements[len(s.Elements)-1]
+			s.Elements[idx] = last
+			s.Dict[last] = idx
+			s.Elements = s.Elements[:len(s.Elements)-1]
+
 			delete(s.Dict, m)
+			s.Buckets.Remove(m)
 			removed++
 		}
 	}
@@ -37,6 +100,13 @@ func (s *SimpleSet) Remove(members ...string) int {
 }
 
 func (s *SimpleSet) IsMember(member string) int {
+	if s.IntSet != nil {
+		if v, isInt := parseCanonicalInt(member); isInt && s.IntSet.Contains(v) {
+			return 1
+		}
+		return 0
+	}
+
 	if _, exist := s.Dict[member]; exist {
 		return 1
 	}
@@ -45,10 +115,64 @@ func (s *SimpleSet) IsMember(member string) int {
 }
 
 func (s *SimpleSet) Members() []string {
-	m := make([]string, 0, len(s.Dict))
-	for k := range s.Dict {
-		m = append(m, k)
+	if s.IntSet != nil {
+		m := make([]string, s.IntSet.Len())
+		for i := range m {
+			m[i] = strconv.FormatInt(s.IntSet.Get(i), 10)
+		}
+		return m
 	}
 
+	m := make([]string, len(s.Elements))
+	copy(m, s.Elements)
+
+	return m
+}
+
+/*
+Call fn on the members from the cursor on, until at least count members are visited,
+and return the next cursor, 0 once all the members are visited.
+The cursor of the hash table encoding is a bucket cursor (see HashBuckets.Scan), the intset is visited
+from the position given by the cursor up to its end in a single call, like the listpack of a hash.
+*/
+func (s *SimpleSet) Scan(cursor uint64, count int, fn func(member string)) uint64 {
+	if s.IntSet == nil {
+		return s.Buckets.Scan(cursor, count, fn)
+	}
+
+	for i := cursor; i < uint64(s.IntSet.Len()); i++ {
+		fn(strconv.FormatInt(s.IntSet.Get(int(i)), 10))
+	}
+
+	return 0
+}
+
+func (s *SimpleSet) Len() int {
+	if s.IntSet != nil {
+		return s.IntSet.Len()
+	}
+
+	return len(s.Elements)
+}
+
+// get the member at the given position, 0 <= idx < Len()
+func (s *SimpleSet) MemberAt(idx int) string {
+	if s.IntSet != nil {
+		return strconv.FormatInt(s.IntSet.Get(idx), 10)
+	}
+
+	return s.Elements[idx]
+}
+
+// get a uniformly random member, the set must not be empty
+func (s *SimpleSet) RandomMember() string {
+	return s.MemberAt(rand.Intn(s.Len()))
+}
+
+// remove and return a uniformly random member, the set must not be empty
+func (s *SimpleSet) Pop() string {
+	m := s.RandomMember()
+	s.Remove(m)
+
 	return m
 }
@@ -0,0 +1,109 @@
+package data_structure
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestSimpleSetRemoveKeepsElementsDense(t *testing.T) {
+	s := CreateSimpleSet("s")
+	s.Add("a", "b", "c", "d")
+	if n := s.Remove("b", "missing", "b"); n != 1 {
+		t.Fatalf("Remove() = %d, want 1", n)
+	}
+
+	if s.Len() != 3 || len(s.Elements) != 3 || len(s.Dict) != 3 {
+		t.Fatalf("Len() = %d, %d elements, %d in the dict, want 3", s.Len(), len(s.Elements), len(s.Dict))
+	}
+	for i, m := range s.Elements {
+		if s.Dict[m] != i {
+			t.Fatalf("Dict[%s] = %d, the member is at %d", m, s.Dict[m], i)
+		}
+	}
+	if s.IsMember("b") != 0 || s.IsMember("d") != 1 {
+		t.Fatalf("IsMember is wrong after Remove")
+	}
+}
+
+func TestSimpleSetPop(t *testing.T) {
+	for _, members := range [][]string{{"1", "2", "3"}, {"a", "b", "c"}} {
+		s := CreateSimpleSet("s")
+		s.Add(members...)
+
+		popped := make([]string, 0)
+		for s.Len() > 0 {
+			popped = append(popped, s.Pop())
+		}
+		sort.Strings(popped)
+		for i, m := range members {
+			if popped[i] != m {
+				t.Fatalf("popped %v, want %v", popped, members)
+			}
+		}
+	}
+}
+
+func TestSimpleSetRandomMemberIsUniform(t *testing.T) {
+	s := CreateSimpleSet("s")
+	for i := range 10 {
+		s.Add("m" + strconv.Itoa(i))
+	}
+	s.Remove("m0", "m5")
+
+	counts := make(map[string]int)
+	for range 8000 {
+		counts[s.RandomMember()]++
+	}
+	if len(counts) != 8 {
+		t.Fatalf("RandomMember() returned %d distinct members, want 8", len(counts))
+	}
+	for m, n := range counts {
+		if n < 700 || n > 1300 {
+			t.Fatalf("RandomMember() returned %s %d times out of 8000", m, n)
+		}
+	}
+}
+
+func TestSimpleSetScanIntSet(t *testing.T) {
+	s := CreateSimpleSet("s")
+	s.Add("3", "1", "2")
+
+	// the intset is returned whole by a single call, whatever the count is
+	got := make([]string, 0)
+	if next := s.Scan(0, 1, func(m string) { got = append(got, m) }); next != 0 {
+		t.Fatalf("Scan of an intset returned the cursor %d, want 0", next)
+	}
+	if len(got) != 3 || got[0] != "1" || got[1] != "2" || got[2] != "3" {
+		t.Fatalf("Scan returned %v, want [1 2 3]", got)
+	}
+}
+
+// the members present during the whole scan are returned, while members are added and removed between the calls
+func TestSimpleSetScanHashTable(t *testing.T) {
+	s := CreateSimpleSet("s")
+	for i := range 500 {
+		s.Add("m" + strconv.Itoa(i))
+	}
+
+	seen := make(map[string]bool)
+	var cursor uint64 = 0
+	for calls := 0; ; calls++ {
+		cursor = s.Scan(cursor, 10, func(m string) { seen[m] = true })
+		if cursor == 0 {
+			break
+		}
+
+		if calls < 20 {
+			s.Add("new" + strconv.Itoa(calls))
+		}
+		// m400..m499 are removed during the scan
+		s.Remove("m" + strconv.Itoa(400+calls%100))
+	}
+
+	for i := range 400 {
+		if !seen["m"+strconv.Itoa(i)] {
+			t.Fatalf("m%d is not returned by the scan", i)
+		}
+	}
+}
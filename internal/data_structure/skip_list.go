@@ -212,21 +212,21 @@ func (sl *SkipList) DeleteNode(x *SkipListNode, update [constant.SkipListMaxLeve
 		} else {
 			update[i].Levels[i].Span--
 		}
+	}
 
-		// fix the backward pointer
-		if x.Levels[0].Forward != nil { // x is not the last node
-			x.Levels[0].Forward.Backward = x.Backward
-		} else {
-			sl.Tail = x.Backward // x's backward node is now the last node
-		}
-
-		// reduce update level (if needed)
-		for sl.Level > 1 && sl.Head.Levels[sl.Level-1].Forward == nil {
-			sl.Level--
-		}
+	// fix the backward pointer
+	if x.Levels[0].Forward != nil { // x is not the last node
+		x.Levels[0].Forward.Backward = x.Backward
+	} else {
+		sl.Tail = x.Backward // x's backward node is now the last node
+	}
 
-		sl.Length--
+	// reduce update level (if needed)
+	for sl.Level > 1 && sl.Head.Levels[sl.Level-1].Forward == nil {
+		sl.Level--
 	}
+
+	sl.Length--
 }
 
 /*
@@ -255,3 +255,327 @@ func (sl *SkipList) Delete(score float64, element string) int {
 
 	return 0
 }
+
+/*
+Find the node by its 1-based rank, walking the spans from the highest level.
+Return nil when the rank is out of range.
+*/
+func (sl *SkipList) GetElementByRank(rank uint32) *SkipListNode {
+	var traversed uint32 = 0
+	x := sl.Head
+
+	for i := sl.Level - 1; i >= 0; i-- {
+		for x.Levels[i].Forward != nil && traversed+x.Levels[i].Span <= rank {
+			traversed += x.Levels[i].Span
+			x = x.Levels[i].Forward
+		}
+
+		if traversed == rank {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// a range of scores, each bound is inclusive unless its Exclusive flag is set
+type ZScoreRange struct {
+	Min          float64
+	Max          float64
+	MinExclusive bool
+	MaxExclusive bool
+}
+
+func (r *ZScoreRange) ValueGteMin(value float64) bool {
+	if r.MinExclusive {
+		return value > r.Min
+	}
+
+	return value >= r.Min
+}
+
+func (r *ZScoreRange) ValueLteMax(value float64) bool {
+	if r.MaxExclusive {
+		return value < r.Max
+	}
+
+	return value <= r.Max
+}
+
+func (r *ZScoreRange) IsEmpty() bool {
+	return r.Min > r.Max || (r.Min == r.Max && (r.MinExclusive || r.MaxExclusive))
+}
+
+// check if at least a part of the range is within the scores of the skip list
+func (sl *SkipList) isInScoreRange(r *ZScoreRange) bool {
+	if r.IsEmpty() {
+		return false
+	}
+
+	if sl.Tail == nil || !r.ValueGteMin(sl.Tail.Score) {
+		return false
+	}
+
+	first := sl.Head.Levels[0].Forward
+	if first == nil || !r.ValueLteMax(first.Score) {
+		return false
+	}
+
+	return true
+}
+
+// find the first node (the one with the lowest score) in the range, nil if there is no node in the range
+func (sl *SkipList) FirstInScoreRange(r *ZScoreRange) *SkipListNode {
+	if !sl.isInScoreRange(r) {
+		return nil
+	}
+
+	x := sl.Head
+	for i := sl.Level - 1; i >= 0; i-- {
+		// go forward while the forward node is out of the range
+		for x.Levels[i].Forward != nil && !r.ValueGteMin(x.Levels[i].Forward.Score) {
+			x = x.Levels[i].Forward
+		}
+	}
+
+	// the range is not empty, so the next node can't be nil
+	x = x.Levels[0].Forward
+	if !r.ValueLteMax(x.Score) {
+		return nil
+	}
+
+	return x
+}
+
+// find the last node (the one with the highest score) in the range, nil if there is no node in the range
+func (sl *SkipList) LastInScoreRange(r *ZScoreRange) *SkipListNode {
+	if !sl.isInScoreRange(r) {
+		return nil
+	}
+
+	x := sl.Head
+	for i := sl.Level - 1; i >= 0; i-- {
+		// go forward while the forward node is in the range
+		for x.Levels[i].Forward != nil && r.ValueLteMax(x.Levels[i].Forward.Score) {
+			x = x.Levels[i].Forward
+		}
+	}
+
+	if !r.ValueGteMin(x.Score) {
+		return nil
+	}
+
+	return x
+}
+
+/*
+A bound of a lexicographical range.
+Inf is -1 for the "-" bound (lower than any string) and 1 for the "+" bound (greater than any string).
+*/
+type ZLexBound struct {
+	Value     string
+	Inf       int
+	Exclusive bool
+}
+
+// a range of elements in lexicographical order, it only makes sense when all the elements have the same score
+type ZLexRange struct {
+	Min ZLexBound
+	Max ZLexBound
+}
+
+// compare an element to a bound
+func compareLex(element string, b *ZLexBound) int {
+	if b.Inf != 0 {
+		return -b.Inf
+	}
+
+	return strings.Compare(element, b.Value)
+}
+
+func (r *ZLexRange) ValueGteMin(element string) bool {
+	if r.Min.Exclusive {
+		return compareLex(element, &r.Min) > 0
+	}
+
+	return compareLex(element, &r.Min) >= 0
+}
+
+func (r *ZLexRange) ValueLteMax(element string) bool {
+	if r.Max.Exclusive {
+		return compareLex(element, &r.Max) < 0
+	}
+
+	return compareLex(element, &r.Max) <= 0
+}
+
+func (r *ZLexRange) IsEmpty() bool {
+	var cmp int
+	switch {
+	case r.Min.Inf != 0 || r.Max.Inf != 0:
+		if r.Min.Inf == r.Max.Inf {
+			cmp = 0
+		} else if r.Min.Inf == 1 || r.Max.Inf == -1 {
+			cmp = 1
+		} else {
+			cmp = -1
+		}
+	default:
+		cmp = strings.Compare(r.Min.Value, r.Max.Value)
+	}
+
+	return cmp > 0 || (cmp == 0 && (r.Min.Exclusive || r.Max.Exclusive))
+}
+
+func (sl *SkipList) isInLexRange(r *ZLexRange) bool {
+	if r.IsEmpty() {
+		return false
+	}
+
+	if sl.Tail == nil || !r.ValueGteMin(sl.Tail.Element) {
+		return false
+	}
+
+	first := sl.Head.Levels[0].Forward
+	if first == nil || !r.ValueLteMax(first.Element) {
+		return false
+	}
+
+	return true
+}
+
+// find the first node in the lexicographical range, nil if there is no node in the range
+func (sl *SkipList) FirstInLexRange(r *ZLexRange) *SkipListNode {
+	if !sl.isInLexRange(r) {
+		return nil
+	}
+
+	x := sl.Head
+	for i := sl.Level - 1; i >= 0; i-- {
+		for x.Levels[i].Forward != nil && !r.ValueGteMin(x.Levels[i].Forward.Element) {
+			x = x.Levels[i].Forward
+		}
+	}
+
+	x = x.Levels[0].Forward
+	if !r.ValueLteMax(x.Element) {
+		return nil
+	}
+
+	return x
+}
+
+// find the last node in the lexicographical range, nil if there is no node in the range
+func (sl *SkipList) LastInLexRange(r *ZLexRange) *SkipListNode {
+	if !sl.isInLexRange(r) {
+		return nil
+	}
+
+	x := sl.Head
+	for i := sl.Level - 1; i >= 0; i-- {
+		for x.Levels[i].Forward != nil && r.ValueLteMax(x.Levels[i].Forward.Element) {
+			x = x.Levels[i].Forward
+		}
+	}
+
+	if !r.ValueGteMin(x.Element) {
+		return nil
+	}
+
+	return x
+}
+
+/*
+Delete all the nodes whose score is in the range, the deleted elements are also removed from dict.
+Return the number of deleted nodes.
+*/
+func (sl *SkipList) DeleteRangeByScore(r *ZScoreRange, dict map[string]float64) int {
+	update := [constant.SkipListMaxLevel]*SkipListNode{}
+	x := sl.Head
+
+	for i := sl.Level - 1; i >= 0; i-- {
+		for x.Levels[i].Forward != nil && !r.ValueGteMin(x.Levels[i].Forward.Score) {
+			x = x.Levels[i].Forward
+		}
+
+		update[i] = x
+	}
+
+	// the current node is the last one with score < (or <=) min
+	x = x.Levels[0].Forward
+
+	removed := 0
+	for x != nil && r.ValueLteMax(x.Score) {
+		next := x.Levels[0].Forward
+		sl.DeleteNode(x, update)
+		delete(dict, x.Element)
+		removed++
+		x = next
+	}
+
+	return removed
+}
+
+/*
+Delete all the nodes in the lexicographical range, the deleted elements are also removed from dict.
+Return the number of deleted nodes.
+*/
+func (sl *SkipList) DeleteRangeByLex(r *ZLexRange, dict map[string]float64) int {
+	update := [constant.SkipListMaxLevel]*SkipListNode{}
+	x := sl.Head
+
+	for i := sl.Level - 1; i >= 0; i-- {
+		for x.Levels[i].Forward != nil && !r.ValueGteMin(x.Levels[i].Forward.Element) {
+			x = x.Levels[i].Forward
+		}
+
+		update[i] = x
+	}
+
+	x = x.Levels[0].Forward
+
+	removed := 0
+	for x != nil && r.ValueLteMax(x.Element) {
+		next := x.Levels[0].Forward
+		sl.DeleteNode(x, update)
+		delete(dict, x.Element)
+		removed++
+		x = next
+	}
+
+	return removed
+}
+
+/*
+Delete all the nodes with 1-based rank from start to end (inclusive), the deleted elements are also removed from dict.
+Return the number of deleted nodes.
+*/
+func (sl *SkipList) DeleteRangeByRank(start uint32, end uint32, dict map[string]float64) int {
+	update := [constant.SkipListMaxLevel]*SkipListNode{}
+	var traversed uint32 = 0
+	x := sl.Head
+
+	for i := sl.Level - 1; i >= 0; i-- {
+		for x.Levels[i].Forward != nil && traversed+x.Levels[i].Span < start {
+			traversed += x.Levels[i].Span
+			x = x.Levels[i].Forward
+		}
+
+		update[i] = x
+	}
+
+	traversed++
+	x = x.Levels[0].Forward
+
+	removed := 0
+	for x != nil && traversed <= end {
+		next := x.Levels[0].Forward
+		sl.DeleteNode(x, update)
+		delete(dict, x.Element)
+		removed++
+		traversed++
+		x = next
+	}
+
+	return removed
+}
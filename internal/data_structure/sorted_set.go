@@ -1,24 +1,73 @@
 package data_structure
 
+import (
+	"mtredis/internal/config"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // NOTE: SortedSet is simplified to ZSet in this file
 
+/*
+A sorted set starts with the compact listpack encoding (element1, score1, element2, score2, ...) kept sorted by score then element,
+and is converted to a skip list plus a dict once it holds too many elements or a too long element.
+The thresholds are config.ZSetMaxListPackEntries and config.ZSetMaxListPackValue.
+The conversion is one way, a sorted set never goes back to the listpack encoding.
+*/
 type ZSet struct {
+	ListPack  *ListPack // listpack encoding, nil once the sorted set is converted
 	ZSkipList *SkipList
 	Dict      map[string]float64 // map element to score
 }
 
+type ZElement struct {
+	Element string
+	Score   float64
+}
+
 func CreatZSet() *ZSet {
 	zs := ZSet{
-		ZSkipList: CreateSkipList(),
-		Dict:      map[string]float64{},
+		ListPack: CreateListPack(),
 	}
 
 	return &zs
 }
 
+func (zs *ZSet) Encoding() string {
+	if zs.ListPack != nil {
+		return "listpack"
+	}
+
+	return "skiplist"
+}
+
+func (zs *ZSet) convertToSkipList() {
+	elements := zs.lpElements()
+	zs.ZSkipList = CreateSkipList()
+	zs.Dict = make(map[string]float64, len(elements))
+	for _, e := range elements {
+		zs.ZSkipList.Insert(e.Score, e.Element)
+		zs.Dict[e.Element] = e.Score
+	}
+
+	zs.ListPack = nil
+}
+
 func (zs *ZSet) Add(score float64, element string) int {
-	if len(element) == 0 {
-		return 0
+	if zs.ListPack != nil && (len(element) > config.ZSetMaxListPackValue ||
+		(zs.lpIndex(element) < 0 && zs.Len()+1 > config.ZSetMaxListPackEntries)) {
+		zs.convertToSkipList()
+	}
+
+	if zs.ListPack != nil {
+		elements := zs.lpElements()
+		if idx := zs.lpIndex(element); idx >= 0 {
+			elements = append(elements[:idx], elements[idx+1:]...)
+		}
+		zs.lpSetElements(lpInsert(elements, ZElement{Element: element, Score: score}))
+
+		return 1
 	}
 
 	currentScore, exist := zs.Dict[element]
@@ -42,6 +91,19 @@ This function retrieves the 0-based rank and score of the node given by the elem
 If reverse if false, the node's rank is calculated from the lowest score. Otherwise, the rank is computed considering with the highest score.
 */
 func (zs *ZSet) GetRank(element string, reverse bool) (rank int64, score float64) {
+	if zs.ListPack != nil {
+		idx := zs.lpIndex(element)
+		if idx < 0 {
+			return -1, 0
+		}
+
+		e := zs.lpElements()[idx]
+		if reverse {
+			return int64(zs.Len() - 1 - idx), e.Score
+		}
+		return int64(idx), e.Score
+	}
+
 	zLength := zs.ZSkipList.Length
 
 	score, exist := zs.Dict[element]
@@ -60,6 +122,15 @@ func (zs *ZSet) GetRank(element string, reverse bool) (rank int64, score float64
 }
 
 func (zs *ZSet) GetScore(element string) (int, float64) {
+	if zs.ListPack != nil {
+		idx := zs.lpIndex(element)
+		if idx < 0 {
+			return -1, 0
+		}
+
+		return 0, zs.lpElements()[idx].Score
+	}
+
 	score, exist := zs.Dict[element]
 	if !exist {
 		return -1, 0
@@ -68,6 +139,373 @@ func (zs *ZSet) GetScore(element string) (int, float64) {
 	return 0, score
 }
 
+/*
+Get the score of every element.
+The returned map must not be modified, it is the dict itself for the skiplist encoding.
+*/
+func (zs *ZSet) Scores() map[string]float64 {
+	if zs.ListPack == nil {
+		return zs.Dict
+	}
+
+	elements := zs.lpElements()
+	res := make(map[string]float64, len(elements))
+	for _, e := range elements {
+		res[e.Element] = e.Score
+	}
+
+	return res
+}
+
+/*
+Remove and return the element with the lowest score, or the one with the highest score if max is true.
+The element is found through the head (or the tail) of the skip list, removing it costs O(log n).
+*/
+func (zs *ZSet) Pop(max bool) (string, float64, bool) {
+	if zs.ListPack != nil {
+		elements := zs.lpElements()
+		if len(elements) == 0 {
+			return "", 0, false
+		}
+
+		idx := 0
+		if max {
+			idx = len(elements) - 1
+		}
+		e := elements[idx]
+		zs.lpSetElements(append(elements[:idx], elements[idx+1:]...))
+
+		return e.Element, e.Score, true
+	}
+
+	x := zs.ZSkipList.Head.Levels[0].Forward
+	if max {
+		x = zs.ZSkipList.Tail
+	}
+	if x == nil {
+		return "", 0, false
+	}
+
+	element, score := x.Element, x.Score
+	zs.ZSkipList.Delete(score, element)
+	delete(zs.Dict, element)
+
+	return element, score, true
+}
+
+// remove the element, return 1 if it was removed or 0 if it does not exist
+func (zs *ZSet) Remove(element string) int {
+	if zs.ListPack != nil {
+		idx := zs.lpIndex(element)
+		if idx < 0 {
+			return 0
+		}
+
+		elements := zs.lpElements()
+		zs.lpSetElements(append(elements[:idx], elements[idx+1:]...))
+
+		return 1
+	}
+
+	score, exist := zs.Dict[element]
+	if !exist {
+		return 0
+	}
+
+	zs.ZSkipList.Delete(score, element)
+	delete(zs.Dict, element)
+
+	return 1
+}
+
 func (zs *ZSet) Len() int {
+	if zs.ListPack != nil {
+		return zs.ListPack.Count / 2
+	}
+
 	return len(zs.Dict)
 }
+
+// get the element with the 0-based rank, which must be within [0, Len())
+func (zs *ZSet) ElementAt(rank int) ZElement {
+	if zs.ListPack != nil {
+		return zs.lpElements()[rank]
+	}
+
+	x := zs.ZSkipList.GetElementByRank(uint32(rank) + 1)
+
+	return ZElement{Element: x.Element, Score: x.Score}
+}
+
+/*
+Get the elements with 0-based ranks from start to stop (inclusive), both must be within [0, Len()).
+If reverse is true, ranks are counted from the highest score.
+*/
+func (zs *ZSet) RangeByRank(start int, stop int, reverse bool) []ZElement {
+	res := make([]ZElement, 0, stop-start+1)
+
+	if zs.ListPack != nil {
+		elements := zs.lpElements()
+		for i := start; i <= stop; i++ {
+			if reverse {
+				res = append(res, elements[len(elements)-1-i])
+			} else {
+				res = append(res, elements[i])
+			}
+		}
+
+		return res
+	}
+
+	var x *SkipListNode
+	if reverse {
+		x = zs.ZSkipList.GetElementByRank(zs.ZSkipList.Length - uint32(start))
+	} else {
+		x = zs.ZSkipList.GetElementByRank(uint32(start) + 1)
+	}
+
+	for i := start; i <= stop && x != nil; i++ {
+		res = append(res, ZElement{Element: x.Element, Score: x.Score})
+		if reverse {
+			x = x.Backward
+		} else {
+			x = x.Levels[0].Forward
+		}
+	}
+
+	return res
+}
+
+/*
+Move from the node by offset positions in the iteration direction, in O(log n) thanks to the ranks.
+Return nil when the position is out of the skip list.
+*/
+func (zs *ZSet) skipNodes(x *SkipListNode, offset int, reverse bool) *SkipListNode {
+	if offset <= 0 || x == nil {
+		return x
+	}
+
+	rank := int64(zs.ZSkipList.GetRank(x.Score, x.Element))
+	if reverse {
+		rank -= int64(offset)
+	} else {
+		rank += int64(offset)
+	}
+	if rank < 1 || rank > int64(zs.ZSkipList.Length) {
+		return nil
+	}
+
+	return zs.ZSkipList.GetElementByRank(uint32(rank))
+}
+
+// collect the nodes starting from x while inRange is true, skipping offset nodes and returning at most limit nodes (limit < 0 means no limit)
+func (zs *ZSet) collect(x *SkipListNode, reverse bool, offset int, limit int, inRange func(x *SkipListNode) bool) []ZElement {
+	res := make([]ZElement, 0)
+	x = zs.skipNodes(x, offset, reverse)
+	for x != nil && limit != 0 && inRange(x) {
+		res = append(res, ZElement{Element: x.Element, Score: x.Score})
+		limit--
+
+		if reverse {
+			x = x.Backward
+		} else {
+			x = x.Levels[0].Forward
+		}
+	}
+
+	return res
+}
+
+// get the elements whose score is in the range, from the lowest score (or the highest if reverse is true)
+func (zs *ZSet) RangeByScore(r *ZScoreRange, reverse bool, offset int, limit int) []ZElement {
+	if zs.ListPack != nil {
+		return lpCollect(zs.lpElements(), reverse, offset, limit, func(e ZElement) bool {
+			return r.ValueGteMin(e.Score) && r.ValueLteMax(e.Score)
+		})
+	}
+
+	if reverse {
+		return zs.collect(zs.ZSkipList.LastInScoreRange(r), true, offset, limit, func(x *SkipListNode) bool {
+			return r.ValueGteMin(x.Score)
+		})
+	}
+
+	return zs.collect(zs.ZSkipList.FirstInScoreRange(r), false, offset, limit, func(x *SkipListNode) bool {
+		return r.ValueLteMax(x.Score)
+	})
+}
+
+// get the elements in the lexicographical range, in order (or in reverse order if reverse is true)
+func (zs *ZSet) RangeByLex(r *ZLexRange, reverse bool, offset int, limit int) []ZElement {
+	if zs.ListPack != nil {
+		return lpCollect(zs.lpElements(), reverse, offset, limit, func(e ZElement) bool {
+			return r.ValueGteMin(e.Element) && r.ValueLteMax(e.Element)
+		})
+	}
+
+	if reverse {
+		return zs.collect(zs.ZSkipList.LastInLexRange(r), true, offset, limit, func(x *SkipListNode) bool {
+			return r.ValueGteMin(x.Element)
+		})
+	}
+
+	return zs.collect(zs.ZSkipList.FirstInLexRange(r), false, offset, limit, func(x *SkipListNode) bool {
+		return r.ValueLteMax(x.Element)
+	})
+}
+
+// remove the elements with 0-based ranks from start to stop (inclusive), return the number of removed elements
+func (zs *ZSet) RemoveRangeByRank(start int, stop int) int {
+	if zs.ListPack != nil {
+		elements := zs.lpElements()
+		zs.lpSetElements(append(elements[:start], elements[stop+1:]...))
+
+		return stop - start + 1
+	}
+
+	return zs.ZSkipList.DeleteRangeByRank(uint32(start)+1, uint32(stop)+1, zs.Dict)
+}
+
+// remove the elements whose score is in the range, return the number of removed elements
+func (zs *ZSet) RemoveRangeByScore(r *ZScoreRange) int {
+	if zs.ListPack != nil {
+		return zs.lpRemoveIf(func(e ZElement) bool {
+			return r.ValueGteMin(e.Score) && r.ValueLteMax(e.Score)
+		})
+	}
+
+	return zs.ZSkipList.DeleteRangeByScore(r, zs.Dict)
+}
+
+// remove the elements in the lexicographical range, return the number of removed elements
+func (zs *ZSet) RemoveRangeByLex(r *ZLexRange) int {
+	if zs.ListPack != nil {
+		return zs.lpRemoveIf(func(e ZElement) bool {
+			return r.ValueGteMin(e.Element) && r.ValueLteMax(e.Element)
+		})
+	}
+
+	return zs.ZSkipList.DeleteRangeByLex(r, zs.Dict)
+}
+
+// count the elements between two nodes (inclusive) by the difference of their ranks
+func (zs *ZSet) countBetween(first *SkipListNode, last *SkipListNode) int {
+	if first == nil || last == nil {
+		return 0
+	}
+
+	firstRank := zs.ZSkipList.GetRank(first.Score, first.Element)
+	lastRank := zs.ZSkipList.GetRank(last.Score, last.Element)
+
+	return int(lastRank-firstRank) + 1
+}
+
+// count the elements whose score is in the range in O(log n)
+func (zs *ZSet) CountInScoreRange(r *ZScoreRange) int {
+	if zs.ListPack != nil {
+		return len(zs.RangeByScore(r, false, 0, -1))
+	}
+
+	return zs.countBetween(zs.ZSkipList.FirstInScoreRange(r), zs.ZSkipList.LastInScoreRange(r))
+}
+
+// count the elements in the lexicographical range in O(log n)
+func (zs *ZSet) CountInLexRange(r *ZLexRange) int {
+	if zs.ListPack != nil {
+		return len(zs.RangeByLex(r, false, 0, -1))
+	}
+
+	return zs.countBetween(zs.ZSkipList.FirstInLexRange(r), zs.ZSkipList.LastInLexRange(r))
+}
+
+// decode the (element, score) pairs of the listpack
+func (zs *ZSet) lpElements() []ZElement {
+	values := zs.ListPack.Values()
+	res := make([]ZElement, 0, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		score, _ := strconv.ParseFloat(values[i+1], 64)
+		res = append(res, ZElement{Element: values[i], Score: score})
+	}
+
+	return res
+}
+
+// re-encode the listpack with the elements, which must be sorted
+func (zs *ZSet) lpSetElements(elements []ZElement) {
+	values := make([]string, 0, 2*len(elements))
+	for _, e := range elements {
+		values = append(values, e.Element, strconv.FormatFloat(e.Score, 'g', -1, 64))
+	}
+
+	zs.ListPack.SetValues(values)
+}
+
+// find the rank of the element in the listpack, -1 if the element does not exist
+func (zs *ZSet) lpIndex(element string) int {
+	res := -1
+	zs.ListPack.Iterate(func(index int, entry []byte) bool {
+		// only the even entries are elements
+		if index%2 == 0 && string(entry) == element {
+			res = index / 2
+			return false
+		}
+
+		return true
+	})
+
+	return res
+}
+
+// remove the elements matching fn from the listpack, return the number of removed elements
+func (zs *ZSet) lpRemoveIf(fn func(e ZElement) bool) int {
+	elements := zs.lpElements()
+	kept := elements[:0]
+	for _, e := range elements {
+		if !fn(e) {
+			kept = append(kept, e)
+		}
+	}
+
+	removed := len(elements) - len(kept)
+	if removed > 0 {
+		zs.lpSetElements(kept)
+	}
+
+	return removed
+}
+
+// insert the element into the sorted elements, keeping the order by score then element
+func lpInsert(elements []ZElement, e ZElement) []ZElement {
+	idx := sort.Search(len(elements), func(i int) bool {
+		return elements[i].Score > e.Score || (elements[i].Score == e.Score && strings.Compare(elements[i].Element, e.Element) > 0)
+	})
+
+	elements = append(elements, ZElement{})
+	copy(elements[idx+1:], elements[idx:])
+	elements[idx] = e
+
+	return elements
+}
+
+// same as collect for the sorted elements of a listpack, the elements in the range are contiguous
+func lpCollect(elements []ZElement, reverse bool, offset int, limit int, inRange func(e ZElement) bool) []ZElement {
+	res := make([]ZElement, 0)
+	for i := 0; i < len(elements) && limit != 0; i++ {
+		e := elements[i]
+		if reverse {
+			e = elements[len(elements)-1-i]
+		}
+		if !inRange(e) {
+			continue
+		}
+
+		if offset > 0 {
+			offset--
+			continue
+		}
+		res = append(res, e)
+		limit--
+	}
+
+	return res
+}
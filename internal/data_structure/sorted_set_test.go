@@ -0,0 +1,201 @@
+package data_structure
+
+import (
+	"math"
+	"math/rand"
+	"mtredis/internal/config"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestZSetEncodingConversion(t *testing.T) {
+	zs := CreatZSet()
+	for i := range config.ZSetMaxListPackEntries {
+		zs.Add(float64(i), "m"+strconv.Itoa(i))
+	}
+	if zs.Encoding() != "listpack" {
+		t.Fatalf("Encoding() = %s with %d elements, want listpack", zs.Encoding(), zs.Len())
+	}
+	// updating the score of an element does not convert the sorted set
+	zs.Add(-1, "m5")
+	if zs.Encoding() != "listpack" {
+		t.Fatalf("updating a score converted the sorted set")
+	}
+
+	zs.Add(1000, "one-more")
+	if zs.Encoding() != "skiplist" {
+		t.Fatalf("Encoding() = %s with %d elements, want skiplist", zs.Encoding(), zs.Len())
+	}
+	if zs.Len() != config.ZSetMaxListPackEntries+1 {
+		t.Fatalf("Len() = %d after the conversion", zs.Len())
+	}
+	if e := zs.ElementAt(0); e.Element != "m5" || e.Score != -1 {
+		t.Fatalf("ElementAt(0) = %v after the conversion, want m5 -1", e)
+	}
+
+	// removing elements does not convert it back
+	for range 100 {
+		zs.Pop(false)
+	}
+	if zs.Encoding() != "skiplist" {
+		t.Fatalf("the sorted set was converted back to a listpack")
+	}
+
+	long := CreatZSet()
+	long.Add(1, strings.Repeat("x", config.ZSetMaxListPackValue))
+	if long.Encoding() != "listpack" {
+		t.Fatalf("an element of the max size converted the sorted set")
+	}
+	long.Add(1, strings.Repeat("x", config.ZSetMaxListPackValue+1))
+	if long.Encoding() != "skiplist" || long.Len() != 2 {
+		t.Fatalf("Encoding() = %s with a long element, want skiplist", long.Encoding())
+	}
+}
+
+// the scores are stored as text in the listpack, they must come back unchanged
+func TestZSetListPackScores(t *testing.T) {
+	zs := CreatZSet()
+	scores := []float64{math.Inf(1), math.Inf(-1), 0.1, -1e-300, math.MaxFloat64, 1 << 60}
+	for i, score := range scores {
+		zs.Add(score, "m"+strconv.Itoa(i))
+	}
+
+	for i, score := range scores {
+		if found, got := zs.GetScore("m" + strconv.Itoa(i)); found != 0 || got != score {
+			t.Fatalf("GetScore(m%d) = %v, want %v", i, got, score)
+		}
+	}
+}
+
+func createSkipListZSet() *ZSet {
+	zs := CreatZSet()
+	zs.convertToSkipList()
+	return zs
+}
+
+// the two encodings must behave the same, the listpack is compared to the skip list on random operations
+func TestZSetEncodingsAgree(t *testing.T) {
+	for seed := range int64(10) {
+		rng := rand.New(rand.NewSource(seed))
+		lp, sl := CreatZSet(), createSkipListZSet()
+
+		for range 300 {
+			member := "m" + strconv.Itoa(rng.Intn(60))
+			score := float64(rng.Intn(20))
+			switch rng.Intn(4) {
+			case 0, 1:
+				lp.Add(score, member)
+				sl.Add(score, member)
+			case 2:
+				if lp.Remove(member) != sl.Remove(member) {
+					t.Fatalf("seed %d: Remove(%s) differs", seed, member)
+				}
+			case 3:
+				max := rng.Intn(2) == 0
+				m1, s1, ok1 := lp.Pop(max)
+				m2, s2, ok2 := sl.Pop(max)
+				if m1 != m2 || s1 != s2 || ok1 != ok2 {
+					t.Fatalf("seed %d: Pop(%v) = %s %v %v and %s %v %v", seed, max, m1, s1, ok1, m2, s2, ok2)
+				}
+			}
+			if lp.Encoding() != "listpack" {
+				t.Fatalf("seed %d: the listpack sorted set was converted", seed)
+			}
+			checkZSetsAgree(t, lp, sl, rng)
+		}
+	}
+}
+
+func checkZSetsAgree(t *testing.T, lp *ZSet, sl *ZSet, rng *rand.Rand) {
+	t.Helper()
+
+	if lp.Len() != sl.Len() {
+		t.Fatalf("Len() = %d and %d", lp.Len(), sl.Len())
+	}
+	if lp.Len() == 0 {
+		return
+	}
+
+	all := lp.RangeByRank(0, lp.Len()-1, false)
+	if !reflect.DeepEqual(all, sl.RangeByRank(0, sl.Len()-1, false)) {
+		t.Fatalf("RangeByRank differs: %v and %v", all, sl.RangeByRank(0, sl.Len()-1, false))
+	}
+	if !reflect.DeepEqual(lp.RangeByRank(0, lp.Len()-1, true), sl.RangeByRank(0, sl.Len()-1, true)) {
+		t.Fatalf("reverse RangeByRank differs")
+	}
+	if !reflect.DeepEqual(lp.Scores(), sl.Scores()) {
+		t.Fatalf("Scores() differs")
+	}
+
+	e := all[rng.Intn(len(all))]
+	for _, reverse := range []bool{false, true} {
+		r1, s1 := lp.GetRank(e.Element, reverse)
+		r2, s2 := sl.GetRank(e.Element, reverse)
+		if r1 != r2 || s1 != s2 {
+			t.Fatalf("GetRank(%s, %v) = %d %v and %d %v", e.Element, reverse, r1, s1, r2, s2)
+		}
+	}
+
+	r := &ZScoreRange{
+		Min:          float64(rng.Intn(20)),
+		Max:          float64(rng.Intn(20)),
+		MinExclusive: rng.Intn(2) == 0,
+		MaxExclusive: rng.Intn(2) == 0,
+	}
+	offset, limit, reverse := rng.Intn(3), rng.Intn(5)-1, rng.Intn(2) == 0
+	if got, want := lp.RangeByScore(r, reverse, offset, limit), sl.RangeByScore(r, reverse, offset, limit); !reflect.DeepEqual(got, want) {
+		t.Fatalf("RangeByScore(%+v, %v, %d, %d) = %v, want %v", *r, reverse, offset, limit, got, want)
+	}
+	if lp.CountInScoreRange(r) != sl.CountInScoreRange(r) {
+		t.Fatalf("CountInScoreRange(%+v) = %d and %d", *r, lp.CountInScoreRange(r), sl.CountInScoreRange(r))
+	}
+}
+
+func TestZSetLexRange(t *testing.T) {
+	for _, zs := range []*ZSet{CreatZSet(), createSkipListZSet()} {
+		for _, member := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+			zs.Add(0, member)
+		}
+
+		members := func(elements []ZElement) string {
+			var sb strings.Builder
+			for _, e := range elements {
+				sb.WriteString(e.Element)
+			}
+			return sb.String()
+		}
+		for _, tt := range []struct {
+			r       ZLexRange
+			reverse bool
+			offset  int
+			limit   int
+			want    string
+		}{
+			{ZLexRange{Min: ZLexBound{Inf: -1}, Max: ZLexBound{Inf: 1}}, false, 0, -1, "abcdefg"},
+			{ZLexRange{Min: ZLexBound{Inf: -1}, Max: ZLexBound{Value: "c"}}, false, 0, -1, "abc"},
+			{ZLexRange{Min: ZLexBound{Inf: -1}, Max: ZLexBound{Value: "c", Exclusive: true}}, false, 0, -1, "ab"},
+			{ZLexRange{Min: ZLexBound{Value: "aaa"}, Max: ZLexBound{Value: "g", Exclusive: true}}, false, 0, -1, "bcdef"},
+			{ZLexRange{Min: ZLexBound{Value: "aaa"}, Max: ZLexBound{Value: "g", Exclusive: true}}, true, 1, 2, "ed"},
+			{ZLexRange{Min: ZLexBound{Value: "z"}, Max: ZLexBound{Inf: 1}}, false, 0, -1, ""},
+		} {
+			if got := members(zs.RangeByLex(&tt.r, tt.reverse, tt.offset, tt.limit)); got != tt.want {
+				t.Errorf("%s: RangeByLex(%+v, %v, %d, %d) = %s, want %s", zs.Encoding(), tt.r, tt.reverse, tt.offset, tt.limit, got, tt.want)
+			}
+			if !tt.reverse && tt.limit < 0 {
+				if got := zs.CountInLexRange(&tt.r); got != len(tt.want) {
+					t.Errorf("%s: CountInLexRange(%+v) = %d, want %d", zs.Encoding(), tt.r, got, len(tt.want))
+				}
+			}
+		}
+
+		r := ZLexRange{Min: ZLexBound{Value: "b", Exclusive: true}, Max: ZLexBound{Value: "e"}}
+		if n := zs.RemoveRangeByLex(&r); n != 3 || zs.Len() != 4 {
+			t.Errorf("%s: RemoveRangeByLex removed %d elements, want 3", zs.Encoding(), n)
+		}
+		if n := zs.RemoveRangeByRank(1, 2); n != 2 || members(zs.RangeByRank(0, zs.Len()-1, false)) != "ag" {
+			t.Errorf("%s: RemoveRangeByRank(1, 2) removed %d elements", zs.Encoding(), n)
+		}
+	}
+}
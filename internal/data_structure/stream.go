@@ -0,0 +1,392 @@
+package data_structure
+
+import (
+	"encoding/binary"
+	"math"
+	"mtredis/internal/config"
+	"strconv"
+)
+
+// the ID of a stream entry: a unix time in milliseconds and a sequence number within the millisecond
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+var StreamMinID = StreamID{Ms: 0, Seq: 0}
+var StreamMaxID = StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+func (id StreamID) Compare(other StreamID) int {
+	switch {
+	case id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq):
+		return -1
+	case id == other:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// the smallest ID greater than this one, false if this is the max ID
+func (id StreamID) Next() (StreamID, bool) {
+	switch {
+	case id.Seq < math.MaxUint64:
+		return StreamID{Ms: id.Ms, Seq: id.Seq + 1}, true
+	case id.Ms < math.MaxUint64:
+		return StreamID{Ms: id.Ms + 1, Seq: 0}, true
+	default:
+		return id, false
+	}
+}
+
+// the greatest ID lower than this one, false if this is the min ID
+func (id StreamID) Prev() (StreamID, bool) {
+	switch {
+	case id.Seq > 0:
+		return StreamID{Ms: id.Ms, Seq: id.Seq - 1}, true
+	case id.Ms > 0:
+		return StreamID{Ms: id.Ms - 1, Seq: math.MaxUint64}, true
+	default:
+		return id, false
+	}
+}
+
+// the key of the ID in the radix tree, big endian so that the keys are ordered like the IDs
+func (id StreamID) encode() []byte {
+	res := make([]byte, 16)
+	binary.BigEndian.PutUint64(res, id.Ms)
+	binary.BigEndian.PutUint64(res[8:], id.Seq)
+
+	return res
+}
+
+type StreamEntry struct {
+	ID     StreamID
+	Fields []string // field value pairs
+}
+
+// the flags of an entry in a block
+const (
+	streamEntryDeleted    = 1 // the entry is deleted, it is skipped until its block is removed
+	streamEntrySameFields = 2 // the entry has the same fields as the master entry, only its values are stored
+)
+
+/*
+A block of consecutive stream entries packed in a listpack, the entries are relative to the master entry (the first one):
+  - flags
+  - ms and seq of the ID minus the ones of the master ID
+  - with streamEntrySameFields: the values, in the order of the master fields
+  - else: the number of fields, then the field value pairs
+
+Since the entries of a stream usually have the same fields, most of them only store their values and two small deltas.
+Deleted entries are only flagged, the block is removed from the stream once all its entries are deleted.
+*/
+type streamBlock struct {
+	MasterID     StreamID
+	MasterFields []string
+	Entries      *ListPack
+	Count        int // number of entries not deleted
+	Deleted      int
+}
+
+// an entry decoded from a block, flagsIdx is the index of its flags in the listpack
+type streamBlockEntry struct {
+	StreamEntry
+	deleted  bool
+	flagsIdx int
+}
+
+func createStreamBlock(id StreamID, fields []string) *streamBlock {
+	masterFields := make([]string, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		masterFields = append(masterFields, fields[i])
+	}
+
+	return &streamBlock{
+		MasterID:     id,
+		MasterFields: masterFields,
+		Entries:      CreateListPack(),
+	}
+}
+
+func (b *streamBlock) hasMasterFields(fields []string) bool {
+	if len(fields) != 2*len(b.MasterFields) {
+		return false
+	}
+	for i, f := range b.MasterFields {
+		if fields[2*i] != f {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *streamBlock) append(id StreamID, fields []string) {
+	values := []string{"0", strconv.FormatUint(id.Ms-b.MasterID.Ms, 10), strconv.FormatUint(id.Seq-b.MasterID.Seq, 10)}
+	if b.hasMasterFields(fields) {
+		values[0] = strconv.Itoa(streamEntrySameFields)
+		for i := 1; i < len(fields); i += 2 {
+			values = append(values, fields[i])
+		}
+	} else {
+		values = append(values, strconv.Itoa(len(fields)/2))
+		values = append(values, fields...)
+	}
+
+	b.Entries.Append(values...)
+	b.Count++
+}
+
+// decode all the entries of the block, deleted ones included
+func (b *streamBlock) entries() []streamBlockEntry {
+	values := b.Entries.Values()
+	res := make([]streamBlockEntry, 0, b.Count+b.Deleted)
+	for i := 0; i < len(values); {
+		flags, _ := strconv.Atoi(values[i])
+		msDelta, _ := strconv.ParseUint(values[i+1], 10, 64)
+		seqDelta, _ := strconv.ParseUint(values[i+2], 10, 64)
+
+		e := streamBlockEntry{
+			StreamEntry: StreamEntry{ID: StreamID{Ms: b.MasterID.Ms + msDelta, Seq: b.MasterID.Seq + seqDelta}},
+			deleted:     flags&streamEntryDeleted != 0,
+			flagsIdx:    i,
+		}
+		i += 3
+
+		if flags&streamEntrySameFields != 0 {
+			e.Fields = make([]string, 0, 2*len(b.MasterFields))
+			for _, f := range b.MasterFields {
+				e.Fields = append(e.Fields, f, values[i])
+				i++
+			}
+		} else {
+			n, _ := strconv.Atoi(values[i])
+			e.Fields = values[i+1 : i+1+2*n]
+			i += 1 + 2*n
+		}
+
+		res = append(res, e)
+	}
+
+	return res
+}
+
+// flag the entries at the given flags indexes as deleted
+func (b *streamBlock) markDeleted(flagsIdxs []int) {
+	values := b.Entries.Values()
+	for _, i := range flagsIdxs {
+		flags, _ := strconv.Atoi(values[i])
+		values[i] = strconv.Itoa(flags | streamEntryDeleted)
+	}
+	b.Entries.SetValues(values)
+
+	b.Count -= len(flagsIdxs)
+	b.Deleted += len(flagsIdxs)
+}
+
+/*
+A stream is an append-only log of entries (field value pairs) ordered by their IDs.
+The entries are packed in blocks of at most config.StreamNodeMaxEntries entries or config.StreamNodeMaxBytes bytes,
+and the blocks are indexed by the ID of their master entry in a radix tree.
+*/
+type Stream struct {
+	Blocks       *RadixTree // master ID => *streamBlock
+	Length       uint64
+	LastID       StreamID // the ID of the last entry added, it stays even if the entry is deleted
+	MaxDeletedID StreamID
+	EntriesAdded uint64
+}
+
+func CreateStream() *Stream {
+	return &Stream{
+		Blocks: CreateRadixTree(),
+	}
+}
+
+// the ID must be greater than the last ID
+func (s *Stream) Append(id StreamID, fields []string) {
+	_, v, exist := s.Blocks.Last()
+	var b *streamBlock
+	if exist {
+		b = v.(*streamBlock)
+	}
+
+	if b == nil || b.Count+b.Deleted >= config.StreamNodeMaxEntries || len(b.Entries.Data) >= config.StreamNodeMaxBytes {
+		b = createStreamBlock(id, fields)
+		s.Blocks.Insert(id.encode(), b)
+	}
+
+	b.append(id, fields)
+	s.Length++
+	s.LastID = id
+	s.EntriesAdded++
+}
+
+/*
+The entries with an ID between start and end (included), at most count of them if count > 0.
+With reverse, the entries are returned from end to start.
+*/
+func (s *Stream) Range(start StreamID, end StreamID, count int, reverse bool) []StreamEntry {
+	res := make([]StreamEntry, 0)
+	if start.Compare(end) > 0 {
+		return res
+	}
+
+	// add the entries of the block in the range, return false once the range or the count is exhausted
+	visit := func(_ []byte, v interface{}) bool {
+		entries := v.(*streamBlock).entries()
+		for i := range entries {
+			e := entries[i]
+			if reverse {
+				e = entries[len(entries)-1-i]
+			}
+
+			if (!reverse && e.ID.Compare(end) > 0) || (reverse && e.ID.Compare(start) < 0) {
+				return false
+			}
+			if e.deleted || e.ID.Compare(start) < 0 || e.ID.Compare(end) > 0 {
+				continue
+			}
+
+			res = append(res, e.StreamEntry)
+			if count > 0 && len(res) >= count {
+				return false
+			}
+		}
+		return true
+	}
+
+	if reverse {
+		s.Blocks.WalkBackFrom(end.encode(), visit)
+	} else {
+		// the block holding start begins before it
+		from := start.encode()
+		if key, _, exist := s.Blocks.Floor(from); exist {
+			from = key
+		}
+		s.Blocks.WalkFrom(from, visit)
+	}
+
+	return res
+}
+
+// the first entry, false if the stream is empty
+func (s *Stream) First() (StreamEntry, bool) {
+	entries := s.Range(StreamMinID, StreamMaxID, 1, false)
+	if len(entries) == 0 {
+		return StreamEntry{}, false
+	}
+
+	return entries[0], true
+}
+
+// the last entry, false if the stream is empty
+func (s *Stream) Last() (StreamEntry, bool) {
+	entries := s.Range(StreamMinID, StreamMaxID, 1, true)
+	if len(entries) == 0 {
+		return StreamEntry{}, false
+	}
+
+	return entries[0], true
+}
+
+// delete the entry with the given ID, return false if it does not exist
+func (s *Stream) Delete(id StreamID) bool {
+	key, v, exist := s.Blocks.Floor(id.encode())
+	if !exist {
+		return false
+	}
+
+	b := v.(*streamBlock)
+	for _, e := range b.entries() {
+		if e.ID == id && !e.deleted {
+			b.markDeleted([]int{e.flagsIdx})
+			if b.Count == 0 {
+				s.Blocks.Remove(key)
+			}
+
+			s.Length--
+			if id.Compare(s.MaxDeletedID) > 0 {
+				s.MaxDeletedID = id
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Remove the first entries while shouldRemove is true, it is given the ID of the entry and the length of the stream.
+Whole blocks are removed first: with approx, the trimming stops at the first block that can not be removed entirely.
+If limit > 0, at most limit entries are removed. Return the number of removed entries.
+*/
+func (s *Stream) trim(shouldRemove func(id StreamID, length uint64) bool, approx bool, limit int64) int64 {
+	var removed int64 = 0
+	for {
+		key, v, exist := s.Blocks.First()
+		if !exist {
+			return removed
+		}
+		b := v.(*streamBlock)
+
+		// the valid entries of the block
+		entries := b.entries()
+		valid := make([]streamBlockEntry, 0, b.Count)
+		for _, e := range entries {
+			if !e.deleted {
+				valid = append(valid, e)
+			}
+		}
+
+		// the whole block can be removed if its last entry can be removed once all the previous ones are
+		last := valid[len(valid)-1]
+		if shouldRemove(last.ID, s.Length-uint64(len(valid))+1) {
+			if limit > 0 && removed+int64(len(valid)) > limit {
+				return removed
+			}
+			s.Blocks.Remove(key)
+			s.Length -= uint64(len(valid))
+			removed += int64(len(valid))
+			continue
+		}
+
+		if approx {
+			return removed
+		}
+
+		flagsIdxs := make([]int, 0)
+		for _, e := range valid {
+			if !shouldRemove(e.ID, s.Length-uint64(len(flagsIdxs))) || (limit > 0 && removed+int64(len(flagsIdxs)) >= limit) {
+				break
+			}
+			flagsIdxs = append(flagsIdxs, e.flagsIdx)
+		}
+		if len(flagsIdxs) > 0 {
+			b.markDeleted(flagsIdxs)
+			s.Length -= uint64(len(flagsIdxs))
+			removed += int64(len(flagsIdxs))
+		}
+
+		return removed
+	}
+}
+
+// remove the oldest entries so that at most maxLen are left
+func (s *Stream) TrimByMaxLen(maxLen uint64, approx bool, limit int64) int64 {
+	return s.trim(func(_ StreamID, length uint64) bool {
+		return length > maxLen
+	}, approx, limit)
+}
+
+// remove the entries with an ID lower than minID
+func (s *Stream) TrimByMinID(minID StreamID, approx bool, limit int64) int64 {
+	return s.trim(func(id StreamID, _ uint64) bool {
+		return id.Compare(minID) < 0
+	}, approx, limit)
+}
@@ -0,0 +1,210 @@
+package data_structure
+
+import (
+	"mtredis/internal/config"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// a stream of n entries with the IDs 1-0 to n-0, every entry has the fields f and g with the values of its ms
+func createTestStream(n int) *Stream {
+	s := CreateStream()
+	for i := 1; i <= n; i++ {
+		v := strconv.Itoa(i)
+		s.Append(StreamID{Ms: uint64(i)}, []string{"f", v, "g", v})
+	}
+
+	return s
+}
+
+func streamIDs(entries []StreamEntry) []uint64 {
+	res := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e.ID.Ms)
+	}
+
+	return res
+}
+
+func idRange(from uint64, to uint64) []uint64 {
+	res := make([]uint64, 0)
+	for i := from; i <= to; i++ {
+		res = append(res, i)
+	}
+
+	return res
+}
+
+func TestStreamIDOrder(t *testing.T) {
+	for _, tt := range []struct {
+		a, b StreamID
+		want int
+	}{
+		{StreamID{1, 5}, StreamID{2, 0}, -1},
+		{StreamID{2, 0}, StreamID{1, 5}, 1},
+		{StreamID{1, 5}, StreamID{1, 6}, -1},
+		{StreamID{1, 5}, StreamID{1, 5}, 0},
+	} {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%v.Compare(%v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	if next, ok := (StreamID{1, 1<<64 - 1}).Next(); !ok || next != (StreamID{2, 0}) {
+		t.Errorf("Next() = %v, %v, want 2-0", next, ok)
+	}
+	if _, ok := StreamMaxID.Next(); ok {
+		t.Errorf("the max ID has a next ID")
+	}
+	if prev, ok := (StreamID{2, 0}).Prev(); !ok || prev != (StreamID{1, 1<<64 - 1}) {
+		t.Errorf("Prev() = %v, %v", prev, ok)
+	}
+	if _, ok := StreamMinID.Prev(); ok {
+		t.Errorf("the min ID has a previous ID")
+	}
+	if s := (StreamID{1526919030474, 55}).String(); s != "1526919030474-55" {
+		t.Errorf("String() = %s", s)
+	}
+}
+
+func TestStreamBlocks(t *testing.T) {
+	s := createTestStream(250)
+	// the blocks are full at config.StreamNodeMaxEntries entries
+	if s.Blocks.Len() != 3 || s.Length != 250 || s.LastID != (StreamID{Ms: 250}) {
+		t.Fatalf("%d blocks, Length = %d, LastID = %v, want 3, 250, 250-0", s.Blocks.Len(), s.Length, s.LastID)
+	}
+
+	// the entries having the master fields only store their values
+	s.Append(StreamID{Ms: 251}, []string{"other", "x"})
+	s.Append(StreamID{Ms: 251, Seq: 1}, []string{"g", "y", "f", "z"})
+	last := s.Range(StreamID{Ms: 249}, StreamMaxID, 0, false)
+	want := [][]string{{"f", "249", "g", "249"}, {"f", "250", "g", "250"}, {"other", "x"}, {"g", "y", "f", "z"}}
+	if len(last) != len(want) {
+		t.Fatalf("Range returned %d entries, want %d", len(last), len(want))
+	}
+	for i, e := range last {
+		if !slices.Equal(e.Fields, want[i]) {
+			t.Errorf("entry %v has the fields %v, want %v", e.ID, e.Fields, want[i])
+		}
+	}
+
+	// a block is also full at config.StreamNodeMaxBytes bytes
+	big := CreateStream()
+	value := make([]byte, config.StreamNodeMaxBytes/4)
+	for i := 1; i <= 10; i++ {
+		big.Append(StreamID{Ms: uint64(i)}, []string{"f", string(value)})
+	}
+	if big.Blocks.Len() < 3 {
+		t.Fatalf("%d blocks for 10 entries of %d bytes", big.Blocks.Len(), len(value))
+	}
+}
+
+func TestStreamRange(t *testing.T) {
+	s := createTestStream(250)
+
+	for _, tt := range []struct {
+		name       string
+		start, end StreamID
+		count      int
+		reverse    bool
+		want       []uint64
+	}{
+		{"all", StreamMinID, StreamMaxID, 0, false, idRange(1, 250)},
+		{"across the blocks", StreamID{Ms: 95}, StreamID{Ms: 205}, 0, false, idRange(95, 205)},
+		{"count", StreamID{Ms: 99}, StreamMaxID, 3, false, []uint64{99, 100, 101}},
+		{"between two IDs", StreamID{Ms: 10, Seq: 1}, StreamID{Ms: 12}, 0, false, []uint64{11, 12}},
+		{"reverse", StreamID{Ms: 99}, StreamID{Ms: 102}, 0, true, []uint64{102, 101, 100, 99}},
+		{"reverse count", StreamMinID, StreamMaxID, 2, true, []uint64{250, 249}},
+		{"empty", StreamID{Ms: 300}, StreamMaxID, 0, false, []uint64{}},
+		{"start after end", StreamID{Ms: 5}, StreamID{Ms: 4}, 0, false, []uint64{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := streamIDs(s.Range(tt.start, tt.end, tt.count, tt.reverse)); !slices.Equal(got, tt.want) {
+				t.Fatalf("Range = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamDelete(t *testing.T) {
+	s := createTestStream(200)
+
+	if !s.Delete(StreamID{Ms: 150}) || s.Delete(StreamID{Ms: 150}) || s.Delete(StreamID{Ms: 150, Seq: 1}) {
+		t.Fatalf("Delete does not report the deleted entries")
+	}
+	if s.Length != 199 || s.MaxDeletedID != (StreamID{Ms: 150}) {
+		t.Fatalf("Length = %d, MaxDeletedID = %v after a deletion", s.Length, s.MaxDeletedID)
+	}
+	if got := streamIDs(s.Range(StreamID{Ms: 149}, StreamID{Ms: 151}, 0, false)); !slices.Equal(got, []uint64{149, 151}) {
+		t.Fatalf("Range = %v, want [149 151]", got)
+	}
+
+	// the block is removed with its last entry
+	for i := 1; i <= 100; i++ {
+		s.Delete(StreamID{Ms: uint64(i)})
+	}
+	if s.Blocks.Len() != 1 || s.Length != 99 {
+		t.Fatalf("%d blocks, Length = %d, want 1, 99", s.Blocks.Len(), s.Length)
+	}
+	if e, _ := s.First(); e.ID != (StreamID{Ms: 101}) {
+		t.Fatalf("First() = %v, want 101-0", e.ID)
+	}
+	// the last ID stays when the last entry is deleted
+	s.Delete(StreamID{Ms: 200})
+	if e, _ := s.Last(); e.ID != (StreamID{Ms: 199}) || s.LastID != (StreamID{Ms: 200}) {
+		t.Fatalf("Last() = %v, LastID = %v, want 199-0 and 200-0", e.ID, s.LastID)
+	}
+}
+
+func TestStreamTrim(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		trim    func(s *Stream) int64
+		removed int64
+		first   uint64
+		blocks  int
+	}{
+		{"maxlen", func(s *Stream) int64 { return s.TrimByMaxLen(120, false, 0) }, 130, 131, 2},
+		// the approximate trimming only removes whole blocks
+		{"approx maxlen", func(s *Stream) int64 { return s.TrimByMaxLen(120, true, 0) }, 100, 101, 2},
+		{"maxlen 0", func(s *Stream) int64 { return s.TrimByMaxLen(0, false, 0) }, 250, 0, 0},
+		{"maxlen over the length", func(s *Stream) int64 { return s.TrimByMaxLen(300, false, 0) }, 0, 1, 3},
+		{"minid", func(s *Stream) int64 { return s.TrimByMinID(StreamID{Ms: 220}, false, 0) }, 219, 220, 1},
+		{"approx minid", func(s *Stream) int64 { return s.TrimByMinID(StreamID{Ms: 220}, true, 0) }, 200, 201, 1},
+		// the limit stops before a block that can not be removed entirely
+		{"limit", func(s *Stream) int64 { return s.TrimByMaxLen(0, true, 150) }, 100, 101, 2},
+		{"limit below a block", func(s *Stream) int64 { return s.TrimByMaxLen(0, true, 99) }, 0, 1, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := createTestStream(250)
+			if removed := tt.trim(s); removed != tt.removed {
+				t.Fatalf("%d entries removed, want %d", removed, tt.removed)
+			}
+			if s.Length != uint64(250-tt.removed) || s.Blocks.Len() != tt.blocks {
+				t.Fatalf("Length = %d with %d blocks, want %d with %d", s.Length, s.Blocks.Len(), 250-tt.removed, tt.blocks)
+			}
+			if e, ok := s.First(); (ok && e.ID.Ms != tt.first) || (!ok && tt.first != 0) {
+				t.Fatalf("First() = %v, %v, want %d-0", e.ID, ok, tt.first)
+			}
+			if got := len(s.Range(StreamMinID, StreamMaxID, 0, false)); got != int(s.Length) {
+				t.Fatalf("Range returned %d entries, Length = %d", got, s.Length)
+			}
+		})
+	}
+}
+
+// the deleted entries count for nothing when trimming
+func TestStreamTrimAfterDelete(t *testing.T) {
+	s := createTestStream(250)
+	for i := 101; i <= 190; i++ {
+		s.Delete(StreamID{Ms: uint64(i)})
+	}
+
+	if removed := s.TrimByMaxLen(55, false, 0); removed != 105 {
+		t.Fatalf("%d entries removed, want 105", removed)
+	}
+	if got := streamIDs(s.Range(StreamMinID, StreamMaxID, 0, false)); !slices.Equal(got, idRange(196, 250)) {
+		t.Fatalf("the entries left are %v, want 196 to 250", got)
+	}
+}
@@ -0,0 +1,319 @@
+package data_structure
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+A t-digest estimates the quantiles of a stream of values, with a better accuracy at the extreme quantiles (p1, p99...).
+The values are summarized by centroids (a mean and a weight): the centroids near the median can absorb many values,
+while the ones near the tails stay small. This is the merging variant: new values are buffered as unmerged centroids,
+and once the buffer is full all the centroids are sorted and merged greedily, a centroid absorbing its neighbour
+as long as its weight stays below the bound given by the compression at its quantile.
+A higher compression keeps more centroids, it is more accurate and takes more memory.
+*/
+type TDigest struct {
+	Compression       float64
+	Capacity          int       // max number of centroids, merged and unmerged
+	Means             []float64 // the merged centroids sorted by mean, then the unmerged ones
+	Weights           []float64
+	MergedNodes       int
+	UnmergedNodes     int
+	MergedWeight      float64
+	UnmergedWeight    float64
+	TotalCompressions int64
+	Min               float64
+	Max               float64
+}
+
+func TDigestCapacity(compression float64) int {
+	return int(6*compression) + 10
+}
+
+func CreateTDigest(compression float64) *TDigest {
+	t := &TDigest{
+		Compression: compression,
+		Capacity:    TDigestCapacity(compression),
+	}
+	t.Reset()
+
+	return t
+}
+
+// remove all the values, the compression is kept
+func (t *TDigest) Reset() {
+	t.Means = make([]float64, 0, t.Capacity)
+	t.Weights = make([]float64, 0, t.Capacity)
+	t.MergedNodes, t.UnmergedNodes = 0, 0
+	t.MergedWeight, t.UnmergedWeight = 0, 0
+	t.TotalCompressions = 0
+	t.Min, t.Max = math.Inf(1), math.Inf(-1)
+}
+
+// the number of values added
+func (t *TDigest) Size() float64 {
+	return t.MergedWeight + t.UnmergedWeight
+}
+
+func (t *TDigest) add(mean float64, weight float64) {
+	if t.MergedNodes+t.UnmergedNodes >= t.Capacity {
+		t.Compress()
+	}
+
+	t.Means = append(t.Means, mean)
+	t.Weights = append(t.Weights, weight)
+	t.UnmergedNodes++
+	t.UnmergedWeight += weight
+	t.Min = min(t.Min, mean)
+	t.Max = max(t.Max, mean)
+}
+
+func (t *TDigest) Add(value float64) {
+	t.add(value, 1)
+}
+
+// add the centroids of the other digests, the compression is kept
+func (t *TDigest) Merge(others []*TDigest) {
+	for _, other := range others {
+		other.Compress()
+		for i := 0; i < other.MergedNodes; i++ {
+			t.add(other.Means[i], other.Weights[i])
+		}
+		// the extreme values are not centroids, they are kept apart
+		t.Min = min(t.Min, other.Min)
+		t.Max = max(t.Max, other.Max)
+	}
+	t.Compress()
+}
+
+// merge the unmerged centroids into the merged ones
+func (t *TDigest) Compress() {
+	if t.UnmergedNodes == 0 {
+		return
+	}
+
+	n := t.MergedNodes + t.UnmergedNodes
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return t.Means[order[i]] < t.Means[order[j]]
+	})
+
+	total := t.MergedWeight + t.UnmergedWeight
+	// the weight of a centroid at the quantile q is bounded by q * (1 - q) / normalizer
+	normalizer := t.Compression / (2 * math.Pi * total * math.Log(total))
+
+	means := make([]float64, 0, t.Capacity)
+	weights := make([]float64, 0, t.Capacity)
+	means = append(means, t.Means[order[0]])
+	weights = append(weights, t.Weights[order[0]])
+	weightSoFar := 0.0
+	for _, i := range order[1:] {
+		cur := len(means) - 1
+		proposed := weights[cur] + t.Weights[i]
+		z := proposed * normalizer
+		q0 := weightSoFar / total
+		q2 := (weightSoFar + proposed) / total
+
+		if z <= q0*(1-q0) && z <= q2*(1-q2) {
+			weights[cur] = proposed
+			means[cur] += (t.Means[i] - means[cur]) * t.Weights[i] / proposed
+		} else {
+			weightSoFar += weights[cur]
+			means = append(means, t.Means[i])
+			weights = append(weights, t.Weights[i])
+		}
+	}
+
+	t.Means, t.Weights = means, weights
+	t.MergedNodes, t.UnmergedNodes = len(means), 0
+	t.MergedWeight, t.UnmergedWeight = total, 0
+	t.TotalCompressions++
+}
+
+func weightedAverage(x1 float64, w1 float64, x2 float64, w2 float64) float64 {
+	if x1 > x2 {
+		x1, w1, x2, w2 = x2, w2, x1, w1
+	}
+	x := (x1*w1 + x2*w2) / (w1 + w2)
+
+	return max(x1, min(x, x2))
+}
+
+// the estimated value at the quantile q in [0, 1], NaN if the digest is empty
+func (t *TDigest) Quantile(q float64) float64 {
+	t.Compress()
+
+	n := t.MergedNodes
+	if n == 0 {
+		return math.NaN()
+	}
+	if n == 1 {
+		return t.Means[0]
+	}
+
+	total := t.MergedWeight
+	index := q * total
+	if index < 1 {
+		return t.Min
+	}
+	if index >= total-1 {
+		return t.Max
+	}
+
+	means, weights := t.Means, t.Weights
+
+	// between the min and the first centroid, the first value is exactly the min
+	if weights[0] > 1 && index < weights[0]/2 {
+		return t.Min + (index-1)/(weights[0]/2-1)*(means[0]-t.Min)
+	}
+	// between the last centroid and the max
+	if weights[n-1] > 1 && total-index <= weights[n-1]/2 {
+		return t.Max - (total-index-1)/(weights[n-1]/2-1)*(t.Max-means[n-1])
+	}
+
+	// interpolate between the centers of the two centroids around the index
+	weightSoFar := weights[0] / 2
+	for i := 0; i < n-1; i++ {
+		dw := (weights[i] + weights[i+1]) / 2
+		if weightSoFar+dw > index {
+			// a centroid of weight 1 is a single value, it is not spread around its mean
+			leftUnit := 0.0
+			if weights[i] == 1 {
+				if index-weightSoFar < 0.5 {
+					return means[i]
+				}
+				leftUnit = 0.5
+			}
+			rightUnit := 0.0
+			if weights[i+1] == 1 {
+				if weightSoFar+dw-index <= 0.5 {
+					return means[i+1]
+				}
+				rightUnit = 0.5
+			}
+			z1 := index - weightSoFar - leftUnit
+			z2 := weightSoFar + dw - index - rightUnit
+
+			return weightedAverage(means[i], z2, means[i+1], z1)
+		}
+		weightSoFar += dw
+	}
+
+	z1 := index - total - weights[n-1]/2
+	z2 := weights[n-1]/2 - z1
+
+	return weightedAverage(means[n-1], z1, t.Max, z2)
+}
+
+/*
+The estimated fraction of the values lower than x, plus half the fraction of the values equal to x.
+NaN if the digest is empty.
+*/
+func (t *TDigest) CDF(x float64) float64 {
+	t.Compress()
+
+	n := t.MergedNodes
+	if n == 0 {
+		return math.NaN()
+	}
+	if x < t.Min {
+		return 0
+	}
+	if x > t.Max {
+		return 1
+	}
+
+	total := t.MergedWeight
+	if n == 1 {
+		if t.Max-t.Min == 0 {
+			return 0.5
+		}
+		return (x - t.Min) / (t.Max - t.Min)
+	}
+
+	means, weights := t.Means, t.Weights
+
+	// before the center of the first centroid, the min is a single value
+	if x < means[0] {
+		if x == t.Min {
+			return 0.5 / total
+		}
+		return (1 + (x-t.Min)/(means[0]-t.Min)*(weights[0]/2-1)) / total
+	}
+	// after the center of the last centroid, the max is a single value
+	if x > means[n-1] {
+		if x == t.Max {
+			return 1 - 0.5/total
+		}
+		return 1 - (1+(t.Max-x)/(t.Max-means[n-1])*(weights[n-1]/2-1))/total
+	}
+
+	weightSoFar := 0.0
+	for i := 0; i < n; i++ {
+		if means[i] == x {
+			// the centroids having exactly this mean count for half
+			dw := 0.0
+			for ; i < n && means[i] == x; i++ {
+				dw += weights[i]
+			}
+			return (weightSoFar + dw/2) / total
+		}
+		if i+1 < n && means[i] < x && x < means[i+1] {
+			leftExcluded, rightExcluded := 0.0, 0.0
+			if weights[i] == 1 {
+				if weights[i+1] == 1 {
+					// two single values and nothing in between
+					return (weightSoFar + 1) / total
+				}
+				leftExcluded = 0.5
+			} else if weights[i+1] == 1 {
+				rightExcluded = 0.5
+			}
+
+			dw := (weights[i]+weights[i+1])/2 - leftExcluded - rightExcluded
+			base := weightSoFar + weights[i]/2 + leftExcluded
+
+			return (base + dw*(x-means[i])/(means[i+1]-means[i])) / total
+		}
+		weightSoFar += weights[i]
+	}
+
+	return 1
+}
+
+/*
+The mean of the values between the quantiles low and high, the centroids on the boundaries count for the part
+of their weight inside the range. NaN if the digest is empty.
+*/
+func (t *TDigest) TrimmedMean(low float64, high float64) float64 {
+	t.Compress()
+
+	if t.MergedNodes == 0 {
+		return math.NaN()
+	}
+
+	total := t.MergedWeight
+	lowWeight, highWeight := low*total, high*total
+
+	sum, count := 0.0, 0.0
+	weightSoFar := 0.0
+	for i := 0; i < t.MergedNodes; i++ {
+		from, to := weightSoFar, weightSoFar+t.Weights[i]
+		weightSoFar = to
+
+		inside := min(to, highWeight) - max(from, lowWeight)
+		if inside > 0 {
+			sum += inside * t.Means[i]
+			count += inside
+		}
+	}
+	if count == 0 {
+		return math.NaN()
+	}
+
+	return sum / count
+}
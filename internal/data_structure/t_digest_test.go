@@ -0,0 +1,155 @@
+package data_structure
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// a digest of the values 0 to n-1 added in a random order
+func uniformTDigest(compression float64, n int) *TDigest {
+	t := CreateTDigest(compression)
+	for _, v := range rand.New(rand.NewSource(1)).Perm(n) {
+		t.Add(float64(v))
+	}
+
+	return t
+}
+
+func TestTDigestEmpty(t *testing.T) {
+	td := CreateTDigest(100)
+	if !math.IsNaN(td.Quantile(0.5)) || !math.IsNaN(td.CDF(1)) || !math.IsNaN(td.TrimmedMean(0.1, 0.9)) {
+		t.Fatalf("an empty digest does not give NaN")
+	}
+	if td.Size() != 0 {
+		t.Fatalf("Size() = %v for an empty digest", td.Size())
+	}
+}
+
+func TestTDigestQuantile(t *testing.T) {
+	td := uniformTDigest(100, 100000)
+	if td.Min != 0 || td.Max != 99999 || td.Size() != 100000 {
+		t.Fatalf("Min, Max, Size() = %v, %v, %v, want 0, 99999, 100000", td.Min, td.Max, td.Size())
+	}
+	if td.Quantile(0) != 0 || td.Quantile(1) != 99999 {
+		t.Fatalf("the extreme quantiles are %v and %v, want the min and the max", td.Quantile(0), td.Quantile(1))
+	}
+
+	// the error is much smaller at the tails than around the median
+	for _, tt := range []struct {
+		q         float64
+		tolerance float64
+	}{
+		{0.001, 10},
+		{0.01, 50},
+		{0.1, 300},
+		{0.5, 500},
+		{0.9, 300},
+		{0.99, 50},
+		{0.999, 10},
+	} {
+		want := tt.q * 100000
+		if got := td.Quantile(tt.q); math.Abs(got-want) > tt.tolerance {
+			t.Errorf("Quantile(%v) = %v, want %v ± %v", tt.q, got, want, tt.tolerance)
+		}
+		if got := td.CDF(want); math.Abs(got-tt.q) > tt.tolerance/100000 {
+			t.Errorf("CDF(%v) = %v, want %v", want, got, tt.q)
+		}
+	}
+	if got := td.TrimmedMean(0.1, 0.9); math.Abs(got-49999.5) > 100 {
+		t.Errorf("TrimmedMean(0.1, 0.9) = %v, want about 49999.5", got)
+	}
+}
+
+// the values of a small digest are all kept, its quantiles are exact
+func TestTDigestExactValues(t *testing.T) {
+	td := CreateTDigest(100)
+	for v := 1; v <= 10; v++ {
+		td.Add(float64(v))
+	}
+
+	for _, tt := range []struct {
+		q    float64
+		want float64
+	}{
+		{0, 1}, {0.05, 1}, {0.15, 2}, {0.5, 6}, {0.95, 10}, {1, 10},
+	} {
+		if got := td.Quantile(tt.q); got != tt.want {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := td.CDF(5); got != 0.45 {
+		t.Errorf("CDF(5) = %v, want 0.45", got)
+	}
+	if got := td.TrimmedMean(0, 1); got != 5.5 {
+		t.Errorf("TrimmedMean(0, 1) = %v, want 5.5", got)
+	}
+}
+
+func TestTDigestCompress(t *testing.T) {
+	td := CreateTDigest(50)
+	for v := range 100000 {
+		td.Add(float64(v))
+		if td.MergedNodes+td.UnmergedNodes > td.Capacity {
+			t.Fatalf("%d centroids after %d values, the capacity is %d", td.MergedNodes+td.UnmergedNodes, v+1, td.Capacity)
+		}
+	}
+	if td.TotalCompressions == 0 {
+		t.Fatalf("the digest was never compressed")
+	}
+
+	td.Compress()
+	if td.UnmergedNodes != 0 || td.UnmergedWeight != 0 || td.MergedWeight != 100000 {
+		t.Fatalf("Compress left %d unmerged nodes, the merged weight is %v", td.UnmergedNodes, td.MergedWeight)
+	}
+	for i := 1; i < td.MergedNodes; i++ {
+		if td.Means[i] < td.Means[i-1] {
+			t.Fatalf("the merged centroids are not sorted at %d", i)
+		}
+	}
+	// the centroids are far fewer than the values
+	if td.MergedNodes > 2*int(td.Compression) {
+		t.Fatalf("%d merged centroids with a compression of %v", td.MergedNodes, td.Compression)
+	}
+}
+
+func TestTDigestMerge(t *testing.T) {
+	low, high := CreateTDigest(100), CreateTDigest(100)
+	for v := range 50000 {
+		low.Add(float64(v))
+		high.Add(float64(50000 + v))
+	}
+
+	merged := CreateTDigest(100)
+	merged.Merge([]*TDigest{low, high})
+	if merged.Size() != 100000 || merged.Min != 0 || merged.Max != 99999 {
+		t.Fatalf("Size(), Min, Max = %v, %v, %v, want 100000, 0, 99999", merged.Size(), merged.Min, merged.Max)
+	}
+	if got := merged.Quantile(0.5); math.Abs(got-50000) > 500 {
+		t.Fatalf("Quantile(0.5) = %v, want about 50000", got)
+	}
+	if got := merged.Quantile(0.01); math.Abs(got-1000) > 30 {
+		t.Fatalf("Quantile(0.01) = %v, want about 1000", got)
+	}
+
+	// the sources are not changed
+	if low.Size() != 50000 || low.Max != 49999 || high.Min != 50000 {
+		t.Fatalf("Merge changed the sources")
+	}
+}
+
+func TestTDigestReset(t *testing.T) {
+	td := uniformTDigest(200, 1000)
+	td.Reset()
+	if td.Size() != 0 || td.MergedNodes != 0 || td.UnmergedNodes != 0 || td.TotalCompressions != 0 {
+		t.Fatalf("Reset kept the values")
+	}
+	if td.Compression != 200 || td.Capacity != TDigestCapacity(200) {
+		t.Fatalf("Reset changed the compression to %v", td.Compression)
+	}
+
+	td.Add(7)
+	if td.Min != 7 || td.Max != 7 || td.Quantile(0.5) != 7 {
+		t.Fatalf("the min and the max are not reset")
+	}
+}
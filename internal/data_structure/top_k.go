@@ -0,0 +1,199 @@
+package data_structure
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// the seed of the hash giving the fingerprint of an item, the rows use their index as seed
+const topKFingerprintSeed = 1919
+
+type topKBucket struct {
+	Fp    uint32
+	Count uint32
+}
+
+type TopKItem struct {
+	Item  string
+	Fp    uint32
+	Count uint32
+}
+
+/*
+Top-K keeps the K items with the highest counts, using HeavyKeeper.
+The sketch is a matrix of Depth rows of Width buckets, each holding a fingerprint and a count.
+An item is hashed to one bucket per row: the count is incremented if the bucket is empty or holds the item's fingerprint,
+otherwise the count of the other item decays by one with a probability of Decay^count, and the bucket is taken over
+once its count reaches 0. Big counts are unlikely to decay, so heavy items keep their buckets while small ones fade out.
+The current leaders are kept in a min-heap of K items, ordered by their count in the sketch.
+*/
+type TopK struct {
+	K      uint32
+	Width  uint32
+	Depth  uint32
+	Decay  float64
+	Array  []topKBucket // depth rows of width buckets
+	Heap   []TopKItem   // min-heap on Count, empty slots have a count of 0
+	lookup []float64    // Decay^i for the small counts
+}
+
+func CreateTopK(k uint32, width uint32, depth uint32, decay float64) *TopK {
+	t := &TopK{
+		K:      k,
+		Width:  width,
+		Depth:  depth,
+		Decay:  decay,
+		Array:  make([]topKBucket, uint64(width)*uint64(depth)),
+		Heap:   make([]TopKItem, k),
+		lookup: make([]float64, 256),
+	}
+	for i := range t.lookup {
+		t.lookup[i] = math.Pow(decay, float64(i))
+	}
+
+	return t
+}
+
+func (t *TopK) decayProbability(count uint32) float64 {
+	if count < uint32(len(t.lookup)) {
+		return t.lookup[count]
+	}
+
+	return math.Pow(t.Decay, float64(count))
+}
+
+func topKFingerprint(item string) uint32 {
+	return murmurHash2([]byte(item), topKFingerprintSeed)
+}
+
+func (t *TopK) bucket(item string, row uint32) *topKBucket {
+	return &t.Array[uint64(row)*uint64(t.Width)+uint64(murmurHash2([]byte(item), row)%t.Width)]
+}
+
+// restore the heap order from the slot i downwards
+func (t *TopK) heapifyDown(i int) {
+	n := len(t.Heap)
+	for {
+		smallest := i
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < n && t.Heap[child].Count < t.Heap[smallest].Count {
+				smallest = child
+			}
+		}
+		if smallest == i {
+			return
+		}
+		t.Heap[i], t.Heap[smallest] = t.Heap[smallest], t.Heap[i]
+		i = smallest
+	}
+}
+
+// restore the heap order from the slot i upwards
+func (t *TopK) heapifyUp(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if t.Heap[parent].Count <= t.Heap[i].Count {
+			return
+		}
+		t.Heap[i], t.Heap[parent] = t.Heap[parent], t.Heap[i]
+		i = parent
+	}
+}
+
+// the slot of the item in the heap, -1 if it is not a leader
+func (t *TopK) heapIndex(item string, fp uint32) int {
+	for i := range t.Heap {
+		if t.Heap[i].Count > 0 && t.Heap[i].Fp == fp && t.Heap[i].Item == item {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// increase the count of the item, return the item expelled from the leaders to make room for it, if any
+func (t *TopK) IncrBy(item string, increment uint32) (string, bool) {
+	fp := topKFingerprint(item)
+
+	var maxCount uint32 = 0
+	for row := uint32(0); row < t.Depth; row++ {
+		b := t.bucket(item, row)
+		switch {
+		case b.Count == 0:
+			b.Fp, b.Count = fp, increment
+		case b.Fp == fp:
+			b.Count = uint32(min(uint64(b.Count)+uint64(increment), math.MaxUint32))
+		default:
+			for incr := increment; incr > 0; incr-- {
+				if rand.Float64() < t.decayProbability(b.Count) {
+					b.Count--
+					if b.Count == 0 {
+						b.Fp, b.Count = fp, incr
+						break
+					}
+				}
+			}
+		}
+		if b.Fp == fp {
+			maxCount = max(maxCount, b.Count)
+		}
+	}
+
+	if maxCount == 0 || maxCount < t.Heap[0].Count {
+		return "", false
+	}
+
+	// a leader gets its new count (it can be lower if one of its buckets has decayed),
+	// else the item takes the place of the smallest leader
+	if i := t.heapIndex(item, fp); i >= 0 {
+		old := t.Heap[i].Count
+		t.Heap[i].Count = maxCount
+		if maxCount < old {
+			t.heapifyUp(i)
+		} else {
+			t.heapifyDown(i)
+		}
+		return "", false
+	}
+
+	expelled, hasExpelled := t.Heap[0].Item, t.Heap[0].Count > 0
+	t.Heap[0] = TopKItem{Item: item, Fp: fp, Count: maxCount}
+	t.heapifyDown(0)
+
+	return expelled, hasExpelled
+}
+
+// whether the item is one of the leaders
+func (t *TopK) Query(item string) bool {
+	return t.heapIndex(item, topKFingerprint(item)) >= 0
+}
+
+// the estimated count of the item in the sketch, the max count of the buckets holding its fingerprint
+func (t *TopK) Count(item string) uint32 {
+	fp := topKFingerprint(item)
+
+	var res uint32 = 0
+	for row := uint32(0); row < t.Depth; row++ {
+		if b := t.bucket(item, row); b.Fp == fp {
+			res = max(res, b.Count)
+		}
+	}
+
+	return res
+}
+
+// the leaders, from the highest count to the lowest
+func (t *TopK) List() []TopKItem {
+	res := make([]TopKItem, 0, len(t.Heap))
+	for _, it := range t.Heap {
+		if it.Count > 0 {
+			res = append(res, it)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Count > res[j].Count
+	})
+
+	return res
+}
@@ -0,0 +1,89 @@
+package data_structure
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func checkTopKHeap(t *testing.T, tk *TopK) {
+	t.Helper()
+
+	for i := range tk.Heap {
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(tk.Heap) && tk.Heap[child].Count < tk.Heap[i].Count {
+				t.Fatalf("the heap slot %d has a count of %d, its child %d has %d", i, tk.Heap[i].Count, child, tk.Heap[child].Count)
+			}
+		}
+	}
+}
+
+// without collisions the counts are exact, and an item expels the smallest leader once the K slots are taken
+func TestTopKExpel(t *testing.T) {
+	tk := CreateTopK(2, 1000, 4, 0.9)
+
+	if _, expelled := tk.IncrBy("a", 5); expelled {
+		t.Fatalf("an item was expelled from an empty heap")
+	}
+	tk.IncrBy("b", 3)
+	if _, expelled := tk.IncrBy("c", 1); expelled || tk.Query("c") {
+		t.Fatalf("c with a count of 1 entered the leaders")
+	}
+
+	if item, expelled := tk.IncrBy("c", 3); !expelled || item != "b" {
+		t.Fatalf("IncrBy(c, 3) expelled %q, %v, want b", item, expelled)
+	}
+	if !tk.Query("a") || tk.Query("b") || !tk.Query("c") {
+		t.Fatalf("the leaders are not a and c")
+	}
+	if tk.Count("a") != 5 || tk.Count("b") != 3 || tk.Count("c") != 4 || tk.Count("missing") != 0 {
+		t.Fatalf("the counts are %d %d %d", tk.Count("a"), tk.Count("b"), tk.Count("c"))
+	}
+
+	list := tk.List()
+	if len(list) != 2 || list[0].Item != "a" || list[0].Count != 5 || list[1].Item != "c" || list[1].Count != 4 {
+		t.Fatalf("List() = %v, want a 5, c 4", list)
+	}
+	checkTopKHeap(t, tk)
+}
+
+// the heavy items of a skewed stream are found despite the collisions of a small sketch
+func TestTopKHeavyHitters(t *testing.T) {
+	tk := CreateTopK(10, 50, 5, 0.9)
+	rng := rand.New(rand.NewSource(1))
+
+	for range 50000 {
+		// the items 0 to 9 are much more frequent than the 1000 other ones
+		item := "heavy" + strconv.Itoa(rng.Intn(10))
+		if rng.Intn(4) == 0 {
+			item = "light" + strconv.Itoa(rng.Intn(1000))
+		}
+		tk.IncrBy(item, 1)
+		if len(tk.Heap) != 10 {
+			t.Fatalf("the heap has %d slots, want 10", len(tk.Heap))
+		}
+	}
+	checkTopKHeap(t, tk)
+
+	for i := range 10 {
+		if !tk.Query("heavy" + strconv.Itoa(i)) {
+			t.Fatalf("heavy%d is not one of the leaders %v", i, tk.List())
+		}
+	}
+	list := tk.List()
+	for i := 1; i < len(list); i++ {
+		if list[i].Count > list[i-1].Count {
+			t.Fatalf("List() is not sorted by count: %v", list)
+		}
+	}
+}
+
+func TestTopKSaturation(t *testing.T) {
+	tk := CreateTopK(1, 10, 1, 0.9)
+	tk.IncrBy("a", 1<<31)
+	tk.IncrBy("a", 1<<31)
+	tk.IncrBy("a", 10)
+	if c := tk.Count("a"); c != 1<<32-1 {
+		t.Fatalf("Count(a) = %d, want the count saturated", c)
+	}
+}
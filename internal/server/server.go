@@ -58,12 +58,16 @@ func RunIOMultiplexingServer() {
 		// if the last execution is more than 100ms before, do it
 		if time.Now().After(lastActiveDeleteExpiredKeys.Add(constant.ActiveDeleteFrequency)) {
 			core.ActiveDeleteExpiredKeys()
+			core.ActiveDeleteExpiredHashFields()
 			lastActiveDeleteExpiredKeys = time.Now()
 		}
 
+		// reply to the blocked clients whose timeout is reached
+		core.HandleBlockedClientsTimeout()
+
 		// wait for file descriptors in the monitoring list to be ready for I/O
-		// this is a blocking call
-		events, err = ioMultiplexer.Wait()
+		// this is a blocking call, it returns early enough to run the next active expiration and the next blocking timeout
+		events, err = ioMultiplexer.Wait(nextTimeout(lastActiveDeleteExpiredKeys))
 		if err != nil {
 			continue
 		}
@@ -92,6 +96,7 @@ func RunIOMultiplexingServer() {
 				if err != nil {
 					if err == io.EOF || err == syscall.ECONNRESET {
 						log.Println("client disconnected")
+						core.RemoveBlockedClient(events[i].Fd)
 						_ = syscall.Close(events[i].Fd)
 						continue
 					}
@@ -108,6 +113,16 @@ func RunIOMultiplexingServer() {
 	}
 }
 
+// compute how long the event loop can wait for I/O before it has to run a timer
+func nextTimeout(lastActiveDeleteExpiredKeys time.Time) time.Duration {
+	timeout := time.Until(lastActiveDeleteExpiredKeys.Add(constant.ActiveDeleteFrequency))
+	if deadline, ok := core.NextBlockedClientDeadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+
+	return max(timeout, 0)
+}
+
 func readCommand(fd int) (*core.Command, error) {
 	var buf = make([]byte, 512)
 	n, err := syscall.Read(fd, buf)